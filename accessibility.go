@@ -0,0 +1,65 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+)
+
+// Preferences are a visitor's display choices, kept on the server for their browser session
+type Preferences struct {
+	LiveUpdatesOff bool // no auto-refresh or ticking clock, screen readers lose their place on every reload
+	ReducedMotion  bool
+}
+
+const sessionCookie = "racergo-session"
+
+// lockedPreferences looks up the preferences for the request's session, the defaults if it doesn't have one
+func (race *Race) lockedPreferences(r *http.Request) Preferences {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return Preferences{}
+	}
+	return race.preferences[cookie.Value]
+}
+
+// SetPreferences saves the preferences for the session
+func (race *Race) SetPreferences(session string, prefs Preferences) {
+	race.Lock()
+	defer race.Unlock()
+	if race.preferences == nil {
+		race.preferences = make(map[string]Preferences)
+	}
+	race.preferences[session] = prefs
+}
+
+// sessionID returns the request's session, starting one if the browser doesn't have one yet
+func sessionID(w http.ResponseWriter, r *http.Request) (string, error) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil && cookie.Value != "" {
+		return cookie.Value, nil
+	}
+	id := make([]byte, 18)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	session := base64.RawURLEncoding.EncodeToString(id)
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: session, Path: "/", HttpOnly: true})
+	return session, nil
+}
+
+func preferencesHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	session, err := sessionID(w, r)
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error starting session - %v", err)
+		return
+	}
+	race.SetPreferences(session, Preferences{
+		LiveUpdatesOff: r.FormValue("liveUpdates") == "off",
+		ReducedMotion:  r.FormValue("reducedMotion") == "on",
+	})
+	referer := r.Referer()
+	if referer == "" {
+		referer = "/"
+	}
+	http.Redirect(w, r, referer, 301)
+}
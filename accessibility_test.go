@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestPreferences(t *testing.T) {
+	race := NewRace()
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	handler(w, r, race)
+	if !strings.Contains(w.Body.String(), `<meta http-equiv="refresh"`) || !strings.Contains(w.Body.String(), `<th scope="col">Overall Place</th>`) {
+		t.Errorf("Expected live updates and labelled columns by default - %s", w.Body.String())
+	}
+
+	form := url.Values{"liveUpdates": {"off"}, "reducedMotion": {"on"}}
+	r, _ = http.NewRequest("POST", "/preferences", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Referer", "/results")
+	w = httptest.NewRecorder()
+	preferencesHandler(w, r, race)
+	if location := w.Header().Get("Location"); location != "/results" {
+		t.Errorf("Expected to go back to the page the preferences were saved from, got %q", location)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != sessionCookie {
+		t.Fatalf("Expected a session cookie, got %v", cookies)
+	}
+
+	r, _ = http.NewRequest("GET", "/", nil)
+	r.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	handler(w, r, race)
+	body := w.Body.String()
+	if strings.Contains(body, `<meta http-equiv="refresh"`) || !strings.Contains(body, "reduced-motion") || !strings.Contains(body, "Live updates are off") {
+		t.Errorf("Expected no refresh and reduced motion for the session - %s", body)
+	}
+
+	r, _ = http.NewRequest("GET", "/", nil)
+	w = httptest.NewRecorder()
+	handler(w, r, race)
+	if !strings.Contains(w.Body.String(), `<meta http-equiv="refresh"`) {
+		t.Errorf("Expected other sessions to keep live updates")
+	}
+}
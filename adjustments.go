@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AdjustTime adds a penalty or bonus to the bib's time and places them again
+func (race *Race) AdjustTime(bib Bib, adjustment Adjustment) error {
+	race.Lock()
+	defer race.Unlock()
+	return race.lockedAdjustTime(bib, adjustment)
+}
+
+func (race *Race) lockedAdjustTime(bib Bib, adjustment Adjustment) error {
+	if race.finalized {
+		return fmt.Errorf("Results have been finalized, cannot adjust a time")
+	}
+	entry, ok := race.bibbedEntries[bib]
+	if !ok {
+		return fmt.Errorf("Bib %d not found", bib)
+	}
+	adjustment.Reason = strings.TrimSpace(adjustment.Reason)
+	if adjustment.Reason == "" {
+		return fmt.Errorf("Every penalty or bonus needs a reason")
+	}
+	if adjustment.Amount == 0 {
+		return fmt.Errorf("The penalty or bonus for bib #%d has no time", bib)
+	}
+	if entry.HasFinished() && entry.AdjustedDuration()+adjustment.Amount <= 0 {
+		return fmt.Errorf("A bonus of %s is more than bib #%d's time of %s", -adjustment.Amount, bib, entry.AdjustedDuration())
+	}
+	entry.Adjustments = append(entry.Adjustments, adjustment)
+	race.lockedSortEntries()
+	race.lockedRecomputePrizes()
+	log.Printf("Bib #%d adjusted %s", bib, adjustment)
+	race.lockedRecordEvent(Event{Kind: "adjust", Bib: bib, Adjustment: &adjustment})
+	return nil
+}
+
+// RemoveAdjustment takes back one of the bib's penalties or bonuses, e.g. after a protest
+func (race *Race) RemoveAdjustment(bib Bib, index int) error {
+	race.Lock()
+	defer race.Unlock()
+	if race.finalized {
+		return fmt.Errorf("Results have been finalized, cannot adjust a time")
+	}
+	entry, ok := race.bibbedEntries[bib]
+	if !ok {
+		return fmt.Errorf("Bib %d not found", bib)
+	}
+	if index < 0 || index >= len(entry.Adjustments) {
+		return fmt.Errorf("Bib #%d has no adjustment %d", bib, index+1)
+	}
+	log.Printf("Bib #%d adjustment %s removed", bib, entry.Adjustments[index])
+	entry.Adjustments = append(entry.Adjustments[:index:index], entry.Adjustments[index+1:]...)
+	race.lockedSortEntries()
+	race.lockedRecomputePrizes()
+	race.lockedRecordEvent(Event{Kind: "removeAdjustment", Bib: bib, Index: index})
+	return nil
+}
+
+// lockedAdjusted lists the entries with penalties or bonuses in place order, for the admin page
+func (race *Race) lockedAdjusted() []*Entry {
+	var adjusted []*Entry
+	for _, e := range race.allEntries {
+		if len(e.Adjustments) > 0 {
+			adjusted = append(adjusted, e)
+		}
+	}
+	return adjusted
+}
+
+func adjustTimeHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	bib, err := strconv.Atoi(r.FormValue("bib"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting bib number", err)
+		return
+	}
+	amount, err := time.ParseDuration(strings.TrimSpace(r.FormValue("amount")))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "The time must be like 30s or 2m for a penalty or -1m for a bonus, not %s", r.FormValue("amount"))
+		return
+	}
+	if err = race.AdjustTime(Bib(bib), Adjustment{Amount: HumanDuration(amount), Reason: r.FormValue("reason")}); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/admin", 301)
+}
+
+func removeAdjustmentHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	bib, err := strconv.Atoi(r.FormValue("bib"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting bib number", err)
+		return
+	}
+	index, err := strconv.Atoi(r.FormValue("index"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting the adjustment", err)
+		return
+	}
+	if err = race.RemoveAdjustment(Bib(bib), index); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/admin", 301)
+}
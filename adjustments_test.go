@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdjustTime(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38}, {Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 31}} {
+		race.AddEntry(e)
+	}
+	startRace(race)
+	for x, bib := range []int{1, 2} {
+		*race.testingTime = race.started.Add(time.Duration(20+x) * time.Minute)
+		linkBibTesting(t, race, bib, false)
+		linkBibTesting(t, race, bib, false)
+	}
+	amy, bob := race.bibbedEntries[1], race.bibbedEntries[2]
+
+	r, _ := http.NewRequest("POST", "/adjustTime?bib=1&amount=2m&reason=missed+the+wall", nil)
+	w := httptest.NewRecorder()
+	adjustTimeHandler(w, r, race)
+	if w.Code != 301 {
+		t.Fatalf("Expected the penalty added, got %d - %s", w.Code, w.Body.String())
+	}
+	if race.allEntries[0] != bob || amy.Duration != HumanDuration(20*time.Minute) || amy.NetDuration() != HumanDuration(22*time.Minute) {
+		t.Errorf("Expected Amy's penalty to drop her behind Bob, got %s adjusted to %s", amy.Duration, amy.NetDuration())
+	}
+	for _, adj := range []Adjustment{{Amount: HumanDuration(time.Minute)}, {Reason: "nothing"}, {Amount: HumanDuration(-time.Hour), Reason: "too much"}} {
+		if err := race.AdjustTime(1, adj); err == nil {
+			t.Errorf("Expected %#v refused", adj)
+		}
+	}
+	if err := race.AdjustTime(1, Adjustment{Amount: HumanDuration(-90 * time.Second), Reason: "early start bonus"}); err != nil {
+		t.Fatalf("Error adding a bonus - %v", err)
+	}
+	if race.allEntries[0] != amy || amy.AdjustedDuration() != HumanDuration(20*time.Minute+30*time.Second) {
+		t.Errorf("Expected Amy's bonus to put her back in first, got %s", amy.AdjustedDuration())
+	}
+
+	r, _ = http.NewRequest("GET", "/finisher?bib=1", nil)
+	w = httptest.NewRecorder()
+	handler(w, r, race)
+	if page := w.Body.String(); !strings.Contains(page, "Penalty &#43;00:02:00.00 missed the wall") || !strings.Contains(page, "Bonus -00:01:30.00 early start bonus") {
+		t.Errorf("Expected the finisher page to explain Amy's time, got %s", page)
+	}
+
+	if err := race.RemoveAdjustment(1, 1); err != nil {
+		t.Fatalf("Error removing the bonus - %v", err)
+	}
+	if len(amy.Adjustments) != 1 || race.allEntries[0] != bob {
+		t.Errorf("Expected only the penalty left, got %v", amy.Adjustments)
+	}
+	replayed := NewRace()
+	if err := replayed.Replay(race.events); err != nil {
+		t.Fatalf("Error replaying - %v", err)
+	}
+	if got := replayed.bibbedEntries[1].Adjustments; len(got) != 1 || got[0].Reason != "missed the wall" || replayed.allEntries[0].Bib != 2 {
+		t.Errorf("Expected the penalty replayed, got %v", got)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Anomaly is a result that looks implausible and needs a person to review it before the results are finalized
+type Anomaly struct {
+	Time     time.Time
+	Bib      Bib
+	Reason   string
+	Reviewed bool
+}
+
+// worldRecord estimates the fastest time ever run over the distance with Riegel's formula from the 5k record of 12:35
+func worldRecord(meters float64) HumanDuration {
+	return HumanDuration(755 * math.Pow(meters/5000, 1.06) * float64(time.Second))
+}
+
+func (race *Race) lockedFlag(bib Bib, reason string, args ...interface{}) {
+	anomaly := Anomaly{Time: race.GetTime(), Bib: bib, Reason: fmt.Sprintf(reason, args...)}
+	log.Printf("Flagged bib #%d for review - %s", bib, anomaly.Reason)
+	race.anomalies = append(race.anomalies, &anomaly)
+}
+
+// lockedCheckFinish flags a just linked finish that is faster than the world record for the race distance, or
+// that has no split at a checkpoint other racers were seen passing, which could be a cut course
+func (race *Race) lockedCheckFinish(entry *Entry) {
+	if record := worldRecord(config.raceDistance); entry.Duration < record {
+		race.lockedFlag(entry.Bib, "Finished in %s, faster than the world record of about %s", entry.Duration, record)
+	}
+	if missed := race.lockedMissedCheckpoints(entry.Bib); len(missed) > 0 {
+		race.lockedFlag(entry.Bib, "No split at %s", strings.Join(missed, ", "))
+	}
+}
+
+// lockedMissedCheckpoints lists the checkpoints reporting passings that haven't seen the bib.  A checkpoint nobody
+// has reported from isn't held against anyone, and a points race doesn't expect every checkpoint to be reached.
+func (race *Race) lockedMissedCheckpoints(bib Bib) []string {
+	if len(config.checkpointPoints) > 0 {
+		return nil
+	}
+	reporting := make(map[string]bool)
+	passed := make(map[string]bool)
+	for _, p := range race.passings {
+		reporting[p.Checkpoint] = true
+		if p.Bib == bib {
+			passed[p.Checkpoint] = true
+		}
+	}
+	missed := []string{}
+	for _, cp := range config.checkpoints {
+		if reporting[cp] && !passed[cp] {
+			missed = append(missed, cp)
+		}
+	}
+	return missed
+}
+
+// lockedCheckConfirm flags a confirmation that came in right after the finish was linked, most likely the
+// same finish entered twice rather than the bib being checked at the end of the chute
+func (race *Race) lockedCheckConfirm(entry *Entry) {
+	if since := race.GetTime().Sub(entry.TimeFinished); since < config.doubleEntryWindow {
+		race.lockedFlag(entry.Bib, "Entered again %s after finishing, possible double entry", HumanDuration(since))
+	}
+}
+
+func (race *Race) lockedOpenAnomalies() int {
+	open := 0
+	for _, a := range race.anomalies {
+		if !a.Reviewed {
+			open++
+		}
+	}
+	return open
+}
+
+// ReviewAnomaly marks the anomaly as reviewed, keeping the result as is or removing the finish time entirely
+func (race *Race) ReviewAnomaly(index int, removeTime bool) error {
+	race.Lock()
+	defer race.Unlock()
+	if index < 0 || index >= len(race.anomalies) {
+		return fmt.Errorf("Anomaly %d not found", index)
+	}
+	anomaly := race.anomalies[index]
+	if anomaly.Reviewed {
+		return fmt.Errorf("Anomaly for bib #%d was already reviewed", anomaly.Bib)
+	}
+	if removeTime {
+		if race.finalized {
+			return fmt.Errorf("Results have been finalized, reopen them to remove a time")
+		}
+		entry, ok := race.bibbedEntries[anomaly.Bib]
+		if !ok {
+			return fmt.Errorf("Bib %d not found", anomaly.Bib)
+		}
+		if !entry.HasFinished() {
+			return fmt.Errorf("Cannot remove time for bib #%d, time is already removed.", anomaly.Bib)
+		}
+		entry.Confirmed = false
+		race.lockedRemoveTime(entry)
+		race.lockedRecomputePrizes()
+	}
+	anomaly.Reviewed = true
+	race.lockedRecordEvent(Event{Kind: "review", Index: index, RemoveTime: removeTime})
+	return nil
+}
+
+// Finalize makes the results official, no more times can be linked or removed until they're reopened
+func (race *Race) Finalize(reopen bool) error {
+	race.Lock()
+	defer race.Unlock()
+	if reopen {
+		race.finalized = false
+		log.Printf("Results reopened")
+		race.lockedRecordEvent(Event{Kind: "reopen"})
+		return nil
+	}
+	if race.started.IsZero() {
+		return fmt.Errorf("Race has not started yet, nothing to finalize")
+	}
+	if open := race.lockedOpenAnomalies(); open > 0 && !race.replaying { // an undone review doesn't undo finalizing
+		return fmt.Errorf("%d timing anomalies still need to be reviewed before finalizing", open)
+	}
+	race.finalized = true
+	log.Printf("Results finalized")
+	race.lockedFlagRecords()
+	race.lockedRecordEvent(Event{Kind: "finalize"})
+	if race.replaying { // everyone was told when the results were finalized the first time
+		return nil
+	}
+	race.logConditionsLater("Finish")
+	race.lockedNotifyClaimants()
+	go race.sendDirectorReports()
+	return nil
+}
+
+func reviewAnomalyHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	index, err := strconv.Atoi(r.FormValue("anomaly"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting anomaly", err)
+		return
+	}
+	err = race.ReviewAnomaly(index, r.FormValue("remove") == "true")
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/review", 301)
+}
+
+func finalizeHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	err := race.Finalize(r.FormValue("reopen") == "true")
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/admin", 301)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWorldRecord(t *testing.T) {
+	if got := worldRecord(5000); got != HumanDuration(755*time.Second) {
+		t.Errorf("Expected the 5k record of 12:35, got %s", got)
+	}
+	if got := worldRecord(42195); got < HumanDuration(time.Hour+55*time.Minute) || got > HumanDuration(2*time.Hour+10*time.Minute) {
+		t.Errorf("Expected a marathon estimate around 2 hours, got %s", got)
+	}
+}
+
+func TestAnomalies(t *testing.T) {
+	race := NewRace()
+	raceStart := time.Now().Add(-time.Hour).Round(time.Second)
+	race.testingTime = &time.Time{}
+	*race.testingTime = raceStart
+	for bib := Bib(1); bib <= 3; bib++ {
+		if err := race.AddEntry(Entry{Bib: bib, Fname: "F", Lname: bib.String(), Age: 30}); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	if err := race.Finalize(false); err == nil {
+		t.Errorf("Expected error finalizing before the race started")
+	}
+	startRace(race)
+
+	// too fast for 5k
+	*race.testingTime = raceStart.Add(10 * time.Minute)
+	if err := race.RecordTimeForBib(1); err != nil {
+		t.Fatalf("Error linking bib - %v", err)
+	}
+	// plausible, but confirmed seconds later
+	*race.testingTime = raceStart.Add(20 * time.Minute)
+	race.RecordTimeForBib(2)
+	*race.testingTime = raceStart.Add(20*time.Minute + 2*time.Second)
+	if err := race.RecordTimeForBib(2); err != nil {
+		t.Fatalf("Error confirming bib - %v", err)
+	}
+	// scanned bibs are linked and confirmed at once without being flagged
+	*race.testingTime = raceStart.Add(21 * time.Minute)
+	if err := race.RecordScannedBib(3); err != nil {
+		t.Fatalf("Error scanning bib - %v", err)
+	}
+	race.RLock()
+	if len(race.anomalies) != 2 || race.anomalies[0].Bib != 1 || race.anomalies[1].Bib != 2 || race.lockedOpenAnomalies() != 2 {
+		t.Errorf("Expected bibs 1 and 2 flagged, got %v", race.anomalies)
+	}
+	race.RUnlock()
+
+	if err := race.Finalize(false); err == nil {
+		t.Errorf("Expected error finalizing with anomalies to review")
+	}
+	if err := race.ReviewAnomaly(0, true); err != nil {
+		t.Errorf("Unexpected error - %v", err)
+	}
+	if race.bibbedEntries[1].HasFinished() {
+		t.Errorf("Expected bib 1's time removed")
+	}
+	if err := race.ReviewAnomaly(0, false); err == nil {
+		t.Errorf("Expected error reviewing an anomaly twice")
+	}
+	if err := race.ReviewAnomaly(2, false); err == nil {
+		t.Errorf("Expected error reviewing a missing anomaly")
+	}
+	if err := race.ReviewAnomaly(1, false); err != nil {
+		t.Errorf("Unexpected error - %v", err)
+	}
+	if !race.bibbedEntries[2].Confirmed {
+		t.Errorf("Expected bib 2 to keep its confirmed result")
+	}
+
+	r, _ := http.NewRequest("GET", "/review", nil)
+	w := httptest.NewRecorder()
+	handler(w, r, race)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected %d, got %d - %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	if err := race.Finalize(false); err != nil {
+		t.Fatalf("Unexpected error finalizing - %v", err)
+	}
+	if err := race.RecordTimeForBib(1); err == nil {
+		t.Errorf("Expected error linking a bib after finalizing")
+	}
+	if err := race.RemoveTimeForBib(3); err == nil {
+		t.Errorf("Expected error removing a time after finalizing")
+	}
+	if err := race.Finalize(true); err != nil {
+		t.Fatalf("Unexpected error reopening - %v", err)
+	}
+	if err := race.RecordTimeForBib(1); err != nil {
+		t.Errorf("Unexpected error linking a bib after reopening - %v", err)
+	}
+}
+
+func TestMissingSplits(t *testing.T) {
+	defer func(checkpoints []string) { config.checkpoints = checkpoints }(config.checkpoints)
+	config.checkpoints = []string{"CP1", "CP2", "CP3"}
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for bib := Bib(1); bib <= 2; bib++ {
+		if err := race.AddEntry(Entry{Bib: bib, Fname: "F", Lname: bib.String(), Age: 30}); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	startRace(race)
+	start := *race.testingTime
+	race.Lock()
+	for _, p := range []Passing{
+		{Checkpoint: "CP1", Bib: 1, Time: start.Add(8 * time.Minute)},
+		{Checkpoint: "CP2", Bib: 1, Time: start.Add(16 * time.Minute)},
+		{Checkpoint: "CP1", Bib: 2, Time: start.Add(9 * time.Minute)}, // skipped CP2, and nobody reported from CP3
+	} {
+		if err := race.lockedRecordPassing(p); err != nil {
+			t.Fatalf("Error recording passing - %v", err)
+		}
+	}
+	race.Unlock()
+
+	*race.testingTime = start.Add(25 * time.Minute)
+	race.RecordTimeForBib(1)
+	race.RecordTimeForBib(2)
+	race.RLock()
+	defer race.RUnlock()
+	if len(race.anomalies) != 1 || race.anomalies[0].Bib != 2 || race.anomalies[0].Reason != "No split at CP2" {
+		t.Errorf("Expected only bib 2 flagged for missing CP2, got %v", race.anomalies)
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mzimmerman/racergo/internal/core"
+)
+
+// APIEntry is an entry as the JSON API sends it, fields are the optional columns by name
+type APIEntry struct {
+	Place        Place             `json:"place,omitempty"`
+	Bib          Bib               `json:"bib"`
+	Fname        string            `json:"fname"`
+	Lname        string            `json:"lname"`
+	Gender       string            `json:"gender"`
+	Age          uint              `json:"age"`
+	Duration     string            `json:"duration,omitempty"`
+	Confirmed    bool              `json:"confirmed"`
+	Registration string            `json:"registration"`
+	Fields       map[string]string `json:"fields,omitempty"`
+}
+
+// APIResult is a finisher as the JSON API sends it, without any of the optional columns
+type APIResult struct {
+	Place         Place    `json:"place"`
+	DivisionPlace Place    `json:"divisionPlace,omitempty"`
+	Division      string   `json:"division"`
+	Bib           Bib      `json:"bib"`
+	Fname         string   `json:"fname"`
+	Lname         string   `json:"lname"`
+	Gender        string   `json:"gender"`
+	Age           uint     `json:"age"`
+	Duration      string   `json:"duration"`
+	Estimated     bool     `json:"estimated,omitempty"`
+	Points        int      `json:"points,omitempty"`
+	Laps          []string `json:"laps,omitempty"`
+}
+
+// APIPrize is a prize and its winners, the winners are ignored when the prizes are set
+type APIPrize struct {
+	Title       string      `json:"title"`
+	LowAge      uint        `json:"lowAge"`
+	HighAge     uint        `json:"highAge"`
+	Gender      string      `json:"gender"`
+	Amount      uint        `json:"amount"`
+	WinAgain    bool        `json:"winAgain,omitempty"`
+	Fundraising bool        `json:"fundraising,omitempty"`
+	Event       string      `json:"event,omitempty"`
+	Wave        string      `json:"wave,omitempty"`
+	Exclude     []string    `json:"exclude,omitempty"`
+	Winners     []APIResult `json:"winners,omitempty"`
+}
+
+// apiNewEntry is an entry to add, without a bib the racer is added unbibbed
+type apiNewEntry struct {
+	Bib    *Bib              `json:"bib"`
+	Fname  string            `json:"fname"`
+	Lname  string            `json:"lname"`
+	Gender string            `json:"gender"`
+	Age    uint              `json:"age"`
+	Fields map[string]string `json:"fields"`
+}
+
+// apiTime is a start or finish, the time is when it happened or now if it's left out
+type apiTime struct {
+	Bib  Bib       `json:"bib"`
+	Time time.Time `json:"time"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	writeJSON(w, status, struct {
+		Error string `json:"error"`
+	}{fmt.Sprintf(format, args...)})
+}
+
+// allowMethods answers 405 Method Not Allowed unless the request is one of the methods
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	w.Header().Set("Allow", fmt.Sprint(methods))
+	writeJSONError(w, http.StatusMethodNotAllowed, "%s isn't allowed, use %v", r.Method, methods)
+	return false
+}
+
+func (race *Race) lockedAPIEntry(e *Entry) APIEntry {
+	entry := APIEntry{Bib: e.Bib, Fname: e.Fname, Lname: e.Lname, Gender: core.Gender(e.Male), Age: e.Age,
+		Confirmed: e.Confirmed, Registration: e.Registration.String(), Fields: make(map[string]string)}
+	if e.HasFinished() {
+		entry.Place = race.lockedPlace(e)
+		entry.Duration = e.Duration.String()
+	}
+	for x, field := range race.optionalEntryFields {
+		if x < len(e.Optional) && e.Optional[x] != "" {
+			entry.Fields[field] = e.Optional[x]
+		}
+	}
+	return entry
+}
+
+func apiResult(row ResultRow) APIResult {
+	var laps []string
+	for _, lap := range row.LapTimes() {
+		laps = append(laps, lap.String())
+	}
+	return APIResult{Place: row.Place, DivisionPlace: row.DivisionPlace, Division: row.Division, Bib: row.Bib, Fname: row.Fname,
+		Lname: row.Lname, Gender: core.Gender(row.Male), Age: row.Age, Duration: row.Duration.String(), Estimated: row.Estimate != nil, Points: row.Points, Laps: laps}
+}
+
+// apiEntriesHandler lists every entry on GET and adds one on POST
+func apiEntriesHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	if !allowMethods(w, r, "GET", "POST") {
+		return
+	}
+	if r.Method == "GET" {
+		race.RLock()
+		entries := make([]APIEntry, len(race.allEntries))
+		for x, e := range race.allEntries {
+			entries[x] = race.lockedAPIEntry(e)
+		}
+		race.RUnlock()
+		writeJSON(w, http.StatusOK, entries)
+		return
+	}
+	var add apiNewEntry
+	if err := json.NewDecoder(r.Body).Decode(&add); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Error reading the entry - %v", err)
+		return
+	}
+	entry := Entry{Bib: NoBib, Fname: add.Fname, Lname: add.Lname, Male: add.Gender == "M", Age: add.Age}
+	if add.Bib != nil {
+		entry.Bib = *add.Bib
+	}
+	if add.Gender != "M" && add.Gender != "F" && add.Gender != "" {
+		writeJSONError(w, http.StatusBadRequest, "Gender must be M or F, not %s", add.Gender)
+		return
+	}
+	race.RLock()
+	fields := race.optionalEntryFields
+	race.RUnlock()
+	entry.Optional = make([]string, len(fields))
+	for name, value := range add.Fields {
+		found := false
+		for x, field := range fields {
+			if field == name {
+				entry.Optional[x], found = value, true
+			}
+		}
+		if !found {
+			writeJSONError(w, http.StatusBadRequest, "The race has no %s field", name)
+			return
+		}
+	}
+	if err := race.AddEntry(entry); err != nil {
+		writeJSONError(w, http.StatusConflict, "%v", err)
+		return
+	}
+	race.RLock()
+	added := race.lockedAPIEntry(race.allEntries[len(race.allEntries)-1])
+	race.RUnlock()
+	writeJSON(w, http.StatusCreated, added)
+}
+
+// apiResultsHandler lists the finishers in place order
+func apiResultsHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	if !allowMethods(w, r, "GET") {
+		return
+	}
+	race.RLock()
+	rows := race.lockedFinishers()
+	results := make([]APIResult, len(rows))
+	for x, row := range rows {
+		results[x] = apiResult(row)
+	}
+	race.RUnlock()
+	writeJSON(w, http.StatusOK, results)
+}
+
+// apiPrizesHandler lists the prizes and their winners on GET and replaces the prizes on POST
+func apiPrizesHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	if !allowMethods(w, r, "GET", "POST") {
+		return
+	}
+	if r.Method == "POST" {
+		var posted []APIPrize
+		if err := json.NewDecoder(r.Body).Decode(&posted); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Error reading the prizes - %v", err)
+			return
+		}
+		prizes := make([]Prize, len(posted))
+		for x, p := range posted {
+			prizes[x] = Prize{Title: p.Title, LowAge: p.LowAge, HighAge: p.HighAge, Gender: p.Gender, Amount: p.Amount,
+				WinAgain: p.WinAgain, Fundraising: p.Fundraising, Event: p.Event, Wave: p.Wave, Exclude: p.Exclude}
+		}
+		if err := race.SetPrizes(prizes); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "%v", err)
+			return
+		}
+	}
+	race.RLock()
+	idx := race.lockedIndex()
+	prizes := make([]APIPrize, len(race.prizes))
+	for x, p := range race.prizes {
+		prizes[x] = APIPrize{Title: p.Title, LowAge: p.LowAge, HighAge: p.HighAge, Gender: p.Gender, Amount: p.Amount,
+			WinAgain: p.WinAgain, Fundraising: p.Fundraising, Event: p.Event, Wave: p.Wave, Exclude: p.Exclude}
+		for _, winner := range p.Winners {
+			prizes[x].Winners = append(prizes[x].Winners, apiResult(race.lockedResultRow(idx, winner)))
+		}
+	}
+	race.RUnlock()
+	writeJSON(w, http.StatusOK, prizes)
+}
+
+// apiStartHandler starts the race now, or at the time given
+func apiStartHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	if !allowMethods(w, r, "POST") {
+		return
+	}
+	var start apiTime
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&start); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Error reading the start - %v", err)
+			return
+		}
+	}
+	var at *time.Time
+	if !start.Time.IsZero() {
+		at = &start.Time
+	}
+	if err := race.CaptureStart(at, "API"); err != nil {
+		writeJSONError(w, http.StatusConflict, "%v", err)
+		return
+	}
+	race.RLock()
+	started := race.started
+	race.RUnlock()
+	writeJSON(w, http.StatusOK, apiTime{Time: started})
+}
+
+// apiFinishHandler records a bib crossing the finish now, or at the time given, the same as a tag read
+func apiFinishHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	if !allowMethods(w, r, "POST") {
+		return
+	}
+	var finish apiTime
+	if err := json.NewDecoder(r.Body).Decode(&finish); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Error reading the finish - %v", err)
+		return
+	}
+	if err := race.RecordTagRead(TagRead{Bib: finish.Bib, Time: finish.Time, Reader: "API"}); err != nil {
+		writeJSONError(w, http.StatusConflict, "%v", err)
+		return
+	}
+	race.RLock()
+	defer race.RUnlock()
+	for _, e := range race.allEntries {
+		if e.Bib == finish.Bib {
+			writeJSON(w, http.StatusOK, race.lockedAPIEntry(e))
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, finish)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPI(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	race.optionalEntryFields = []string{"Team"}
+	call := func(handler func(http.ResponseWriter, *http.Request, *Race), method, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(method, "/api", strings.NewReader(body)), race)
+		return w
+	}
+
+	if w := call(apiEntriesHandler, "POST", `{"bib":1,"fname":"Amy","lname":"Brown","gender":"F","age":38,"fields":{"Team":"Harriers"}}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected Amy added, got %d %s", w.Code, w.Body.String())
+	}
+	if w := call(apiEntriesHandler, "POST", `{"bib":2,"fname":"Bob","lname":"Adams","gender":"M","age":31}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected Bob added, got %d %s", w.Code, w.Body.String())
+	}
+	if w := call(apiEntriesHandler, "POST", `{"bib":1,"fname":"Cal","lname":"Cole","gender":"M","age":52}`); w.Code != http.StatusConflict {
+		t.Errorf("Expected a duplicate bib refused, got %d %s", w.Code, w.Body.String())
+	}
+	if w := call(apiEntriesHandler, "POST", `{"fname":"Cal","lname":"Cole","gender":"M","fields":{"Club":"Y"}}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected an unknown field refused, got %d %s", w.Code, w.Body.String())
+	}
+	if w := call(apiEntriesHandler, "DELETE", ""); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected DELETE not allowed, got %d", w.Code)
+	}
+	if w := call(apiFinishHandler, "POST", `{"bib":1}`); w.Code != http.StatusConflict {
+		t.Errorf("Expected no finish before the start, got %d %s", w.Code, w.Body.String())
+	}
+
+	if w := call(apiStartHandler, "POST", ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected the race started, got %d %s", w.Code, w.Body.String())
+	}
+	*race.testingTime = race.testingTime.Add(20 * time.Minute)
+	w := call(apiFinishHandler, "POST", `{"bib":2}`)
+	var bob APIEntry
+	if err := json.NewDecoder(w.Body).Decode(&bob); err != nil || w.Code != http.StatusOK || bob.Place != 1 || bob.Duration != "00:20:00.00" {
+		t.Fatalf("Expected Bob finished first, got %d %#v %v", w.Code, bob, err)
+	}
+
+	var entries []APIEntry
+	json.NewDecoder(call(apiEntriesHandler, "GET", "").Body).Decode(&entries)
+	if len(entries) != 2 || entries[1].Fname != "Amy" || entries[1].Fields["Team"] != "Harriers" || entries[1].Place != 0 {
+		t.Errorf("Expected Bob then Amy with her team, got %#v", entries)
+	}
+	var results []APIResult
+	json.NewDecoder(call(apiResultsHandler, "GET", "").Body).Decode(&results)
+	if len(results) != 1 || results[0].Bib != 2 || results[0].Gender != "M" {
+		t.Errorf("Expected only Bob in the results, got %#v", results)
+	}
+
+	race.allEntries[0].Confirmed = true // only confirmed finishers win prizes
+	w = call(apiPrizesHandler, "POST", `[{"title":"Overall","lowAge":0,"highAge":200,"gender":"O","amount":1}]`)
+	var prizes []APIPrize
+	if err := json.NewDecoder(w.Body).Decode(&prizes); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Expected the prizes set, got %d %v", w.Code, err)
+	}
+	if len(prizes) != 1 || len(prizes[0].Winners) != 1 || prizes[0].Winners[0].Fname != "Bob" {
+		t.Errorf("Expected Bob winning Overall, got %#v", prizes)
+	}
+	if w := call(apiPrizesHandler, "POST", `{`); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"error"`) {
+		t.Errorf("Expected bad JSON refused with an error, got %d %s", w.Code, w.Body.String())
+	}
+}
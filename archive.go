@@ -0,0 +1,75 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// archiveHandler bundles everything recorded on race day into one zip to keep after the race,
+// the results in the re-uploadable format and as IOF XML along with the incident log and volunteer hours
+func archiveHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	w.Header().Set("Content-type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", archiveName()))
+	if err := race.WriteArchive(w); err != nil {
+		log.Printf("Error writing archive - %v", err)
+	}
+}
+
+func archiveName() string {
+	return fmt.Sprintf("%s-%s-archive.zip", config.webserverHostname, time.Now().In(time.Local).Format("2006-01-02"))
+}
+
+// WriteArchive writes the race day zip, see archiveHandler
+func (race *Race) WriteArchive(w io.Writer) error {
+	archive := zip.NewWriter(w)
+	file, err := archive.Create("results.csv")
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(file)
+	race.WriteRedactedCSV(writer)
+	writer.Flush()
+	race.RLock()
+	for name, write := range map[string]func(*csv.Writer){
+		"incidents.csv":  race.lockedWriteIncidents,
+		"volunteers.csv": race.lockedWriteVolunteerHours,
+		"awards.csv":     race.lockedWriteAwards,
+		"hometowns.csv":  race.lockedWriteHometowns,
+		"records.csv":    race.lockedWriteRecordCandidates,
+		"race.csv":       race.lockedWriteRaceDetails,
+		"weather.csv":    race.lockedWriteWeather,
+	} {
+		file, err := archive.Create(name)
+		if err != nil {
+			race.RUnlock()
+			return err
+		}
+		writer := csv.NewWriter(file)
+		write(writer)
+		writer.Flush()
+	}
+	file, err = archive.Create("results.iof.xml")
+	if err == nil {
+		err = race.lockedWriteIOF(file)
+	}
+	race.RUnlock()
+	if err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// lockedWriteAwards writes every prize winner in the order they're announced
+func (race *Race) lockedWriteAwards(writer *csv.Writer) {
+	writer.Write([]string{"Prize", "Place", "Bib", "Fname", "Lname", "Age", "Duration"})
+	for _, prize := range race.prizes {
+		for x, winner := range prize.Winners {
+			writer.Write([]string{prize.Title, Place(x + 1).String(), winner.Bib.String(), winner.Fname, winner.Lname, fmt.Sprintf("%d", winner.Age), winner.Duration.String()})
+		}
+	}
+}
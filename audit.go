@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mzimmerman/racergo/internal/core"
+)
+
+var auditHeaders = []string{"Bib", "Race Time", "Removal"}
+
+// lockedWriteAudit writes the audit log in the order the bibs were linked, confirmed and removed
+func (race *Race) lockedWriteAudit(writer *csv.Writer) {
+	writer.Write(auditHeaders)
+	for _, a := range race.auditLog {
+		writer.Write([]string{a.Bib.String(), a.Duration.String(), strconv.FormatBool(a.Remove)})
+	}
+}
+
+// parseAudit reads an audit log written by lockedWriteAudit, possibly corrected in a spreadsheet
+func parseAudit(rows [][]string) ([]Audit, error) {
+	if len(rows) == 0 || !equalStringSlices(rows[0], auditHeaders) {
+		return nil, fmt.Errorf("Expected the audit columns %s", strings.Join(auditHeaders, ","))
+	}
+	audits := make([]Audit, 0, len(rows)-1)
+	for x, row := range rows[1:] {
+		bib, err := strconv.Atoi(row[0])
+		if err != nil || bib < 0 {
+			return nil, fmt.Errorf("Row %d - %s is not a bib number", x+2, row[0])
+		}
+		duration, err := core.ParseHumanDuration(row[1])
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("Row %d - %s is not a race time", x+2, row[1])
+		}
+		remove, err := strconv.ParseBool(row[2])
+		if err != nil {
+			return nil, fmt.Errorf("Row %d - Removal must be true or false, not %s", x+2, row[2])
+		}
+		audits = append(audits, Audit{Bib: Bib(bib), Duration: duration, Remove: remove})
+	}
+	return audits, nil
+}
+
+type auditedTime struct {
+	duration  HumanDuration
+	confirmed bool
+}
+
+// lockedApplyAudit sets every finish time by playing the audit log from the start: a bib's first link is its time,
+// the next one confirms it and a removal clears it.  Nothing changes unless the whole log makes sense.
+func (race *Race) lockedApplyAudit(audits []Audit) error {
+	if race.started.IsZero() {
+		return fmt.Errorf("Race has not started yet, cannot apply an audit log")
+	}
+	if race.finalized {
+		return fmt.Errorf("Results have been finalized, cannot apply an audit log")
+	}
+	times := make(map[Bib]*auditedTime)
+	for x, a := range audits {
+		entry, ok := race.bibbedEntries[a.Bib]
+		if !ok {
+			return fmt.Errorf("Audit row %d - Bib %d not found", x+1, a.Bib)
+		}
+		if !entry.Registration.HasSpot() {
+			return fmt.Errorf("Audit row %d - Bib #%d is %s and doesn't have a spot in the race", x+1, a.Bib, entry.Registration)
+		}
+		t, ok := times[a.Bib]
+		if !ok {
+			t = &auditedTime{}
+			times[a.Bib] = t
+		}
+		switch {
+		case a.Remove && t.confirmed:
+			return fmt.Errorf("Audit row %d - Bib #%d already confirmed, cannot remove its time", x+1, a.Bib)
+		case a.Remove:
+			t.duration = 0
+		case t.duration == 0:
+			t.duration = a.Duration
+		case !t.confirmed:
+			t.confirmed = true
+		default:
+			return fmt.Errorf("Audit row %d - Bib #%d already confirmed!", x+1, a.Bib)
+		}
+	}
+	for bib, entry := range race.bibbedEntries {
+		t, ok := times[bib]
+		if !ok {
+			t = &auditedTime{}
+		}
+		entry.Duration = t.duration
+		entry.Confirmed = t.confirmed && t.duration > 0
+		entry.TimeFinished = time.Time{}
+		if t.duration > 0 {
+			entry.TimeFinished = race.lockedStartOf(entry).Add(time.Duration(t.duration))
+		}
+	}
+	race.auditLog = append(make([]Audit, 0, len(audits)), audits...)
+	race.lockedSortEntries()
+	race.lockedRecomputePrizes()
+	return nil
+}
+
+// ImportAudit replaces every finish time with the ones in the corrected audit log
+func (race *Race) ImportAudit(audits []Audit) error {
+	race.Lock()
+	defer race.Unlock()
+	if err := race.lockedApplyAudit(audits); err != nil {
+		return err
+	}
+	race.lockedRecordEvent(Event{Kind: "audit", Audit: audits})
+	log.Printf("Imported an audit log of %d rows", len(audits))
+	return nil
+}
+
+func auditCSVHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	w.Header().Set("Content-type", "application/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s-%s-audit.csv\"", config.webserverHostname, time.Now().In(time.Local).Format("2006-01-02")))
+	writer := csv.NewWriter(w)
+	race.RLock()
+	race.lockedWriteAudit(writer)
+	race.RUnlock()
+	writer.Flush()
+}
+
+func uploadAuditHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error getting Reader - %s", err)
+		return
+	}
+	part, err := reader.NextPart()
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error getting Part - %s", err)
+		return
+	}
+	csvReader := csv.NewReader(part)
+	csvReader.FieldsPerRecord = len(auditHeaders)
+	rows, err := csvReader.ReadAll()
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error Reading CSV file - %s", err)
+		return
+	}
+	audits, err := parseAudit(rows)
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	if err = race.ImportAudit(audits); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/audit", 301)
+}
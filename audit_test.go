@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditRoundTrip(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38},
+		{Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 31},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	startRace(race)
+	*race.testingTime = race.testingTime.Add(20 * time.Minute)
+	linkBibTesting(t, race, 1, false)
+	linkBibTesting(t, race, 1, false)
+	*race.testingTime = race.testingTime.Add(time.Minute)
+	linkBibTesting(t, race, 2, false)
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	race.RLock()
+	race.lockedWriteAudit(writer)
+	race.RUnlock()
+	writer.Flush()
+	want := "Bib,Race Time,Removal\n1,00:20:00.00,false\n1,00:20:00.00,false\n2,00:21:00.00,false\n"
+	if buf.String() != want {
+		t.Fatalf("Expected audit CSV\n%s\ngot\n%s", want, buf.String())
+	}
+
+	// offline, the timer noticed Bob actually finished ahead of Amy
+	corrected := strings.Replace(buf.String(), "2,00:21:00.00", "2,00:19:30.00", 1) + "2,00:21:10.00,false\n1,00:22:00.00,true\n"
+	rows, _ := csv.NewReader(strings.NewReader(corrected)).ReadAll()
+	audits, err := parseAudit(rows)
+	if err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if err = race.ImportAudit(audits); err == nil {
+		t.Errorf("Expected removing Amy's confirmed time to be refused")
+	}
+	if race.bibbedEntries[2].Duration != HumanDuration(21*time.Minute) {
+		t.Errorf("Expected nothing changed by the refused audit log, got %s", race.bibbedEntries[2].Duration)
+	}
+	if err = race.ImportAudit(audits[:len(audits)-1]); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if bob := race.bibbedEntries[2]; race.allEntries[0] != bob || bob.Duration != HumanDuration(19*time.Minute+30*time.Second) || !bob.Confirmed {
+		t.Errorf("Expected Bob confirmed in first at 19:30, got %#v", bob)
+	}
+	if len(race.auditLog) != 4 {
+		t.Errorf("Expected the imported audit log kept, got %v", race.auditLog)
+	}
+	replayed := NewRace()
+	if err = replayed.Replay(race.events); err != nil {
+		t.Fatalf("Error replaying - %v", err)
+	}
+	if got, want := raceCSV(replayed), raceCSV(race); got != want {
+		t.Errorf("Expected the replayed import to match\n%s\ngot\n%s", want, got)
+	}
+	if _, err = parseAudit([][]string{{"Bib", "Time"}}); err == nil {
+		t.Errorf("Expected the wrong columns to be refused")
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Role is what a signed in user is allowed to do, each role can do everything the roles before it can
+type Role int
+
+const (
+	rolePublic Role = iota
+	roleTimer       // links bibs and runs the finish line, can't upload racers or change the audit
+	roleAdmin
+)
+
+var roleNames = map[string]Role{"timer": roleTimer, "admin": roleAdmin}
+
+func (r Role) String() string {
+	for name, role := range roleNames {
+		if role == r {
+			return name
+		}
+	}
+	return "public"
+}
+
+// User is someone who can sign in, from RACERGOUSERS
+type User struct {
+	Name     string
+	Password string
+	Role     Role
+}
+
+// parseUsers reads RACERGOUSERS entries like amy:secret:admin or finish:line:timer
+func parseUsers(list []string) (map[string]User, error) {
+	users := make(map[string]User)
+	for _, spec := range list {
+		parts := strings.SplitN(spec, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("users must be like name:password:admin, not %s", spec)
+		}
+		role, ok := roleNames[strings.ToLower(parts[2])]
+		if !ok {
+			return nil, fmt.Errorf("%s's role must be admin or timer, not %s", parts[0], parts[2])
+		}
+		users[parts[0]] = User{parts[0], parts[1], role}
+	}
+	return users, nil
+}
+
+// publicPaths are open to everyone, the racers' and spectators' pages and the feeds that check their own keys
+var publicPaths = map[string]bool{
+	"/": true, "/m/finishers": true, "/theme": true, "/preferences": true, "/results.txt": true, "/results.atom": true, "/winners.atom": true, "/results.iof.xml": true, "/results.jsonld": true, "/results/print": true, "/results/divisions": true, "/live": true,
+	"/schedule.ics": true, "/survey": true, "/submitSurvey": true, "/lookup": true, "/finisher": true, "/badge.png": true,
+	"/sponsors": true, "/fundraising": true, "/info": true, "/splits": true, "/tracking": true, "/team": true, "/stats": true,
+	"/stats.json": true, "/hometowns": true, "/sms": true, "/lora": true, "/transfer": true, "/requestTransferLink": true, "/cheer": true, "/submitCheer": true, "/claim": true, "/submitClaim": true,
+	"/submitTransfer": true, "/api/results": true, "/api/suggest": true, "/opensearch.xml": true, "/races/": true, "/static/": true, "/fonts/": true, "/sponsors/": true,
+}
+
+// timerPaths are what the finish line crew needs on race day, everything not public or timer is admin only
+var timerPaths = map[string]bool{
+	"/dayof": true, "/m": true, "/linkBib": true, "/checkBib": true, "/confirmBib": true, "/assignTime": true,
+	"/start": true, "/selectStart": true, "/api/batch": true, "/racergo.Timing/": true, "/checkInRacer": true,
+	"/accountFor": true, "/onCourse": true, "/chute": true, "/chuteTime": true, "/chuteBib": true,
+	"/chuteAction": true, "/logConditions": true, "/printLabel": true, "/tickets": true, "/reprintTicket": true, "/api/start": true, "/api/finish": true,
+}
+
+// publicReads are public to look at but need admin to change
+var publicReads = map[string]bool{"/api/prizes": true}
+
+// pathRole is the role needed for a request, paths under a directory like /static/ go by the directory
+func pathRole(method, path string) Role {
+	if publicReads[path] && (method == "GET" || method == "HEAD") {
+		return rolePublic
+	}
+	for _, p := range []string{path, subtree(path)} {
+		if publicPaths[p] {
+			return rolePublic
+		}
+		if timerPaths[p] {
+			return roleTimer
+		}
+	}
+	return roleAdmin
+}
+
+func subtree(path string) string {
+	if x := strings.Index(path[1:], "/"); x >= 0 {
+		return path[:x+2]
+	}
+	return path
+}
+
+// authenticate is who signed in with HTTP basic auth, ok is false for a wrong name or password
+func authenticate(r *http.Request) (User, bool) {
+	name, password, ok := r.BasicAuth()
+	if !ok {
+		return User{}, false
+	}
+	user, ok := config.users[name]
+	given, want := sha256.Sum256([]byte(password)), sha256.Sum256([]byte(user.Password))
+	if !hmac.Equal(given[:], want[:]) || !ok {
+		return User{}, false
+	}
+	return user, true
+}
+
+// authorized checks the request is allowed to reach the path, asking the browser to sign in if it isn't.
+// Everything is open until RACERGOUSERS is set.
+func authorized(w http.ResponseWriter, r *http.Request) bool {
+	if len(config.users) == 0 {
+		return true
+	}
+	need := pathRole(r.Method, r.URL.Path)
+	if need == rolePublic {
+		return true
+	}
+	user, ok := authenticate(r)
+	if ok && user.Role >= need {
+		return true
+	}
+	if ok {
+		log.Printf("%s (%s) isn't allowed %s %s from %s", user.Name, user.Role, r.Method, r.URL.Path, clientIP(r))
+		http.Error(w, fmt.Sprintf("%s needs %s access", r.URL.Path, need), http.StatusForbidden)
+		return false
+	}
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Basic realm="%s"`, strings.Replace(config.raceName, `"`, "'", -1)))
+	http.Error(w, "Sign in to continue", http.StatusUnauthorized)
+	return false
+}
+
+// requestUser names who made a request for the log, blank if they didn't sign in
+func requestUser(r *http.Request) string {
+	if name, _, ok := r.BasicAuth(); ok {
+		return name
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseUsers(t *testing.T) {
+	users, err := parseUsers([]string{"amy:secret:admin", "finish:line:Timer", "bob:pass:word:admin"})
+	if err == nil {
+		t.Errorf("Expected an error for a role of word:admin, got %v", users)
+	}
+	users, err = parseUsers([]string{"amy:secret:admin", "finish:line:Timer"})
+	if err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if users["amy"].Role != roleAdmin || users["finish"].Role != roleTimer || users["finish"].Password != "line" {
+		t.Errorf("Expected amy as an admin and finish as a timer, got %v", users)
+	}
+	for _, bad := range []string{"amy", "amy:secret", ":secret:admin", "amy::admin", "amy:secret:owner"} {
+		if _, err = parseUsers([]string{bad}); err == nil {
+			t.Errorf("Expected an error for %s", bad)
+		}
+	}
+}
+
+func TestAuthorized(t *testing.T) {
+	defer func(users map[string]User) { config.users = users }(config.users)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	tests := []struct {
+		path, user, password string
+		code                 int
+	}{
+		{"/", "", "", http.StatusOK},
+		{"/static/bootstrap.min.css", "", "", http.StatusOK},
+		{"/races/kids/lookup", "", "", http.StatusOK},
+		{"/linkBib", "", "", http.StatusUnauthorized},
+		{"/linkBib", "finish", "wrong", http.StatusUnauthorized},
+		{"/linkBib", "nobody", "line", http.StatusUnauthorized},
+		{"/linkBib", "finish", "line", http.StatusOK},
+		{"/racergo.Timing/Link", "finish", "line", http.StatusOK},
+		{"/uploadRacers", "finish", "line", http.StatusForbidden},
+		{"/admin/stats", "finish", "line", http.StatusForbidden},
+		{"/uploadRacers", "amy", "secret", http.StatusOK},
+		{"/linkBib", "amy", "secret", http.StatusOK},
+	}
+	config.users = nil
+	w := httptest.NewRecorder()
+	logRequests(ok).ServeHTTP(w, httptest.NewRequest("POST", "/uploadRacers", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected everything open without RACERGOUSERS, got %d", w.Code)
+	}
+	config.users, _ = parseUsers([]string{"amy:secret:admin", "finish:line:timer"})
+	for _, test := range tests {
+		r := httptest.NewRequest("POST", test.path, nil)
+		if test.user != "" {
+			r.SetBasicAuth(test.user, test.password)
+		}
+		w := httptest.NewRecorder()
+		logRequests(ok).ServeHTTP(w, r)
+		if w.Code != test.code {
+			t.Errorf("%s as %q - expected %d, got %d", test.path, test.user, test.code, w.Code)
+		}
+		if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s - expected the browser asked to sign in", test.path)
+		}
+	}
+	if pathRole("GET", "/api/prizes") != rolePublic || pathRole("POST", "/api/prizes") != roleAdmin {
+		t.Errorf("Expected the prizes public to read and admin to change")
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg" // backgrounds can be JPEG or PNG
+	"image/png"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// the size recommended for OpenGraph images so the badge isn't cropped when shared
+const (
+	badgeWidth  = 1200
+	badgeHeight = 630
+)
+
+// Finisher is what the public personal result page and badge show about a finished Entry
+type Finisher struct {
+	Bib           Bib
+	Fname         string
+	Lname         string
+	Duration      HumanDuration // the net time
+	Gun           HumanDuration // the gun time when it differs from the net time
+	Place         Place
+	Division      string
+	DivisionPlace Place
+	Adjustments   []Adjustment // the penalties and bonuses in the time
+	Points        int          // the checkpoint points scored
+	RaceName      string
+}
+
+func (race *Race) finisherURL(bib Bib) string {
+	return race.raceURL(fmt.Sprintf("/finisher?bib=%d", bib))
+}
+
+func (race *Race) badgeURL(bib Bib) string {
+	return race.raceURL(fmt.Sprintf("/badge.png?bib=%d", bib))
+}
+
+func (race *Race) lockedFinisher(bib Bib) (Finisher, error) {
+	entry, ok := race.bibbedEntries[bib]
+	if !ok {
+		return Finisher{}, fmt.Errorf("No racer with bib #%d", bib)
+	}
+	if !entry.HasFinished() {
+		return Finisher{}, fmt.Errorf("Bib #%d hasn't finished yet", bib)
+	}
+	if entry.Status == StatusDQ {
+		return Finisher{}, fmt.Errorf("Bib #%d was disqualified", bib)
+	}
+	finisher := Finisher{
+		Bib:           bib,
+		Fname:         entry.Fname,
+		Lname:         entry.Lname,
+		Duration:      entry.NetDuration(),
+		Division:      race.lockedDivisionOf(entry),
+		DivisionPlace: race.lockedDivisionPlaces()[entry],
+		Adjustments:   entry.Adjustments,
+		Points:        entry.Points,
+		RaceName:      race.lockedName(),
+	}
+	if entry.HasNetTime() {
+		finisher.Gun = entry.Duration
+	}
+	finisher.Place = race.lockedPlace(entry)
+	return finisher, nil
+}
+
+// badgeFont is a 5x7 pixel font, scaled up when drawn.  Lower case letters are drawn as upper case
+// and anything else missing is drawn as a question mark.
+var badgeFont = map[rune][7]string{
+	' ':  {".....", ".....", ".....", ".....", ".....", ".....", "....."},
+	'A':  {".###.", "#...#", "#...#", "#####", "#...#", "#...#", "#...#"},
+	'B':  {"####.", "#...#", "#...#", "####.", "#...#", "#...#", "####."},
+	'C':  {".###.", "#...#", "#....", "#....", "#....", "#...#", ".###."},
+	'D':  {"####.", "#...#", "#...#", "#...#", "#...#", "#...#", "####."},
+	'E':  {"#####", "#....", "#....", "####.", "#....", "#....", "#####"},
+	'F':  {"#####", "#....", "#....", "####.", "#....", "#....", "#...."},
+	'G':  {".###.", "#...#", "#....", "#.###", "#...#", "#...#", ".####"},
+	'H':  {"#...#", "#...#", "#...#", "#####", "#...#", "#...#", "#...#"},
+	'I':  {".###.", "..#..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'J':  {"..###", "...#.", "...#.", "...#.", "...#.", "#..#.", ".##.."},
+	'K':  {"#...#", "#..#.", "#.#..", "##...", "#.#..", "#..#.", "#...#"},
+	'L':  {"#....", "#....", "#....", "#....", "#....", "#....", "#####"},
+	'M':  {"#...#", "##.##", "#.#.#", "#.#.#", "#...#", "#...#", "#...#"},
+	'N':  {"#...#", "#...#", "##..#", "#.#.#", "#..##", "#...#", "#...#"},
+	'O':  {".###.", "#...#", "#...#", "#...#", "#...#", "#...#", ".###."},
+	'P':  {"####.", "#...#", "#...#", "####.", "#....", "#....", "#...."},
+	'Q':  {".###.", "#...#", "#...#", "#...#", "#.#.#", "#..#.", ".##.#"},
+	'R':  {"####.", "#...#", "#...#", "####.", "#.#..", "#..#.", "#...#"},
+	'S':  {".####", "#....", "#....", ".###.", "....#", "....#", "####."},
+	'T':  {"#####", "..#..", "..#..", "..#..", "..#..", "..#..", "..#.."},
+	'U':  {"#...#", "#...#", "#...#", "#...#", "#...#", "#...#", ".###."},
+	'V':  {"#...#", "#...#", "#...#", "#...#", "#...#", ".#.#.", "..#.."},
+	'W':  {"#...#", "#...#", "#...#", "#.#.#", "#.#.#", "#.#.#", ".#.#."},
+	'X':  {"#...#", "#...#", ".#.#.", "..#..", ".#.#.", "#...#", "#...#"},
+	'Y':  {"#...#", "#...#", ".#.#.", "..#..", "..#..", "..#..", "..#.."},
+	'Z':  {"#####", "....#", "...#.", "..#..", ".#...", "#....", "#####"},
+	'0':  {".###.", "#...#", "#..##", "#.#.#", "##..#", "#...#", ".###."},
+	'1':  {"..#..", ".##..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'2':  {".###.", "#...#", "....#", "...#.", "..#..", ".#...", "#####"},
+	'3':  {"#####", "...#.", "..#..", "...#.", "....#", "#...#", ".###."},
+	'4':  {"...#.", "..##.", ".#.#.", "#..#.", "#####", "...#.", "...#."},
+	'5':  {"#####", "#....", "####.", "....#", "....#", "#...#", ".###."},
+	'6':  {"..##.", ".#...", "#....", "####.", "#...#", "#...#", ".###."},
+	'7':  {"#####", "....#", "...#.", "..#..", ".#...", ".#...", ".#..."},
+	'8':  {".###.", "#...#", "#...#", ".###.", "#...#", "#...#", ".###."},
+	'9':  {".###.", "#...#", "#...#", ".####", "....#", "...#.", ".##.."},
+	':':  {".....", "..#..", "..#..", ".....", "..#..", "..#..", "....."},
+	'.':  {".....", ".....", ".....", ".....", ".....", ".##..", ".##.."},
+	',':  {".....", ".....", ".....", ".....", ".##..", "..#..", ".#..."},
+	'-':  {".....", ".....", ".....", "#####", ".....", ".....", "....."},
+	'#':  {".#.#.", ".#.#.", "#####", ".#.#.", "#####", ".#.#.", ".#.#."},
+	'\'': {"..#..", "..#..", ".#...", ".....", ".....", ".....", "....."},
+	'&':  {".##..", "#..#.", "#.#..", ".#...", "#.#.#", "#..#.", ".##.#"},
+	'/':  {".....", "....#", "...#.", "..#..", ".#...", "#....", "....."},
+	'!':  {"..#..", "..#..", "..#..", "..#..", "..#..", ".....", "..#.."},
+	'?':  {".###.", "#...#", "....#", "...#.", "..#..", ".....", "..#.."},
+}
+
+// textWidth is how many pixels wide text is drawn at the given scale, glyphs are 5 wide with a 1 pixel gap
+func textWidth(text string, scale int) int {
+	n := len([]rune(text))
+	if n == 0 {
+		return 0
+	}
+	return (n*6 - 1) * scale
+}
+
+// drawCentered draws a line of text centered across img with its top at y, shrinking
+// the scale until the line fits within the margins
+func drawCentered(img *image.RGBA, text string, y, scale int, c color.Color) {
+	text = strings.ToUpper(text)
+	for scale > 1 && textWidth(text, scale) > img.Bounds().Dx()-80 {
+		scale--
+	}
+	x := (img.Bounds().Dx() - textWidth(text, scale)) / 2
+	src := image.NewUniform(c)
+	for _, r := range text {
+		glyph, ok := badgeFont[r]
+		if !ok {
+			glyph = badgeFont['?']
+		}
+		for row, line := range glyph {
+			for col, px := range line {
+				if px == '#' {
+					draw.Draw(img, image.Rect(x+col*scale, y+row*scale, x+(col+1)*scale, y+(row+1)*scale), src, image.ZP, draw.Src)
+				}
+			}
+		}
+		x += 6 * scale
+	}
+}
+
+// badgeBackground loads the race branded background, stretched to the badge size, or falls back to a plain gradient
+func badgeBackground() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, badgeWidth, badgeHeight))
+	if config.badgeBackground != "" {
+		f, err := os.Open(config.badgeBackground)
+		if err == nil {
+			defer f.Close()
+			bg, _, err := image.Decode(f)
+			if err == nil {
+				b := bg.Bounds()
+				for y := 0; y < badgeHeight; y++ {
+					for x := 0; x < badgeWidth; x++ {
+						img.Set(x, y, bg.At(b.Min.X+x*b.Dx()/badgeWidth, b.Min.Y+y*b.Dy()/badgeHeight))
+					}
+				}
+				return img
+			}
+		}
+	}
+	for y := 0; y < badgeHeight; y++ {
+		shade := uint8(40 + 80*y/badgeHeight)
+		draw.Draw(img, image.Rect(0, y, badgeWidth, y+1), image.NewUniform(color.RGBA{0, shade / 2, shade, 255}), image.ZP, draw.Src)
+	}
+	return img
+}
+
+// Badge draws the finisher's name, time and place over the race background
+func (f Finisher) Badge() image.Image {
+	img := badgeBackground()
+	// darken a band behind the text so it reads over any background
+	draw.Draw(img, image.Rect(0, 90, badgeWidth, 540), image.NewUniform(color.RGBA{0, 0, 0, 160}), image.ZP, draw.Over)
+	white := color.RGBA{255, 255, 255, 255}
+	gold := color.RGBA{255, 204, 51, 255}
+	drawCentered(img, f.RaceName, 120, 8, gold)
+	drawCentered(img, f.Fname+" "+f.Lname, 220, 12, white)
+	drawCentered(img, f.Duration.String(), 340, 12, white)
+	drawCentered(img, f.Place.Ordinal()+" overall - "+f.DivisionPlace.Ordinal()+" "+f.Division, 460, 6, gold)
+	return img
+}
+
+func badgeHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	bib, err := strconv.Atoi(r.FormValue("bib"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error %s getting bib", err), http.StatusBadRequest)
+		return
+	}
+	race.RLock()
+	finisher, err := race.lockedFinisher(Bib(bib))
+	race.RUnlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-type", "image/png")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"%s-%d.png\"", config.webserverHostname, bib))
+	png.Encode(w, finisher.Badge())
+}
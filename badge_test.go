@@ -0,0 +1,94 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFinisherBadge(t *testing.T) {
+	race := NewRace()
+	raceStart := time.Now().Add(-time.Hour).Round(time.Second)
+	race.testingTime = &time.Time{}
+	*race.testingTime = raceStart
+	for _, e := range []Entry{
+		{Bib: 7, Fname: "Amy", Lname: "Brown", Age: 38},
+		{Bib: 8, Fname: "Bob", Lname: "Adams", Male: true, Age: 31},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	startRace(race)
+	*race.testingTime = raceStart.Add(21*time.Minute + 5*time.Second)
+	if err := race.RecordTimeForBib(7); err != nil {
+		t.Fatalf("Error linking bib - %v", err)
+	}
+
+	race.RLock()
+	finisher, err := race.lockedFinisher(7)
+	if err != nil || finisher.Place != 1 || finisher.Division != "F30-39" || finisher.DivisionPlace != 1 {
+		t.Errorf("Unexpected finisher %#v - %v", finisher, err)
+	}
+	if _, err := race.lockedFinisher(8); err == nil {
+		t.Errorf("Expected error for a racer who hasn't finished")
+	}
+	if _, err := race.lockedFinisher(99); err == nil {
+		t.Errorf("Expected error for a missing bib")
+	}
+	race.RUnlock()
+
+	r, _ := http.NewRequest("GET", "/badge.png?bib=7", nil)
+	w := httptest.NewRecorder()
+	badgeHandler(w, r, race)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected %d, got %d - %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	img, err := png.Decode(w.Body)
+	if err != nil {
+		t.Fatalf("Badge is not a PNG - %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != badgeWidth || b.Dy() != badgeHeight {
+		t.Errorf("Expected a %dx%d badge, got %v", badgeWidth, badgeHeight, b)
+	}
+	r, _ = http.NewRequest("GET", "/badge.png?bib=8", nil)
+	w = httptest.NewRecorder()
+	badgeHandler(w, r, race)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected %d for an unfinished racer's badge, got %d", http.StatusNotFound, w.Code)
+	}
+
+	r, _ = http.NewRequest("GET", "/finisher?bib=7", nil)
+	w = httptest.NewRecorder()
+	handler(w, r, race)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected %d, got %d - %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	for _, want := range []string{`property="og:image" content="` + race.badgeURL(7) + `"`, `property="og:url" content="` + race.finisherURL(7) + `"`, "1st overall and 1st in F30-39"} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("Expected finisher page to contain %s", want)
+		}
+	}
+}
+
+func TestDrawCentered(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 200, 50))
+	white := color.RGBA{255, 255, 255, 255}
+	// far too wide at scale 10, has to shrink to fit inside the margins
+	drawCentered(img, "long name", 0, 10, white)
+	for y := 0; y < 50; y++ {
+		for _, x := range []int{0, 39, 161, 199} {
+			if img.RGBAAt(x, y) == white {
+				t.Fatalf("Expected text to stay within the margins, found a pixel at %d,%d", x, y)
+			}
+		}
+	}
+	if textWidth("", 3) != 0 || textWidth("ab", 3) != 33 {
+		t.Errorf("Unexpected text widths %d and %d", textWidth("", 3), textWidth("ab", 3))
+	}
+}
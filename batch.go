@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mzimmerman/racergo/internal/core"
+)
+
+// BatchOp is one change in a batch from an external correction script.  The entry is found by its
+// Place in the results when given, otherwise by its Bib.
+type BatchOp struct {
+	Op       string `json:"op"` // assignBib, setTime or setStatus
+	Place    Place  `json:"place,omitempty"`
+	Bib      Bib    `json:"bib"`
+	NewBib   Bib    `json:"newBib"`
+	Duration string `json:"duration"` // empty or -- removes the time
+	Status   string `json:"status"`
+	Confirm  bool   `json:"confirm,omitempty"` // setTime only, the time was checked against a second record like the paper backup
+}
+
+// BatchError is the operation that failed validation, nothing in the batch was applied
+type BatchError struct {
+	Operation int    `json:"operation"`
+	Error     string `json:"error"`
+}
+
+// ApplyBatch applies every operation or none of them.  The operations are tried against copies of the
+// entries so a failure part way through leaves the race untouched.
+func (race *Race) ApplyBatch(ops []BatchOp) *BatchError {
+	race.Lock()
+	defer race.Unlock()
+	return race.lockedApplyBatch(ops)
+}
+
+// lockedApplyBatch is ApplyBatch for importers that worked the operations out under the same lock
+func (race *Race) lockedApplyBatch(ops []BatchOp) *BatchError {
+	if race.finalized {
+		return &BatchError{Operation: -1, Error: "Results have been finalized, cannot apply changes"}
+	}
+	staged := make(map[*Entry]*Entry)
+	bibs := make(map[Bib]*Entry, len(race.bibbedEntries)) // staged bib assignments, pointing to the original entries
+	for bib, e := range race.bibbedEntries {
+		bibs[bib] = e
+	}
+	for x, op := range ops {
+		var original *Entry
+		if op.Place > 0 {
+			if int(op.Place) > len(race.allEntries) {
+				return &BatchError{x, fmt.Sprintf("No entry in place %d", op.Place)}
+			}
+			original = race.allEntries[op.Place-1]
+		} else if original = bibs[op.Bib]; original == nil {
+			return &BatchError{x, fmt.Sprintf("Bib %d not found", op.Bib)}
+		}
+		entry, ok := staged[original]
+		if !ok {
+			copied := *original
+			entry = &copied
+			staged[original] = entry
+		}
+		switch op.Op {
+		case "assignBib":
+			if op.NewBib < 0 && op.NewBib != NoBib {
+				return &BatchError{x, fmt.Sprintf("%d is not a valid bib", op.NewBib)}
+			}
+			if dest, ok := bibs[op.NewBib]; ok && dest != original {
+				return &BatchError{x, fmt.Sprintf("Bib #%d already assigned to %s %s", op.NewBib, dest.Fname, dest.Lname)}
+			}
+			if bibs[entry.Bib] == original {
+				delete(bibs, entry.Bib)
+			}
+			entry.Bib = op.NewBib
+			if entry.Bib != NoBib {
+				bibs[entry.Bib] = original
+			}
+		case "setTime":
+			if race.started.IsZero() {
+				return &BatchError{x, "Race has not started yet, cannot set a time"}
+			}
+			duration, err := core.ParseHumanDuration(op.Duration)
+			if err != nil {
+				return &BatchError{x, err.Error()}
+			}
+			entry.Duration = duration
+			entry.TimeFinished = time.Time{}
+			if duration > 0 {
+				entry.TimeFinished = race.lockedStartOf(entry).Add(time.Duration(duration))
+				entry.Confirmed = entry.Confirmed || op.Confirm
+			} else {
+				entry.Confirmed = false
+			}
+		case "setStatus":
+			status, err := core.ParseRegistrationStatus(op.Status)
+			if err != nil {
+				return &BatchError{x, err.Error()}
+			}
+			if !status.HasSpot() && entry.HasFinished() {
+				return &BatchError{x, fmt.Sprintf("Bib #%d has a finish time and can't be %s", entry.Bib, status)}
+			}
+			entry.Registration = status
+		default:
+			return &BatchError{x, fmt.Sprintf("Unknown operation %q", op.Op)}
+		}
+	}
+	for original, entry := range staged {
+		if entry.Duration != original.Duration {
+			delete(race.estimates, original.Bib) // corrected, so no longer an estimate
+		}
+		*original = *entry
+	}
+	race.bibbedEntries = bibs
+	race.lockedSortEntries()
+	race.lockedRecomputePrizes()
+	log.Printf("Applied a batch of %d operations to %d entries", len(ops), len(staged))
+	race.lockedRecordEvent(Event{Kind: "batch", Ops: ops})
+	return nil
+}
+
+// batchHandler applies a JSON list of operations, replying with how many were applied or which one failed
+func batchHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	w.Header().Set("Content-type", "application/json")
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(BatchError{Operation: -1, Error: "POST a JSON list of operations"})
+		return
+	}
+	var ops []BatchOp
+	if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(BatchError{Operation: -1, Error: fmt.Sprintf("Error reading operations - %v", err)})
+		return
+	}
+	if batchErr := race.ApplyBatch(ops); batchErr != nil {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(batchErr)
+		return
+	}
+	json.NewEncoder(w).Encode(struct {
+		Applied int `json:"applied"`
+	}{len(ops)})
+}
+
+// setTimeHandler corrects one racer's time from the admin results table, through the same checks as a batch
+func setTimeHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	bib, err := strconv.Atoi(r.FormValue("bib"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting bib number", err)
+		return
+	}
+	if batchErr := race.ApplyBatch([]BatchOp{{Op: "setTime", Bib: Bib(bib), Duration: r.FormValue("duration")}}); batchErr != nil {
+		showErrorForAdmin(w, r.Referer(), "%s", batchErr.Error)
+		return
+	}
+	http.Redirect(w, r, r.Referer(), 301)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyBatch(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38},
+		{Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 31},
+		{Bib: NoBib, Fname: "Cal", Lname: "Cole", Male: true, Age: 52},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	startRace(race)
+	*race.testingTime = race.testingTime.Add(20 * time.Minute)
+	linkBibTesting(t, race, 1, false)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest("POST", "/api/batch", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		batchHandler(w, r, race)
+		return w
+	}
+	// the bib swap is fine until the last operation fails, so nothing may change
+	w := post(`[{"op":"assignBib","bib":1,"newBib":9},{"op":"assignBib","bib":2,"newBib":1},{"op":"setStatus","bib":9,"status":"Lost"}]`)
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), `"operation":2`) {
+		t.Errorf("Expected the third operation to fail, got %d %s", w.Code, w.Body.String())
+	}
+	if race.bibbedEntries[1].Fname != "Amy" || race.bibbedEntries[2].Fname != "Bob" || race.bibbedEntries[9] != nil {
+		t.Errorf("Expected no bibs changed by the failed batch, got %v", race.bibbedEntries)
+	}
+
+	calPlace := 0
+	for x, e := range race.allEntries {
+		if e.Fname == "Cal" {
+			calPlace = x + 1
+		}
+	}
+	w = post(fmt.Sprintf(`[{"op":"assignBib","bib":1,"newBib":9},{"op":"assignBib","bib":2,"newBib":1},{"op":"assignBib","bib":9,"newBib":2},{"op":"setTime","bib":1,"duration":"00:18:30.00"},{"op":"setStatus","place":%d,"status":"Withdrawn"}]`, calPlace))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"applied":5`) {
+		t.Fatalf("Expected the batch applied, got %d %s", w.Code, w.Body.String())
+	}
+	if bob := race.bibbedEntries[1]; bob.Fname != "Bob" || bob.Duration != HumanDuration(18*time.Minute+30*time.Second) || race.allEntries[0] != bob {
+		t.Errorf("Expected Bob wearing bib 1 and finished first, got %#v", bob)
+	}
+	if race.bibbedEntries[2].Fname != "Amy" || race.allEntries[2].Fname != "Cal" || race.allEntries[2].Registration != Withdrawn {
+		t.Errorf("Expected Amy wearing bib 2 and Cal withdrawn, got %v %v", race.bibbedEntries[2], race.allEntries[2])
+	}
+	if w := post(`[{"op":"assignBib","bib":1,"newBib":2}]`); w.Code != http.StatusConflict {
+		t.Errorf("Expected a duplicate bib refused, got %d", w.Code)
+	}
+}
+
+func TestAdminRowActions(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38},
+		{Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 31},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	startRace(race)
+	*race.testingTime = race.testingTime.Add(20 * time.Minute)
+	linkBibTesting(t, race, 1, false)
+
+	r, _ := http.NewRequest("GET", "/admin", nil)
+	w := httptest.NewRecorder()
+	handler(w, r, race)
+	body := w.Body.String()
+	for _, want := range []string{`value="00:20:00.00"`, `<button class="btn btn-success btn-sm" type="submit">Confirm</button>`, `href="/events?bib=2"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %s on the admin page", want)
+		}
+	}
+
+	r, _ = http.NewRequest("POST", "/setTime?bib=1&duration=00:19:45.50", nil)
+	r.Header.Set("Referer", "/admin")
+	w = httptest.NewRecorder()
+	setTimeHandler(w, r, race)
+	if w.Code != 301 || race.bibbedEntries[1].Duration != HumanDuration(19*time.Minute+45*time.Second+500*time.Millisecond) {
+		t.Errorf("Expected Amy's time corrected, got %d %s", w.Code, race.bibbedEntries[1].Duration)
+	}
+	r, _ = http.NewRequest("POST", "/setTime?bib=2&duration=soon", nil)
+	w = httptest.NewRecorder()
+	setTimeHandler(w, r, race)
+	if w.Code != 409 || race.bibbedEntries[2].HasFinished() {
+		t.Errorf("Expected a bad time refused, got %d", w.Code)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// lockedUnconfirmed returns the finishers still waiting to be confirmed, in place order, limited to those in the
+// first throughPlace places and who finished more than olderThan ago when those are set
+func (race *Race) lockedUnconfirmed(throughPlace int, olderThan time.Duration, now time.Time) []*Entry {
+	unconfirmed := make([]*Entry, 0)
+	for place, e := range race.allEntries {
+		if !e.HasFinished() || (throughPlace > 0 && place >= throughPlace) {
+			break // finishers are sorted first, in place order
+		}
+		if e.Confirmed || e.Bib < 0 || (olderThan > 0 && now.Sub(e.TimeFinished) < olderThan) {
+			continue
+		}
+		unconfirmed = append(unconfirmed, e)
+	}
+	return unconfirmed
+}
+
+// BulkConfirm confirms the unconfirmed finishes picked by lockedUnconfirmed, sending each racer their result e-mail
+func (race *Race) BulkConfirm(throughPlace int, olderThan time.Duration) (int, error) {
+	if throughPlace <= 0 && olderThan <= 0 {
+		return 0, fmt.Errorf("Choose a place or an age for the finishes to confirm")
+	}
+	race.Lock()
+	defer race.Unlock()
+	now := race.GetTime()
+	confirmed := 0
+	for _, e := range race.lockedUnconfirmed(throughPlace, olderThan, now) {
+		if err := race.lockedRecordTimeForBib(e.Bib, now, false); err != nil {
+			return confirmed, err
+		}
+		confirmed++
+	}
+	log.Printf("Bulk confirmed %d finishes", confirmed)
+	return confirmed, nil
+}
+
+// parseBulkConfirm reads the place and minutes from the bulk confirm form, blank for either is no limit
+func parseBulkConfirm(r *http.Request) (int, time.Duration, error) {
+	throughPlace, olderThan := 0, 0
+	var err error
+	if val := r.FormValue("place"); val != "" {
+		if throughPlace, err = strconv.Atoi(val); err != nil || throughPlace < 0 {
+			return 0, 0, fmt.Errorf("%s is not a place", val)
+		}
+	}
+	if val := r.FormValue("minutes"); val != "" {
+		if olderThan, err = strconv.Atoi(val); err != nil || olderThan < 0 {
+			return 0, 0, fmt.Errorf("%s is not a number of minutes", val)
+		}
+	}
+	return throughPlace, time.Duration(olderThan) * time.Minute, nil
+}
+
+func bulkConfirmHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	throughPlace, olderThan, err := parseBulkConfirm(r)
+	if err == nil {
+		_, err = race.BulkConfirm(throughPlace, olderThan)
+	}
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/admin", 301)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBulkConfirm(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38},
+		{Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 31},
+		{Bib: 3, Fname: "Cal", Lname: "Cole", Male: true, Age: 52},
+		{Bib: 4, Fname: "Dee", Lname: "Dunn", Age: 44},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	startRace(race)
+	for _, bib := range []int{1, 2, 3, 4} {
+		*race.testingTime = race.testingTime.Add(2 * time.Minute)
+		linkBibTesting(t, race, bib, false)
+	}
+	linkBibTesting(t, race, 2, false) // Bob was confirmed by the chute crew
+
+	r, _ := http.NewRequest("GET", "/bulkConfirm?minutes=2", nil)
+	w := httptest.NewRecorder()
+	handler(w, r, race)
+	if body := w.Body.String(); !strings.Contains(body, "Amy Brown") || !strings.Contains(body, "Cal Cole") || strings.Contains(body, "Bob Adams") || strings.Contains(body, "Dee Dunn") {
+		t.Errorf("Expected Amy and Cal to review, who finished at least 2 minutes ago - %s", body)
+	}
+	if _, err := race.BulkConfirm(0, 0); err == nil {
+		t.Errorf("Expected an error confirming without a place or age")
+	}
+	confirmed, err := race.BulkConfirm(0, 2*time.Minute)
+	if err != nil || confirmed != 2 {
+		t.Fatalf("Expected 2 confirmed, got %d - %v", confirmed, err)
+	}
+	if !race.bibbedEntries[1].Confirmed || !race.bibbedEntries[3].Confirmed || race.bibbedEntries[4].Confirmed {
+		t.Errorf("Expected Amy and Cal confirmed, Dee still waiting")
+	}
+	if confirmed, _ = race.BulkConfirm(4, 0); confirmed != 1 || !race.bibbedEntries[4].Confirmed {
+		t.Errorf("Expected Dee confirmed through 4th place, got %d", confirmed)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const icalTimeFormat = "20060102T150405Z"
+
+func (race *Race) calendarURL() string {
+	return race.raceURL("/schedule.ics")
+}
+
+// icalEscape escapes text values, see RFC 5545 section 3.3.11
+func icalEscape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(text)
+}
+
+// writeICalLine writes the content line folded at 75 octets, see RFC 5545 section 3.1
+func writeICalLine(w io.Writer, line string) {
+	for len(line) > 75 {
+		cut := 75
+		for cut > 0 && line[cut]&0xC0 == 0x80 { // don't split a UTF-8 character
+			cut--
+		}
+		fmt.Fprintf(w, "%s\r\n ", line[:cut])
+		line = line[cut:]
+	}
+	fmt.Fprintf(w, "%s\r\n", line)
+}
+
+// lockedWriteCalendar writes the schedule as an iCalendar feed.  Each item's UID comes from what it is, so a
+// subscribed calendar moves the existing event when its time changes instead of adding another.
+func (race *Race) lockedWriteCalendar(w io.Writer) {
+	now := race.GetTime().UTC().Format(icalTimeFormat)
+	for _, line := range []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//racergo//Race Schedule//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:" + icalEscape(race.lockedName()),
+		"REFRESH-INTERVAL;VALUE=DURATION:PT1H",
+		"X-PUBLISHED-TTL:PT1H",
+	} {
+		writeICalLine(w, line)
+	}
+	for _, item := range race.schedule {
+		uid := fnv.New64a()
+		io.WriteString(uid, race.slug+item.What) // the main race's items keep the UIDs they had before races were hosted
+		for _, line := range []string{
+			"BEGIN:VEVENT",
+			fmt.Sprintf("UID:%x@%s", uid.Sum64(), config.webserverHostname),
+			"DTSTAMP:" + now,
+			"DTSTART:" + item.At.UTC().Format(icalTimeFormat),
+			"SUMMARY:" + icalEscape(fmt.Sprintf("%s - %s", race.lockedName(), item.What)),
+			"URL:" + race.raceURL("/info"),
+			"END:VEVENT",
+		} {
+			writeICalLine(w, line)
+		}
+	}
+	writeICalLine(w, "END:VCALENDAR")
+}
+
+// calendarHandler serves the schedule for entrants to subscribe to, calendar apps fetch it again as it changes
+func calendarHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	w.Header().Set("Content-type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", "inline; filename=\"schedule.ics\"")
+	race.RLock()
+	defer race.RUnlock()
+	race.lockedWriteCalendar(w)
+}
+
+// scheduleDay is the day schedule items are on, the race date if it's set, otherwise today
+func (race *Race) scheduleDay() time.Time {
+	if !config.raceDate.IsZero() {
+		return config.raceDate
+	}
+	return race.GetTime()
+}
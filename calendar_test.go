@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendar(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 5, 20, 12, 0, 0, 0, time.Local)
+	raceDate := config.raceDate
+	defer func() { config.raceDate = raceDate }()
+	config.raceDate = time.Date(2014, 6, 1, 0, 0, 0, 0, time.Local)
+	for _, item := range [][2]string{{"09:30", "Wave 1 start"}, {"11:00", "Awards, and raffle"}} {
+		if err := race.AddScheduleItem(item[0], item[1]); err != nil {
+			t.Fatalf("Unexpected error - %v", err)
+		}
+	}
+	r, _ := http.NewRequest("GET", "/schedule.ics", nil)
+	w := httptest.NewRecorder()
+	calendarHandler(w, r, race)
+	body := w.Body.String()
+	if !strings.HasPrefix(body, "BEGIN:VCALENDAR\r\n") || !strings.HasSuffix(body, "END:VCALENDAR\r\n") || strings.Count(body, "BEGIN:VEVENT") != 2 {
+		t.Errorf("Expected a calendar with 2 events - %s", body)
+	}
+	start := "DTSTART:" + time.Date(2014, 6, 1, 9, 30, 0, 0, time.Local).UTC().Format(icalTimeFormat)
+	if unfolded := strings.Replace(body, "\r\n ", "", -1); !strings.Contains(unfolded, start) || !strings.Contains(unfolded, `Awards\, and raffle`) {
+		t.Errorf("Expected the start on race day and escaped commas - %s", body)
+	}
+	for _, line := range strings.Split(body, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Expected lines folded at 75 octets, got %q", line)
+		}
+	}
+	uid := body[strings.Index(body, "UID:"):]
+	uid = uid[:strings.Index(uid, "\r\n")]
+
+	race.RemoveScheduleItem(0)
+	race.AddScheduleItem("09:45", "Wave 1 start")
+	w = httptest.NewRecorder()
+	calendarHandler(w, r, race)
+	if body = w.Body.String(); !strings.Contains(body, uid) || strings.Contains(body, start) {
+		t.Errorf("Expected the moved start to keep its UID %s - %s", uid, body)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// lockedActiveEntries counts the entries holding a spot in the race
+func (race *Race) lockedActiveEntries() int {
+	active := 0
+	for _, e := range race.allEntries {
+		if e.Registration.HasSpot() {
+			active++
+		}
+	}
+	return active
+}
+
+// lockedWaitlist returns the waitlisted entries in the order they will be promoted
+func (race *Race) lockedWaitlist() []*Entry {
+	waitlist := make([]*Entry, 0, len(race.waitlist))
+	for _, e := range race.waitlist {
+		if e.Registration == Waitlisted {
+			waitlist = append(waitlist, e)
+		}
+	}
+	return waitlist
+}
+
+// lockedPromote fills any open spots from the front of the waitlist, letting the promoted entrants know
+func (race *Race) lockedPromote() {
+	for _, e := range race.lockedWaitlist() {
+		if race.capacity > 0 && race.lockedActiveEntries() >= race.capacity {
+			return
+		}
+		e.Registration = Registered
+		log.Printf("Promoted %s %s from the waitlist", e.Fname, e.Lname)
+		if !race.replaying {
+			subject, text := race.lockedWaitlistEmail(*e)
+			go sendEmail(*e, race.optionalEmailIndex, subject, text)
+		}
+	}
+}
+
+// SetCapacity limits how many entries can hold a spot in the race, 0 for unlimited.
+// Raising the capacity promotes entrants from the waitlist, lowering it never bumps anyone.
+func (race *Race) SetCapacity(capacity int) error {
+	if capacity < 0 {
+		return fmt.Errorf("Capacity cannot be negative, got %d", capacity)
+	}
+	race.Lock()
+	defer race.Unlock()
+	race.capacity = capacity
+	race.lockedPromote()
+	race.lockedRecordEvent(Event{Kind: "capacity", Capacity: capacity})
+	return nil
+}
+
+// Withdraw gives up the entry's spot in the race and promotes the next entrant on the waitlist
+func (race *Race) Withdraw(nonce string, place Place) error {
+	race.Lock()
+	defer race.Unlock()
+	placeIndex := int(place - 1)
+	if placeIndex < 0 || placeIndex >= len(race.allEntries) {
+		return fmt.Errorf("placeIndex of %d is out of bounds", placeIndex)
+	}
+	entry := race.allEntries[placeIndex]
+	if nonce != entry.Nonce() {
+		return fmt.Errorf("Error withdrawing entry - audit record was out of date, try your change again")
+	}
+	if entry.HasFinished() {
+		return fmt.Errorf("%s %s has already finished, cannot withdraw", entry.Fname, entry.Lname)
+	}
+	entry.Registration = Withdrawn
+	log.Printf("%s %s withdrew", entry.Fname, entry.Lname)
+	race.lockedPromote()
+	race.lockedRecomputePrizes()
+	race.lockedRecordEvent(Event{Kind: "withdraw", Nonce: nonce, Place: place})
+	return nil
+}
+
+func withdrawHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	place, err := strconv.Atoi(r.FormValue("Place"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting place", err)
+		return
+	}
+	err = race.Withdraw(r.FormValue("Nonce"), Place(place))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/admin", 301)
+}
+
+func setCapacityHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	capacity, err := strconv.Atoi(r.FormValue("capacity"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting capacity", err)
+		return
+	}
+	err = race.SetCapacity(capacity)
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/admin", 301)
+}
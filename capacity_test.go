@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func withdrawTestEntry(t *testing.T, race *Race, bib Bib, code int) {
+	race.RLock()
+	values := url.Values{}
+	for x, e := range race.allEntries {
+		if e.Bib == bib {
+			values.Set("Place", strconv.Itoa(x+1))
+			values.Set("Nonce", e.Nonce())
+		}
+	}
+	race.RUnlock()
+	r, _ := http.NewRequest("POST", "/withdraw?"+values.Encode(), nil)
+	w := httptest.NewRecorder()
+	withdrawHandler(w, r, race)
+	if w.Code != code {
+		t.Errorf("Withdrawing bib %s - expected %d, got %d - %s", bib, code, w.Code, w.Body.String())
+	}
+}
+
+func TestCapacityWaitlist(t *testing.T) {
+	race := NewRace()
+	if err := race.SetCapacity(2); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	for x := 1; x <= 4; x++ {
+		if err := race.AddEntry(Entry{Bib: Bib(x), Fname: strconv.Itoa(x), Lname: "L", Age: 30}); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	status := func(bib Bib) RegistrationStatus {
+		race.RLock()
+		defer race.RUnlock()
+		return race.bibbedEntries[bib].Registration
+	}
+	for bib, want := range map[Bib]RegistrationStatus{1: Registered, 2: Registered, 3: Waitlisted, 4: Waitlisted} {
+		if got := status(bib); got != want {
+			t.Errorf("Bib %s - expected %s, got %s", bib, want, got)
+		}
+	}
+
+	// waitlisted entries keep their place in line even after being edited
+	race.RLock()
+	entry := *race.bibbedEntries[3]
+	nonce := entry.Nonce()
+	race.RUnlock()
+	entry.Bib = 13
+	if err := race.ModifyEntry(nonce, 3, entry); err != nil {
+		t.Fatalf("Error modifying entry - %v", err)
+	}
+
+	withdrawTestEntry(t, race, 1, http.StatusMovedPermanently)
+	for bib, want := range map[Bib]RegistrationStatus{1: Withdrawn, 2: Registered, 13: Registered, 4: Waitlisted} {
+		if got := status(bib); got != want {
+			t.Errorf("Bib %s - expected %s, got %s", bib, want, got)
+		}
+	}
+	withdrawTestEntry(t, race, 1, http.StatusMovedPermanently) // withdrawing again doesn't promote anyone
+	if got := status(4); got != Waitlisted {
+		t.Errorf("Bib 4 - expected %s, got %s", Waitlisted, got)
+	}
+	if err := race.SetCapacity(0); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if got := status(4); got != Registered {
+		t.Errorf("Bib 4 - expected %s after removing the capacity, got %s", Registered, got)
+	}
+
+	startRace(race)
+	if err := race.RecordTimeForBib(1); err == nil {
+		t.Errorf("Expected error recording a time for a withdrawn entry")
+	}
+	if err := race.RecordTimeForBib(2); err != nil {
+		t.Errorf("Unexpected error - %v", err)
+	}
+	withdrawTestEntry(t, race, 2, 409) // already finished
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxCheerLength keeps cheers short enough for the announcer to read out as the racer crosses the line
+const maxCheerLength = 140
+
+// CheerStatus tracks a spectator's cheer through moderation, only approved cheers are shown
+type CheerStatus uint8
+
+const (
+	CheerPending CheerStatus = iota
+	CheerApproved
+	CheerRejected
+)
+
+func (cs CheerStatus) String() string {
+	switch cs {
+	case CheerApproved:
+		return "Approved"
+	case CheerRejected:
+		return "Rejected"
+	}
+	return "Pending"
+}
+
+// Cheer is a message from a spectator for a racer, read out and e-mailed when they finish
+type Cheer struct {
+	ID      int
+	Bib     Bib
+	From    string
+	Message string
+	Sent    time.Time
+	Status  CheerStatus
+}
+
+func (c Cheer) String() string {
+	if c.From == "" {
+		return c.Message
+	}
+	return fmt.Sprintf("%s - %s", c.Message, c.From)
+}
+
+// SubmitCheer queues a spectator's message for the bib until an admin approves it
+func (race *Race) SubmitCheer(bib Bib, from, message string) error {
+	from, message = strings.TrimSpace(from), strings.TrimSpace(message)
+	if message == "" {
+		return fmt.Errorf("The cheer is blank")
+	}
+	if utf8.RuneCountInString(message) > maxCheerLength {
+		return fmt.Errorf("Cheers can be up to %d characters, that one is %d", maxCheerLength, utf8.RuneCountInString(message))
+	}
+	race.Lock()
+	defer race.Unlock()
+	if race.finalized {
+		return fmt.Errorf("The race is over, cheers are closed")
+	}
+	if _, ok := race.bibbedEntries[bib]; !ok {
+		return fmt.Errorf("No racer with bib #%d", bib)
+	}
+	race.cheers = append(race.cheers, &Cheer{ID: len(race.cheers) + 1, Bib: bib, From: from, Message: message, Sent: race.GetTime()})
+	log.Printf("Cheer %d for bib #%d waiting for approval", len(race.cheers), bib)
+	return nil
+}
+
+// ModerateCheer approves or rejects the cheer
+func (race *Race) ModerateCheer(id int, approve bool) error {
+	race.Lock()
+	defer race.Unlock()
+	if id < 1 || id > len(race.cheers) {
+		return fmt.Errorf("Cheer %d not found", id)
+	}
+	race.cheers[id-1].Status = CheerRejected
+	if approve {
+		race.cheers[id-1].Status = CheerApproved
+	}
+	race.lockedEntriesChanged()
+	return nil
+}
+
+// lockedCheersFor lists the bib's approved cheers
+func (race *Race) lockedCheersFor(bib Bib) []string {
+	var cheers []string
+	for _, c := range race.cheers {
+		if c.Bib == bib && c.Status == CheerApproved {
+			cheers = append(cheers, c.String())
+		}
+	}
+	return cheers
+}
+
+func submitCheerHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	bib, err := strconv.Atoi(r.FormValue("bib"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting bib number", err)
+		return
+	}
+	if err = race.SubmitCheer(Bib(bib), r.FormValue("from"), r.FormValue("message")); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/cheer?sent=true", 301)
+}
+
+func cheerActionHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting cheer", err)
+		return
+	}
+	switch r.FormValue("action") {
+	case "approve":
+		err = race.ModerateCheer(id, true)
+	case "reject":
+		err = race.ModerateCheer(id, false)
+	default:
+		err = fmt.Errorf("Unknown cheer action %s", r.FormValue("action"))
+	}
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/cheers", 301)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheers(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	race.AddEntry(Entry{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38})
+
+	r, _ := http.NewRequest("POST", "/submitCheer?bib=1&message=Go+Mom!&from=Sam", nil)
+	w := httptest.NewRecorder()
+	submitCheerHandler(w, r, race)
+	if w.Code != 301 || len(race.cheers) != 1 || race.cheers[0].Status != CheerPending {
+		t.Fatalf("Expected the cheer queued for approval, got %d - %s", w.Code, w.Body.String())
+	}
+	for _, bad := range []struct {
+		bib     Bib
+		message string
+	}{{1, " "}, {1, strings.Repeat("go ", 50)}, {2, "Go!"}} {
+		if err := race.SubmitCheer(bad.bib, "", bad.message); err == nil {
+			t.Errorf("Expected %q for bib %d refused", bad.message, bad.bib)
+		}
+	}
+	race.SubmitCheer(1, "", "Not nice")
+
+	startRace(race)
+	*race.testingTime = race.testingTime.Add(20 * time.Minute)
+	linkBibTesting(t, race, 1, false)
+	race.RLock()
+	recent := race.lockedRecentRacers(10)
+	race.RUnlock()
+	if len(recent[0].Cheers) != 0 {
+		t.Errorf("Expected no cheers read out before approval, got %v", recent[0].Cheers)
+	}
+
+	r, _ = http.NewRequest("POST", "/cheerAction?id=1&action=approve", nil)
+	w = httptest.NewRecorder()
+	cheerActionHandler(w, r, race)
+	if err := race.ModerateCheer(2, false); err != nil || w.Code != 301 {
+		t.Fatalf("Error moderating cheers - %d %v", w.Code, err)
+	}
+	r, _ = http.NewRequest("GET", "/results", nil)
+	w = httptest.NewRecorder()
+	handler(w, r, race)
+	if page := w.Body.String(); !strings.Contains(page, "Go Mom! - Sam") || strings.Contains(page, "Not nice") {
+		t.Errorf("Expected only the approved cheer on the announcer screen, got %s", page)
+	}
+	race.RLock()
+	_, text, html := resultsEmail(race.lockedResultSummary(race.bibbedEntries[1]))
+	race.RUnlock()
+	if !strings.Contains(text, "Cheers from your supporters:\nGo Mom! - Sam\n") || !strings.Contains(html, "Go Mom! - Sam") {
+		t.Errorf("Expected the cheer in the results e-mail, got %s", text)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChipRead is a tag seen by a chip timing reader
+type ChipRead struct {
+	Tag        string
+	Time       time.Time // zero when the reader didn't send one, the read is timed when it arrives
+	Checkpoint string    // START for the readers on the start mat, blank for the finish
+}
+
+// ChipStats is how the chip timing is going, for the admin page
+type ChipStats struct {
+	Tags     int                  // tags mapped to bibs
+	Reads    int                  // reads of mapped tags
+	Unknown  int                  // reads of tags that aren't mapped
+	LastRead time.Time            // the last read of a mapped tag
+	Readers  map[string]time.Time // the connected readers by address, with when they connected
+}
+
+// ReaderList is the connected readers in the order they connected
+func (cs ChipStats) ReaderList() []string {
+	list := make([]string, 0, len(cs.Readers))
+	for addr := range cs.Readers {
+		list = append(list, addr)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return cs.Readers[list[i]].Before(cs.Readers[list[j]])
+	})
+	return list
+}
+
+// parseChipRead reads a line from a chip timing reader, one of
+//
+//	IPICO      aa, the reader, a 12 digit tag, 4 digits of signal, yymmddhhmmss, the hundredths and checksum in hex, e.g. from an Elite or Lite reader
+//	tag,time   a tag and optionally the time it was read, comma or tab separated, as exported by RFID Race Timing Systems
+//	           readers and LLRP middleware.  The time is 15:04:05.000 today or RFC 3339, other columns are ignored.
+func parseChipRead(line string, now time.Time) (ChipRead, error) {
+	line = strings.TrimSpace(line)
+	if len(line) >= 36 && strings.HasPrefix(line, "aa") && !strings.ContainsAny(line[:36], ",\t") {
+		return parseIPICORead(line[:36], now.Location())
+	}
+	fields := strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == '\t' })
+	if len(fields) == 0 {
+		return ChipRead{}, fmt.Errorf("blank read")
+	}
+	read := ChipRead{Tag: normalizeTag(fields[0])}
+	if len(fields) == 1 || strings.TrimSpace(fields[1]) == "" {
+		return read, nil
+	}
+	at := strings.TrimSpace(fields[1])
+	if t, err := time.Parse(time.RFC3339Nano, at); err == nil {
+		read.Time = t
+		return read, nil
+	}
+	t, err := time.ParseInLocation("15:04:05.999999999", at, now.Location())
+	if err != nil {
+		return ChipRead{}, fmt.Errorf("%s isn't a time of day or RFC 3339 time", at)
+	}
+	y, m, d := now.Date()
+	read.Time = time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), now.Location())
+	return read, nil
+}
+
+func parseIPICORead(line string, loc *time.Location) (ChipRead, error) {
+	var sum byte
+	for x := 2; x < 34; x++ {
+		sum += line[x]
+	}
+	if check, err := strconv.ParseUint(line[34:36], 16, 8); err != nil || byte(check) != sum {
+		return ChipRead{}, fmt.Errorf("IPICO read %s has a bad checksum", line)
+	}
+	t, err := time.ParseInLocation("060102150405", line[20:32], loc)
+	if err != nil {
+		return ChipRead{}, fmt.Errorf("IPICO read %s has a bad time - %v", line, err)
+	}
+	hundredths, err := strconv.ParseUint(line[32:34], 16, 8)
+	if err != nil || hundredths > 99 {
+		return ChipRead{}, fmt.Errorf("IPICO read %s has bad hundredths", line)
+	}
+	return ChipRead{Tag: normalizeTag(line[4:16]), Time: t.Add(time.Duration(hundredths) * 10 * time.Millisecond)}, nil
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToUpper(strings.TrimSpace(tag))
+}
+
+// parseChipTags reads the uploaded CSV mapping tags to bibs, it needs Tag and Bib columns
+func parseChipTags(data []byte) (map[string]Bib, error) {
+	rows, _, err := readCSV(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("the tag file is empty")
+	}
+	tagCol, bibCol := -1, -1
+	for x, column := range rows[0] {
+		switch strings.ToLower(strings.TrimSpace(column)) {
+		case "tag":
+			tagCol = x
+		case "bib":
+			bibCol = x
+		}
+	}
+	if tagCol < 0 || bibCol < 0 {
+		return nil, fmt.Errorf("the tag file needs Tag and Bib columns, it has %s", strings.Join(rows[0], ", "))
+	}
+	tags := make(map[string]Bib, len(rows)-1)
+	for x, row := range rows[1:] {
+		tag := normalizeTag(row[tagCol])
+		if tag == "" {
+			continue
+		}
+		bib, err := strconv.Atoi(strings.TrimSpace(row[bibCol]))
+		if err != nil || bib < 0 {
+			return nil, fmt.Errorf("row %d has %s for tag %s's bib", x+2, row[bibCol], tag)
+		}
+		if other, ok := tags[tag]; ok && other != Bib(bib) {
+			return nil, fmt.Errorf("tag %s is on both bib %d and bib %d", tag, other, bib)
+		}
+		tags[tag] = Bib(bib)
+	}
+	return tags, nil
+}
+
+// SetChipTags replaces which tag is on which bib
+func (race *Race) SetChipTags(tags map[string]Bib) {
+	race.Lock()
+	defer race.Unlock()
+	race.chipTags = tags
+	race.chipStats.Tags = len(tags)
+	race.lockedRecordEvent(Event{Kind: "chipTags", Tags: tags})
+	log.Printf("Loaded %d chip tags", len(tags))
+}
+
+// RecordChipRead links the tag's bib just as if its finish was keyed in, reads of tags not on a bib are counted and refused
+func (race *Race) RecordChipRead(read ChipRead, reader string) error {
+	race.Lock()
+	bib, ok := race.chipTags[read.Tag]
+	if !ok {
+		race.chipStats.Unknown++
+		race.Unlock()
+		return fmt.Errorf("tag %s isn't on a bib", read.Tag)
+	}
+	race.chipStats.Reads++
+	race.chipStats.LastRead = race.GetTime()
+	race.Unlock()
+	return race.RecordTagRead(TagRead{Bib: bib, Time: read.Time, Checkpoint: read.Checkpoint, Reader: reader})
+}
+
+func (race *Race) chipReaderConnected(addr string, connected bool) {
+	race.Lock()
+	defer race.Unlock()
+	if race.chipStats.Readers == nil {
+		race.chipStats.Readers = make(map[string]time.Time)
+	}
+	if connected {
+		race.chipStats.Readers[addr] = time.Now()
+	} else {
+		delete(race.chipStats.Readers, addr)
+	}
+}
+
+// serveChipReaders accepts connections from chip timing readers until the listener is closed
+func serveChipReaders(race *Race, ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go race.readChips(conn)
+	}
+}
+
+// readChips records each line the reader sends, a reader sends every tag many times as it crosses the mat
+// and only the first read of a bib counts
+func (race *Race) readChips(conn net.Conn) {
+	defer conn.Close()
+	addr := conn.RemoteAddr().String()
+	reader := "chip reader " + addr
+	checkpoint := chipReaderCheckpoint(addr)
+	log.Printf("Chip reader connected from %s", addr)
+	race.chipReaderConnected(addr, true)
+	defer race.chipReaderConnected(addr, false)
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		read, err := parseChipRead(line, time.Now())
+		if err != nil {
+			log.Printf("Ignoring %s from %s - %v", line, addr, err)
+			continue
+		}
+		read.Checkpoint = checkpoint
+		if err = race.RecordChipRead(read, reader); err != nil {
+			log.Printf("Ignoring tag %s from %s - %v", read.Tag, addr, err)
+		}
+	}
+	log.Printf("Chip reader %s disconnected - %v", addr, scanner.Err())
+}
+
+// chipReaderCheckpoint is START for the readers listed in RACERGOCHIPSTARTREADERS by host or host:port
+func chipReaderCheckpoint(addr string) string {
+	host, _, _ := net.SplitHostPort(addr)
+	for _, start := range config.chipStartReaders {
+		if start == addr || start == host {
+			return startCheckpoint
+		}
+	}
+	return ""
+}
+
+func uploadChipTagsHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error getting Reader - %s", err)
+		return
+	}
+	data, err := readUpload(r, "tags")
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error reading the tag file - %v", err)
+		return
+	}
+	tags, err := parseChipTags(data)
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	race.SetChipTags(tags)
+	http.Redirect(w, r, "/admin", 301)
+}
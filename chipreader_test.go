@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+// ipicoLine makes a read as an IPICO reader sends it, with its checksum
+func ipicoLine(tag, at string, hundredths int) string {
+	body := fmt.Sprintf("01%s0000%s%02x", tag, at, hundredths)
+	var sum byte
+	for x := range body {
+		sum += body[x]
+	}
+	return fmt.Sprintf("aa%s%02xFS", body, sum)
+}
+
+func TestParseChipRead(t *testing.T) {
+	now := time.Date(2014, 6, 1, 10, 0, 0, 0, time.Local)
+	read, err := parseChipRead(ipicoLine("058001c95ae7", "140601091530", 0x2a)+"\r\n", now)
+	if err != nil || read.Tag != "058001C95AE7" || !read.Time.Equal(time.Date(2014, 6, 1, 9, 15, 30, 420*int(time.Millisecond), time.Local)) {
+		t.Errorf("Expected the IPICO read at 9:15:30.42, got %#v %v", read, err)
+	}
+	bad := []byte(ipicoLine("058001c95ae7", "140601091530", 0x2a))
+	bad[10] = 'f'
+	if _, err = parseChipRead(string(bad), now); err == nil {
+		t.Errorf("Expected a garbled IPICO read refused")
+	}
+	tests := []struct {
+		line string
+		tag  string
+		at   time.Time
+	}{
+		{"e2003412,09:20:01.250", "E2003412", time.Date(2014, 6, 1, 9, 20, 1, 250*int(time.Millisecond), time.Local)},
+		{"E2003412\t2014-06-01T13:20:01Z\t2", "E2003412", time.Date(2014, 6, 1, 13, 20, 1, 0, time.UTC)},
+		{"E2003412", "E2003412", time.Time{}},
+	}
+	for _, test := range tests {
+		read, err := parseChipRead(test.line, now)
+		if err != nil || read.Tag != test.tag || !read.Time.Equal(test.at) {
+			t.Errorf("%q - expected %s at %s, got %#v %v", test.line, test.tag, test.at, read, err)
+		}
+	}
+	if _, err = parseChipRead("E2003412,quarter past", now); err == nil {
+		t.Errorf("Expected a bad time refused")
+	}
+}
+
+func TestParseChipTags(t *testing.T) {
+	tags, err := parseChipTags([]byte("Bib,Tag\n1,e200\n2,E201\n3,\n"))
+	if err != nil || len(tags) != 2 || tags["E200"] != 1 || tags["E201"] != 2 {
+		t.Errorf("Expected two tags, got %v %v", tags, err)
+	}
+	for _, data := range []string{"Bib,Chip\n1,E200\n", "Tag,Bib\nE200,one\n", "Tag,Bib\nE200,1\nE200,2\n"} {
+		if _, err = parseChipTags([]byte(data)); err == nil {
+			t.Errorf("Expected %q refused", data)
+		}
+	}
+}
+
+func TestChipReader(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{{Bib: 1, Fname: "Amy", Lname: "Brown"}, {Bib: 2, Fname: "Bob", Lname: "Adams", Male: true}} {
+		race.AddEntry(e)
+	}
+	race.SetChipTags(map[string]Bib{"058001C95AE7": 1, "058001C95AE8": 2})
+	startRace(race)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening - %v", err)
+	}
+	defer ln.Close()
+	go serveChipReaders(race, ln)
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Error connecting - %v", err)
+	}
+	at := race.started.Add(18 * time.Minute)
+	fmt.Fprintf(conn, "%s\r\n", ipicoLine("058001c95ae8", at.Format("060102150405"), 50))
+	fmt.Fprintf(conn, "%s\r\n", ipicoLine("058001c95ae8", at.Add(time.Second).Format("060102150405"), 0))
+	fmt.Fprintf(conn, "%s\r\n", ipicoLine("058001c95ae9", at.Format("060102150405"), 0))
+	fmt.Fprintf(conn, "058001C95AE7,%s\r\n", at.Add(2*time.Minute).Format(time.RFC3339))
+	conn.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		race.RLock()
+		reads, connected := race.chipStats.Reads, len(race.chipStats.Readers)
+		race.RUnlock()
+		if reads == 3 && connected == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected three reads of known tags, got %d", reads)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	race.RLock()
+	defer race.RUnlock()
+	if race.chipStats.Unknown != 1 {
+		t.Errorf("Expected one read of an unknown tag, got %d", race.chipStats.Unknown)
+	}
+	if bob := race.allEntries[0]; bob.Bib != 2 || bob.Duration != HumanDuration(18*time.Minute+500*time.Millisecond) {
+		t.Errorf("Expected Bob first at his first read, got %#v", bob)
+	}
+	if amy := race.allEntries[1]; amy.Bib != 1 || amy.Duration != HumanDuration(20*time.Minute) {
+		t.Errorf("Expected Amy second, got %#v", amy)
+	}
+}
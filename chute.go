@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// In chute mode, like select timing, the line only records that someone finished and volunteers
+// record bibs in order as runners come out of the chute.  The two are paired by order as they come in.
+
+// ChuteTime records a finish time at the line without a bib, returning its position in the queue
+func (race *Race) ChuteTime() (int, error) {
+	race.Lock()
+	defer race.Unlock()
+	if race.started.IsZero() {
+		return 0, fmt.Errorf("Race has not started yet, cannot record a finish")
+	}
+	if race.finalized {
+		return 0, fmt.Errorf("Results have been finalized, cannot record a finish")
+	}
+	race.chute.Times = append(race.chute.Times, HumanDuration(race.GetTime().Sub(race.started)))
+	position := len(race.chute.Times)
+	race.lockedPairChute()
+	return position, nil
+}
+
+// ChuteBib records the next bib out of the chute
+func (race *Race) ChuteBib(bib Bib) error {
+	race.Lock()
+	defer race.Unlock()
+	if race.started.IsZero() {
+		return fmt.Errorf("Race has not started yet, cannot record a finish")
+	}
+	race.chute.Bibs = append(race.chute.Bibs, bib)
+	race.lockedPairChute()
+	return nil
+}
+
+// EditChute inserts or deletes a time or bib where the line and chute got out of step
+func (race *Race) EditChute(action string, position int) error {
+	race.Lock()
+	defer race.Unlock()
+	if err := race.chute.Edit(action, position); err != nil {
+		return err
+	}
+	race.lockedPairChute()
+	return nil
+}
+
+// lockedPairChute gives the times at the front of the queue to their bibs, stopping at the first
+// row that's waiting on the other side or needs fixing so nothing after it gets paired out of order
+func (race *Race) lockedPairChute() {
+	paired := 0
+	for _, row := range race.lockedPaperRows(&race.chute) {
+		if row.Problem != "" {
+			break
+		}
+		finished := race.started.Add(time.Duration(row.Time))
+		if err := race.lockedRecordTimeForBib(row.Bib, finished, false); err != nil {
+			log.Printf("Error pairing chute bib #%d - %v", row.Bib, err)
+			break
+		}
+		if err := race.lockedRecordTimeForBib(row.Bib, finished, false); err != nil {
+			log.Printf("Error confirming chute bib #%d - %v", row.Bib, err)
+			break
+		}
+		paired++
+	}
+	race.chute.Times = race.chute.Times[paired:]
+	race.chute.Bibs = race.chute.Bibs[paired:]
+	race.chutePaired += paired
+}
+
+func chuteTimeHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	if _, err := race.ChuteTime(); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/chute", 301)
+}
+
+func chuteBibHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	bib, err := strconv.Atoi(r.FormValue("bib"))
+	if err != nil || bib < 0 {
+		showErrorForAdmin(w, r.Referer(), "%s is not a bib number", r.FormValue("bib"))
+		return
+	}
+	if err = race.ChuteBib(Bib(bib)); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/chute", 301)
+}
+
+func chuteActionHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	position, err := strconv.Atoi(r.FormValue("position"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting position", err)
+		return
+	}
+	if err = race.EditChute(r.FormValue("action"), position); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/chute", 301)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChuteMode(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 34},
+		{Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 41},
+		{Bib: 3, Fname: "Cal", Lname: "Cole", Male: true, Age: 29},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	if _, err := race.ChuteTime(); err == nil {
+		t.Errorf("Expected error recording a finish before the start")
+	}
+	startRace(race)
+	for _, minutes := range []time.Duration{19, 20, 21} {
+		*race.testingTime = race.started.Add(minutes * time.Minute)
+		if _, err := race.ChuteTime(); err != nil {
+			t.Fatalf("Unexpected error - %v", err)
+		}
+	}
+	// the chute volunteer writes down a bib that isn't in the race before the real ones
+	for _, bib := range []Bib{2, 7, 1} {
+		if err := race.ChuteBib(bib); err != nil {
+			t.Fatalf("Unexpected error - %v", err)
+		}
+	}
+	if race.chutePaired != 1 || race.bibbedEntries[2].Duration != HumanDuration(19*time.Minute) || !race.bibbedEntries[2].Confirmed {
+		t.Fatalf("Expected Bob paired with the first time, got %d paired - %#v", race.chutePaired, race.bibbedEntries[2])
+	}
+	if race.bibbedEntries[1].HasFinished() {
+		t.Errorf("Expected pairing to stop at the unknown bib")
+	}
+
+	r, _ := http.NewRequest("GET", "/chute", nil)
+	w := httptest.NewRecorder()
+	handler(w, r, race)
+	if body := w.Body.String(); !strings.Contains(body, "Bib #7 not found") || !strings.Contains(body, `action="chuteAction"`) {
+		t.Errorf("Expected the unknown bib on the chute page - %s", body)
+	}
+
+	if err := race.EditChute("deleteBib", 1); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if race.chutePaired != 2 || race.bibbedEntries[1].Duration != HumanDuration(20*time.Minute) {
+		t.Errorf("Expected Amy paired with the second time once the stray bib was deleted, got %#v", race.bibbedEntries[1])
+	}
+	if len(race.chute.Times) != 1 || len(race.chute.Bibs) != 0 {
+		t.Errorf("Expected the third time waiting for a bib, got %v %v", race.chute.Times, race.chute.Bibs)
+	}
+	if err := race.ChuteBib(3); err != nil || race.bibbedEntries[3].Duration != HumanDuration(21*time.Minute) {
+		t.Errorf("Expected Cal paired with the third time - %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxClaimLength is plenty to describe a missed split or a wrong time without inviting essays
+const maxClaimLength = 500
+
+// ResultClaim is a racer's report that their result is wrong or missing, taken until the correction deadline
+type ResultClaim struct {
+	ID       int
+	Bib      Bib
+	Message  string
+	Sent     time.Time
+	Resolved bool
+}
+
+// lockedClaimsOpen returns why result claims can't be sent in, nil while they can
+func (race *Race) lockedClaimsOpen() error {
+	if race.started.IsZero() {
+		return fmt.Errorf("The race hasn't started yet, there are no results to correct")
+	}
+	if race.finalized {
+		return fmt.Errorf("The results are final, corrections are closed")
+	}
+	if !config.correctionDeadline.IsZero() && !race.GetTime().Before(config.correctionDeadline) {
+		return fmt.Errorf("The correction deadline of %s has passed", config.correctionDeadline.Format("Jan 2 3:04 PM"))
+	}
+	return nil
+}
+
+// SubmitClaim records the racer's report for the race director to look into
+func (race *Race) SubmitClaim(bib Bib, message string) error {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return fmt.Errorf("Describe what's wrong with the result")
+	}
+	if utf8.RuneCountInString(message) > maxClaimLength {
+		return fmt.Errorf("Keep it to %d characters, that's %d", maxClaimLength, utf8.RuneCountInString(message))
+	}
+	race.Lock()
+	defer race.Unlock()
+	if err := race.lockedClaimsOpen(); err != nil {
+		return err
+	}
+	if _, ok := race.bibbedEntries[bib]; !ok {
+		return fmt.Errorf("No racer with bib #%d", bib)
+	}
+	race.claims = append(race.claims, &ResultClaim{ID: len(race.claims) + 1, Bib: bib, Message: message, Sent: race.GetTime()})
+	log.Printf("Result claim %d sent in for bib #%d", len(race.claims), bib)
+	return nil
+}
+
+// ResolveClaim marks the claim looked into, or back to open
+func (race *Race) ResolveClaim(id int, resolved bool) error {
+	race.Lock()
+	defer race.Unlock()
+	if id < 1 || id > len(race.claims) {
+		return fmt.Errorf("Claim %d not found", id)
+	}
+	race.claims[id-1].Resolved = resolved
+	return nil
+}
+
+// lockedNotifyClaimants e-mails everyone who sent in a claim their final result, once per racer
+func (race *Race) lockedNotifyClaimants() {
+	notified := make(map[Bib]bool)
+	for _, c := range race.claims {
+		entry, ok := race.bibbedEntries[c.Bib]
+		if !ok || notified[c.Bib] {
+			continue
+		}
+		notified[c.Bib] = true
+		subject, text := race.lockedResultsFinalEmail(*entry, race.lockedFinalResult(entry))
+		go sendEmail(*entry, race.optionalEmailIndex, subject, text)
+	}
+}
+
+// lockedFinalResult describes the entry's result for the results final e-mail
+func (race *Race) lockedFinalResult(e *Entry) string {
+	switch {
+	case e.Status != StatusOK:
+		return e.Status.Title()
+	case e.Ranked():
+		return fmt.Sprintf("%s, %s", e.NetDuration(), race.lockedStanding(e))
+	}
+	return "No finish time"
+}
+
+// CheckCorrectionDeadline finalizes the results once RACERGOCORRECTIONDEADLINE passes, which closes the claims and
+// sends the finalization e-mails.  It only tries once, results reopened after the deadline are left for the race
+// director to finalize again.
+func (race *Race) CheckCorrectionDeadline() error {
+	race.Lock()
+	if config.correctionDeadline.IsZero() || race.deadlineHandled || race.GetTime().Before(config.correctionDeadline) {
+		race.Unlock()
+		return nil
+	}
+	race.deadlineHandled = true
+	finalized := race.finalized
+	race.Unlock()
+	if finalized {
+		return nil
+	}
+	if err := race.Finalize(false); err != nil {
+		log.Printf("The correction deadline passed but the results couldn't be finalized - %v", err)
+		return err
+	}
+	log.Printf("Results finalized at the correction deadline")
+	return nil
+}
+
+// watchCorrectionDeadline waits for the correction deadline until the program exits
+func watchCorrectionDeadline(race *Race) {
+	for range time.Tick(time.Second) {
+		race.CheckCorrectionDeadline()
+	}
+}
+
+func submitClaimHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	bib, err := strconv.Atoi(r.FormValue("bib"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting bib number", err)
+		return
+	}
+	if err = race.SubmitClaim(Bib(bib), r.FormValue("message")); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/claim?sent=true", 301)
+}
+
+func resolveClaimHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting claim", err)
+		return
+	}
+	if err = race.ResolveClaim(id, r.FormValue("reopen") != "true"); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/claims", 301)
+}
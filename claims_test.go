@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCorrectionDeadline(t *testing.T) {
+	defer func(deadline time.Time) { config.correctionDeadline = deadline }(config.correctionDeadline)
+	queue := emailQueue
+	defer func() { emailQueue = queue }()
+	emailQueue = NewMailQueue(0, 0)
+	delivered := make(chan *Mail, 2)
+	emailQueue.deliver = func(m *Mail) error {
+		delivered <- m
+		return nil
+	}
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	race.SetOptionalFields([]string{config.emailField})
+	race.AddEntry(Entry{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38, Optional: []string{"amy@example.com"}})
+	if err := race.SubmitClaim(1, "My time is missing"); err == nil {
+		t.Errorf("Expected claims refused before the race")
+	}
+	startRace(race)
+	config.correctionDeadline = race.started.Add(2 * time.Hour)
+	*race.testingTime = race.started.Add(20 * time.Minute)
+	linkBibTesting(t, race, 1, false)
+	linkBibTesting(t, race, 1, false)
+	if err := race.SubmitClaim(1, " "); err == nil {
+		t.Errorf("Expected a blank claim refused")
+	}
+	if err := race.SubmitClaim(9, "Not me"); err == nil {
+		t.Errorf("Expected a claim for a bib that isn't in the race refused")
+	}
+	r, _ := http.NewRequest("POST", "/submitClaim?bib=1&message=I+was+18+minutes", nil)
+	w := httptest.NewRecorder()
+	submitClaimHandler(w, r, race)
+	if w.Code != 301 || len(race.claims) != 1 || race.claims[0].Message != "I was 18 minutes" {
+		t.Fatalf("Expected Amy's claim recorded, got %d %s", w.Code, w.Body.String())
+	}
+	get := func(path string) string {
+		r, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		handler(w, r, race)
+		return w.Body.String()
+	}
+	if page := get("/finisher?bib=1"); !strings.Contains(page, "Something wrong with this result?") {
+		t.Errorf("Expected the finisher page to link to the claim form, got %s", page)
+	}
+
+	*race.testingTime = race.started.Add(time.Hour)
+	for x := 0; race.ReviewAnomaly(x, false) == nil; x++ {
+	}
+	if race.CheckCorrectionDeadline(); race.finalized {
+		t.Fatalf("Expected the results left open before the deadline")
+	}
+	*race.testingTime = config.correctionDeadline
+	if err := race.CheckCorrectionDeadline(); err != nil || !race.finalized {
+		t.Fatalf("Expected the results finalized at the deadline - %v", err)
+	}
+	for x := 0; x < 2; x++ { // her results when she finished, then the final results
+		select {
+		case <-delivered:
+		case <-time.After(time.Second):
+			t.Fatalf("Expected Amy e-mailed her final result")
+		}
+	}
+	// the results e-mail is queued from its own goroutine, so the two can be sent in either order
+	status, final := emailQueue.Status(), false
+	for _, sent := range status.Recent {
+		final = final || sent.To == "amy@example.com" && strings.HasSuffix(sent.Subject, "Final Results")
+	}
+	if len(status.Recent) != 2 || !final {
+		t.Errorf("Expected the final results e-mail to Amy, got %#v", status.Recent)
+	}
+	if err := race.SubmitClaim(1, "One more thing"); err == nil {
+		t.Errorf("Expected claims closed once the results are final")
+	}
+	if page := get("/claim"); !strings.Contains(page, "corrections are closed") {
+		t.Errorf("Expected the claim form closed, got %s", page)
+	}
+
+	race.Finalize(true)
+	if race.CheckCorrectionDeadline(); race.finalized {
+		t.Errorf("Expected results reopened after the deadline left for the race director")
+	}
+	if err := race.SubmitClaim(1, "One more thing"); err == nil || !strings.Contains(err.Error(), "deadline") {
+		t.Errorf("Expected claims closed after the deadline, got %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import "time"
+
+// withMonotonic gives a wall clock time, e.g. a start time read back from a snapshot, a monotonic clock reading.
+// Elapsed times measured from it are then unaffected by the system clock changing mid-race,
+// whether NTP catching up, a daylight saving change or a volunteer fixing the laptop's clock.
+func withMonotonic(t time.Time) time.Time {
+	now := time.Now()
+	return now.Add(t.Round(0).Sub(now.Round(0)))
+}
+
+// clockDrift is how far the wall clock has moved away from the monotonic clock since the start,
+// zero if the start has no monotonic reading to compare against
+func clockDrift(started, now time.Time) time.Duration {
+	return now.Round(0).Sub(started.Round(0)) - now.Sub(started)
+}
+
+// lockedClockDrift returns how far the clock has drifted since the start, either way, if it's enough to warn the admin about
+func (race *Race) lockedClockDrift() HumanDuration {
+	if race.started.IsZero() || race.testingTime != nil {
+		return 0
+	}
+	drift := clockDrift(race.started, time.Now())
+	if drift < 0 {
+		drift = -drift
+	}
+	if drift < time.Second {
+		return 0
+	}
+	return HumanDuration(drift)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithMonotonic(t *testing.T) {
+	fromSnapshot := time.Now().Add(-time.Hour).Round(0)
+	started := withMonotonic(fromSnapshot)
+	if !started.Equal(fromSnapshot) {
+		t.Errorf("Expected the same instant, got %s instead of %s", started, fromSnapshot)
+	}
+	if !strings.Contains(started.String(), "m=") {
+		t.Errorf("Expected a monotonic clock reading - %s", started)
+	}
+	if drift := clockDrift(started, time.Now()); drift > time.Millisecond || drift < -time.Millisecond {
+		t.Errorf("Expected no drift without a clock change, got %s", drift)
+	}
+	// a time without a monotonic reading is measured by the wall clock, which can't drift from itself
+	if drift := clockDrift(fromSnapshot, time.Now().Round(0)); drift != 0 {
+		t.Errorf("Expected no drift measured without monotonic readings, got %s", drift)
+	}
+}
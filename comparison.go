@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// comparisonTop is how many of the fastest women and men are listed for each year
+const comparisonTop = 3
+
+// YearSummary is one year's line in the year over year comparison
+type YearSummary struct {
+	Year      string
+	Current   bool
+	Finishers int
+	Change    int // finishers compared with the year before, 0 for the oldest year
+	Median    HumanDuration
+	Weather   string
+	Women     []PastFinisher
+	Men       []PastFinisher
+}
+
+// lockedWriteRaceDetails writes race.csv, so an archive can be matched to the same race in later years
+func (race *Race) lockedWriteRaceDetails(writer *csv.Writer) {
+	writer.Write([]string{"Race", "Date"})
+	writer.Write([]string{race.lockedName(), race.scheduleDay().Format("2006-01-02")})
+}
+
+// lockedWriteWeather writes the conditions logged on race day in the order they were logged
+func (race *Race) lockedWriteWeather(writer *csv.Writer) {
+	writer.Write([]string{"When", "At", "Temperature", "Temperature Units", "Wind", "Wind Units", "Precipitation", "Precipitation Units"})
+	for _, c := range race.weather {
+		writer.Write([]string{c.When, c.At.Format(time.RFC3339), strconv.FormatFloat(c.Temperature, 'f', -1, 64), c.Units.Temperature,
+			strconv.FormatFloat(c.Wind, 'f', -1, 64), c.Units.Wind, strconv.FormatFloat(c.Precipitation, 'f', -1, 64), c.Units.Precipitation})
+	}
+}
+
+// readArchiveDetails reads the race.csv and weather.csv an archive was written with, either may be missing from older archives
+func (past *PastRace) readArchiveDetails(details, weather []byte) error {
+	if details != nil {
+		rows, _, err := readCSV(details)
+		if err != nil {
+			return err
+		}
+		if len(rows) > 1 && len(rows[1]) > 0 {
+			past.Name = rows[1][0]
+		}
+	}
+	if weather == nil {
+		return nil
+	}
+	rows, _, err := readCSV(weather)
+	if err != nil {
+		return err
+	}
+	for x, row := range rows {
+		if x == 0 || len(row) < 8 {
+			continue
+		}
+		var c Conditions
+		c.When = row[0]
+		c.At, _ = time.Parse(time.RFC3339, row[1])
+		if c.Temperature, err = strconv.ParseFloat(row[2], 64); err != nil {
+			return fmt.Errorf("weather.csv line %d - %v", x+1, err)
+		}
+		c.Wind, _ = strconv.ParseFloat(row[4], 64)
+		c.Precipitation, _ = strconv.ParseFloat(row[6], 64)
+		c.Units.Temperature, c.Units.Wind, c.Units.Precipitation = row[3], row[5], row[7]
+		past.Weather = append(past.Weather, c)
+	}
+	return nil
+}
+
+// summarizeYear works out a year's line from its finishers in finish order
+func summarizeYear(year string, finishers []PastFinisher, weather []Conditions) YearSummary {
+	summary := YearSummary{Year: year, Finishers: len(finishers)}
+	if len(finishers) > 0 {
+		durations := make([]HumanDuration, len(finishers))
+		for x, f := range finishers {
+			durations[x] = f.Duration
+		}
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		summary.Median = durations[len(durations)/2]
+		if len(durations)%2 == 0 {
+			summary.Median = (durations[len(durations)/2-1] + durations[len(durations)/2]) / 2
+		}
+	}
+	for _, f := range finishers {
+		if f.Male && len(summary.Men) < comparisonTop {
+			summary.Men = append(summary.Men, f)
+		} else if !f.Male && len(summary.Women) < comparisonTop {
+			summary.Women = append(summary.Women, f)
+		}
+	}
+	for _, c := range weather {
+		if summary.Weather == "" || c.When == "Start" {
+			summary.Weather = c.String()
+		}
+		if c.When == "Start" {
+			break
+		}
+	}
+	return summary
+}
+
+// lockedYearOverYear is this year and the previous years of the same race, newest first.  A previous
+// year without a race name in its archive is taken to be the same race.
+func (race *Race) lockedYearOverYear() []YearSummary {
+	var finishers []PastFinisher
+	for _, row := range race.lockedFinishers() {
+		finishers = append(finishers, PastFinisher{Name: row.Fname + " " + row.Lname, Male: row.Male, Duration: row.Duration})
+	}
+	current := summarizeYear(strconv.Itoa(race.scheduleDay().Year()), finishers, race.weather)
+	current.Current = true
+	years := []YearSummary{current}
+	for x := len(previousYears) - 1; x >= 0; x-- {
+		past := previousYears[x]
+		if past.Name != "" && !strings.EqualFold(past.Name, race.lockedName()) {
+			continue
+		}
+		years = append(years, summarizeYear(past.Year, past.Finishers, past.Weather))
+	}
+	for x := 0; x < len(years)-1; x++ {
+		years[x].Change = years[x].Finishers - years[x+1].Finishers
+	}
+	return years
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestYearOverYear(t *testing.T) {
+	defer func(years []PastRace) { previousYears = years }(previousYears)
+	dir, err := ioutil.TempDir("", "comparison")
+	if err != nil {
+		t.Fatalf("Error making a temp dir - %v", err)
+	}
+	defer os.RemoveAll(dir)
+	lastYear := finishedRace(t, 21*time.Minute, 35*time.Minute, 40*time.Minute)
+	rain := Conditions{When: "Start", At: time.Date(2013, 6, 1, 9, 0, 0, 0, time.Local), Temperature: 12, Precipitation: 2.5}
+	rain.Units.Temperature = "°C"
+	lastYear.weather = append(lastYear.weather, rain)
+	archive, err := os.Create(filepath.Join(dir, "2013.zip"))
+	if err != nil {
+		t.Fatalf("Error creating the archive - %v", err)
+	}
+	if err = lastYear.WriteArchive(archive); err != nil {
+		t.Fatalf("Error writing the archive - %v", err)
+	}
+	archive.Close()
+	past, err := loadPastRace("2013", filepath.Join(dir, "2013.zip"))
+	if err != nil {
+		t.Fatalf("Error loading the archive - %v", err)
+	}
+	if past.Name != config.raceName || len(past.Weather) != 1 || past.Weather[0].Precipitation != 2.5 || len(past.Finishers) != 3 {
+		t.Fatalf("Expected the race name, the rain and three finishers from the archive, got %+v", past)
+	}
+	other := PastRace{Year: "2012", Name: "Kids Fun Run", Finishers: []PastFinisher{{"Tiny Tot", false, HumanDuration(9 * time.Minute)}}}
+	previousYears = []PastRace{other, past}
+
+	race := finishedRace(t, 20*time.Minute, 24*time.Minute)
+	race.RLock()
+	years := race.lockedYearOverYear()
+	race.RUnlock()
+	if len(years) != 2 {
+		t.Fatalf("Expected this year and 2013 but not the kids race, got %+v", years)
+	}
+	if !years[0].Current || years[0].Finishers != 2 || years[0].Change != -1 || years[0].Median != HumanDuration(22*time.Minute) {
+		t.Errorf("Expected this year's two finishers with a 22:00 median, got %+v", years[0])
+	}
+	if years[1].Year != "2013" || years[1].Median != HumanDuration(35*time.Minute) || years[1].Weather != rain.String() {
+		t.Errorf("Expected 2013's 35:00 median in the rain, got %+v", years[1])
+	}
+	if len(years[1].Women) != 2 || len(years[1].Men) != 1 || years[1].Men[0].Duration != HumanDuration(35*time.Minute) {
+		t.Errorf("Expected 2013's two women and one man, got %+v %+v", years[1].Women, years[1].Men)
+	}
+
+	w := httptest.NewRecorder()
+	RaceHandler(handler).ServeHTTP(w, httptest.NewRequest("GET", "/comparison", nil))
+	if !strings.Contains(w.Body.String(), "Year over year") {
+		t.Errorf("Expected the comparison page, got %d - %s", w.Code, w.Body.String())
+	}
+}
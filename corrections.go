@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mzimmerman/racergo/internal/core"
+)
+
+// Correction records a single field changed on an existing entry by a late re-sync
+// from the registration provider
+type Correction struct {
+	Time  time.Time
+	Bib   Bib
+	Field string
+	Old   string
+	New   string
+}
+
+// registrationClient fetches the re-sync, a provider that stops answering shouldn't hang the admin page
+var registrationClient = &http.Client{Timeout: 30 * time.Second}
+
+func correctionsHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	var in io.Reader
+	if reader, err := r.MultipartReader(); err == nil {
+		part, err := reader.NextPart()
+		if err != nil {
+			showErrorForAdmin(w, r.Referer(), "Error getting Part - %s", err)
+			return
+		}
+		in = part
+	} else {
+		if config.registrationURL == "" {
+			showErrorForAdmin(w, r.Referer(), "No corrections file uploaded and RACERGOREGISTRATIONURL is not set")
+			return
+		}
+		resp, err := registrationClient.Get(config.registrationURL)
+		if err != nil {
+			showErrorForAdmin(w, r.Referer(), "Error fetching registrations from %s - %v", config.registrationURL, err)
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			showErrorForAdmin(w, r.Referer(), "Error fetching registrations from %s - %s", config.registrationURL, resp.Status)
+			return
+		}
+		in = resp.Body
+	}
+	rawEntries, err := csv.NewReader(in).ReadAll()
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error Reading CSV file - %s", err)
+		return
+	}
+	if _, err := race.ApplyCorrections(rawEntries); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/corrections", 301)
+}
+
+// ApplyCorrections matches the rows of a registration export to the existing entries by Bib
+// and updates names, ages, genders and optional fields in place, leaving all timing data alone.
+// Blank cells never overwrite existing data.  The changes made are returned and kept for the
+// corrections report.
+func (race *Race) ApplyCorrections(rawEntries [][]string) ([]Correction, error) {
+	if len(rawEntries) <= 1 {
+		return nil, fmt.Errorf("Either blank file or only supplied the header row")
+	}
+	bibCol := -1
+	for col, title := range rawEntries[0] {
+		if title == "Bib" {
+			bibCol = col
+		}
+	}
+	if bibCol < 0 {
+		return nil, fmt.Errorf("CSV file missing the Bib field, cannot match corrections to racers")
+	}
+	race.Lock()
+	defer race.Unlock()
+	defer race.lockedEntriesChanged()
+	optionalIndex := make(map[string]int)
+	for x, fn := range race.optionalEntryFields {
+		optionalIndex[fn] = x
+	}
+	now := race.GetTime()
+	changes := make([]Correction, 0)
+	for row := 1; row < len(rawEntries); row++ {
+		if bibCol >= len(rawEntries[row]) {
+			continue
+		}
+		tmpBib, err := strconv.Atoi(rawEntries[row][bibCol])
+		if err != nil {
+			continue // unbibbed registrations can't be matched to a result
+		}
+		bib := Bib(tmpBib)
+		entry, ok := race.bibbedEntries[bib]
+		if !ok {
+			changes = append(changes, Correction{Time: now, Bib: bib, Field: "Bib", New: "not found in race, skipped"})
+			continue
+		}
+		change := func(field, old, new string) bool {
+			if new == "" || old == new {
+				return false
+			}
+			if restrictedField(field) {
+				old, new = "(restricted)", "(restricted)"
+			}
+			changes = append(changes, Correction{Time: now, Bib: bib, Field: field, Old: old, New: new})
+			return true
+		}
+		for col, val := range rawEntries[row] {
+			switch field := rawEntries[0][col]; field {
+			case "Fname":
+				if change(field, entry.Fname, val) {
+					entry.Fname = val
+				}
+			case "Lname":
+				if change(field, entry.Lname, val) {
+					entry.Lname = val
+				}
+			case "Age":
+				// compared as numbers, "09" is the same age as 9
+				if age, err := strconv.Atoi(val); err == nil && age >= 0 && uint(age) != entry.Age && change(field, strconv.Itoa(int(entry.Age)), strconv.Itoa(age)) {
+					entry.Age = uint(age)
+				}
+			case "Gender":
+				if (val == "M" || val == "F") && change(field, core.Gender(entry.Male), val) {
+					entry.Male = val == "M"
+				}
+			default:
+				if x, ok := optionalIndex[field]; ok && x < len(entry.Optional) && change(field, entry.Optional[x], val) {
+					entry.Optional[x] = val
+				}
+			}
+		}
+	}
+	race.corrections = append(race.corrections, changes...)
+	race.lockedRecomputePrizes()
+	race.lockedRecordEvent(Event{Kind: "corrections", Rows: rawEntries})
+	log.Printf("Applied %d corrections from registration re-sync", len(changes))
+	return changes, nil
+}
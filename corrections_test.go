@@ -0,0 +1,75 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestApplyCorrections(t *testing.T) {
+	race := NewRace()
+	startRace(race)
+	if !testUploadRacersHelper(t, "test_runners.csv", 301, race) {
+		t.Fatal()
+	}
+	if err := race.RecordTimeForBib(1); err != nil {
+		t.Errorf("Error linking bib - %v", err)
+	}
+	race.RLock()
+	duration := race.bibbedEntries[1].Duration
+	race.RUnlock()
+
+	dir, err := ioutil.TempDir("", "corrections")
+	if err != nil {
+		t.Fatalf("Error making a temp dir - %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "corrections.csv")
+	if err := ioutil.WriteFile(path, []byte(`"Bib","Fname","Lname","Age","Gender","TShirt"
+1,"A","Bee",52,"M",""
+2,"C","D",37,"F","XL"
+3,"E","F","021","F","S"
+9,"X","Y",30,"M","M"
+`), 0666); err != nil {
+		t.Fatalf("Error writing corrections file - %v", err)
+	}
+	req, err := uploadFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	w := httptest.NewRecorder()
+	correctionsHandler(w, req, race)
+	if w.Code != http.StatusMovedPermanently {
+		t.Fatalf("Expected redirect, got %d - %s", w.Code, w.Body.String())
+	}
+
+	race.RLock()
+	defer race.RUnlock()
+	expected := []Correction{
+		{Bib: 1, Field: "Lname", Old: "B", New: "Bee"},
+		{Bib: 1, Field: "Age", Old: "51", New: "52"},
+		{Bib: 2, Field: "Gender", Old: "M", New: "F"},
+		{Bib: 2, Field: "TShirt", Old: "L", New: "XL"},
+		{Bib: 9, Field: "Bib", New: "not found in race, skipped"},
+	}
+	if len(race.corrections) != len(expected) {
+		t.Fatalf("Expected %d corrections, got %d - %v", len(expected), len(race.corrections), race.corrections)
+	}
+	for x := range expected {
+		got := race.corrections[x]
+		got.Time = time.Time{}
+		if got != expected[x] {
+			t.Errorf("Expected %v, got %v", expected[x], got)
+		}
+	}
+	if e := race.bibbedEntries[1]; e.Lname != "Bee" || e.Age != 52 || e.Duration != duration {
+		t.Errorf("Correction not applied or timing data lost - %#v", e)
+	}
+	if e := race.bibbedEntries[2]; e.Male || e.Optional[3] != "XL" {
+		t.Errorf("Correction not applied - %#v", e)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// CSVFormat is how an uploaded CSV file was detected to be written
+type CSVFormat struct {
+	Encoding  string
+	Delimiter rune
+}
+
+func (f CSVFormat) String() string {
+	name := map[rune]string{',': "comma", ';': "semicolon", '\t': "tab"}[f.Delimiter]
+	return fmt.Sprintf("%s, %s delimited", f.Encoding, name)
+}
+
+// windows1252 maps the bytes 0x80-0x9F, where Windows-1252 differs from Latin-1, to their runes.
+// The five bytes Windows-1252 leaves undefined are passed through as their Latin-1 control characters.
+var windows1252 = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡',
+	'ˆ', '‰', 'Š', '‹', 'Œ', '\u008D', 'Ž', '\u008F',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—',
+	'˜', '™', 'š', '›', 'œ', '\u009D', 'ž', 'Ÿ',
+}
+
+// decodeText converts the uploaded bytes to UTF-8, detecting the encoding from a byte order mark
+// or falling back to Windows-1252 for anything that isn't valid UTF-8, as older Excel exports are
+func decodeText(data []byte) (string, string) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:]), "UTF-8 with BOM"
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}), bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		bigEndian := data[0] == 0xFE
+		units := make([]uint16, 0, len(data)/2)
+		for x := 2; x+1 < len(data); x += 2 {
+			if bigEndian {
+				units = append(units, uint16(data[x])<<8|uint16(data[x+1]))
+			} else {
+				units = append(units, uint16(data[x+1])<<8|uint16(data[x]))
+			}
+		}
+		if bigEndian {
+			return string(utf16.Decode(units)), "UTF-16BE"
+		}
+		return string(utf16.Decode(units)), "UTF-16LE"
+	case utf8.Valid(data):
+		return string(data), "UTF-8"
+	}
+	var text bytes.Buffer
+	for _, b := range data {
+		if b >= 0x80 && b <= 0x9F {
+			text.WriteRune(windows1252[b-0x80])
+		} else {
+			text.WriteRune(rune(b))
+		}
+	}
+	return text.String(), "Windows-1252"
+}
+
+// detectDelimiter picks whichever of comma, semicolon or tab appears most in the header row,
+// ignoring any inside quotes.  European versions of Excel write semicolons.
+func detectDelimiter(text string) rune {
+	counts := make(map[rune]int)
+	quoted := false
+	for _, r := range text {
+		if r == '"' {
+			quoted = !quoted
+		}
+		if quoted {
+			continue
+		}
+		if r == '\n' {
+			break
+		}
+		counts[r]++
+	}
+	delimiter := ','
+	for _, r := range []rune{';', '\t'} {
+		if counts[r] > counts[delimiter] {
+			delimiter = r
+		}
+	}
+	return delimiter
+}
+
+// readCSV decodes and parses an uploaded CSV file, returning the format it was detected to be in
+func readCSV(data []byte) ([][]string, CSVFormat, error) {
+	return readCSVFields(data, 0)
+}
+
+// readCSVFields is readCSV with the csv.Reader's FieldsPerRecord, -1 allows rows of any length
+func readCSVFields(data []byte, fieldsPerRecord int) ([][]string, CSVFormat, error) {
+	text, encoding := decodeText(data)
+	if !strings.Contains(text, "\n") {
+		text = strings.Replace(text, "\r", "\n", -1) // classic Mac line endings
+	}
+	format := CSVFormat{Encoding: encoding, Delimiter: detectDelimiter(text)}
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = format.Delimiter
+	reader.FieldsPerRecord = fieldsPerRecord
+	records, err := reader.ReadAll()
+	return records, format, err
+}
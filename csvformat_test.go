@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		format string
+		want   [][]string
+	}{
+		{"plain", []byte("Fname,Lname\nAmy,Brown\n"), "UTF-8, comma delimited", [][]string{{"Fname", "Lname"}, {"Amy", "Brown"}}},
+		{"bom", []byte("\xEF\xBB\xBFFname,Lname\r\nAmy,Brown\r\n"), "UTF-8 with BOM, comma delimited", [][]string{{"Fname", "Lname"}, {"Amy", "Brown"}}},
+		{"semicolon", []byte("Fname;Lname;City\nRené;Müller;\"Zürich, CH\"\n"), "UTF-8, semicolon delimited", [][]string{{"Fname", "Lname", "City"}, {"René", "Müller", "Zürich, CH"}}},
+		{"windows-1252", []byte("Fname;Lname\nRen\xE9;O\x92Brien\n"), "Windows-1252, semicolon delimited", [][]string{{"Fname", "Lname"}, {"René", "O’Brien"}}},
+		{"tab", []byte("Fname\tLname\nAmy\tBrown\n"), "UTF-8, tab delimited", [][]string{{"Fname", "Lname"}, {"Amy", "Brown"}}},
+		{"utf-16", []byte("\xFF\xFEF\x00,\x00L\x00\n\x00A\x00,\x00B\x00\n\x00"), "UTF-16LE, comma delimited", [][]string{{"F", "L"}, {"A", "B"}}},
+		{"multiline", []byte("Fname,Notes\nAmy,\"line one\nline two\"\n"), "UTF-8, comma delimited", [][]string{{"Fname", "Notes"}, {"Amy", "line one\nline two"}}},
+		{"mac", []byte("Fname,Lname\rAmy,Brown\r"), "UTF-8, comma delimited", [][]string{{"Fname", "Lname"}, {"Amy", "Brown"}}},
+		{"quoted delimiters", []byte("\"Last; First\",Age\n\"Brown; Amy\",30\n"), "UTF-8, comma delimited", [][]string{{"Last; First", "Age"}, {"Brown; Amy", "30"}}},
+	}
+	for _, test := range tests {
+		got, format, err := readCSV(test.data)
+		if err != nil {
+			t.Errorf("%s - unexpected error %v", test.name, err)
+			continue
+		}
+		if format.String() != test.format {
+			t.Errorf("%s - expected format %s, got %s", test.name, test.format, format)
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("%s - expected %q, got %q", test.name, test.want, got)
+			continue
+		}
+		for x := range got {
+			if !equalStringSlices(got[x], test.want[x]) {
+				t.Errorf("%s - expected %q, got %q", test.name, test.want[x], got[x])
+			}
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// overridablePages are the public pages an organizer may replace with their own template, and what they're for
+var overridablePages = map[string]string{
+	"default":  "Results",
+	"results":  "Announcer screen",
+	"finisher": "Finisher certificate",
+	"info":     "Race day info",
+}
+
+func overridablePageNames() []string {
+	names := make([]string, 0, len(overridablePages))
+	for name := range overridablePages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lockedParseTemplates parses raceResults.template then the race's custom pages over top of it,
+// so a custom page replaces the default one but can still use the shared blocks like "header"
+func (race *Race) lockedParseTemplates() (*template.Template, error) {
+	tmpl, err := template.New("template").Funcs(raceResultsFuncMap).ParseFiles("raceResults.template")
+	if err != nil {
+		return nil, err
+	}
+	for _, page := range overridablePageNames() {
+		if src, ok := race.templateOverrides[page]; ok {
+			if _, err = tmpl.New(page).Parse(src); err != nil {
+				return nil, fmt.Errorf("Custom %s page - %v", page, err)
+			}
+		}
+	}
+	return tmpl, nil
+}
+
+// sampleRace is a race with a few made up finishers, used to try out a custom page before it goes live
+func sampleRace() *Race {
+	started := time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	now := started.Add(30 * time.Minute)
+	race := &Race{
+		eventState:     newEventState(),
+		exportPresets:  defaultExportPresets(),
+		categorySet:    config.categorySet,
+		categories:     categorySets[config.categorySet],
+		requiredFields: parseFieldList(config.requiredFields),
+		testingTime:    &now,
+	}
+	race.started = started
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 34, Duration: HumanDuration(19*time.Minute + 42*time.Second), Confirmed: true},
+		{Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 41, Duration: HumanDuration(21*time.Minute + 5*time.Second), Confirmed: true},
+		{Bib: 3, Fname: "Cal", Lname: "Cole", Male: true, Age: 12, Duration: HumanDuration(24 * time.Minute)},
+		{Bib: 4, Fname: "Dee", Lname: "Diaz", Age: 57},
+	} {
+		race.AddEntry(e)
+	}
+	race.AddScheduleItem("11:00", "Awards")
+	race.PostAnnouncement("Sample announcement")
+	return race
+}
+
+// ValidateTemplateOverride checks the custom page parses and runs against sample data without touching the real race
+func ValidateTemplateOverride(page, src string) error {
+	if _, ok := overridablePages[page]; !ok {
+		return fmt.Errorf("The %s page can't be customized", page)
+	}
+	sample := sampleRace()
+	sample.templateOverrides = map[string]string{page: src}
+	path := "/" + page
+	if page == "finisher" {
+		path += "?bib=1"
+	}
+	r, err := http.NewRequest("GET", path, nil)
+	if err != nil {
+		return err
+	}
+	return sample.GenerateTemplate(templateRequest{name: page, writer: ioutil.Discard, request: r})
+}
+
+// SetTemplateOverride makes the custom page live once it's been validated
+func (race *Race) SetTemplateOverride(page, src string) error {
+	if err := ValidateTemplateOverride(page, src); err != nil {
+		return err
+	}
+	race.Lock()
+	defer race.Unlock()
+	if race.templateOverrides == nil {
+		race.templateOverrides = make(map[string]string)
+	}
+	race.templateOverrides[page] = src
+	log.Printf("Custom template activated for the %s page", page)
+	return nil
+}
+
+// ResetTemplateOverride rolls the page back to the default template
+func (race *Race) ResetTemplateOverride(page string) {
+	race.Lock()
+	defer race.Unlock()
+	delete(race.templateOverrides, page)
+	log.Printf("Reset the %s page to the default template", page)
+}
+
+func uploadTemplateHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	err := r.ParseMultipartForm(1 << 20)
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error getting Reader - %s", err)
+		return
+	}
+	file, _, err := r.FormFile("template")
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error getting template - %s", err)
+		return
+	}
+	defer file.Close()
+	src, err := ioutil.ReadAll(file)
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error reading template - %s", err)
+		return
+	}
+	if err = race.SetTemplateOverride(r.FormValue("page"), string(src)); err != nil {
+		showErrorForAdmin(w, r.Referer(), "Template not activated - %v", err)
+		return
+	}
+	http.Redirect(w, r, "/admin/templates", 301)
+}
+
+func resetTemplateHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	race.ResetTemplateOverride(r.FormValue("page"))
+	http.Redirect(w, r, "/admin/templates", 301)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplateOverride(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	if err := race.AddEntry(Entry{Bib: 7, Fname: "Amy", Lname: "Brown", Age: 34}); err != nil {
+		t.Fatalf("Error adding entry - %v", err)
+	}
+	for page, src := range map[string]string{
+		"admin":   `hacked`,
+		"results": `{{range .Results}}`,
+		"default": `{{index .Results 99}}`,
+		"info":    `{{template "nonexistent" .}}`,
+	} {
+		if err := race.SetTemplateOverride(page, src); err == nil {
+			t.Errorf("Expected error activating %q for the %s page", src, page)
+		}
+	}
+	if len(race.templateOverrides) != 0 {
+		t.Errorf("Expected no overrides activated, got %v", race.templateOverrides)
+	}
+
+	custom := `{{template "header" .}}</head><body>{{range .Results}}<p>{{.Entry.Bib}} {{.Entry.Fname}} {{formatPace .Entry.Duration}}</p>{{end}}</body></html>`
+	if err := race.SetTemplateOverride("default", custom); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	get := func(path string) string {
+		r, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		handler(w, r, race)
+		if w.Code != http.StatusOK {
+			t.Errorf("Unexpected %d for %s - %s", w.Code, path, w.Body.String())
+		}
+		return w.Body.String()
+	}
+	if body := get("/"); !strings.Contains(body, "<p>7 Amy --</p>") || strings.Contains(body, "Find a Racer") {
+		t.Errorf("Expected the custom results page - %s", body)
+	}
+	if body := get("/results"); !strings.Contains(body, "Recent Race Results") {
+		t.Errorf("Expected the default announcer page to be unaffected - %s", body)
+	}
+	race.ResetTemplateOverride("default")
+	if body := get("/"); !strings.Contains(body, "Find a Racer") {
+		t.Errorf("Expected the default results page after reset - %s", body)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// lockedCutoffRemaining is how long until the course closes, zero once it has.  ok is false without a cutoff or before the start.
+func (race *Race) lockedCutoffRemaining(now time.Time) (remaining time.Duration, ok bool) {
+	if config.cutoff <= 0 || race.started.IsZero() {
+		return 0, false
+	}
+	remaining = race.started.Add(config.cutoff).Sub(now)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, true
+}
+
+func (race *Race) lockedPhoneOf(e *Entry) string {
+	for x, field := range race.optionalEntryFields {
+		if field == config.phoneField && x < len(e.Optional) {
+			return e.Optional[x]
+		}
+	}
+	return ""
+}
+
+// lockedOnCourse returns the racers with a spot in the race who haven't finished or been accounted for yet
+func (race *Race) lockedOnCourse() []*Entry {
+	var onCourse []*Entry
+	for _, e := range race.allEntries {
+		if _, accounted := race.accountedFor[e.Bib]; e.Bib >= 0 && e.Registration.HasSpot() && !e.HasFinished() && e.Status == StatusOK && !accounted {
+			onCourse = append(onCourse, e)
+		}
+	}
+	return onCourse
+}
+
+// CheckCutoff announces the course closing as each warning before the cutoff passes, and the closure itself,
+// returning the announcement if one was made.  Warnings that passed together, e.g. after a restart, are announced once.
+func (race *Race) CheckCutoff() string {
+	race.Lock()
+	defer race.Unlock()
+	remaining, ok := race.lockedCutoffRemaining(race.GetTime())
+	if !ok {
+		return ""
+	}
+	if race.cutoffAnnounced == nil {
+		race.cutoffAnnounced = make(map[time.Duration]bool)
+	}
+	var due time.Duration = -1
+	for _, warning := range append([]time.Duration{0}, config.cutoffWarnings...) {
+		if remaining <= warning && !race.cutoffAnnounced[warning] {
+			race.cutoffAnnounced[warning] = true
+			if due < 0 || warning < due {
+				due = warning
+			}
+		}
+	}
+	if due < 0 {
+		return ""
+	}
+	text := "The course is now closed"
+	if due > 0 {
+		text = fmt.Sprintf("Course closes in %s", humanMinutes(due))
+	}
+	race.announcements = append([]*Announcement{{Posted: race.GetTime(), Text: text}}, race.announcements...)
+	log.Printf("Cutoff announcement posted - %s", text)
+	if config.cutoffSMS {
+		for _, e := range race.lockedOnCourse() {
+			if phone := race.lockedPhoneOf(e); phone != "" {
+				go sendSMS(phone, race.lockedCutoffSMS(text))
+			}
+		}
+	}
+	return text
+}
+
+// humanMinutes says a warning the way it's announced, e.g. 15 minutes or 1 hour
+func humanMinutes(d time.Duration) string {
+	switch {
+	case d == time.Hour:
+		return "1 hour"
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%d hours", d/time.Hour)
+	case d == time.Minute:
+		return "1 minute"
+	}
+	return fmt.Sprintf("%d minutes", d/time.Minute)
+}
+
+// watchCutoff checks for closure announcements until the program exits
+func watchCutoff(race *Race) {
+	for range time.Tick(time.Second) {
+		race.CheckCutoff()
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCutoffAnnouncements(t *testing.T) {
+	defer func(cutoff time.Duration, warnings []time.Duration) {
+		config.cutoff, config.cutoffWarnings = cutoff, warnings
+	}(config.cutoff, config.cutoffWarnings)
+	config.cutoff = time.Hour
+	config.cutoffWarnings = []time.Duration{30 * time.Minute, 15 * time.Minute, 5 * time.Minute}
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	if text := race.CheckCutoff(); text != "" {
+		t.Errorf("Expected no announcement before the start, got %q", text)
+	}
+	startRace(race)
+	for _, step := range []struct {
+		at       time.Duration
+		expected string
+	}{
+		{10 * time.Minute, ""},
+		{31 * time.Minute, "Course closes in 30 minutes"},
+		{32 * time.Minute, ""},
+		{56 * time.Minute, "Course closes in 5 minutes"}, // the 15 minute warning was missed, only the latest is announced
+		{58 * time.Minute, ""},
+		{61 * time.Minute, "The course is now closed"},
+		{62 * time.Minute, ""},
+	} {
+		*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local).Add(step.at)
+		if text := race.CheckCutoff(); text != step.expected {
+			t.Errorf("%s - expected %q, got %q", step.at, step.expected, text)
+		}
+	}
+	if len(race.announcements) != 3 || race.announcements[0].Text != "The course is now closed" {
+		t.Errorf("Expected 3 announcements, the closure latest, got %d", len(race.announcements))
+	}
+
+	r, _ := http.NewRequest("GET", "/results", nil)
+	w := httptest.NewRecorder()
+	handler(w, r, race)
+	if !strings.Contains(w.Body.String(), "Course closed") {
+		t.Errorf("Expected the course shown closed - %s", w.Body.String())
+	}
+}
+
+func TestHumanMinutes(t *testing.T) {
+	for d, expected := range map[time.Duration]string{
+		time.Minute:      "1 minute",
+		15 * time.Minute: "15 minutes",
+		time.Hour:        "1 hour",
+		2 * time.Hour:    "2 hours",
+		90 * time.Minute: "90 minutes",
+	} {
+		if got := humanMinutes(d); got != expected {
+			t.Errorf("%s - expected %s, got %s", d, expected, got)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// civilTwilightAngle is how far below the horizon the sun is at civil dawn and dusk, when it's too dark to see the course
+const civilTwilightAngle = -6.0
+
+// parseLocation reads a latitude,longitude pair like 39.74,-104.99, west and south are negative
+func parseLocation(location string) (float64, float64, error) {
+	parts := strings.Split(location, ",")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("%s is not a latitude,longitude", location)
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return 0, 0, fmt.Errorf("%s is not a latitude", parts[0])
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil || lon < -180 || lon > 180 {
+		return 0, 0, fmt.Errorf("%s is not a longitude", parts[1])
+	}
+	return lat, lon, nil
+}
+
+// civilTwilight is when civil dawn and dusk are on the day, using the sunrise equation which is good to a minute or two.
+// ok is false when the sun doesn't get that far below or above the horizon, near the poles.
+func civilTwilight(day time.Time, lat, lon float64) (dawn, dusk time.Time, ok bool) {
+	rad := math.Pi / 180
+	midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	n := math.Floor(float64(midnight.Unix())/86400 + 2440587.5 - 2451545.0 + 0.5) // days since noon on January 1st 2000
+	meanNoon := n - lon/360
+	anomaly := math.Mod(357.5291+0.98560028*meanNoon, 360)
+	center := 1.9148*math.Sin(anomaly*rad) + 0.02*math.Sin(2*anomaly*rad) + 0.0003*math.Sin(3*anomaly*rad)
+	longitude := math.Mod(anomaly+center+180+102.9372, 360)
+	transit := 2451545.0 + meanNoon + 0.0053*math.Sin(anomaly*rad) - 0.0069*math.Sin(2*longitude*rad)
+	declination := math.Asin(math.Sin(longitude*rad) * math.Sin(23.44*rad))
+	cosHourAngle := (math.Sin(civilTwilightAngle*rad) - math.Sin(lat*rad)*math.Sin(declination)) / (math.Cos(lat*rad) * math.Cos(declination))
+	if cosHourAngle < -1 || cosHourAngle > 1 {
+		return dawn, dusk, false
+	}
+	hourAngle := math.Acos(cosHourAngle) / rad
+	julian := func(jd float64) time.Time {
+		return time.Unix(0, int64((jd-2440587.5)*86400*1e9)).In(day.Location())
+	}
+	return julian(transit - hourAngle/360), julian(transit + hourAngle/360), true
+}
+
+// lockedPlannedStart is when the race started, or before the start when the schedule says it will
+func (race *Race) lockedPlannedStart() (time.Time, bool) {
+	if !race.started.IsZero() {
+		return race.started, true
+	}
+	for _, item := range race.schedule {
+		if strings.Contains(strings.ToLower(item.What), "start") {
+			return item.At, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// lockedDaylightWarnings warns when the start is before civil dawn or the cutoff is after civil dusk at the course,
+// nothing unless RACERGOLOCATION is set
+func (race *Race) lockedDaylightWarnings() []string {
+	if config.courseLocation == "" {
+		return nil
+	}
+	lat, lon, err := parseLocation(config.courseLocation)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	start, ok := race.lockedPlannedStart()
+	if !ok {
+		return nil
+	}
+	start = start.In(time.Local)
+	dawn, dusk, ok := civilTwilight(start, lat, lon)
+	if !ok {
+		return nil
+	}
+	warnings := []string{}
+	if start.Before(dawn) {
+		warnings = append(warnings, fmt.Sprintf("The start at %s is before civil dawn at %s, racers will start in the dark", start.Format("3:04 PM"), dawn.Format("3:04 PM")))
+	}
+	if closes := start.Add(config.cutoff); config.cutoff > 0 && closes.After(dusk) {
+		warnings = append(warnings, fmt.Sprintf("The course closes at %s, after civil dusk at %s, the last racers will be out in the dark", closes.Format("3:04 PM"), dusk.Format("3:04 PM")))
+	}
+	return warnings
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCivilTwilight(t *testing.T) {
+	mdt := time.FixedZone("MDT", -6*60*60)
+	dawn, dusk, ok := civilTwilight(time.Date(2014, 10, 15, 9, 0, 0, 0, mdt), 39.74, -104.99)
+	if !ok {
+		t.Fatalf("Expected twilight in Denver")
+	}
+	// civil twilight in Denver on October 15th 2014 runs from about 6:45 AM to 6:49 PM
+	if expected := time.Date(2014, 10, 15, 6, 45, 0, 0, mdt); dawn.Sub(expected) > 3*time.Minute || expected.Sub(dawn) > 3*time.Minute {
+		t.Errorf("Expected civil dawn around %s, got %s", expected.Format(time.Kitchen), dawn.Format(time.Kitchen))
+	}
+	if expected := time.Date(2014, 10, 15, 18, 49, 0, 0, mdt); dusk.Sub(expected) > 3*time.Minute || expected.Sub(dusk) > 3*time.Minute {
+		t.Errorf("Expected civil dusk around %s, got %s", expected.Format(time.Kitchen), dusk.Format(time.Kitchen))
+	}
+	if _, _, ok = civilTwilight(time.Date(2014, 6, 21, 12, 0, 0, 0, time.UTC), 80, 15); ok {
+		t.Errorf("Expected no twilight in the midnight sun")
+	}
+	if _, _, err := parseLocation("39.74"); err == nil {
+		t.Errorf("Expected an error without a longitude")
+	}
+}
+
+func TestDaylightWarnings(t *testing.T) {
+	location, cutoff, raceDate := config.courseLocation, config.cutoff, config.raceDate
+	defer func() { config.courseLocation, config.cutoff, config.raceDate = location, cutoff, raceDate }()
+	config.courseLocation = "39.74,-104.99"
+	local := time.Local
+	defer func() { time.Local = local }()
+	time.Local = time.FixedZone("MDT", -6*60*60) // the race's timezone, as RACERGOTIMEZONE sets it
+	config.raceDate = time.Date(2014, 10, 15, 0, 0, 0, 0, time.Local)
+
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 10, 1, 12, 0, 0, 0, time.Local)
+	if warnings := race.lockedDaylightWarnings(); len(warnings) != 0 {
+		t.Errorf("Expected no warnings without a start, got %v", warnings)
+	}
+	config.cutoff = 13 * time.Hour
+	race.AddScheduleItem("06:15", "Ultra start")
+	warnings := race.lockedDaylightWarnings()
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "before civil dawn") || !strings.Contains(warnings[1], "closes at 7:15 PM, after civil dusk") {
+		t.Errorf("Expected a dark start and finish, got %v", warnings)
+	}
+
+	config.cutoff = time.Hour
+	race.RemoveScheduleItem(0)
+	race.AddScheduleItem("12:00", "Marathon start")
+	if warnings := race.lockedDaylightWarnings(); len(warnings) != 0 {
+		t.Errorf("Expected no warnings for a midday race, got %v", warnings)
+	}
+
+	config.cutoff = 7 * time.Hour
+	r, _ := http.NewRequest("GET", "/admin", nil)
+	w := httptest.NewRecorder()
+	handler(w, r, race)
+	if body := w.Body.String(); !strings.Contains(body, "after civil dusk") {
+		t.Errorf("Expected the dusk warning on the admin page - %s", body)
+	}
+}
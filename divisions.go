@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mzimmerman/racergo/internal/core"
+)
+
+// Category is an age bracket entries are divided into, split by gender for division places and prizes
+type Category struct {
+	Name    string
+	LowAge  uint
+	HighAge uint
+}
+
+// division names the gender and category, e.g. F30-39 or M Masters
+func (c Category) division(male bool) string {
+	if c.Name != "" && c.Name[0] >= '0' && c.Name[0] <= '9' {
+		return core.Gender(male) + c.Name
+	}
+	return core.Gender(male) + " " + c.Name
+}
+
+func ageBrackets(width uint) []Category {
+	categories := make([]Category, 0, 100/width+1)
+	for low := uint(0); low < 100; low += width {
+		categories = append(categories, Category{fmt.Sprintf("%d-%d", low, low+width-1), low, low + width - 1})
+	}
+	return append(categories, Category{"100+", 100, ^uint(0)})
+}
+
+// categorySets are the standard ways of splitting a race into age brackets, selectable per race
+var categorySets = map[string][]Category{
+	"decades":   ageBrackets(10),
+	"five year": ageBrackets(5),
+	"masters": {
+		{"Open", 0, 39},
+		{"Masters", 40, 49},
+		{"Grandmasters", 50, 59},
+		{"Veterans", 60, ^uint(0)},
+	},
+}
+
+// categorySetNames lists the category sets in a stable order for display
+func categorySetNames() []string {
+	names := make([]string, 0, len(categorySets))
+	for name := range categorySets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lockedDivisionOf is the gender and category an entry is placed in, e.g. F30-39.  Races that don't
+// collect age or gender divide by whichever they do collect, or place everyone Overall.
+func (race *Race) lockedDivisionOf(e *Entry) string {
+	byGender := race.lockedRequires("Gender")
+	if race.lockedRequires("Age") {
+		for _, c := range race.categories {
+			if e.Age >= c.LowAge && e.Age <= c.HighAge {
+				if !byGender {
+					return c.Name
+				}
+				return c.division(e.Male)
+			}
+		}
+	}
+	if !byGender {
+		return "Overall"
+	}
+	return core.Gender(e.Male)
+}
+
+// lockedPlace is the entry's overall place, zero when they haven't finished or were disqualified
+func (race *Race) lockedPlace(e *Entry) Place {
+	return race.lockedIndex().places[e]
+}
+
+// lockedDivisionPlaces returns the place of every finisher within their division,
+// entries that haven't finished don't have a division place
+func (race *Race) lockedDivisionPlaces() map[*Entry]Place {
+	return race.lockedIndex().divisionPlaces
+}
+
+func (race *Race) SetCategories(name string) error {
+	categories, ok := categorySets[name]
+	if !ok {
+		return fmt.Errorf("Unknown category set %s", name)
+	}
+	race.Lock()
+	defer race.Unlock()
+	race.categorySet = name
+	race.categories = categories
+	race.lockedRecordEvent(Event{Kind: "categories", Categories: name})
+	return nil
+}
+
+// GeneratePrizes replaces the prizes with an overall prize for each gender followed by
+// a prize for each gender in every category of the race's category set.  Races that don't
+// collect gender get a single prize for each, races that don't collect age only get overall prizes.
+func (race *Race) GeneratePrizes(amount uint) {
+	race.Lock()
+	defer race.Unlock()
+	byGender := race.lockedRequires("Gender")
+	prizes := []Prize{{Title: "Overall", LowAge: 0, HighAge: ^uint(0), Gender: "O", Amount: 1}}
+	if byGender {
+		prizes = []Prize{
+			{Title: "Men's Overall", LowAge: 0, HighAge: ^uint(0), Gender: "M", Amount: 1},
+			{Title: "Women's Overall", LowAge: 0, HighAge: ^uint(0), Gender: "F", Amount: 1},
+		}
+	}
+	for _, c := range race.categories {
+		if !race.lockedRequires("Age") {
+			break
+		}
+		if !byGender {
+			prizes = append(prizes, Prize{Title: c.Name, LowAge: c.LowAge, HighAge: c.HighAge, Gender: "O", Amount: amount, WinAgain: true})
+			continue
+		}
+		prizes = append(prizes,
+			Prize{Title: "Men's " + c.Name, LowAge: c.LowAge, HighAge: c.HighAge, Gender: "M", Amount: amount, WinAgain: true},
+			Prize{Title: "Women's " + c.Name, LowAge: c.LowAge, HighAge: c.HighAge, Gender: "F", Amount: amount, WinAgain: true},
+		)
+	}
+	race.prizes = prizes
+	race.lockedRecomputePrizes()
+}
+
+func setCategoriesHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	err := race.SetCategories(r.FormValue("categories"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	if r.FormValue("generatePrizes") == "true" {
+		amount, err := strconv.Atoi(r.FormValue("amount"))
+		if err != nil || amount < 1 {
+			showErrorForAdmin(w, r.Referer(), "%s is not a valid number of winners per category", r.FormValue("amount"))
+			return
+		}
+		race.GeneratePrizes(uint(amount))
+	}
+	http.Redirect(w, r, "/admin", 301)
+}
+
+// parseCategories reads RACERGODIVISIONS age brackets like 0-19, Masters=40-49 or 60+, the bracket is the
+// name unless one is given
+func parseCategories(list []string) ([]Category, error) {
+	categories := make([]Category, 0, len(list))
+	for _, spec := range list {
+		name, ages := spec, spec
+		if x := strings.Index(spec, "="); x >= 0 {
+			name, ages = strings.TrimSpace(spec[:x]), strings.TrimSpace(spec[x+1:])
+		}
+		c := Category{Name: name, HighAge: ^uint(0)}
+		if strings.HasSuffix(ages, "+") {
+			low, err := strconv.ParseUint(strings.TrimSuffix(ages, "+"), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("division %s must be ages like 30-39 or 60+", spec)
+			}
+			c.LowAge = uint(low)
+		} else {
+			bounds := strings.SplitN(ages, "-", 2)
+			if len(bounds) != 2 {
+				return nil, fmt.Errorf("division %s must be ages like 30-39 or 60+", spec)
+			}
+			low, err := strconv.ParseUint(strings.TrimSpace(bounds[0]), 10, 32)
+			high, err2 := strconv.ParseUint(strings.TrimSpace(bounds[1]), 10, 32)
+			if err != nil || err2 != nil || low > high {
+				return nil, fmt.Errorf("division %s must be ages like 30-39 or 60+", spec)
+			}
+			c.LowAge, c.HighAge = uint(low), uint(high)
+		}
+		if c.Name == "" {
+			return nil, fmt.Errorf("division %s needs a name", spec)
+		}
+		for _, other := range categories {
+			if c.LowAge <= other.HighAge && other.LowAge <= c.HighAge {
+				return nil, fmt.Errorf("divisions %s and %s overlap", other.Name, c.Name)
+			}
+		}
+		categories = append(categories, c)
+	}
+	return categories, nil
+}
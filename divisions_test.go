@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDivisionPlaces(t *testing.T) {
+	race := NewRace()
+	raceStart := time.Now().Add(-time.Hour).Round(time.Second)
+	race.testingTime = &time.Time{}
+	*race.testingTime = raceStart
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "A", Lname: "B", Male: false, Age: 30},
+		{Bib: 2, Fname: "C", Lname: "D", Male: false, Age: 39},
+		{Bib: 3, Fname: "E", Lname: "F", Male: true, Age: 35},
+		{Bib: 4, Fname: "G", Lname: "H", Male: false, Age: 40},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	startRace(race)
+	for x, bib := range []Bib{2, 3, 4, 1} {
+		*race.testingTime = raceStart.Add(time.Duration(x+20) * time.Minute)
+		if err := race.RecordTimeForBib(bib); err != nil {
+			t.Errorf("Error linking bib - %v", err)
+		}
+	}
+	race.RLock()
+	defer race.RUnlock()
+	tests := []struct {
+		bib      Bib
+		standing string
+	}{
+		{2, "1st overall and 1st in F30-39"},
+		{3, "2nd overall and 1st in M30-39"},
+		{4, "3rd overall and 1st in F40-49"},
+		{1, "4th overall and 2nd in F30-39"},
+	}
+	for _, test := range tests {
+		if got := race.lockedStanding(race.bibbedEntries[test.bib]); got != test.standing {
+			t.Errorf("Bib %s - expected %q, got %q", test.bib, test.standing, got)
+		}
+	}
+}
+
+func TestCategorySets(t *testing.T) {
+	race := NewRace()
+	raceStart := time.Now().Add(-time.Hour).Round(time.Second)
+	race.testingTime = &time.Time{}
+	*race.testingTime = raceStart
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "A", Lname: "B", Male: true, Age: 44},
+		{Bib: 2, Fname: "C", Lname: "D", Male: true, Age: 47},
+		{Bib: 3, Fname: "E", Lname: "F", Male: false, Age: 52},
+		{Bib: 4, Fname: "G", Lname: "H", Male: true, Age: 25},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	if err := race.SetCategories("nope"); err == nil {
+		t.Errorf("Expected error setting an unknown category set")
+	}
+	r, _ := http.NewRequest("POST", "/setCategories?categories=masters&generatePrizes=true&amount=2", nil)
+	w := httptest.NewRecorder()
+	setCategoriesHandler(w, r, race)
+	if w.Code != http.StatusMovedPermanently {
+		t.Fatalf("Expected redirect, got %d - %s", w.Code, w.Body.String())
+	}
+	startRace(race)
+	for x, bib := range []Bib{1, 2, 3, 4} {
+		*race.testingTime = raceStart.Add(time.Duration(x+20) * time.Minute)
+		for y := 0; y < 2; y++ { // link then confirm
+			if err := race.RecordTimeForBib(bib); err != nil {
+				t.Errorf("Error linking bib - %v", err)
+			}
+		}
+	}
+	race.RLock()
+	defer race.RUnlock()
+	if got, want := race.lockedStanding(race.bibbedEntries[2]), "2nd overall and 2nd in M Masters"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if got, want := race.lockedStanding(race.bibbedEntries[3]), "3rd overall and 1st in F Grandmasters"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if len(race.prizes) != 10 {
+		t.Fatalf("Expected 10 prizes, got %d", len(race.prizes))
+	}
+	winners := map[string][]Bib{}
+	for _, p := range race.prizes {
+		for _, e := range p.Winners {
+			winners[p.Title] = append(winners[p.Title], e.Bib)
+		}
+	}
+	if got := winners["Men's Masters"]; len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("Wrong Men's Masters winners - %v", got)
+	}
+	if got := winners["Women's Overall"]; len(got) != 1 || got[0] != 3 {
+		t.Errorf("Wrong Women's Overall winners - %v", got)
+	}
+	if got := winners["Men's Open"]; len(got) != 1 || got[0] != 4 {
+		t.Errorf("Wrong Men's Open winners - %v", got)
+	}
+}
+
+func TestParseCategories(t *testing.T) {
+	categories, err := parseCategories([]string{"0-19", "Open=20-39", "Masters=40+"})
+	if err != nil {
+		t.Fatalf("Error parsing divisions - %v", err)
+	}
+	if len(categories) != 3 || categories[0] != (Category{"0-19", 0, 19}) || categories[1] != (Category{"Open", 20, 39}) || categories[2] != (Category{"Masters", 40, ^uint(0)}) {
+		t.Errorf("Wrong divisions parsed - %#v", categories)
+	}
+	if categories[0].division(true) != "M0-19" || categories[2].division(false) != "F Masters" {
+		t.Errorf("Expected divisions named like the standard sets, got %s and %s", categories[0].division(true), categories[2].division(false))
+	}
+	for _, bad := range [][]string{{"20-10"}, {"Open"}, {"=20-29"}, {"0-19", "Teens=13-19"}, {"x+"}} {
+		if _, err := parseCategories(bad); err == nil {
+			t.Errorf("Expected %v refused", bad)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	sendgrid "github.com/mzimmerman/sendgrid-go"
+)
+
+// Mail is an e-mail ready to go out through the configured MailTransport
+type Mail struct {
+	To          string // the address, optionally with the name, e.g. Amy Brown <amy@example.com>
+	From        string
+	Subject     string
+	Text        string
+	HTML        string // sent as an alternative to the text, left out if blank
+	Attachments []MailAttachment
+}
+
+// MailAttachment is a file sent along with an e-mail
+type MailAttachment struct {
+	Name string
+	Data []byte
+}
+
+// NewMail starts an e-mail from RACERGOFROMEMAIL
+func NewMail(to, subject, text string) *Mail {
+	return &Mail{To: to, From: config.emailFrom, Subject: subject, Text: text}
+}
+
+// Attach adds a copy of the file to the e-mail
+func (m *Mail) Attach(name string, data []byte) {
+	m.Attachments = append(m.Attachments, MailAttachment{Name: name, Data: append([]byte(nil), data...)})
+}
+
+// MailTransport delivers e-mails, SendGrid's API or the club's own mail server
+type MailTransport interface {
+	// Configured returns why e-mails can't be sent, nil when they can
+	Configured() error
+	Send(m *Mail) error
+}
+
+// mailTransport is SMTP when RACERGOSMTPHOST is set, SendGrid otherwise
+var mailTransport MailTransport = sendgridTransport{}
+
+type sendgridTransport struct{}
+
+func (sendgridTransport) Configured() error {
+	if config.sendgriduser == "" || config.sendgriduser == SENDGRIDUSER {
+		return fmt.Errorf("E-mail isn't configured, set RACERGOSMTPHOST for your own mail server or RACERGOSENDGRIDUSER and RACERGOSENDGRIDPASS for SendGrid")
+	}
+	return nil
+}
+
+func (sendgridTransport) Send(m *Mail) error {
+	sg := sendgrid.NewMail()
+	sg.AddTo(m.To)
+	sg.SetSubject(m.Subject)
+	sg.SetText(m.Text)
+	if m.HTML != "" {
+		sg.SetHTML(m.HTML)
+	}
+	sg.SetFrom(m.From)
+	for _, a := range m.Attachments {
+		sg.AddAttachment(a.Name, bytes.NewReader(a.Data))
+	}
+	return sendgrid.NewSendGridClient(config.sendgriduser, config.sendgridpass).Send(sg)
+}
+
+// smtpTLSModes are how the connection to RACERGOSMTPHOST is secured, with the port each uses by default
+var smtpTLSModes = map[string]int{
+	"starttls": 587, // upgraded after connecting, what most providers' submission port expects
+	"tls":      465, // TLS from the start
+	"none":     25,  // only for a relay on the local network, passwords are refused without TLS
+}
+
+type smtpTransport struct{}
+
+func (smtpTransport) Configured() error {
+	if config.smtpHost == "" {
+		return fmt.Errorf("E-mail isn't configured, set RACERGOSMTPHOST")
+	}
+	return nil
+}
+
+func (smtpTransport) Send(m *Mail) error {
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return fmt.Errorf("Error with the from address %s - %v", m.From, err)
+	}
+	to, err := mail.ParseAddress(m.To)
+	if err != nil {
+		return fmt.Errorf("Error with the address %s - %v", m.To, err)
+	}
+	msg, err := m.message(from, to, time.Now())
+	if err != nil {
+		return err
+	}
+	addr := net.JoinHostPort(config.smtpHost, strconv.Itoa(config.smtpPort))
+	tlsConfig := &tls.Config{ServerName: config.smtpHost}
+	var c *smtp.Client
+	if config.smtpTLS == "tls" {
+		conn, err := tls.Dial("tcp", addr, tlsConfig)
+		if err != nil {
+			return err
+		}
+		if c, err = smtp.NewClient(conn, config.smtpHost); err != nil {
+			conn.Close()
+			return err
+		}
+	} else if c, err = smtp.Dial(addr); err != nil {
+		return err
+	}
+	defer c.Close()
+	if config.smtpTLS == "starttls" {
+		if err = c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if config.smtpUser != "" {
+		if err = c.Auth(smtp.PlainAuth("", config.smtpUser, config.smtpPass, config.smtpHost)); err != nil {
+			return err
+		}
+	}
+	if err = c.Mail(from.Address); err != nil {
+		return err
+	}
+	if err = c.Rcpt(to.Address); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(msg); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// message renders the e-mail as MIME, the text and HTML as alternatives followed by the attachments
+func (m *Mail) message(from, to *mail.Address, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	mixed := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\nContent-Type: multipart/mixed; boundary=%s\r\n\r\n",
+		from, to, mime.QEncoding.Encode("utf-8", m.Subject), now.Format(time.RFC1123Z), mixed.Boundary())
+	var body bytes.Buffer
+	alternatives := multipart.NewWriter(&body)
+	writeText := func(contentType, text string) error {
+		part, err := alternatives.CreatePart(textproto.MIMEHeader{"Content-Type": {contentType + "; charset=utf-8"}, "Content-Transfer-Encoding": {"quoted-printable"}})
+		if err != nil {
+			return err
+		}
+		qp := quotedprintable.NewWriter(part)
+		if _, err = qp.Write([]byte(text)); err != nil {
+			return err
+		}
+		return qp.Close()
+	}
+	if err := writeText("text/plain", m.Text); err != nil {
+		return nil, err
+	}
+	if m.HTML != "" {
+		if err := writeText("text/html", m.HTML); err != nil {
+			return nil, err
+		}
+	}
+	alternatives.Close()
+	part, err := mixed.CreatePart(textproto.MIMEHeader{"Content-Type": {"multipart/alternative; boundary=" + alternatives.Boundary()}})
+	if err != nil {
+		return nil, err
+	}
+	part.Write(body.Bytes())
+	for _, a := range m.Attachments {
+		contentType := mime.TypeByExtension(filepath.Ext(a.Name))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		part, err := mixed.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {contentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Name})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoded := base64.StdEncoding.EncodeToString(a.Data)
+		for len(encoded) > 76 {
+			fmt.Fprintf(part, "%s\r\n", encoded[:76])
+			encoded = encoded[76:]
+		}
+		fmt.Fprintf(part, "%s\r\n", encoded)
+	}
+	if err = mixed.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
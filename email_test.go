@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// fakeSMTP accepts one e-mail on the listener and sends what it got on the channel
+func fakeSMTP(t *testing.T, l net.Listener, got chan<- []string) {
+	conn, err := l.Accept()
+	if err != nil {
+		t.Errorf("Error accepting - %v", err)
+		close(got)
+		return
+	}
+	tp := textproto.NewConn(conn)
+	defer tp.Close()
+	tp.PrintfLine("220 localhost ready")
+	var envelope []string
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			close(got)
+			return
+		}
+		switch cmd := strings.ToUpper(strings.Fields(line)[0]); cmd {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250 localhost")
+		case "MAIL", "RCPT":
+			envelope = append(envelope, line)
+			tp.PrintfLine("250 OK")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				close(got)
+				return
+			}
+			got <- append(envelope, string(data))
+			tp.PrintfLine("250 queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 %s not implemented", cmd)
+		}
+	}
+}
+
+func TestSMTPTransport(t *testing.T) {
+	defer func(host string, port int, tls string) {
+		config.smtpHost, config.smtpPort, config.smtpTLS = host, port, tls
+	}(config.smtpHost, config.smtpPort, config.smtpTLS)
+	if err := (smtpTransport{}).Configured(); err == nil {
+		t.Errorf("Expected SMTP unconfigured without a host")
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening - %v", err)
+	}
+	defer l.Close()
+	got := make(chan []string, 1)
+	go fakeSMTP(t, l, got)
+	config.smtpHost, config.smtpTLS = "127.0.0.1", "none"
+	config.smtpPort = l.Addr().(*net.TCPAddr).Port
+
+	m := NewMail("Zoë Brown <zoe@example.com>", "Your Results", "You finished 1st!")
+	m.From = "results@example.com"
+	m.HTML = "<p>You finished <b>1st</b>!</p>"
+	m.Attach("awards.csv", []byte("Prize,Place\nOverall,1\n"))
+	if err := (smtpTransport{}).Send(m); err != nil {
+		t.Fatalf("Error sending - %v", err)
+	}
+	sent := <-got
+	if len(sent) != 3 || sent[0] != "MAIL FROM:<results@example.com>" || sent[1] != "RCPT TO:<zoe@example.com>" {
+		t.Fatalf("Wrong envelope - %q", sent)
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(sent[2]))
+	if err != nil {
+		t.Fatalf("Error reading the message - %v", err)
+	}
+	if subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject")); subject != "Your Results" {
+		t.Errorf("Wrong subject %q", subject)
+	}
+	if to, err := msg.Header.AddressList("To"); err != nil || to[0].Name != "Zoë Brown" {
+		t.Errorf("Expected the name kept in the To header, got %v - %v", to, err)
+	}
+	_, params, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	body, err := parts.NextPart()
+	if err != nil {
+		t.Fatalf("Error reading the body - %v", err)
+	}
+	mediaType, params, _ := mime.ParseMediaType(body.Header.Get("Content-Type"))
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("Expected the text and HTML as alternatives, got %s", mediaType)
+	}
+	alternatives := multipart.NewReader(body, params["boundary"])
+	for _, want := range []string{"You finished 1st!", "<p>You finished <b>1st</b>!</p>"} {
+		part, err := alternatives.NextPart()
+		if err != nil {
+			t.Fatalf("Error reading %q - %v", want, err)
+		}
+		if text, _ := ioutil.ReadAll(part); string(text) != want {
+			t.Errorf("Expected %q, got %q", want, text)
+		}
+	}
+	attachment, err := parts.NextPart()
+	if err != nil || attachment.FileName() != "awards.csv" {
+		t.Fatalf("Expected awards.csv attached - %v", err)
+	}
+	data, _ := ioutil.ReadAll(base64.NewDecoder(base64.StdEncoding, attachment))
+	if string(data) != "Prize,Place\nOverall,1\n" {
+		t.Errorf("Wrong attachment %q", data)
+	}
+}
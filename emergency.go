@@ -0,0 +1,90 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/csv"
+	"fmt"
+	"strings"
+)
+
+// EmergencyContact is one restricted field of a racer, only ever shown on the medical lookup
+type EmergencyContact struct {
+	Field string
+	Value string
+}
+
+// restrictedField reports whether the optional field holds emergency contact details or medical notes,
+// which are kept off every public page and standard export
+func restrictedField(field string) bool {
+	if config.medicalField != "" && field == config.medicalField {
+		return true
+	}
+	for _, f := range config.emergencyFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// medicalPINMatches checks the PIN medical staff give for the emergency contact lookup, the lookup is off without RACERGOMEDICALPIN
+func medicalPINMatches(pin string) bool {
+	return config.medicalPIN != "" && subtle.ConstantTimeCompare([]byte(pin), []byte(config.medicalPIN)) == 1
+}
+
+// lockedEmergencyContact looks up the emergency contact details of the racer wearing the bib
+func (race *Race) lockedEmergencyContact(bib Bib) (*Entry, []EmergencyContact, error) {
+	entry, ok := race.bibbedEntries[bib]
+	if !ok {
+		return nil, nil, fmt.Errorf("Bib %d not found", bib)
+	}
+	contacts := make([]EmergencyContact, 0)
+	for x, field := range race.optionalEntryFields {
+		if restrictedField(field) && field != config.medicalField && x < len(entry.Optional) {
+			contacts = append(contacts, EmergencyContact{Field: field, Value: entry.Optional[x]})
+		}
+	}
+	return entry, contacts, nil
+}
+
+// lockedMedicalNotes returns the racer's confidential medical and allergy notes, empty if they have none
+func (race *Race) lockedMedicalNotes(e *Entry) string {
+	for x, field := range race.optionalEntryFields {
+		if field == config.medicalField && x < len(e.Optional) {
+			return strings.TrimSpace(e.Optional[x])
+		}
+	}
+	return ""
+}
+
+// lockedMedicalFlag tells finish line and aid station volunteers that medical has notes on the bib without showing them
+func (race *Race) lockedMedicalFlag(bib Bib) bool {
+	e, ok := race.bibbedEntries[bib]
+	return ok && race.lockedMedicalNotes(e) != ""
+}
+
+// lockedKeepEmergencyContact copies the emergency contact details from the existing entry,
+// they aren't on the admin forms so a modified entry would otherwise lose them
+func (race *Race) lockedKeepEmergencyContact(mod *Entry, src *Entry) {
+	for x, field := range race.optionalEntryFields {
+		if restrictedField(field) && x < len(mod.Optional) && x < len(src.Optional) {
+			mod.Optional[x] = src.Optional[x]
+		}
+	}
+}
+
+// WriteRedactedCSV writes the race like WriteCSV with the emergency contact details blanked, for downloads that leave the server
+func (race *Race) WriteRedactedCSV(writer *csv.Writer) error {
+	return race.writeCSV(writer, true)
+}
+
+// lockedRedacted returns the optional fields with the emergency contact details blanked
+func (race *Race) lockedRedacted(optional []string) []string {
+	redacted := make([]string, len(optional))
+	for x := range optional {
+		if x >= len(race.optionalEntryFields) || !restrictedField(race.optionalEntryFields[x]) {
+			redacted[x] = optional[x]
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmergencyContacts(t *testing.T) {
+	defer func(pin string) { config.medicalPIN = pin }(config.medicalPIN)
+	config.medicalPIN = "4321"
+	race := NewRace()
+	if err := race.SetOptionalFields([]string{"Email", "Emergency Phone"}); err != nil {
+		t.Fatalf("Error setting optional fields - %v", err)
+	}
+	if err := race.AddEntry(Entry{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38, Optional: []string{"amy@host.com", "555-0100"}}); err != nil {
+		t.Fatalf("Error adding entry - %v", err)
+	}
+
+	lookup := func(pin string) string {
+		r, _ := http.NewRequest("POST", "/emergency", strings.NewReader(url.Values{"bib": {"1"}, "pin": {pin}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		handler(w, r, race)
+		return w.Body.String()
+	}
+	if body := lookup("1234"); strings.Contains(body, "555-0100") || !strings.Contains(body, "Wrong PIN") {
+		t.Errorf("Expected the wrong PIN to be refused - %s", body)
+	}
+	if body := lookup("4321"); !strings.Contains(body, "555-0100") {
+		t.Errorf("Expected the emergency phone with the right PIN - %s", body)
+	}
+
+	r, _ := http.NewRequest("GET", "/admin", nil)
+	w := httptest.NewRecorder()
+	handler(w, r, race)
+	if body := w.Body.String(); strings.Contains(body, "555-0100") || !strings.Contains(body, "amy@host.com") {
+		t.Errorf("Expected the emergency phone kept off the admin page - %s", body)
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	race.WriteRedactedCSV(writer)
+	writer.Flush()
+	if strings.Contains(buf.String(), "555-0100") || !strings.Contains(buf.String(), "Emergency Phone") {
+		t.Errorf("Expected the emergency phone blanked in the download - %s", buf.String())
+	}
+	buf.Reset()
+	race.WriteCSV(writer)
+	writer.Flush()
+	if !strings.Contains(buf.String(), "555-0100") {
+		t.Errorf("Expected the emergency phone kept in the snapshot - %s", buf.String())
+	}
+	buf.Reset()
+	race.WriteCSVColumns(writer, []string{"Bib", "Emergency Phone"})
+	writer.Flush()
+	if strings.Contains(buf.String(), "555-0100") {
+		t.Errorf("Expected the emergency phone blanked in a custom export - %s", buf.String())
+	}
+	if err := race.SaveExportPreset("Medical", []string{"Bib", "Emergency Phone"}); err == nil {
+		t.Errorf("Expected the emergency phone to be refused as an export preset column")
+	}
+
+	race.Lock()
+	mod := *race.allEntries[0]
+	mod.Optional = []string{"amy@host.com", ""}
+	race.Unlock()
+	mod.Fname = "Amelia"
+	if err := race.ModifyEntry(race.allEntries[0].Nonce(), 1, mod); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if e := race.bibbedEntries[1]; e.Fname != "Amelia" || e.Optional[1] != "555-0100" {
+		t.Errorf("Expected the emergency phone kept when the entry was modified, got %#v", e)
+	}
+}
+
+func TestMedicalNotes(t *testing.T) {
+	defer func(pin string) { config.medicalPIN = pin }(config.medicalPIN)
+	config.medicalPIN = "4321"
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	if err := race.SetOptionalFields([]string{"Email", "Medical Notes"}); err != nil {
+		t.Fatalf("Error setting optional fields - %v", err)
+	}
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38, Optional: []string{"amy@host.com", "Bee sting allergy, EpiPen in bag"}},
+		{Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 31, Optional: []string{"bob@host.com", ""}},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	startRace(race)
+	*race.testingTime = race.testingTime.Add(10 * time.Minute)
+	if reply := race.RecordSMS("+15550100", "CP1 1 2"); !strings.HasSuffix(reply, "medical has notes, check the medical lookup") || strings.Contains(reply, "bibs") {
+		t.Errorf("Expected the aid station told medical has notes without saying whose, got %q", reply)
+	}
+
+	r, _ := http.NewRequest("POST", "/emergency", strings.NewReader(url.Values{"bib": {"1"}, "pin": {"4321"}}.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	handler(w, r, race)
+	if body := w.Body.String(); !strings.Contains(body, "Bee sting allergy") {
+		t.Errorf("Expected the medical notes on the medical lookup - %s", body)
+	}
+	for _, page := range []string{"/admin", "/audit", "/lookup?q=Amy"} {
+		r, _ = http.NewRequest("GET", page, nil)
+		w = httptest.NewRecorder()
+		handler(w, r, race)
+		if strings.Contains(w.Body.String(), "Bee sting") {
+			t.Errorf("Expected the medical notes kept off %s", page)
+		}
+	}
+}
+
+func TestMirrorKeepsEmergencyContacts(t *testing.T) {
+	primary := NewRace()
+	if err := primary.SetOptionalFields([]string{"Email", "Emergency Phone"}); err != nil {
+		t.Fatalf("Error setting optional fields - %v", err)
+	}
+	if err := primary.AddEntry(Entry{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38, Optional: []string{"amy@host.com", "555-0100"}}); err != nil {
+		t.Fatalf("Error adding entry - %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler := downloadHandler
+		if r.URL.Path == "/syncSnapshot" {
+			handler = syncSnapshotHandler
+		}
+		handler(w, r, primary)
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/download")
+	if err != nil {
+		t.Fatalf("Error downloading - %v", err)
+	}
+	var download bytes.Buffer
+	download.ReadFrom(resp.Body)
+	resp.Body.Close()
+	if strings.Contains(download.String(), "555-0100") {
+		t.Errorf("Expected the emergency phone blanked in the download - %s", download.String())
+	}
+
+	backup := NewRace()
+	backup.syncRole = SyncBackup
+	for x := 0; x < 2; x++ { // the first mirror adds Amy, mirroring again must not lose anything
+		data, err := fetchSnapshot(server.URL)
+		if err != nil {
+			t.Fatalf("Error fetching the snapshot - %v", err)
+		}
+		if _, err = backup.MergeSnapshot(data, true); err != nil {
+			t.Fatalf("Error mirroring - %v", err)
+		}
+		if amy := backup.bibbedEntries[1]; len(amy.Optional) != 2 || amy.Optional[1] != "555-0100" {
+			t.Fatalf("Expected the backup to keep Amy's emergency phone, got %#v", amy)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Estimate is a finish time given to a racer the line missed, bounded by the finishers a photo or witness
+// put them between.  After or Before is NoBib when they were first or last of the finishers.
+type Estimate struct {
+	Bib      Bib
+	After    Bib
+	Before   Bib
+	Low      HumanDuration
+	High     HumanDuration // 0 when they finished after everyone recorded
+	Duration HumanDuration
+	Evidence string // how they're known to have finished, e.g. photo 14:32 or the sweeper
+}
+
+func (est Estimate) String() string {
+	var between []string
+	if est.After != NoBib {
+		between = append(between, fmt.Sprintf("after #%d", est.After))
+	}
+	if est.Before != NoBib {
+		between = append(between, fmt.Sprintf("before #%d", est.Before))
+	}
+	return fmt.Sprintf("Estimated %s - %s", strings.Join(between, " and "), est.Evidence)
+}
+
+// lockedEstimateBounds works out the range of times the missed racer could have finished in and suggests the
+// middle of it, or the neighbor's time when only one is known
+func (race *Race) lockedEstimateBounds(bib, after, before Bib) (Estimate, error) {
+	est := Estimate{Bib: bib, After: after, Before: before}
+	entry, ok := race.bibbedEntries[bib]
+	switch {
+	case race.started.IsZero():
+		return est, fmt.Errorf("Race has not started yet, cannot insert a finisher")
+	case race.finalized:
+		return est, fmt.Errorf("Results have been finalized, cannot insert a finisher")
+	case !ok:
+		return est, fmt.Errorf("Bib %d not found", bib)
+	case !entry.Registration.HasSpot():
+		return est, fmt.Errorf("Bib #%d is %s and doesn't have a spot in the race", bib, entry.Registration)
+	case entry.HasFinished():
+		return est, fmt.Errorf("Bib #%d already finished in %s", bib, entry.Duration)
+	case after == NoBib && before == NoBib:
+		return est, fmt.Errorf("Enter the finisher just ahead of or just behind bib #%d", bib)
+	}
+	for _, neighbor := range []struct {
+		bib   Bib
+		bound *HumanDuration
+	}{{after, &est.Low}, {before, &est.High}} {
+		if neighbor.bib == NoBib {
+			continue
+		}
+		e, ok := race.bibbedEntries[neighbor.bib]
+		if !ok || !e.HasFinished() {
+			return est, fmt.Errorf("Bib #%d hasn't finished, choose a recorded finisher", neighbor.bib)
+		}
+		*neighbor.bound = e.Duration
+	}
+	if est.High > 0 && est.High < est.Low {
+		return est, fmt.Errorf("Bib #%d finished in %s, ahead of bib #%d in %s", before, est.High, after, est.Low)
+	}
+	switch {
+	case after == NoBib:
+		est.Duration = est.High
+	case before == NoBib:
+		est.Duration = est.Low
+	default:
+		est.Duration = est.Low + (est.High-est.Low)/2
+	}
+	return est, nil
+}
+
+// InsertMissedFinisher gives the racer the estimated time, which has to be between their neighbors' times.
+// The time is confirmed since someone has checked the evidence, and flagged as an estimate in the results.
+func (race *Race) InsertMissedFinisher(est Estimate) error {
+	race.Lock()
+	defer race.Unlock()
+	bounds, err := race.lockedEstimateBounds(est.Bib, est.After, est.Before)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(est.Evidence) == "" {
+		return fmt.Errorf("Note how bib #%d is known to have finished, e.g. the photo or who saw them", est.Bib)
+	}
+	if est.Duration < bounds.Low || (bounds.High > 0 && est.Duration > bounds.High) {
+		return fmt.Errorf("%s is outside the neighbors' times of %s to %s", est.Duration, bounds.Low, bounds.High)
+	}
+	est.Low, est.High = bounds.Low, bounds.High
+	entry := race.bibbedEntries[est.Bib]
+	entry.Duration = est.Duration
+	entry.TimeFinished = race.lockedStartOf(entry).Add(time.Duration(est.Duration))
+	entry.Confirmed = true
+	if race.estimates == nil {
+		race.estimates = make(map[Bib]*Estimate)
+	}
+	race.estimates[est.Bib] = &est
+	race.lockedSortEntries()
+	race.lockedRecomputePrizes()
+	log.Printf("Bib #%d inserted with estimated duration - %s, %s", est.Bib, est.Duration, est.Evidence)
+	race.lockedRecordEvent(Event{Kind: "estimate", Bib: est.Bib, Estimate: &est})
+	return nil
+}
+
+// parseOptionalBib reads a bib form value, NoBib when it's blank
+func parseOptionalBib(val string) (Bib, error) {
+	if strings.TrimSpace(val) == "" {
+		return NoBib, nil
+	}
+	bib, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(val), "#"))
+	if err != nil || bib < 0 {
+		return NoBib, fmt.Errorf("%s is not a bib number", val)
+	}
+	return Bib(bib), nil
+}
+
+func insertMissedFinisherHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	est := Estimate{Evidence: strings.TrimSpace(r.FormValue("evidence"))}
+	var err error
+	for _, field := range []struct {
+		name string
+		dst  *Bib
+	}{{"bib", &est.Bib}, {"after", &est.After}, {"before", &est.Before}} {
+		if *field.dst, err = parseOptionalBib(r.FormValue(field.name)); err != nil {
+			showErrorForAdmin(w, r.Referer(), "%v", err)
+			return
+		}
+	}
+	if est.Duration, err = parsePaperTime(r.FormValue("duration")); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	if err = race.InsertMissedFinisher(est); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/admin", 301)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInsertMissedFinisher(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38},
+		{Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 31},
+		{Bib: 3, Fname: "Cal", Lname: "Cole", Male: true, Age: 52},
+		{Bib: 4, Fname: "Dee", Lname: "Dunn", Age: 44},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	startRace(race)
+	for _, finish := range []struct {
+		bib   int
+		after time.Duration
+	}{{1, 20 * time.Minute}, {3, 22 * time.Minute}} {
+		*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local).Add(finish.after)
+		linkBibTesting(t, race, finish.bib, false)
+	}
+	race.RLock()
+	est, err := race.lockedEstimateBounds(2, 1, 3)
+	race.RUnlock()
+	if err != nil || est.Low != HumanDuration(20*time.Minute) || est.High != HumanDuration(22*time.Minute) || est.Duration != HumanDuration(21*time.Minute) {
+		t.Errorf("Expected Bob between 20 and 22 minutes, got %#v - %v", est, err)
+	}
+	for _, bad := range []Estimate{
+		{Bib: 2, After: 1, Before: 3, Duration: HumanDuration(21 * time.Minute)},                        // no evidence
+		{Bib: 2, After: 1, Before: 3, Duration: HumanDuration(23 * time.Minute), Evidence: "photo"},     // outside the bounds
+		{Bib: 2, After: 3, Before: 1, Duration: HumanDuration(21 * time.Minute), Evidence: "photo"},     // neighbors backwards
+		{Bib: 2, After: 4, Before: NoBib, Duration: HumanDuration(21 * time.Minute), Evidence: "photo"}, // neighbor didn't finish
+		{Bib: 1, After: NoBib, Before: 3, Duration: HumanDuration(21 * time.Minute), Evidence: "photo"}, // already finished
+		{Bib: 2, After: NoBib, Before: NoBib, Duration: HumanDuration(21 * time.Minute), Evidence: "photo"},
+	} {
+		if err := race.InsertMissedFinisher(bad); err == nil {
+			t.Errorf("Expected an error inserting %#v", bad)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/missedFinisher?bib=2&after=1&before=3", nil)
+	handler(w, r, race)
+	if body := w.Body.String(); !strings.Contains(body, "finished between 00:20:00.00 and 00:22:00.00") || !strings.Contains(body, `value="00:21:00.00"`) {
+		t.Errorf("Expected the bounds and suggested time, got %s", body)
+	}
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("POST", "/insertMissedFinisher", strings.NewReader(url.Values{
+		"bib": {"2"}, "after": {"1"}, "before": {"3"}, "duration": {"00:21:30.00"}, "evidence": {"Finish photo"},
+	}.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	insertMissedFinisherHandler(w, r, race)
+	if w.Code != 301 {
+		t.Fatalf("Expected a redirect, got %d %s", w.Code, w.Body)
+	}
+	race.RLock()
+	results := race.lockedResults("")
+	race.RUnlock()
+	if results[1].Bib != 2 || results[1].Estimate == nil || results[1].Duration != HumanDuration(21*time.Minute+30*time.Second) || !results[1].Confirmed {
+		t.Errorf("Expected Bob second with a confirmed estimate, got %#v", results[1])
+	}
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	err = race.WriteCSVColumns(writer, []string{"Bib", "Estimated"})
+	writer.Flush()
+	if err != nil || !strings.Contains(buf.String(), "2,Estimated after #1 and before #3 - Finish photo") {
+		t.Errorf("Expected the estimate in the export, got %s - %v", buf.String(), err)
+	}
+
+	// the estimate survives an undo of something after it, and goes with the time when it's removed
+	linkBibTesting(t, race, 4, false)
+	if err := race.Undo(); err != nil {
+		t.Fatalf("Unexpected error undoing - %v", err)
+	}
+	if race.estimates[2] == nil {
+		t.Errorf("Expected Bob's estimate to survive the undo")
+	}
+	race.Lock()
+	race.lockedRemoveTime(race.bibbedEntries[2])
+	race.Unlock()
+	if race.estimates[2] != nil {
+		t.Errorf("Expected Bob's estimate to go with his time")
+	}
+}
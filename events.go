@@ -0,0 +1,364 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/mzimmerman/racergo/internal/core"
+)
+
+// Event is one command that changed the race, in the order it was applied.  Replaying the events
+// on an empty race rebuilds the racers, the start and every finish time.
+type Event struct {
+	Seq          int            `json:"seq"`
+	Kind         string         `json:"kind"` // fields, start, startCapture, selectStart, addEntry, modifyEntry, withdraw, capacity, lottery, transfer, corrections, merge, capture, discard, link, remove, batch, audit, weather, estimate, prizes, categories, passing, startCrossing, chipTags, waveStart, adjust, removeAdjustment, status, review, finalize, reopen or undo
+	Time         time.Time      `json:"time"`
+	Fields       []string       `json:"fields,omitempty"`
+	Bib          Bib            `json:"bib,omitempty"`
+	CheckConfirm bool           `json:"checkConfirm,omitempty"`
+	Place        Place          `json:"place,omitempty"`
+	Nonce        string         `json:"nonce,omitempty"`
+	Entry        *Entry         `json:"entry,omitempty"`
+	Ops          []BatchOp      `json:"ops,omitempty"`
+	Audit        []Audit        `json:"audit,omitempty"`
+	Weather      *Conditions    `json:"weather,omitempty"`
+	Source       string         `json:"source,omitempty"`  // what captured a start
+	Capture      int            `json:"capture,omitempty"` // the start capture selected
+	Estimate     *Estimate      `json:"estimate,omitempty"`
+	Prizes       []Prize        `json:"prizes,omitempty"`
+	Categories   string         `json:"categories,omitempty"` // the category set chosen
+	Passing      *Passing       `json:"passing,omitempty"`
+	Tags         map[string]Bib `json:"tags,omitempty"`
+	Wave         string         `json:"wave,omitempty"` // the wave started
+	Adjustment   *Adjustment    `json:"adjustment,omitempty"`
+	Index        int            `json:"index,omitempty"`      // the adjustment removed or the anomaly reviewed
+	Status       string         `json:"status,omitempty"`     // DNS, DNF, DQ or blank to clear it
+	RemoveTime   bool           `json:"removeTime,omitempty"` // the reviewed anomaly's finish time was removed
+	Capacity     int            `json:"capacity,omitempty"`
+	Draw         *LotteryDraw   `json:"draw,omitempty"`
+	Transfer     *Transfer      `json:"transfer,omitempty"`   // the approved transfer
+	Rows         [][]string     `json:"rows,omitempty"`       // the registration export the corrections came from
+	Unassigned   int            `json:"unassigned,omitempty"` // the captured time held, discarded or linked
+	Started      *time.Time     `json:"started,omitempty"`    // the start a mirrored primary had
+	Entries      []Entry        `json:"entries,omitempty"`    // the entries as they were after merging
+}
+
+func (ev Event) String() string {
+	at := ev.Time.Format("3:04:05 PM")
+	switch ev.Kind {
+	case "link":
+		return fmt.Sprintf("#%d bib %d linked at %s", ev.Seq, ev.Bib, at)
+	case "remove":
+		return fmt.Sprintf("#%d bib %d time removed at %s", ev.Seq, ev.Bib, at)
+	case "addEntry", "modifyEntry":
+		return fmt.Sprintf("#%d %s %s %s at %s", ev.Seq, ev.Kind, ev.Entry.Fname, ev.Entry.Lname, at)
+	case "batch":
+		return fmt.Sprintf("#%d batch of %d operations at %s", ev.Seq, len(ev.Ops), at)
+	case "audit":
+		return fmt.Sprintf("#%d audit log of %d rows imported at %s", ev.Seq, len(ev.Audit), at)
+	case "weather":
+		return fmt.Sprintf("#%d %s conditions %s at %s", ev.Seq, ev.Weather.When, ev.Weather, at)
+	case "startCapture":
+		return fmt.Sprintf("#%d start captured again by %s at %s", ev.Seq, ev.Source, ev.Time.Format("3:04:05.00 PM"))
+	case "selectStart":
+		return fmt.Sprintf("#%d start capture %d selected at %s", ev.Seq, ev.Capture+1, at)
+	case "passing":
+		return fmt.Sprintf("#%d bib %d passed %s at %s", ev.Seq, ev.Passing.Bib, ev.Passing.Checkpoint, ev.Passing.Time.Format("3:04:05 PM"))
+	case "startCrossing":
+		return fmt.Sprintf("#%d bib %d crossed the start at %s", ev.Seq, ev.Bib, ev.Time.Format("3:04:05.00 PM"))
+	case "status":
+		return fmt.Sprintf("#%d bib %d marked %q at %s", ev.Seq, ev.Bib, ev.Status, at)
+	case "adjust":
+		return fmt.Sprintf("#%d bib %d adjusted %s at %s", ev.Seq, ev.Bib, ev.Adjustment, at)
+	case "waveStart":
+		return fmt.Sprintf("#%d %s wave started at %s", ev.Seq, ev.Wave, ev.Time.Format("3:04:05.00 PM"))
+	case "estimate":
+		return fmt.Sprintf("#%d bib %d inserted with an estimated %s at %s", ev.Seq, ev.Bib, ev.Estimate.Duration, at)
+	case "review":
+		return fmt.Sprintf("#%d anomaly %d reviewed at %s", ev.Seq, ev.Index+1, at)
+	case "capacity":
+		return fmt.Sprintf("#%d capacity set to %d at %s", ev.Seq, ev.Capacity, at)
+	case "lottery":
+		return fmt.Sprintf("#%d lottery drawn with seed %d at %s", ev.Seq, ev.Draw.Seed, at)
+	case "transfer":
+		return fmt.Sprintf("#%d bib %d transferred to %s %s at %s", ev.Seq, ev.Transfer.Bib, ev.Transfer.To.Fname, ev.Transfer.To.Lname, at)
+	case "corrections":
+		return fmt.Sprintf("#%d corrections from %d registrations at %s", ev.Seq, len(ev.Rows)-1, at)
+	case "merge":
+		return fmt.Sprintf("#%d %d entries merged from another laptop at %s", ev.Seq, len(ev.Entries), at)
+	case "capture":
+		return fmt.Sprintf("#%d time %d captured for bib %d at %s", ev.Seq, ev.Unassigned, ev.Bib, ev.Time.Format("3:04:05.00 PM"))
+	case "discard":
+		return fmt.Sprintf("#%d captured time %d discarded at %s", ev.Seq, ev.Unassigned, at)
+	}
+	return fmt.Sprintf("#%d %s at %s", ev.Seq, ev.Kind, at)
+}
+
+// lockedRecordEvent appends the event to the race's log and the event file, nothing is recorded while replaying
+func (race *Race) lockedRecordEvent(ev Event) {
+	race.lockedEntriesChanged()
+	if race.replaying {
+		return
+	}
+	ev.Seq = len(race.events) + 1
+	if ev.Time.IsZero() {
+		ev.Time = race.GetTime()
+	}
+	race.events = append(race.events, ev)
+	if race.eventLog != nil {
+		if err := json.NewEncoder(race.eventLog).Encode(ev); err != nil {
+			log.Printf("Error writing event %d to the event log - %v", ev.Seq, err)
+		}
+		if f, ok := race.eventLog.(*os.File); ok {
+			f.Sync() // on the disk before the change is acknowledged, in case the laptop loses power
+		}
+	}
+	race.lockedNotifyWatchers()
+}
+
+// effectiveEvents drops the undone events and the undos themselves, leaving what to replay.
+// Logged weather, start crossings and checkpoint passings aren't commands made at the timing table, the prizes,
+// categories, chip tags, capacity, lottery, transfers and corrections are set up before the race, merges come from
+// another laptop, captured times are only held until they're linked or discarded, and finalizing is taken back by
+// reopening, so an undo skips over them.
+func effectiveEvents(events []Event) []Event {
+	effective := make([]Event, 0, len(events))
+	for _, ev := range events {
+		if ev.Kind != "undo" {
+			effective = append(effective, ev)
+		} else if last := lastUndoable(effective); last >= 0 {
+			effective = append(effective[:last], effective[last+1:]...)
+		}
+	}
+	return effective
+}
+
+// lastUndoable is the index of the last event an undo takes back, -1 when there's nothing to undo
+func lastUndoable(events []Event) int {
+	for x := len(events) - 1; x >= 0; x-- {
+		switch events[x].Kind {
+		case "weather", "prizes", "categories", "passing", "startCrossing", "chipTags", "capacity", "lottery", "transfer", "corrections", "merge", "capture", "discard", "finalize", "reopen":
+		default:
+			return x
+		}
+	}
+	return -1
+}
+
+// Replay applies the events to the race as if the commands were made again at the times they were recorded
+func (race *Race) Replay(events []Event) error {
+	race.Lock()
+	testingTime := race.testingTime
+	race.replaying = true
+	race.Unlock()
+	defer func() {
+		race.Lock()
+		race.testingTime = testingTime
+		race.replaying = false
+		race.events = append(race.events[:0:0], events...)
+		race.Unlock()
+	}()
+	for _, ev := range effectiveEvents(events) {
+		at := ev.Time
+		race.Lock()
+		race.testingTime = &at
+		race.Unlock()
+		if err := race.applyEvent(ev); err != nil {
+			return fmt.Errorf("Error replaying event %s - %v", ev, err)
+		}
+	}
+	return nil
+}
+
+func (race *Race) applyEvent(ev Event) error {
+	switch ev.Kind {
+	case "fields":
+		return race.SetOptionalFields(ev.Fields)
+	case "start":
+		race.Lock()
+		defer race.Unlock()
+		return race.lockedStart(&ev.Time, ev.Source)
+	case "startCapture":
+		return race.CaptureStart(&ev.Time, ev.Source)
+	case "selectStart":
+		return race.SelectStart(ev.Capture)
+	case "addEntry":
+		return race.AddEntry(*ev.Entry)
+	case "modifyEntry":
+		return race.ModifyEntry(ev.Nonce, ev.Place, *ev.Entry)
+	case "withdraw":
+		return race.Withdraw(ev.Nonce, ev.Place)
+	case "capacity":
+		return race.SetCapacity(ev.Capacity)
+	case "lottery":
+		_, err := race.RunLottery(ev.Draw.Capacity, ev.Draw.Bonus, ev.Draw.Seed)
+		return err
+	case "transfer":
+		race.Lock()
+		defer race.Unlock()
+		return race.lockedReplayTransfer(ev.Transfer)
+	case "corrections":
+		_, err := race.ApplyCorrections(ev.Rows)
+		return err
+	case "merge":
+		race.Lock()
+		defer race.Unlock()
+		race.lockedApplyMerge(ev)
+		return nil
+	case "capture":
+		id, err := race.CaptureTime(ev.Bib)
+		if err == nil && id != ev.Unassigned {
+			err = fmt.Errorf("captured time %d replayed as %d", ev.Unassigned, id)
+		}
+		return err
+	case "discard":
+		return race.DiscardTime(ev.Unassigned)
+	case "link":
+		if ev.Unassigned != 0 {
+			return race.AssignTime(ev.Unassigned, ev.Bib)
+		}
+		race.Lock()
+		defer race.Unlock()
+		return race.lockedRecordTimeForBib(ev.Bib, ev.Time, ev.CheckConfirm)
+	case "remove":
+		race.Lock()
+		defer race.Unlock()
+		entry, ok := race.bibbedEntries[ev.Bib]
+		if !ok {
+			return fmt.Errorf("Bib %d not found", ev.Bib)
+		}
+		race.lockedRemoveTime(entry)
+		return nil
+	case "batch":
+		if batchErr := race.ApplyBatch(ev.Ops); batchErr != nil {
+			return fmt.Errorf("operation %d - %s", batchErr.Operation, batchErr.Error)
+		}
+		return nil
+	case "audit":
+		race.Lock()
+		defer race.Unlock()
+		return race.lockedApplyAudit(ev.Audit)
+	case "weather":
+		race.Lock()
+		defer race.Unlock()
+		race.weather = append(race.weather, *ev.Weather)
+		return nil
+	case "estimate":
+		return race.InsertMissedFinisher(*ev.Estimate)
+	case "prizes":
+		return race.SetPrizes(ev.Prizes)
+	case "categories":
+		return race.SetCategories(ev.Categories)
+	case "startCrossing":
+		race.Lock()
+		defer race.Unlock()
+		return race.lockedRecordStartCrossing(ev.Bib, ev.Time)
+	case "chipTags":
+		race.SetChipTags(ev.Tags)
+		return nil
+	case "waveStart":
+		return race.StartWave(ev.Wave, &ev.Time, ev.Source)
+	case "adjust":
+		return race.AdjustTime(ev.Bib, *ev.Adjustment)
+	case "removeAdjustment":
+		return race.RemoveAdjustment(ev.Bib, ev.Index)
+	case "status":
+		status, err := core.ParseResultStatus(ev.Status)
+		if err != nil {
+			return err
+		}
+		return race.SetStatus(ev.Bib, status)
+	case "passing":
+		race.Lock()
+		defer race.Unlock()
+		return race.lockedRecordPassing(*ev.Passing)
+	case "review":
+		return race.ReviewAnomaly(ev.Index, ev.RemoveTime)
+	case "finalize", "reopen":
+		return race.Finalize(ev.Kind == "reopen")
+	}
+	return fmt.Errorf("Unknown event %s", ev.Kind)
+}
+
+// Undo takes back the last change by replaying everything before it on an empty race and taking its racers
+func (race *Race) Undo() error {
+	race.Lock()
+	defer race.Unlock()
+	if race.finalized {
+		return fmt.Errorf("Results have been finalized, cannot undo")
+	}
+	effective := effectiveEvents(race.events)
+	last := lastUndoable(effective)
+	if last < 0 {
+		return fmt.Errorf("Nothing to undo")
+	}
+	undone := effective[last]
+	replayed := &Race{
+		eventState:     newEventState(),
+		categories:     race.categories,
+		requiredFields: race.requiredFields,
+		capacity:       config.capacity,
+	}
+	if err := replayed.Replay(append(effective[:last:last], effective[last+1:]...)); err != nil {
+		return err
+	}
+	race.eventState = replayed.eventState
+	race.lockedEntriesChanged()
+	race.lockedRecomputePrizes()
+	race.lockedRecordEvent(Event{Kind: "undo", Bib: undone.Bib})
+	log.Printf("Undid event %s", undone)
+	return nil
+}
+
+// ReadEvents loads an event file written with RACERGOEVENTLOG
+func ReadEvents(in io.Reader) ([]Event, error) {
+	events := make([]Event, 0)
+	decoder := json.NewDecoder(in)
+	for {
+		var ev Event
+		err := decoder.Decode(&ev)
+		if err == io.EOF {
+			return events, nil
+		}
+		if err != nil {
+			return events, fmt.Errorf("Error reading event %d - %v", len(events)+1, err)
+		}
+		events = append(events, ev)
+	}
+}
+
+// openEventLog replays the event file if there is one, recovering the race after a crash, then keeps appending to it
+func openEventLog(race *Race, path string) error {
+	if fd, err := os.Open(path); err == nil {
+		events, err := ReadEvents(fd)
+		fd.Close()
+		if err != nil {
+			return err
+		}
+		if err = race.Replay(events); err != nil {
+			return err
+		}
+		log.Printf("Recovered the race from %d events in %s", len(events), path)
+	}
+	fd, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	race.Lock()
+	race.eventLog = fd
+	race.Unlock()
+	return nil
+}
+
+func undoHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	if err := race.Undo(); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/admin", 301)
+}
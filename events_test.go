@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func raceCSV(race *Race) string {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	race.WriteCSV(writer)
+	writer.Flush()
+	return buf.String()
+}
+
+func TestReplayEvents(t *testing.T) {
+	race := NewRace()
+	var eventLog bytes.Buffer
+	race.eventLog = &eventLog
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 8, 30, 0, 0, time.Local)
+	if err := race.SetOptionalFields([]string{"Email"}); err != nil {
+		t.Fatalf("Error setting optional fields - %v", err)
+	}
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38, Optional: []string{"amy@host.com"}},
+		{Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 31, Optional: []string{"bob@host.com"}},
+		{Bib: 3, Fname: "Cal", Lname: "Cole", Male: true, Age: 52, Optional: []string{"cal@host.com"}},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	startRace(race)
+	*race.testingTime = race.testingTime.Add(18 * time.Minute)
+	linkBibTesting(t, race, 2, false)
+	*race.testingTime = race.testingTime.Add(2 * time.Minute)
+	linkBibTesting(t, race, 1, false)
+	linkBibTesting(t, race, 3, false)
+	linkBibTesting(t, race, 3, true)
+	if batchErr := race.ApplyBatch([]BatchOp{{Op: "assignBib", Bib: 3, NewBib: 7}}); batchErr != nil {
+		t.Fatalf("Unexpected error - %v", batchErr.Error)
+	}
+
+	events, err := ReadEvents(&eventLog)
+	if err != nil {
+		t.Fatalf("Error reading the event log - %v", err)
+	}
+	if len(events) != len(race.events) {
+		t.Fatalf("Expected %d events in the log, got %d", len(race.events), len(events))
+	}
+	replayed := NewRace()
+	if err = replayed.Replay(events); err != nil {
+		t.Fatalf("Error replaying - %v", err)
+	}
+	if got, want := raceCSV(replayed), raceCSV(race); got != want {
+		t.Errorf("Expected the replayed race to match\n%s\ngot\n%s", want, got)
+	}
+
+	// undo the bib change, then the removed time
+	if err = race.Undo(); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if race.bibbedEntries[3] == nil || race.bibbedEntries[7] != nil {
+		t.Errorf("Expected Cal back in bib 3")
+	}
+	if err = race.Undo(); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if cal := race.bibbedEntries[3]; cal.Duration != HumanDuration(20*time.Minute) {
+		t.Errorf("Expected Cal's time back after undoing the remove, got %s", cal.Duration)
+	}
+	undos, _ := ReadEvents(&eventLog)
+	replayed = NewRace()
+	if err = replayed.Replay(append(events, undos...)); err != nil {
+		t.Fatalf("Error replaying - %v", err)
+	}
+	if got, want := raceCSV(replayed), raceCSV(race); got != want {
+		t.Errorf("Expected the replayed undos to match\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestRecoverFromEventLog(t *testing.T) {
+	defer func(checkpoints []string) { config.checkpoints = checkpoints }(config.checkpoints)
+	config.checkpoints = nil
+	dir, err := ioutil.TempDir("", "events")
+	if err != nil {
+		t.Fatalf("Error making a temp dir - %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "racergo-events.jsonl")
+	race := NewRace()
+	if err = openEventLog(race, path); err != nil {
+		t.Fatalf("Error opening the event log - %v", err)
+	}
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	if err = race.SetPrizes([]Prize{{Title: "Overall", Gender: "O", HighAge: 100, Amount: 1}}); err != nil {
+		t.Fatalf("Error setting prizes - %v", err)
+	}
+	if err = race.SetCategories("decades"); err != nil {
+		t.Fatalf("Error setting categories - %v", err)
+	}
+	if err = race.AddEntry(Entry{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38}); err != nil {
+		t.Fatalf("Error adding entry - %v", err)
+	}
+	startRace(race)
+	start := race.GetTime()
+	if err = race.RecordTagRead(TagRead{Bib: 1, Time: start.Add(10 * time.Minute), Checkpoint: "CP1"}); err != nil {
+		t.Fatalf("Error recording a passing - %v", err)
+	}
+	*race.testingTime = start.Add(20 * time.Minute)
+	linkBibTesting(t, race, 1, false)
+	if err = race.RecordTagRead(TagRead{Bib: 1, Time: start.Add(9 * time.Minute), Checkpoint: "CP1"}); err != nil {
+		t.Fatalf("Error recording a passing - %v", err)
+	}
+
+	// the process dies and comes back up
+	recovered := NewRace()
+	if err = openEventLog(recovered, path); err != nil {
+		t.Fatalf("Error recovering from the event log - %v", err)
+	}
+	if !recovered.started.Equal(race.started) || raceCSV(recovered) != raceCSV(race) {
+		t.Errorf("Expected the start and results recovered\n%s\ngot\n%s", raceCSV(race), raceCSV(recovered))
+	}
+	if len(recovered.prizes) != 1 || recovered.prizes[0].Title != "Overall" || recovered.categorySet != "decades" {
+		t.Errorf("Expected the prizes and categories recovered, got %v %s", recovered.prizes, recovered.categorySet)
+	}
+	if len(recovered.passings) != 1 || recovered.passings[0].Split(start) != HumanDuration(9*time.Minute) {
+		t.Errorf("Expected Amy's earliest CP1 split recovered, got %v", recovered.passings)
+	}
+	// the passing reported late isn't what an undo takes back
+	if err = recovered.Undo(); err != nil {
+		t.Fatalf("Unexpected error undoing - %v", err)
+	}
+	if recovered.bibbedEntries[1].HasFinished() || len(recovered.passings) != 1 || len(recovered.prizes) != 1 {
+		t.Errorf("Expected the undo to take back Amy's finish and keep the rest, got %v %v", recovered.passings, recovered.prizes)
+	}
+}
+
+// replayLogged replays the race's events, as written to the event log, on a new race
+func replayLogged(t *testing.T, race *Race) *Race {
+	var eventLog bytes.Buffer
+	encoder := json.NewEncoder(&eventLog)
+	race.RLock()
+	for _, ev := range race.events {
+		encoder.Encode(ev)
+	}
+	race.RUnlock()
+	events, err := ReadEvents(&eventLog)
+	if err != nil {
+		t.Fatalf("Error reading the event log - %v", err)
+	}
+	replayed := NewRace()
+	if err = replayed.Replay(events); err != nil {
+		t.Fatalf("Error replaying - %v", err)
+	}
+	return replayed
+}
+
+func registrations(race *Race) map[Bib]RegistrationStatus {
+	race.RLock()
+	defer race.RUnlock()
+	statuses := make(map[Bib]RegistrationStatus)
+	for _, e := range race.allEntries {
+		statuses[e.Bib] = e.Registration
+	}
+	return statuses
+}
+
+func TestReplayWithdrawAndCapacity(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 8, 0, 0, 0, time.Local)
+	if err := race.SetCapacity(2); err != nil {
+		t.Fatalf("Error setting capacity - %v", err)
+	}
+	for _, e := range []Entry{{Bib: 1, Fname: "Amy", Lname: "Brown"}, {Bib: 2, Fname: "Bob", Lname: "Adams"}, {Bib: 3, Fname: "Cal", Lname: "Cole"}} {
+		race.AddEntry(e)
+	}
+	race.Lock()
+	nonce := race.allEntries[0].Nonce()
+	race.Unlock()
+	if err := race.Withdraw(nonce, 1); err != nil {
+		t.Fatalf("Error withdrawing Amy - %v", err)
+	}
+	replayed := replayLogged(t, race)
+	if got := registrations(replayed); got[1] != Withdrawn || got[3] != Registered {
+		t.Errorf("Expected Amy withdrawn and Cal promoted, got %v", got)
+	}
+	if replayed.capacity != 2 {
+		t.Errorf("Expected the capacity replayed, got %d", replayed.capacity)
+	}
+}
+
+func TestReplayLottery(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 5, 1, 8, 0, 0, 0, time.Local)
+	for x := 1; x <= 6; x++ {
+		race.AddEntry(Entry{Bib: Bib(x), Fname: "Racer", Lname: strconv.Itoa(x)})
+	}
+	if _, err := race.RunLottery(3, 1, 42); err != nil {
+		t.Fatalf("Error running the lottery - %v", err)
+	}
+	replayed := replayLogged(t, race)
+	if got, want := registrations(replayed), registrations(race); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the same draw replayed\n%v\ngot\n%v", want, got)
+	}
+	if len(replayed.lotteryDraws) != 1 || replayed.lotteryDraws[0].Seed != 42 || !replayed.lotteryDraws[0].Time.Equal(race.lotteryDraws[0].Time) {
+		t.Errorf("Expected the draw recorded, got %v", replayed.lotteryDraws)
+	}
+}
+
+func TestReplayTransfer(t *testing.T) {
+	race := transferTestRace(t)
+	race.RLock()
+	token := race.lockedTransferToken(race.bibbedEntries[1])
+	race.RUnlock()
+	transfer, err := race.RequestTransfer(1, token, Entry{Fname: "Cal", Lname: "Cole", Male: true, Age: 45}, "cal@host.com")
+	if err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if err = race.ApproveTransfer(transfer.ID); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	replayed := replayLogged(t, race)
+	if got, want := raceCSV(replayed), raceCSV(race); got != want {
+		t.Errorf("Expected the transfer replayed\n%s\ngot\n%s", want, got)
+	}
+	if len(replayed.transfers) != 1 || replayed.transfers[0].Status != TransferApproved || replayed.nextTransferID != transfer.ID {
+		t.Errorf("Expected the approved transfer kept, got %v", replayed.transfers)
+	}
+}
+
+func TestReplayCorrections(t *testing.T) {
+	race := transferTestRace(t)
+	if _, err := race.ApplyCorrections([][]string{{"Bib", "Fname", "Email"}, {"1", "Amelia", "amelia@host.com"}}); err != nil {
+		t.Fatalf("Error applying corrections - %v", err)
+	}
+	replayed := replayLogged(t, race)
+	if got, want := raceCSV(replayed), raceCSV(race); got != want || !strings.Contains(got, "Amelia,Brown") {
+		t.Errorf("Expected the corrections replayed\n%s\ngot\n%s", want, got)
+	}
+	if len(replayed.corrections) != 2 {
+		t.Errorf("Expected the corrections report kept, got %v", replayed.corrections)
+	}
+}
+
+func TestReplayFinalize(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	race.AddEntry(Entry{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38})
+	race.AddEntry(Entry{Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 31})
+	startRace(race)
+	*race.testingTime = race.started.Add(20 * time.Minute)
+	linkBibTesting(t, race, 1, false)
+	linkBibTesting(t, race, 1, false) // confirmed right away, flagged as a possible double entry
+	*race.testingTime = race.started.Add(21 * time.Minute)
+	linkBibTesting(t, race, 2, false)
+	*race.testingTime = race.started.Add(22 * time.Minute)
+	linkBibTesting(t, race, 2, false)
+	if err := race.Finalize(false); err == nil {
+		t.Fatalf("Expected the anomaly to need reviewing first")
+	}
+	for x := 0; race.ReviewAnomaly(x, false) == nil; x++ {
+	}
+	if err := race.Finalize(false); err != nil {
+		t.Fatalf("Error finalizing - %v", err)
+	}
+	replayed := replayLogged(t, race)
+	if !replayed.finalized || replayed.lockedOpenAnomalies() != 0 {
+		t.Errorf("Expected the results to come back finalized with the anomaly reviewed")
+	}
+	if err := race.Finalize(true); err != nil {
+		t.Fatalf("Error reopening - %v", err)
+	}
+	if replayed = replayLogged(t, race); replayed.finalized {
+		t.Errorf("Expected the results to come back reopened")
+	}
+	if err := race.Undo(); err != nil {
+		t.Fatalf("Error undoing - %v", err)
+	}
+	if race.lockedOpenAnomalies() != 1 || !race.bibbedEntries[2].Confirmed {
+		t.Errorf("Expected the undo to skip the finalize and take back the review")
+	}
+}
+
+func TestReplayCapturedTimes(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for bib := Bib(1); bib <= 3; bib++ {
+		if err := race.AddEntry(Entry{Bib: bib, Fname: "F", Lname: bib.String(), Age: 30}); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	startRace(race)
+	start := *race.testingTime
+	*race.testingTime = start.Add(20 * time.Minute)
+	first, _ := race.CaptureTime(1)
+	*race.testingTime = start.Add(21 * time.Minute)
+	spectator, _ := race.CaptureTime(9)
+	*race.testingTime = start.Add(22 * time.Minute)
+	last, _ := race.CaptureTime(2)
+	*race.testingTime = start.Add(23 * time.Minute)
+	if err := race.AssignTime(first, 1); err != nil {
+		t.Fatalf("Error assigning - %v", err)
+	}
+	if err := race.DiscardTime(spectator); err != nil {
+		t.Fatalf("Error discarding - %v", err)
+	}
+
+	// the server restarts with the last captured time still waiting
+	replayed := replayLogged(t, race)
+	replayed.testingTime = race.testingTime
+	if d := replayed.bibbedEntries[1].Duration; d != HumanDuration(20*time.Minute) {
+		t.Errorf("Expected the assigned time replayed, got %s", d)
+	}
+	if len(replayed.unassigned) != 1 || replayed.unassigned[0].ID != last {
+		t.Fatalf("Expected only the waiting time held after the restart, got %v", replayed.unassigned)
+	}
+	if err := replayed.AssignTime(last, 3); err != nil {
+		t.Fatalf("Error assigning after the restart - %v", err)
+	}
+	if d := replayed.bibbedEntries[3].Duration; d != HumanDuration(22*time.Minute) {
+		t.Errorf("Expected the time captured before the restart, got %s", d)
+	}
+	if id, _ := replayed.CaptureTime(2); id != last+1 {
+		t.Errorf("Expected the captured time ids to carry on after the restart, got %d", id)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mzimmerman/racergo/internal/core"
+)
+
+// ExportPreset is a saved selection and ordering of columns for the CSV download
+type ExportPreset struct {
+	Name    string
+	Columns []string
+}
+
+// computedColumns can be exported on top of the headers and are never taken as optional fields from an upload.
+// Most are worked out from the results and ignored when uploaded, Registration and Raised are read back in.
+var computedColumns = []string{"Division", "Division Place", "Gun Time", "Chip Time", "Adjustments", "Points", "Status", "Pace", "Registration", "Raised", "Estimated"}
+
+func defaultExportPresets() map[string][]string {
+	return map[string][]string{
+		"results":          {"Overall Place", "Bib", "Fname", "Lname", "Gender", "Age", "Division", "Division Place", "Gun Time", "Chip Time", "Pace", "Status"},
+		"USATF submission": {"Overall Place", "Fname", "Lname", "Gender", "Age", "Duration"},
+		"awards list":      {"Overall Place", "Bib", "Fname", "Lname", "Gender", "Age", "Division", "Division Place", "Duration"},
+		"mailing list":     {"Fname", "Lname", config.emailField},
+		"fundraising":      {"Bib", "Fname", "Lname", config.emailField, "Raised"},
+	}
+}
+
+// lockedColumnValue returns the value of the named column for the entry, places come from the index.
+// Columns that aren't a reserved header, computed column or an optional field are blank.
+func (race *Race) lockedColumnValue(entry *Entry, column string, idx *entryIndex) string {
+	switch column {
+	case "Division":
+		return race.lockedDivisionOf(entry)
+	case "Division Place":
+		return idx.divisionPlaces[entry].String()
+	case "Gun Time":
+		return entry.Duration.String()
+	case "Chip Time":
+		return entry.NetDuration().String()
+	case "Adjustments":
+		adjustments := make([]string, len(entry.Adjustments))
+		for x, a := range entry.Adjustments {
+			adjustments[x] = a.String()
+		}
+		return strings.Join(adjustments, "; ")
+	case "Status":
+		return entry.Status.String()
+	case "Points":
+		return strconv.Itoa(entry.Points)
+	case "Pace":
+		return entry.NetDuration().Pace(config.raceDistance, config.paceUnit)
+	case "Registration":
+		return entry.Registration.String()
+	case "Raised":
+		return strconv.FormatFloat(entry.Raised, 'f', 2, 64)
+	case "Estimated":
+		if est, ok := race.estimates[entry.Bib]; ok && entry.HasFinished() {
+			return est.String()
+		}
+		return ""
+	case "Fname":
+		return entry.Fname
+	case "Lname":
+		return entry.Lname
+	case "Age":
+		return strconv.Itoa(int(entry.Age))
+	case "Gender":
+		return core.Gender(entry.Male)
+	case "Bib":
+		return entry.Bib.String()
+	case "Overall Place":
+		return idx.places[entry].String()
+	case "Duration":
+		return entry.Duration.String()
+	case "Time Finished":
+		return entry.TimeFinishedString()
+	case "Confirmed":
+		return fmt.Sprintf("%t", entry.Confirmed)
+	}
+	for x, fn := range race.optionalEntryFields {
+		if fn == column && x < len(entry.Optional) && !restrictedField(fn) {
+			return entry.Optional[x]
+		}
+	}
+	return ""
+}
+
+func (race *Race) lockedValidColumn(column string) bool {
+	for _, h := range append(core.Headers, computedColumns...) {
+		if h == column {
+			return true
+		}
+	}
+	for _, fn := range race.optionalEntryFields {
+		if fn == column {
+			return !restrictedField(fn)
+		}
+	}
+	return false
+}
+
+// WriteCSVColumns writes every entry with only the requested columns, in the requested order.
+// Unlike WriteCSV the output is not meant to be uploaded again so there is no race start row.
+func (race *Race) WriteCSVColumns(writer *csv.Writer, columns []string) error {
+	race.RLock()
+	defer race.RUnlock()
+	err := writer.Write(columns)
+	if err != nil {
+		return err
+	}
+	row := make([]string, len(columns))
+	idx := race.lockedIndex()
+	for _, entry := range race.allEntries {
+		for x, column := range columns {
+			row[x] = race.lockedColumnValue(entry, column, idx)
+		}
+		err = writer.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (race *Race) ExportPreset(name string) ([]string, bool) {
+	race.RLock()
+	defer race.RUnlock()
+	columns, ok := race.exportPresets[name]
+	return columns, ok
+}
+
+func (race *Race) lockedExportPresets() []ExportPreset {
+	presets := make([]ExportPreset, 0, len(race.exportPresets))
+	for name, columns := range race.exportPresets {
+		presets = append(presets, ExportPreset{Name: name, Columns: columns})
+	}
+	sort.Slice(presets, func(i, j int) bool {
+		return presets[i].Name < presets[j].Name
+	})
+	return presets
+}
+
+func (race *Race) SaveExportPreset(name string, columns []string) error {
+	race.Lock()
+	defer race.Unlock()
+	if name == "" {
+		return fmt.Errorf("Export preset needs a name")
+	}
+	if len(columns) == 0 {
+		return fmt.Errorf("Export preset %s needs at least one column", name)
+	}
+	for _, column := range columns {
+		if !race.lockedValidColumn(column) {
+			return fmt.Errorf("Unknown column %s in export preset %s", column, name)
+		}
+	}
+	race.exportPresets[name] = columns
+	return nil
+}
+
+func parseColumns(val string) []string {
+	columns := make([]string, 0)
+	for _, column := range strings.Split(val, ",") {
+		if column = strings.TrimSpace(column); column != "" {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+func saveExportPresetHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	err := race.SaveExportPreset(r.FormValue("name"), parseColumns(r.FormValue("columns")))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/admin", 301)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/mzimmerman/racergo/internal/core"
+)
+
+func downloadWith(t *testing.T, race *Race, values url.Values) *httptest.ResponseRecorder {
+	r, err := http.NewRequest("GET", "/download?"+values.Encode(), nil)
+	if err != nil {
+		t.Fatalf("Error creating request - %v", err)
+	}
+	w := httptest.NewRecorder()
+	downloadHandler(w, r, race)
+	return w
+}
+
+func TestExportColumns(t *testing.T) {
+	race := NewRace()
+	if err := race.SetOptionalFields([]string{"Email", "TShirt"}); err != nil {
+		t.Fatalf("Error setting optional fields - %v", err)
+	}
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "A", Lname: "B", Male: true, Age: 51, Optional: []string{"ab@host.com", "M"}},
+		{Bib: 2, Fname: "C", Lname: "D", Male: false, Age: 21, Optional: []string{"cd@host.com", "S"}},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	tests := []struct {
+		values url.Values
+		code   int
+		want   string
+	}{
+		{url.Values{"columns": {"Lname, Bib"}}, http.StatusOK, "Lname,Bib\nB,1\nD,2\n"},
+		{url.Values{"columns": {"Bib,Nope"}}, http.StatusOK, "Bib,Nope\n1,\n2,\n"},
+		{url.Values{"preset": {"mailing list"}}, http.StatusOK, "Fname,Lname,Email\nA,B,ab@host.com\nC,D,cd@host.com\n"},
+		{url.Values{"preset": {"USATF submission"}}, http.StatusOK, "Overall Place,Fname,Lname,Gender,Age,Duration\n--,A,B,M,51,--\n--,C,D,F,21,--\n"},
+		{url.Values{"preset": {"missing"}}, 409, ""},
+	}
+	for _, test := range tests {
+		w := downloadWith(t, race, test.values)
+		if w.Code != test.code {
+			t.Errorf("%v - expected %d, got %d", test.values, test.code, w.Code)
+			continue
+		}
+		if test.code == http.StatusOK && w.Body.String() != test.want {
+			t.Errorf("%v - wanted:\n%q\ngot:\n%q", test.values, test.want, w.Body.String())
+		}
+	}
+
+	if err := race.SaveExportPreset("bibs", []string{"Bib", "Unknown"}); err == nil {
+		t.Errorf("Expected error saving preset with an unknown column")
+	}
+	if err := race.SaveExportPreset("bibs", []string{"Bib", "TShirt"}); err != nil {
+		t.Errorf("Unexpected error saving preset - %v", err)
+	}
+	w := downloadWith(t, race, url.Values{"preset": {"bibs"}})
+	if want := "Bib,TShirt\n1,M\n2,S\n"; w.Body.String() != want {
+		t.Errorf("Wanted:\n%q\ngot:\n%q", want, w.Body.String())
+	}
+}
+
+func TestExportResults(t *testing.T) {
+	race := NewRace()
+	raceStart := time.Now().Add(-time.Hour).Round(time.Second)
+	race.testingTime = &time.Time{}
+	*race.testingTime = raceStart
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "A", Lname: "B", Male: true, Age: 34},
+		{Bib: 2, Fname: "C", Lname: "D", Male: true, Age: 38},
+		{Bib: 3, Fname: "E", Lname: "F", Male: false, Age: 31},
+		{Bib: 4, Fname: "G", Lname: "H", Male: true, Age: 52},
+		{Bib: NoBib, Fname: "I", Lname: "J", Male: false, Age: 9},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	startRace(race)
+	for x, bib := range []Bib{2, 3, 1} {
+		*race.testingTime = raceStart.Add(time.Duration(x+20) * time.Minute)
+		if err := race.RecordTimeForBib(bib); err != nil {
+			t.Errorf("Error linking bib - %v", err)
+		}
+	}
+	w := downloadWith(t, race, url.Values{"preset": {"results"}})
+	want := `Overall Place,Bib,Fname,Lname,Gender,Age,Division,Division Place,Gun Time,Chip Time,Pace,Status
+1,2,C,D,M,38,M30-39,1,00:20:00.00,00:20:00.00,6:26/mi,
+2,3,E,F,F,31,F30-39,1,00:21:00.00,00:21:00.00,6:46/mi,
+3,1,A,B,M,34,M30-39,2,00:22:00.00,00:22:00.00,7:05/mi,
+--,--,I,J,F,9,F0-9,--,--,--,--,
+--,4,G,H,M,52,M50-59,--,--,--,--,
+`
+	if w.Body.String() != want {
+		t.Errorf("Wanted:\n%s\ngot:\n%s", want, w.Body.String())
+	}
+}
+
+func TestParseDistance(t *testing.T) {
+	tests := []struct {
+		val    string
+		meters float64
+		err    bool
+	}{
+		{"5k", 5000, false},
+		{"10 km", 10000, false},
+		{"400m", 400, false},
+		{"13.1mi", 13.1 * 1609.344, false},
+		{"26.2 Miles", 26.2 * 1609.344, false},
+		{"5", 0, true},
+		{"k", 0, true},
+		{"5 furlongs", 0, true},
+	}
+	for _, test := range tests {
+		meters, err := core.ParseDistance(test.val)
+		if (err != nil) != test.err {
+			t.Errorf("%s - unexpected error result - %v", test.val, err)
+		}
+		if meters != test.meters {
+			t.Errorf("%s - expected %f, got %f", test.val, test.meters, meters)
+		}
+	}
+}
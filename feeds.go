@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxFeedEntries is how many of the latest finishes the feed carries, readers only look at what's new
+const maxFeedEntries = 100
+
+// atomFeed is an Atom feed, see RFC 4287
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Link    atomLink `xml:"link"`
+	Summary string   `xml:"summary"`
+}
+
+// atomID is a tag URI, it stays the same for the race on this host so readers don't show an entry twice
+func (race *Race) atomID(specific string) string {
+	host := config.webserverHostname
+	if x := strings.LastIndex(host, ":"); x >= 0 {
+		host = host[:x]
+	}
+	return fmt.Sprintf("tag:%s,%s:%s%s", host, race.scheduleDay().Format("2006-01-02"), strings.TrimPrefix(race.Path()+"/", "/"), specific)
+}
+
+func (race *Race) lockedAtomFeed(path, title string, entries []atomEntry) atomFeed {
+	updated := race.started
+	for _, e := range entries {
+		if t, _ := time.Parse(time.RFC3339, e.Updated); t.After(updated) {
+			updated = t
+		}
+	}
+	if updated.IsZero() {
+		updated = race.GetTime()
+	}
+	return atomFeed{
+		ID:      race.atomID(strings.TrimPrefix(path, "/")),
+		Title:   race.lockedName() + " " + title,
+		Updated: updated.UTC().Format(time.RFC3339),
+		Author:  atomAuthor{Name: race.lockedName()},
+		Links:   []atomLink{{Rel: "self", Href: race.raceURL(path)}, {Href: race.raceURL("/")}},
+		Entries: entries,
+	}
+}
+
+// lockedFinishersFeed has the latest confirmed finishes first, unconfirmed times can still change so they're left
+// out until the finish line crew confirms them
+func (race *Race) lockedFinishersFeed() atomFeed {
+	var rows []ResultRow
+	for _, row := range race.lockedFinishers() {
+		if row.Confirmed {
+			rows = append(rows, row)
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].TimeFinished.After(rows[j].TimeFinished) })
+	if len(rows) > maxFeedEntries {
+		rows = rows[:maxFeedEntries]
+	}
+	entries := make([]atomEntry, len(rows))
+	for x, row := range rows {
+		summary := fmt.Sprintf("%s %s, bib #%d, finished %s overall", row.Fname, row.Lname, row.Bib, row.Place.Ordinal())
+		if row.DivisionPlace != 0 {
+			summary += fmt.Sprintf(" and %s in %s", row.DivisionPlace.Ordinal(), row.Division)
+		}
+		entries[x] = atomEntry{
+			ID:      race.atomID(fmt.Sprintf("finish/%d", row.Bib)),
+			Title:   fmt.Sprintf("%s %s - %s", row.Fname, row.Lname, row.NetDuration()),
+			Updated: row.TimeFinished.UTC().Format(time.RFC3339),
+			Link:    atomLink{Href: race.raceURL(fmt.Sprintf("/finisher?bib=%d", row.Bib))},
+			Summary: summary + " in " + row.NetDuration().String(),
+		}
+	}
+	return race.lockedAtomFeed("/results.atom", "Finishers", entries)
+}
+
+// lockedWinnersFeed has an entry for each prize winner once their finish is confirmed.  A winner that's bumped by a
+// later finisher drops off the feed and the new winner is added, the prize and place are part of the entry's id.
+func (race *Race) lockedWinnersFeed() atomFeed {
+	var entries []atomEntry
+	for _, prize := range race.prizes {
+		for x, winner := range prize.Winners {
+			if !winner.Confirmed && !prize.Fundraising {
+				continue
+			}
+			updated, with := winner.TimeFinished, winner.NetDuration().String()
+			if prize.Fundraising || updated.IsZero() {
+				updated = race.GetTime()
+			}
+			if prize.Fundraising {
+				with = fmt.Sprintf("$%.2f raised", winner.Raised)
+			}
+			entries = append(entries, atomEntry{
+				ID:      race.atomID(fmt.Sprintf("prize/%s/%d/%d", url.PathEscape(prize.Title), x+1, winner.Bib)),
+				Title:   fmt.Sprintf("%s %s - %s %s", prize.Title, Place(x+1).Ordinal(), winner.Fname, winner.Lname),
+				Updated: updated.UTC().Format(time.RFC3339),
+				Link:    atomLink{Href: race.raceURL(fmt.Sprintf("/finisher?bib=%d", winner.Bib))},
+				Summary: fmt.Sprintf("%s %s, bib #%d, age %d, took %s place in %s with %s", winner.Fname, winner.Lname, winner.Bib, winner.Age, Place(x+1).Ordinal(), prize.Title, with),
+			})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Updated > entries[j].Updated })
+	return race.lockedAtomFeed("/winners.atom", "Award Winners", entries)
+}
+
+func writeAtom(w http.ResponseWriter, feed atomFeed) {
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	fmt.Fprint(w, xml.Header)
+	xml.NewEncoder(w).Encode(feed)
+}
+
+func finishersFeedHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	race.RLock()
+	feed := race.lockedFinishersFeed()
+	race.RUnlock()
+	writeAtom(w, feed)
+}
+
+func winnersFeedHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	race.RLock()
+	feed := race.lockedWinnersFeed()
+	race.RUnlock()
+	writeAtom(w, feed)
+}
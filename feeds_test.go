@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAtomFeeds(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38}, {Bib: 2, Fname: "Bea", Lname: "Adams", Age: 31}, {Bib: 3, Fname: "Cal", Lname: "Cole", Male: true, Age: 44}} {
+		race.AddEntry(e)
+	}
+	race.SetPrizes([]Prize{{Title: "Overall Female", HighAge: ^uint(0), Gender: "F", Amount: 1}})
+	startRace(race)
+	for x, bib := range []int{1, 2, 3} {
+		*race.testingTime = race.started.Add(time.Duration(20+x) * time.Minute)
+		linkBibTesting(t, race, bib, false)
+		if bib != 3 {
+			linkBibTesting(t, race, bib, false)
+		}
+	}
+	get := func(path string, h func(http.ResponseWriter, *http.Request, *Race)) atomFeed {
+		r, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		h(w, r, race)
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/atom+xml") {
+			t.Errorf("Wrong content type for %s - %s", path, ct)
+		}
+		var feed atomFeed
+		if err := xml.Unmarshal(w.Body.Bytes(), &feed); err != nil {
+			t.Fatalf("Error reading %s - %v\n%s", path, err, w.Body.String())
+		}
+		return feed
+	}
+
+	feed := get("/results.atom", finishersFeedHandler)
+	if len(feed.Entries) != 2 || feed.Entries[0].Title != "Bea Adams - 00:21:00.00" || feed.Entries[1].Title != "Amy Brown - 00:20:00.00" {
+		t.Fatalf("Expected the confirmed finishers newest first, got %#v", feed.Entries)
+	}
+	if feed.Entries[1].ID != "tag:localhost,2014-06-01:finish/1" || feed.Entries[1].Link.Href != "http://localhost:8080/finisher?bib=1" {
+		t.Errorf("Wrong id or link - %#v", feed.Entries[1])
+	}
+	if want := time.Date(2014, 6, 1, 9, 21, 0, 0, time.Local).UTC().Format(time.RFC3339); feed.Updated != want {
+		t.Errorf("Expected the feed updated at the latest finish %s, got %s", want, feed.Updated)
+	}
+
+	feed = get("/winners.atom", winnersFeedHandler)
+	if len(feed.Entries) != 1 || feed.Entries[0].Title != "Overall Female 1st - Amy Brown" {
+		t.Errorf("Expected Amy's prize in the feed, got %#v", feed.Entries)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// parseFieldList splits a comma separated list of CSV header names
+func parseFieldList(list string) []string {
+	fields := make([]string, 0)
+	for _, f := range strings.Split(list, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// SetRequiredFields sets the columns an upload must have.  Names are always required, Age and Gender
+// are only used for divisions and prizes when the race requires them, e.g. fun runs that don't collect them.
+func (race *Race) SetRequiredFields(fields []string) error {
+	seen := make(map[string]bool)
+	for _, f := range fields {
+		if seen[f] {
+			return fmt.Errorf("Field %s is listed twice", f)
+		}
+		seen[f] = true
+	}
+	for _, f := range []string{"Fname", "Lname"} {
+		if !seen[f] {
+			return fmt.Errorf("%s must always be required", f)
+		}
+	}
+	race.Lock()
+	defer race.Unlock()
+	race.requiredFields = fields
+	race.lockedEntriesChanged()
+	race.lockedRecomputePrizes()
+	return nil
+}
+
+func (race *Race) GetRequiredFields() []string {
+	race.RLock()
+	defer race.RUnlock()
+	return race.requiredFields
+}
+
+func (race *Race) lockedRequires(field string) bool {
+	for _, f := range race.requiredFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func setRequiredFieldsHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	err := race.SetRequiredFields(parseFieldList(r.FormValue("requiredFields")))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/admin", 301)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRequiredFields(t *testing.T) {
+	race := NewRace()
+	if err := race.SetRequiredFields([]string{"Fname", "Age"}); err == nil {
+		t.Errorf("Expected error when Lname isn't required")
+	}
+	if err := race.SetRequiredFields([]string{"Fname", "Lname", "Fname"}); err == nil {
+		t.Errorf("Expected error listing a field twice")
+	}
+	if got := parseFieldList(" Fname, Lname,,Email "); len(got) != 3 || got[2] != "Email" {
+		t.Errorf("Unexpected field list %q", got)
+	}
+	if err := ioutil.WriteFile("funRunTemp", []byte("Fname,Lname,Bib\nAmy,Brown,1\nBob,Adams,2\nCal,Cole,3\n"), 0666); err != nil {
+		t.Fatalf("Error writing upload - %v", err)
+	}
+	defer os.Remove("funRunTemp")
+	testUploadRacersHelper(t, "funRunTemp", 409, race)
+	// a fun run that collects neither age nor gender
+	if err := race.SetRequiredFields(parseFieldList("Fname,Lname")); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	testUploadRacersHelper(t, "funRunTemp", 301, race)
+	race.GeneratePrizes(3)
+	race.RLock()
+	if len(race.prizes) != 1 || race.prizes[0].Gender != "O" {
+		t.Errorf("Expected only an overall prize, got %v", race.prizes)
+	}
+	race.RUnlock()
+
+	raceStart := time.Now().Add(-time.Hour).Round(time.Second)
+	race.testingTime = &time.Time{}
+	*race.testingTime = raceStart
+	startRace(race)
+	for x, bib := range []Bib{2, 1} {
+		*race.testingTime = raceStart.Add(time.Duration(x+20) * time.Minute)
+		race.RecordTimeForBib(bib)
+		race.RecordTimeForBib(bib)
+	}
+	race.RLock()
+	bob := race.bibbedEntries[2]
+	if division := race.lockedDivisionOf(bob); division != "Overall" {
+		t.Errorf("Expected everyone in the Overall division, got %s", division)
+	}
+	if standing := race.lockedStanding(bob); standing != "1st overall" {
+		t.Errorf("Expected just the overall place, got %s", standing)
+	}
+	if winners := race.prizes[0].Winners; len(winners) != 1 || winners[0] != bob {
+		t.Errorf("Expected Bob to win overall, got %v", winners)
+	}
+	race.RUnlock()
+
+	// gender prizes aren't awarded when the race doesn't know anyone's gender
+	race.SetPrizes([]Prize{{Title: "Women's Overall", LowAge: 0, HighAge: ^uint(0), Gender: "F", Amount: 1}})
+	race.RLock()
+	if len(race.prizes[0].Winners) != 0 {
+		t.Errorf("Expected no winners for a gender prize, got %v", race.prizes[0].Winners)
+	}
+	race.RUnlock()
+
+	// age without gender divides by category alone
+	race.SetRequiredFields(parseFieldList("Fname,Lname,Age"))
+	race.RLock()
+	if division := race.lockedDivisionOf(bob); division != "0-9" {
+		t.Errorf("Expected the age category alone, got %s", division)
+	}
+	race.RUnlock()
+}
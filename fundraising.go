@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mzimmerman/racergo/internal/core"
+)
+
+// fundraisingOrder returns the entries who have raised money, biggest fundraiser first
+func fundraisingOrder(allEntries []*Entry) []*Entry {
+	raisers := make([]*Entry, 0)
+	for _, e := range allEntries {
+		if e.Raised > 0 {
+			raisers = append(raisers, e)
+		}
+	}
+	sort.SliceStable(raisers, func(i, j int) bool {
+		return raisers[i].Raised > raisers[j].Raised
+	})
+	return raisers
+}
+
+// ImportDonations sets each entrant's fundraising total from a CSV with a header row containing
+// Raised and either Bib or the e-mail field to match rows to entries.  Totals replace what was
+// there before since platforms export running totals.  Rows that don't match an entry are
+// counted and skipped.
+func (race *Race) ImportDonations(rawEntries [][]string) (updated int, skipped int, err error) {
+	if len(rawEntries) <= 1 {
+		return 0, 0, fmt.Errorf("Either blank file or only supplied the header row")
+	}
+	bibCol, emailCol, raisedCol := -1, -1, -1
+	for col, title := range rawEntries[0] {
+		switch title {
+		case "Bib":
+			bibCol = col
+		case config.emailField:
+			emailCol = col
+		case "Raised":
+			raisedCol = col
+		}
+	}
+	if raisedCol < 0 {
+		return 0, 0, fmt.Errorf("CSV file missing the Raised field")
+	}
+	if bibCol < 0 && emailCol < 0 {
+		return 0, 0, fmt.Errorf("CSV file needs a Bib or %s field to match donations to racers", config.emailField)
+	}
+	race.Lock()
+	defer race.Unlock()
+	byEmail := make(map[string]*Entry)
+	if race.optionalEmailIndex >= 0 {
+		for _, e := range race.allEntries {
+			if race.optionalEmailIndex < len(e.Optional) && e.Optional[race.optionalEmailIndex] != "" {
+				byEmail[strings.ToLower(e.Optional[race.optionalEmailIndex])] = e
+			}
+		}
+	}
+	for row := 1; row < len(rawEntries); row++ {
+		if raisedCol >= len(rawEntries[row]) {
+			skipped++
+			continue
+		}
+		amount, err := core.ParseAmount(rawEntries[row][raisedCol])
+		if err != nil {
+			return updated, skipped, fmt.Errorf("Error parsing Raised on row %d - %v", row+1, err)
+		}
+		var entry *Entry
+		if bibCol >= 0 && bibCol < len(rawEntries[row]) {
+			if bib, err := strconv.Atoi(rawEntries[row][bibCol]); err == nil {
+				entry = race.bibbedEntries[Bib(bib)]
+			}
+		}
+		if entry == nil && emailCol >= 0 && emailCol < len(rawEntries[row]) {
+			entry = byEmail[strings.ToLower(rawEntries[row][emailCol])]
+		}
+		if entry == nil {
+			skipped++
+			continue
+		}
+		entry.Raised = amount
+		updated++
+	}
+	race.lockedRecomputePrizes()
+	log.Printf("Imported donations for %d entries, skipped %d rows", updated, skipped)
+	return updated, skipped, nil
+}
+
+func (race *Race) lockedTotalRaised() float64 {
+	total := 0.0
+	for _, e := range race.allEntries {
+		total += e.Raised
+	}
+	return total
+}
+
+func uploadDonationsHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	var in io.Reader
+	if reader, err := r.MultipartReader(); err == nil {
+		part, err := reader.NextPart()
+		if err != nil {
+			showErrorForAdmin(w, r.Referer(), "Error getting Part - %s", err)
+			return
+		}
+		in = part
+	} else {
+		if config.fundraisingURL == "" {
+			showErrorForAdmin(w, r.Referer(), "No donations file uploaded and RACERGOFUNDRAISINGURL is not set")
+			return
+		}
+		resp, err := http.Get(config.fundraisingURL)
+		if err != nil {
+			showErrorForAdmin(w, r.Referer(), "Error fetching donations from %s - %v", config.fundraisingURL, err)
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			showErrorForAdmin(w, r.Referer(), "Error fetching donations from %s - %s", config.fundraisingURL, resp.Status)
+			return
+		}
+		in = resp.Body
+	}
+	rawEntries, err := csv.NewReader(in).ReadAll()
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error Reading CSV file - %s", err)
+		return
+	}
+	if _, _, err := race.ImportDonations(rawEntries); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/fundraising", 301)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mzimmerman/racergo/internal/core"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		err  bool
+	}{
+		{"25", 25, false},
+		{"$1,250.50", 1250.5, false},
+		{" 0.00", 0, false},
+		{"-5", 0, true},
+		{"lots", 0, true},
+	}
+	for _, test := range tests {
+		got, err := core.ParseAmount(test.in)
+		if (err != nil) != test.err || got != test.want {
+			t.Errorf("core.ParseAmount(%q) = %f, %v - wanted %f, error %t", test.in, got, err, test.want, test.err)
+		}
+	}
+}
+
+func TestImportDonations(t *testing.T) {
+	race := NewRace()
+	if err := race.SetOptionalFields([]string{"Email"}); err != nil {
+		t.Fatalf("Error setting optional fields - %v", err)
+	}
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "A", Age: 30, Optional: []string{"amy@host.com"}},
+		{Bib: 2, Fname: "Bob", Lname: "B", Male: true, Age: 40, Optional: []string{"bob@host.com"}},
+		{Bib: 3, Fname: "Cat", Lname: "C", Age: 50, Optional: []string{"cat@host.com"}},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	race.SetPrizes([]Prize{
+		{Title: "Top Fundraiser", LowAge: 0, HighAge: ^uint(0), Gender: "O", Amount: 2, Fundraising: true},
+		{Title: "Overall", LowAge: 0, HighAge: ^uint(0), Gender: "O", Amount: 1},
+	})
+
+	if _, _, err := race.ImportDonations([][]string{{"Bib", "Amount"}, {"1", "5"}}); err == nil {
+		t.Errorf("Expected error importing without a Raised column")
+	}
+	if _, _, err := race.ImportDonations([][]string{{"Name", "Raised"}, {"Amy", "5"}}); err == nil {
+		t.Errorf("Expected error importing without a way to match entries")
+	}
+	updated, skipped, err := race.ImportDonations([][]string{
+		{"Bib", "Email", "Raised"},
+		{"1", "", "$150.00"},
+		{"", "BOB@host.com", "$1,200"},
+		{"9", "nobody@host.com", "10"},
+	})
+	if err != nil || updated != 2 || skipped != 1 {
+		t.Fatalf("Expected 2 updated and 1 skipped, got %d and %d - %v", updated, skipped, err)
+	}
+
+	race.RLock()
+	winners := race.prizes[0].Winners
+	if len(winners) != 2 || winners[0].Fname != "Bob" || winners[1].Fname != "Amy" {
+		t.Errorf("Expected Bob then Amy to win the fundraising prize, got %v", winners)
+	}
+	if len(race.prizes[1].Winners) != 0 {
+		t.Errorf("Expected no finish prize winners before anyone finished, got %v", race.prizes[1].Winners)
+	}
+	if total := race.lockedTotalRaised(); total != 1350 {
+		t.Errorf("Expected $1350 raised, got %f", total)
+	}
+	race.RUnlock()
+
+	// later syncs replace the running totals
+	if _, _, err := race.ImportDonations([][]string{{"Bib", "Raised"}, {"1", "2000"}}); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	r, _ := http.NewRequest("GET", "/fundraising", nil)
+	w := httptest.NewRecorder()
+	handler(w, r, race)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected %d, got %d - %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	if amy, bob := strings.Index(body, "$2000.00"), strings.Index(body, "$1200.00"); amy < 0 || bob < 0 || amy > bob {
+		t.Errorf("Expected Amy's $2000.00 ranked above Bob's $1200.00 on the leaderboard")
+	}
+	if !strings.Contains(body, "$3200.00 raised") {
+		t.Errorf("Expected the total raised on the leaderboard")
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// A gRPC service for timing decoders, see timing.proto.  gRPC is protobuf messages framed over HTTP/2, which
+// net/http already speaks on the https port, so the few messages are encoded here instead of pulling in grpc-go.
+
+// gRPC status codes, see https://grpc.github.io/grpc/core/md_doc_statuscodes.html
+const (
+	grpcOK                = 0
+	grpcInvalidArgument   = 3
+	grpcResourceExhausted = 8
+	grpcUnimplemented     = 12
+)
+
+// maxGRPCMessage is the largest message accepted, a tag read is a few dozen bytes so anything near it is a bad client
+const maxGRPCMessage = 4 << 20
+
+var errGRPCMessageTooLarge = fmt.Errorf("messages over %d bytes aren't accepted", maxGRPCMessage)
+
+// resultsPoll is how often StreamResults looks for new finishers
+var resultsPoll = time.Second
+
+// protoField is a decoded field, either a varint or length delimited bytes
+type protoField struct {
+	num    int
+	varint uint64
+	bytes  []byte
+}
+
+func decodeProto(msg []byte) ([]protoField, error) {
+	fields := []protoField{}
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return nil, fmt.Errorf("bad field key")
+		}
+		msg = msg[n:]
+		field := protoField{num: int(key >> 3)}
+		switch key & 7 {
+		case 0:
+			if field.varint, n = binary.Uvarint(msg); n <= 0 {
+				return nil, fmt.Errorf("bad varint in field %d", field.num)
+			}
+			msg = msg[n:]
+		case 2:
+			length, n := binary.Uvarint(msg)
+			if n <= 0 || uint64(len(msg)-n) < length {
+				return nil, fmt.Errorf("bad length in field %d", field.num)
+			}
+			field.bytes = msg[n : n+int(length)]
+			msg = msg[n+int(length):]
+		default:
+			return nil, fmt.Errorf("unsupported wire type %d in field %d", key&7, field.num)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func appendProtoVarint(msg []byte, num int, val uint64) []byte {
+	if val == 0 {
+		return msg
+	}
+	msg = binary.AppendUvarint(msg, uint64(num)<<3)
+	return binary.AppendUvarint(msg, val)
+}
+
+func appendProtoString(msg []byte, num int, val string) []byte {
+	if val == "" {
+		return msg
+	}
+	msg = binary.AppendUvarint(msg, uint64(num)<<3|2)
+	msg = binary.AppendUvarint(msg, uint64(len(val)))
+	return append(msg, val...)
+}
+
+// readGRPCMessage reads one length prefixed message, io.EOF when the client has finished sending
+func readGRPCMessage(r io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, err
+	}
+	if prefix[0] != 0 {
+		return nil, fmt.Errorf("compressed messages aren't supported")
+	}
+	length := binary.BigEndian.Uint32(prefix[1:])
+	if length > maxGRPCMessage {
+		return nil, errGRPCMessageTooLarge
+	}
+	msg := make([]byte, length)
+	_, err := io.ReadFull(r, msg)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return msg, err
+}
+
+func writeGRPCMessage(w http.ResponseWriter, msg []byte) error {
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	if _, err := w.Write(append(frame, msg...)); err != nil {
+		return err
+	}
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// TagRead is a timing decoder's read of a bib's tag
+type TagRead struct {
+	Bib        Bib
+	Time       time.Time
+	Checkpoint string
+	Reader     string
+}
+
+func parseTagRead(msg []byte) (TagRead, error) {
+	var tr TagRead
+	fields, err := decodeProto(msg)
+	if err != nil {
+		return tr, err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			tr.Bib = Bib(f.varint)
+		case 2:
+			tr.Time = time.Unix(0, int64(f.varint)*int64(time.Millisecond))
+		case 3:
+			tr.Checkpoint = strings.ToUpper(string(f.bytes))
+		case 4:
+			tr.Reader = string(f.bytes)
+		}
+	}
+	return tr, nil
+}
+
+// RecordTagRead records the read, the first read of a bib at the finish links its finish time waiting for
+// confirmation and later reads are ignored, decoders read a tag many times as it crosses the mat.
+// A read at the START checkpoint is the racer's start crossing for their net time.
+func (race *Race) RecordTagRead(tr TagRead) error {
+	race.Lock()
+	defer race.Unlock()
+	if tr.Time.IsZero() {
+		tr.Time = race.GetTime()
+	}
+	if tr.Checkpoint == startCheckpoint {
+		return race.lockedRecordStartCrossing(tr.Bib, tr.Time)
+	}
+	if tr.Checkpoint != "" && tr.Checkpoint != "FINISH" {
+		return race.lockedRecordPassing(Passing{Checkpoint: tr.Checkpoint, Bib: tr.Bib, Time: tr.Time, Source: tr.Reader})
+	}
+	entry, ok := race.bibbedEntries[tr.Bib]
+	if !ok {
+		return fmt.Errorf("Bib %d not found", tr.Bib)
+	}
+	if entry.HasFinished() {
+		return nil
+	}
+	return race.lockedRecordTimeForBib(tr.Bib, tr.Time, false)
+}
+
+func grpcHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	if r.Method != "POST" || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "gRPC requests only, see timing.proto", http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	status, message := grpcOK, ""
+	switch r.URL.Path {
+	case "/racergo.Timing/ReadTags":
+		status, message = race.grpcReadTags(w, r)
+	case "/racergo.Timing/StreamResults":
+		status, message = race.grpcStreamResults(w, r)
+	default:
+		status, message = grpcUnimplemented, fmt.Sprintf("Unknown method %s", r.URL.Path)
+	}
+	w.Header().Set("Grpc-Status", fmt.Sprintf("%d", status))
+	w.Header().Set("Grpc-Message", message)
+}
+
+func (race *Race) grpcReadTags(w http.ResponseWriter, r *http.Request) (int, string) {
+	var accepted, rejected uint64
+	for {
+		msg, err := readGRPCMessage(r.Body)
+		if err == io.EOF {
+			break
+		}
+		if err == errGRPCMessageTooLarge {
+			return grpcResourceExhausted, err.Error()
+		}
+		if err != nil {
+			return grpcInvalidArgument, err.Error()
+		}
+		tr, err := parseTagRead(msg)
+		if err != nil {
+			return grpcInvalidArgument, err.Error()
+		}
+		if err = race.RecordTagRead(tr); err != nil {
+			log.Printf("Tag read of bib #%d from %s rejected - %v", tr.Bib, tr.Reader, err)
+			rejected++
+			continue
+		}
+		accepted++
+	}
+	writeGRPCMessage(w, appendProtoVarint(appendProtoVarint(nil, 1, accepted), 2, rejected))
+	return grpcOK, ""
+}
+
+// grpcStreamResults sends the finishers so far then any new ones, until the decoder hangs up.
+// A finisher is sent again when their place or time changes.
+func (race *Race) grpcStreamResults(w http.ResponseWriter, r *http.Request) (int, string) {
+	sent := make(map[Bib]string)
+	for {
+		race.RLock()
+		results := [][]byte{}
+		places := race.lockedIndex().places
+		for _, e := range race.allEntries {
+			if !e.HasFinished() {
+				continue
+			}
+			// a disqualified finisher is still sent, without a place
+			msg := appendProtoVarint(nil, 1, uint64(places[e]))
+			msg = appendProtoVarint(msg, 2, uint64(e.Bib))
+			msg = appendProtoString(msg, 3, e.Fname+" "+e.Lname)
+			msg = appendProtoVarint(msg, 4, uint64(time.Duration(e.Duration)/time.Millisecond))
+			if e.Confirmed {
+				msg = appendProtoVarint(msg, 5, 1)
+			}
+			if sent[e.Bib] != string(msg) {
+				sent[e.Bib] = string(msg)
+				results = append(results, msg)
+			}
+		}
+		race.RUnlock()
+		for _, msg := range results {
+			if err := writeGRPCMessage(w, msg); err != nil {
+				return grpcOK, ""
+			}
+		}
+		select {
+		case <-r.Context().Done():
+			return grpcOK, ""
+		case <-time.After(resultsPoll):
+		}
+	}
+}
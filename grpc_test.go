@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func grpcFrame(msg []byte) []byte {
+	frame := make([]byte, 5)
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	return append(frame, msg...)
+}
+
+func tagRead(bib int, at time.Time, checkpoint string) []byte {
+	msg := appendProtoVarint(nil, 1, uint64(bib))
+	msg = appendProtoVarint(msg, 2, uint64(at.UnixNano()/int64(time.Millisecond)))
+	msg = appendProtoString(msg, 3, checkpoint)
+	return grpcFrame(appendProtoString(msg, 4, "mat-1"))
+}
+
+func TestGRPCTiming(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 28},
+		{Bib: 2, Fname: "Bea", Lname: "Adams", Age: 31},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	startRace(race)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		grpcHandler(w, r, race)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+	client := server.Client()
+
+	start := *race.testingTime
+	var body bytes.Buffer
+	body.Write(tagRead(1, start.Add(18*time.Minute), ""))
+	body.Write(tagRead(1, start.Add(18*time.Minute+time.Second), "FINISH")) // the same tag read again crossing the mat
+	body.Write(tagRead(2, start.Add(19*time.Minute), ""))
+	body.Write(tagRead(9, start.Add(20*time.Minute), ""))
+	resp, err := client.Post(server.URL+"/racergo.Timing/ReadTags", "application/grpc", &body)
+	if err != nil {
+		t.Fatalf("Error calling ReadTags - %v", err)
+	}
+	msg, err := readGRPCMessage(resp.Body)
+	ioutil.ReadAll(resp.Body) // the trailers come after the body
+	resp.Body.Close()
+	if err != nil || resp.ProtoMajor != 2 || resp.Trailer.Get("Grpc-Status") != "0" {
+		t.Fatalf("Expected a summary over HTTP/2 with an OK status, got %v %s - %v", resp.Proto, resp.Trailer, err)
+	}
+	if fields, _ := decodeProto(msg); len(fields) != 2 || fields[0].varint != 3 || fields[1].varint != 1 {
+		t.Errorf("Expected 3 accepted and 1 rejected, got %v", fields)
+	}
+	if d := race.bibbedEntries[1].Duration; d != HumanDuration(18*time.Minute) {
+		t.Errorf("Expected Amy's first read as her finish, got %s", d)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "POST", server.URL+"/racergo.Timing/StreamResults", bytes.NewReader(grpcFrame(nil)))
+	req.Header.Set("Content-Type", "application/grpc")
+	if resp, err = client.Do(req); err != nil {
+		t.Fatalf("Error calling StreamResults - %v", err)
+	}
+	defer resp.Body.Close()
+	for x, expected := range []string{"Amy Brown", "Bea Adams"} {
+		msg, err := readGRPCMessage(resp.Body)
+		if err != nil {
+			t.Fatalf("Error reading result %d - %v", x+1, err)
+		}
+		fields, _ := decodeProto(msg)
+		if len(fields) < 4 || fields[0].varint != uint64(x+1) || string(fields[2].bytes) != expected {
+			t.Errorf("Expected %s in place %d, got %v", expected, x+1, fields)
+		}
+	}
+
+	resp, err = client.Post(server.URL+"/racergo.Timing/Nope", "application/grpc", nil)
+	if err != nil {
+		t.Fatalf("Error calling an unknown method - %v", err)
+	}
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.Trailer.Get("Grpc-Status") != "12" {
+		t.Errorf("Expected unimplemented, got %s", resp.Trailer)
+	}
+}
+
+func TestGRPCMessageTooLarge(t *testing.T) {
+	race := NewRace()
+	frame := make([]byte, 5)
+	binary.BigEndian.PutUint32(frame[1:], 0xFFFFFFFF)
+	r := httptest.NewRequest("POST", "/racergo.Timing/ReadTags", bytes.NewReader(frame))
+	r.Header.Set("Content-Type", "application/grpc")
+	w := httptest.NewRecorder()
+	grpcHandler(w, r, race)
+	if status := w.Header().Get("Grpc-Status"); status != fmt.Sprintf("%d", grpcResourceExhausted) {
+		t.Errorf("Expected an oversized message refused as resource exhausted, got status %s - %s", status, w.Header().Get("Grpc-Message"))
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Geocode is where a hometown was geocoded to
+type Geocode struct {
+	Found     bool // false when the geocoder doesn't know the hometown, so it isn't looked up again
+	Latitude  float64
+	Longitude float64
+	State     string
+	Country   string
+}
+
+// geocoder looks up a hometown like "Boulder, CO"
+type geocoder func(query string) (Geocode, error)
+
+// hometownGeocoder is set from RACERGOGEOCODER, nil if hometowns aren't geocoded
+var hometownGeocoder geocoder
+
+// geocodePace is the wait between lookups, Nominatim's usage policy is at most one a second
+var geocodePace = time.Second
+
+// parseGeocoder reads RACERGOGEOCODER, one of
+//
+//	nominatim[:<search URL>]  OpenStreetMap's Nominatim, or your own server's, e.g. nominatim:http://localhost:8080/search
+//	file:<path>               a CSV with Place, Latitude, Longitude, State and Country columns for geocoding offline
+func parseGeocoder(spec string) (geocoder, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	switch parts[0] {
+	case "nominatim":
+		search := "https://nominatim.openstreetmap.org/search"
+		if len(parts) == 2 && parts[1] != "" {
+			search = parts[1]
+		}
+		return nominatimGeocoder(search), nil
+	case "file":
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, fmt.Errorf("Expected file:<path>")
+		}
+		data, err := ioutil.ReadFile(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		return tableGeocoder(data)
+	}
+	return nil, fmt.Errorf("Unknown geocoder %s, expected nominatim or file", parts[0])
+}
+
+func nominatimGeocoder(search string) geocoder {
+	return func(query string) (Geocode, error) {
+		var place Geocode
+		req, err := http.NewRequest("GET", search+"?"+url.Values{"q": {query}, "format": {"jsonv2"}, "addressdetails": {"1"}, "limit": {"1"}}.Encode(), nil)
+		if err != nil {
+			return place, err
+		}
+		req.Header.Set("User-Agent", "racergo ("+config.webserverHostname+")") // required by the usage policy
+		resp, err := syncClient.Do(req)
+		if err != nil {
+			return place, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return place, fmt.Errorf("%s", resp.Status)
+		}
+		var results []struct {
+			Lat, Lon string
+			Address  struct {
+				State   string `json:"state"`
+				Country string `json:"country"`
+			} `json:"address"`
+		}
+		if err = json.NewDecoder(resp.Body).Decode(&results); err != nil || len(results) == 0 {
+			return place, err
+		}
+		place.Latitude, err = strconv.ParseFloat(results[0].Lat, 64)
+		if err == nil {
+			place.Longitude, err = strconv.ParseFloat(results[0].Lon, 64)
+		}
+		place.Found, place.State, place.Country = err == nil, results[0].Address.State, results[0].Address.Country
+		return place, err
+	}
+}
+
+func tableGeocoder(data []byte) (geocoder, error) {
+	rows, _, err := readCSV(data)
+	if err != nil {
+		return nil, err
+	}
+	places := make(map[string]Geocode)
+	for x, row := range rows {
+		if len(row) < 5 {
+			return nil, fmt.Errorf("Row %d has %d columns, expected Place, Latitude, Longitude, State and Country", x+1, len(row))
+		}
+		place := Geocode{Found: true, State: row[3], Country: row[4]}
+		var err error
+		if place.Latitude, err = strconv.ParseFloat(row[1], 64); err == nil {
+			place.Longitude, err = strconv.ParseFloat(row[2], 64)
+		}
+		if err != nil {
+			if x == 0 {
+				continue // the header
+			}
+			return nil, fmt.Errorf("Row %d doesn't have a latitude and longitude", x+1)
+		}
+		places[strings.ToLower(strings.TrimSpace(row[0]))] = place
+	}
+	return func(query string) (Geocode, error) {
+		return places[strings.ToLower(query)], nil
+	}, nil
+}
+
+// GeocodeCache keeps the hometowns already looked up, saved to RACERGOGEOCODECACHE so a restart doesn't look them up again
+type GeocodeCache struct {
+	places map[string]Geocode
+	path   string
+	sync.Mutex
+}
+
+var geocodes = &GeocodeCache{places: make(map[string]Geocode)}
+
+func (gc *GeocodeCache) Load(path string) error {
+	gc.Lock()
+	defer gc.Unlock()
+	gc.path = path
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &gc.places)
+}
+
+func (gc *GeocodeCache) Lookup(hometown string) (Geocode, bool) {
+	gc.Lock()
+	defer gc.Unlock()
+	place, ok := gc.places[strings.ToLower(hometown)]
+	return place, ok
+}
+
+func (gc *GeocodeCache) Store(hometown string, place Geocode) {
+	gc.Lock()
+	defer gc.Unlock()
+	gc.places[strings.ToLower(hometown)] = place
+	if gc.path == "" {
+		return
+	}
+	data, err := json.Marshal(gc.places)
+	if err == nil {
+		err = ioutil.WriteFile(gc.path, data, 0644)
+	}
+	if err != nil {
+		log.Printf("Error saving the geocode cache %s - %v", gc.path, err)
+	}
+}
+
+// lockedHometown joins the racer's RACERGOHOMETOWNFIELDS, e.g. "Boulder, CO"
+func (race *Race) lockedHometown(e *Entry) string {
+	var parts []string
+	for _, field := range config.hometownFields {
+		for x, f := range race.optionalEntryFields {
+			if strings.EqualFold(f, field) && x < len(e.Optional) && strings.TrimSpace(e.Optional[x]) != "" {
+				parts = append(parts, strings.TrimSpace(e.Optional[x]))
+			}
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// GeocodeHometowns looks up every racer's hometown that isn't in the cache yet, returning how many were looked up
+func (race *Race) GeocodeHometowns() (int, error) {
+	if hometownGeocoder == nil {
+		return 0, fmt.Errorf("Geocoding isn't configured, set RACERGOGEOCODER")
+	}
+	race.RLock()
+	seen := make(map[string]bool)
+	var hometowns []string
+	for _, e := range race.allEntries {
+		hometown := race.lockedHometown(e)
+		if _, cached := geocodes.Lookup(hometown); hometown != "" && !cached && !seen[strings.ToLower(hometown)] {
+			seen[strings.ToLower(hometown)] = true
+			hometowns = append(hometowns, hometown)
+		}
+	}
+	race.RUnlock()
+	for x, hometown := range hometowns {
+		if x > 0 {
+			time.Sleep(geocodePace)
+		}
+		place, err := hometownGeocoder(hometown)
+		if err != nil {
+			return x, fmt.Errorf("Error geocoding %s - %v", hometown, err)
+		}
+		geocodes.Store(hometown, place)
+	}
+	return len(hometowns), nil
+}
+
+// OriginCount is how many racers came from a state or country
+type OriginCount struct {
+	Name  string
+	Count int
+}
+
+// MapPoint is a hometown on the participants map, X and Y are percentages across and down the map
+type MapPoint struct {
+	Hometown string
+	Count    int
+	X, Y     float64
+	Radius   float64
+}
+
+// HometownReport is where the racers came from
+type HometownReport struct {
+	Points    []MapPoint
+	States    []OriginCount
+	Countries []OriginCount
+	Located   int
+	Unknown   []string // hometowns the geocoder couldn't find or that haven't been looked up yet
+}
+
+func sortedOrigins(counts map[string]int) []OriginCount {
+	origins := make([]OriginCount, 0, len(counts))
+	for name, count := range counts {
+		origins = append(origins, OriginCount{Name: name, Count: count})
+	}
+	sort.Slice(origins, func(i, j int) bool {
+		if origins[i].Count != origins[j].Count {
+			return origins[i].Count > origins[j].Count
+		}
+		return origins[i].Name < origins[j].Name
+	})
+	return origins
+}
+
+// lockedHometownReport counts the racers with a spot by where they came from, fitting the map to their hometowns
+func (race *Race) lockedHometownReport() HometownReport {
+	var report HometownReport
+	states, countries := make(map[string]int), make(map[string]int)
+	points := make(map[string]int) // index in Points by latitude and longitude
+	var places []Geocode
+	unknown := make(map[string]bool)
+	for _, e := range race.allEntries {
+		hometown := race.lockedHometown(e)
+		if hometown == "" || !e.Registration.HasSpot() {
+			continue
+		}
+		place, ok := geocodes.Lookup(hometown)
+		if !ok || !place.Found {
+			if !unknown[hometown] {
+				unknown[hometown] = true
+				report.Unknown = append(report.Unknown, hometown)
+			}
+			continue
+		}
+		report.Located++
+		if place.State != "" {
+			states[place.State+", "+place.Country]++
+		}
+		countries[place.Country]++
+		key := fmt.Sprintf("%.3f,%.3f", place.Latitude, place.Longitude)
+		x, ok := points[key]
+		if !ok {
+			x = len(report.Points)
+			points[key] = x
+			report.Points = append(report.Points, MapPoint{Hometown: hometown})
+			places = append(places, place)
+		}
+		report.Points[x].Count++
+	}
+	sort.Strings(report.Unknown)
+	report.States, report.Countries = sortedOrigins(states), sortedOrigins(countries)
+	if len(places) == 0 {
+		return report
+	}
+	minLat, maxLat, minLon, maxLon := 90.0, -90.0, 180.0, -180.0
+	for _, p := range places {
+		minLat, maxLat = math.Min(minLat, p.Latitude), math.Max(maxLat, p.Latitude)
+		minLon, maxLon = math.Min(minLon, p.Longitude), math.Max(maxLon, p.Longitude)
+	}
+	latSpan, lonSpan := math.Max(maxLat-minLat, 1), math.Max(maxLon-minLon, 1)
+	for x, p := range places {
+		report.Points[x].X = 5 + 90*(p.Longitude-minLon)/lonSpan
+		report.Points[x].Y = 5 + 90*(maxLat-p.Latitude)/latSpan
+		report.Points[x].Radius = 0.5 + math.Sqrt(float64(report.Points[x].Count))
+	}
+	return report
+}
+
+// lockedWriteHometowns writes the racers by state and country for the organizer's report
+func (race *Race) lockedWriteHometowns(writer *csv.Writer) {
+	report := race.lockedHometownReport()
+	writer.Write([]string{"Origin", "Racers"})
+	for _, origins := range [][]OriginCount{report.Countries, report.States} {
+		for _, o := range origins {
+			writer.Write([]string{o.Name, strconv.Itoa(o.Count)})
+		}
+	}
+	if len(report.Unknown) > 0 {
+		writer.Write([]string{"Hometowns not located", strconv.Itoa(len(report.Unknown))})
+	}
+}
+
+func geocodeHometownsHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	if hometownGeocoder == nil {
+		showErrorForAdmin(w, r.Referer(), "Geocoding isn't configured, set RACERGOGEOCODER")
+		return
+	}
+	go func() {
+		n, err := race.GeocodeHometowns()
+		if err != nil {
+			log.Printf("%v", err)
+		}
+		log.Printf("Geocoded %d hometowns", n)
+	}()
+	http.Redirect(w, r, "/hometowns", 301)
+}
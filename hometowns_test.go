@@ -0,0 +1,108 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNominatimGeocoder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("q") != "Boulder, CO" || r.Header.Get("User-Agent") == "" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		w.Write([]byte(`[{"lat":"40.0150","lon":"-105.2705","address":{"state":"Colorado","country":"United States"}}]`))
+	}))
+	defer server.Close()
+	geocode, err := parseGeocoder("nominatim:" + server.URL)
+	if err != nil {
+		t.Fatalf("Error parsing the geocoder - %v", err)
+	}
+	place, err := geocode("Boulder, CO")
+	if err != nil || !place.Found || place.Latitude != 40.015 || place.State != "Colorado" {
+		t.Errorf("Expected Boulder, got %#v - %v", place, err)
+	}
+	if place, err = geocode("Nowhere"); err != nil || place.Found {
+		t.Errorf("Expected Nowhere not to be found, got %#v - %v", place, err)
+	}
+	if _, err = parseGeocoder("google"); err == nil {
+		t.Errorf("Expected an error for an unknown geocoder")
+	}
+}
+
+func TestHometownReport(t *testing.T) {
+	defer func(g geocoder, cache *GeocodeCache, pace time.Duration) {
+		hometownGeocoder, geocodes, geocodePace = g, cache, pace
+	}(hometownGeocoder, geocodes, geocodePace)
+	var err error
+	hometownGeocoder, err = tableGeocoder([]byte("Place,Latitude,Longitude,State,Country\nBoulder,40.015,-105.27,Colorado,United States\nDenver,39.74,-104.99,Colorado,United States\nCalgary,51.05,-114.07,Alberta,Canada\n"))
+	if err != nil {
+		t.Fatalf("Error reading the table - %v", err)
+	}
+	dir, err := ioutil.TempDir("", "geocodes")
+	if err != nil {
+		t.Fatalf("Error making a temp dir - %v", err)
+	}
+	defer os.RemoveAll(dir)
+	geocodes, geocodePace = &GeocodeCache{places: make(map[string]Geocode)}, 0
+	if err = geocodes.Load(filepath.Join(dir, "geocodes.json")); err != nil {
+		t.Fatalf("Expected a missing cache to be fine, got %v", err)
+	}
+	race := NewRace()
+	if err := race.SetOptionalFields([]string{"Email", "City"}); err != nil {
+		t.Fatalf("Error setting fields - %v", err)
+	}
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38, Optional: []string{"amy@host.com", "Boulder"}},
+		{Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 31, Optional: []string{"bob@host.com", "boulder"}},
+		{Bib: 3, Fname: "Cal", Lname: "Cole", Male: true, Age: 52, Optional: []string{"cal@host.com", "Calgary"}},
+		{Bib: 4, Fname: "Dee", Lname: "Dunn", Age: 44, Optional: []string{"dee@host.com", "Atlantis"}},
+		{Bib: 5, Fname: "Eve", Lname: "Earl", Age: 29, Optional: []string{"eve@host.com", ""}},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	if n, err := race.GeocodeHometowns(); err != nil || n != 3 {
+		t.Errorf("Expected Boulder, Calgary and Atlantis looked up once each, got %d - %v", n, err)
+	}
+	if n, _ := race.GeocodeHometowns(); n != 0 {
+		t.Errorf("Expected nothing left to look up, got %d", n)
+	}
+	reloaded := &GeocodeCache{places: make(map[string]Geocode)}
+	if err = reloaded.Load(filepath.Join(dir, "geocodes.json")); err != nil {
+		t.Fatalf("Error reloading the cache - %v", err)
+	}
+	if place, ok := reloaded.Lookup("BOULDER"); !ok || !place.Found {
+		t.Errorf("Expected Boulder saved in the cache, got %#v", place)
+	}
+
+	race.RLock()
+	report := race.lockedHometownReport()
+	race.RUnlock()
+	if report.Located != 3 || len(report.Points) != 2 || report.Points[0].Count != 2 {
+		t.Errorf("Expected 3 racers at 2 points, got %#v", report)
+	}
+	if len(report.Countries) != 2 || report.Countries[0] != (OriginCount{"United States", 2}) || len(report.States) != 2 {
+		t.Errorf("Expected 2 countries and 2 states, got %v %v", report.Countries, report.States)
+	}
+	if len(report.Unknown) != 1 || report.Unknown[0] != "Atlantis" {
+		t.Errorf("Expected Atlantis not located, got %v", report.Unknown)
+	}
+	if p := report.Points[1]; p.X != 5 || p.Y != 5 {
+		t.Errorf("Expected Calgary in the top left of the map, got %v", p)
+	}
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/hometowns", nil)
+	handler(w, r, race)
+	if body := w.Body.String(); w.Code != http.StatusOK || !strings.Contains(body, "<title>Calgary - 1</title>") || !strings.Contains(body, "Alberta, Canada") {
+		t.Errorf("Expected the map and counts, got %d %s", w.Code, body)
+	}
+}
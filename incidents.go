@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// incidentKinds are the kinds of incident the log is sorted into
+var incidentKinds = []string{"Medical", "Course Issue", "Lost Item", "Found Item", "Other"}
+
+// Incident is an entry in the race day incident log, kept for insurance and reporting
+type Incident struct {
+	ID          int
+	Kind        string
+	Time        time.Time
+	Location    string
+	Description string
+	Resolution  string
+	Resolved    bool
+	Edited      time.Time // last changed after being logged, zero if never edited
+}
+
+// RaceTime is when the incident happened on the race clock, -- if it was before the start
+func (i Incident) RaceTime(started time.Time) HumanDuration {
+	if started.IsZero() || i.Time.Before(started) {
+		return 0
+	}
+	return HumanDuration(i.Time.Sub(started))
+}
+
+func validIncidentKind(kind string) error {
+	for _, k := range incidentKinds {
+		if k == kind {
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown incident kind %s, must be one of %s", kind, strings.Join(incidentKinds, ", "))
+}
+
+// LogIncident records the incident at the current time
+func (race *Race) LogIncident(kind, location, description string) error {
+	if err := validIncidentKind(kind); err != nil {
+		return err
+	}
+	if strings.TrimSpace(description) == "" {
+		return fmt.Errorf("Describe the incident")
+	}
+	race.Lock()
+	defer race.Unlock()
+	race.nextIncidentID++
+	race.incidents = append(race.incidents, &Incident{
+		ID:          race.nextIncidentID,
+		Kind:        kind,
+		Time:        race.GetTime(),
+		Location:    strings.TrimSpace(location),
+		Description: strings.TrimSpace(description),
+	})
+	log.Printf("%s incident logged at %s - %s", kind, location, description)
+	return nil
+}
+
+// EditIncident updates the incident, e.g. to record how it was resolved.  The time it was logged doesn't change.
+func (race *Race) EditIncident(id int, kind, location, description, resolution string, resolved bool) error {
+	if err := validIncidentKind(kind); err != nil {
+		return err
+	}
+	race.Lock()
+	defer race.Unlock()
+	for _, incident := range race.incidents {
+		if incident.ID == id {
+			incident.Kind = kind
+			incident.Location = strings.TrimSpace(location)
+			if description = strings.TrimSpace(description); description != "" {
+				incident.Description = description
+			}
+			incident.Resolution = strings.TrimSpace(resolution)
+			incident.Resolved = resolved
+			incident.Edited = race.GetTime()
+			return nil
+		}
+	}
+	return fmt.Errorf("Incident %d not found", id)
+}
+
+// lockedWriteIncidents writes the incident log as CSV
+func (race *Race) lockedWriteIncidents(writer *csv.Writer) {
+	writer.Write([]string{"Time", "Race Time", "Kind", "Location", "Description", "Resolution", "Resolved", "Last Edited"})
+	for _, incident := range race.incidents {
+		edited := ""
+		if !incident.Edited.IsZero() {
+			edited = incident.Edited.Format("2006-01-02 15:04:05")
+		}
+		writer.Write([]string{incident.Time.Format("2006-01-02 15:04:05"), incident.RaceTime(race.started).String(), incident.Kind, incident.Location, incident.Description, incident.Resolution, strconv.FormatBool(incident.Resolved), edited})
+	}
+}
+
+func logIncidentHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	if err := race.LogIncident(r.FormValue("kind"), r.FormValue("location"), r.FormValue("description")); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/incidents", 301)
+}
+
+func editIncidentHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting incident", err)
+		return
+	}
+	err = race.EditIncident(id, r.FormValue("kind"), r.FormValue("location"), r.FormValue("description"), r.FormValue("resolution"), r.FormValue("resolved") == "true")
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/incidents", 301)
+}
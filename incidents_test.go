@@ -0,0 +1,67 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIncidentLog(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 8, 30, 0, 0, time.Local)
+	if err := race.LogIncident("Lost Item", "Registration", "Blue jacket"); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if err := race.LogIncident("Alien", "Mile 1", "Landing"); err == nil {
+		t.Errorf("Expected error for an unknown kind")
+	}
+	if err := race.LogIncident("Medical", "Mile 1", " "); err == nil {
+		t.Errorf("Expected error without a description")
+	}
+	*race.testingTime = race.testingTime.Add(30 * time.Minute)
+	startRace(race)
+	*race.testingTime = race.testingTime.Add(12 * time.Minute)
+	if err := race.LogIncident("Medical", "Mile 1", "Runner twisted ankle"); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	*race.testingTime = race.testingTime.Add(5 * time.Minute)
+	if err := race.EditIncident(2, "Medical", "Mile 1", "", "Walked back with EMT", true); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if err := race.EditIncident(3, "Medical", "", "", "", true); err == nil {
+		t.Errorf("Expected error editing an unknown incident")
+	}
+	if race.incidents[1].Description != "Runner twisted ankle" || !race.incidents[1].Resolved || race.incidents[1].RaceTime(race.started) != HumanDuration(12*time.Minute) {
+		t.Errorf("Expected the medical incident resolved at 12 minutes race time, got %#v", race.incidents[1])
+	}
+	if race.incidents[0].RaceTime(race.started) != 0 {
+		t.Errorf("Expected no race time before the start")
+	}
+
+	r, _ := http.NewRequest("GET", "/archive.zip", nil)
+	w := httptest.NewRecorder()
+	archiveHandler(w, r, race)
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("Error reading archive - %v", err)
+	}
+	files := make(map[string]string)
+	for _, f := range archive.File {
+		rc, _ := f.Open()
+		data, _ := ioutil.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+	if !strings.Contains(files["incidents.csv"], "2014-06-01 09:12:00,00:12:00.00,Medical,Mile 1,Runner twisted ankle,Walked back with EMT,true,2014-06-01 09:17:00") {
+		t.Errorf("Expected the incident log in the archive - %s", files["incidents.csv"])
+	}
+	if !strings.HasPrefix(files["results.csv"], "Fname,Lname") || !strings.HasPrefix(files["volunteers.csv"], "Name,Role") {
+		t.Errorf("Expected results and volunteers in the archive, got %v", files)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// entryIndex finds entries by name, team and division without scanning allEntries.  It's rebuilt the first
+// time it's needed after the entries change, so an upload of thousands of racers only builds it once.
+type entryIndex struct {
+	version        int
+	positions      map[*Entry]int      // where each entry is in allEntries
+	names          map[string][]*Entry // by normalized full name
+	trigrams       map[string][]*Entry // by every three letter run in the normalized full names
+	teams          map[string][]*Entry // by normalized RACERGOTEAMFIELD
+	teamNames      map[string]string   // the team as most of its racers spelled it, by normalized name
+	divisions      map[string][]*Entry
+	places         map[*Entry]Place // the overall place of every entry who's ranked
+	divisionPlaces map[*Entry]Place
+}
+
+// lockedEntriesChanged marks the index out of date, call it whenever the entries, their times or their divisions change
+func (race *Race) lockedEntriesChanged() {
+	race.indexLock.Lock()
+	race.entriesVersion++
+	race.indexLock.Unlock()
+}
+
+// lockedIndex is the index of the entries as they are now, safe to use while the race is only read locked.
+// It must not be modified.
+func (race *Race) lockedIndex() *entryIndex {
+	race.indexLock.Lock()
+	defer race.indexLock.Unlock()
+	if race.index == nil || race.index.version != race.entriesVersion {
+		race.index = race.lockedBuildIndex()
+		race.index.version = race.entriesVersion
+	}
+	return race.index
+}
+
+func trigrams(name string) []string {
+	runes := []rune(name)
+	var grams []string
+	for x := 0; x+3 <= len(runes); x++ {
+		grams = append(grams, string(runes[x:x+3]))
+	}
+	return grams
+}
+
+func (race *Race) lockedBuildIndex() *entryIndex {
+	idx := &entryIndex{
+		positions:      make(map[*Entry]int, len(race.allEntries)),
+		names:          make(map[string][]*Entry),
+		trigrams:       make(map[string][]*Entry),
+		teams:          make(map[string][]*Entry),
+		teamNames:      make(map[string]string),
+		divisions:      make(map[string][]*Entry),
+		places:         make(map[*Entry]Place),
+		divisionPlaces: make(map[*Entry]Place),
+	}
+	teamIndex := -1
+	for x, fn := range race.optionalEntryFields {
+		if fn == config.teamField {
+			teamIndex = x
+		}
+	}
+	counts := make(map[string]Place)
+	spellings := make(map[string]int)
+	for x, e := range race.allEntries {
+		idx.positions[e] = x
+		name := normalizeName(e.Fname + " " + e.Lname)
+		idx.names[name] = append(idx.names[name], e)
+		seen := make(map[string]bool)
+		for _, gram := range trigrams(name) {
+			if !seen[gram] {
+				seen[gram] = true
+				idx.trigrams[gram] = append(idx.trigrams[gram], e)
+			}
+		}
+		if teamIndex >= 0 && teamIndex < len(e.Optional) {
+			if team := normalizeName(e.Optional[teamIndex]); team != "" {
+				idx.teams[team] = append(idx.teams[team], e)
+				spelling := strings.TrimSpace(e.Optional[teamIndex])
+				if spellings[spelling]++; spellings[spelling] > spellings[idx.teamNames[team]] {
+					idx.teamNames[team] = spelling
+				}
+			}
+		}
+		division := race.lockedDivisionOf(e)
+		idx.divisions[division] = append(idx.divisions[division], e)
+		if e.Ranked() {
+			idx.places[e] = Place(len(idx.places) + 1)
+			counts[division]++
+			idx.divisionPlaces[e] = counts[division]
+		}
+	}
+	return idx
+}
+
+// nameMatches are the entries whose full name contains the normalized query, in allEntries order.  Queries of
+// three letters or more only check the entries sharing the query's rarest trigram.
+func (idx *entryIndex) nameMatches(query string, all []*Entry) []*Entry {
+	candidates := all
+	for _, gram := range trigrams(query) {
+		if list := idx.trigrams[gram]; len(list) < len(candidates) {
+			candidates = list
+		}
+	}
+	var matches []*Entry
+	for _, e := range candidates {
+		if strings.Contains(normalizeName(e.Fname+" "+e.Lname), query) {
+			matches = append(matches, e)
+		}
+	}
+	return matches
+}
+
+// Team is a team's racers in overall place order
+type Team struct {
+	Name      string
+	Rows      []ResultRow
+	Finishers int
+}
+
+// lockedTeam is everyone on the team, nil if nobody entered it
+func (race *Race) lockedTeam(name string) *Team {
+	idx := race.lockedIndex()
+	key := normalizeName(name)
+	entries := idx.teams[key]
+	if len(entries) == 0 {
+		return nil
+	}
+	team := &Team{Name: idx.teamNames[key]}
+	for _, e := range entries {
+		row := race.lockedResultRow(idx, e)
+		if row.Place > 0 {
+			team.Finishers++
+		}
+		team.Rows = append(team.Rows, row)
+	}
+	return team
+}
+
+// lockedTeams lists every team by name with how many are on it
+func (race *Race) lockedTeams() []Team {
+	idx := race.lockedIndex()
+	teams := make([]Team, 0, len(idx.teams))
+	for key, entries := range idx.teams {
+		team := Team{Name: idx.teamNames[key]}
+		for _, e := range entries {
+			if e.HasFinished() {
+				team.Finishers++
+			}
+			team.Rows = append(team.Rows, ResultRow{Entry: e})
+		}
+		teams = append(teams, team)
+	}
+	sort.Slice(teams, func(i, j int) bool {
+		return strings.ToLower(teams[i].Name) < strings.ToLower(teams[j].Name)
+	})
+	return teams
+}
+
+// lockedResultRow is an entry's row in the results
+func (race *Race) lockedResultRow(idx *entryIndex, e *Entry) ResultRow {
+	row := ResultRow{
+		Entry:         e,
+		Division:      race.lockedDivisionOf(e),
+		DivisionPlace: idx.divisionPlaces[e],
+		Estimate:      race.estimates[e.Bib],
+		Place:         idx.places[e],
+	}
+	return row
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEntryIndex(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	if err := race.SetOptionalFields([]string{"Team"}); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38, Optional: []string{"Harriers"}},
+		{Bib: 2, Fname: "Bob", Lname: "Browning", Male: true, Age: 31, Optional: []string{" harriers "}},
+		{Bib: 3, Fname: "Cal", Lname: "Cole", Male: true, Age: 33, Optional: []string{""}},
+		{Bib: 4, Fname: "Dee", Lname: "Dunn", Age: 44, Optional: []string{"Harriers"}},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	startRace(race)
+	names := func(results []LookupResult) string {
+		var found []string
+		for _, r := range results {
+			found = append(found, r.Fname)
+		}
+		return strings.Join(found, ",")
+	}
+	if got := names(race.Lookup("brown")); got != "Amy,Bob" {
+		t.Errorf("Expected Amy and Bob for brown, got %s", got)
+	}
+	if got := names(race.Lookup("wni")); got != "Bob" {
+		t.Errorf("Expected Bob for wni, got %s", got)
+	}
+	if got := names(race.Lookup("3")); got != "Cal" {
+		t.Errorf("Expected Cal by bib, got %s", got)
+	}
+
+	*race.testingTime = race.testingTime.Add(20 * time.Minute)
+	linkBibTesting(t, race, 3, false)
+	*race.testingTime = race.testingTime.Add(time.Minute)
+	linkBibTesting(t, race, 2, false)
+	// the index follows the finishes
+	if results := race.Lookup("bob"); len(results) != 1 || results[0].Place != 2 {
+		t.Errorf("Expected Bob in 2nd after finishing, got %v", results)
+	}
+	race.RLock()
+	team := race.lockedTeam("HARRIERS")
+	teams := race.lockedTeams()
+	divisions := race.lockedDivisionResults()
+	race.RUnlock()
+	if team == nil || team.Name != "Harriers" || len(team.Rows) != 3 || team.Finishers != 1 || team.Rows[0].Fname != "Bob" {
+		t.Fatalf("Expected Bob finished ahead of Amy and Dee on the Harriers, got %+v", team)
+	}
+	if len(teams) != 1 {
+		t.Errorf("Expected only the Harriers, got %v", teams)
+	}
+	if len(divisions) != 1 || len(divisions[0].Rows) != 2 || divisions[0].Rows[1].DivisionPlace != 2 || divisions[0].Rows[1].Place != 2 {
+		t.Errorf("Expected Cal and Bob 1st and 2nd in the men's 30s, got %+v", divisions)
+	}
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/team?name=harriers", nil), race)
+	if body := w.Body.String(); !strings.Contains(body, "Browning") || strings.Contains(body, "Cole") {
+		t.Errorf("Expected only the Harriers on the team page - %s", body)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScheduleItem is something happening on race day, e.g. packet pickup, the start or awards
+type ScheduleItem struct {
+	At   time.Time
+	What string
+}
+
+// Announcement is a note for racers and spectators, e.g. parking or a course change
+type Announcement struct {
+	Posted time.Time
+	Text   string
+}
+
+const scheduleTimeFormat = "15:04"
+
+// AddScheduleItem adds the item on race day at the given clock time, keeping the schedule in time order
+func (race *Race) AddScheduleItem(clock string, what string) error {
+	what = strings.TrimSpace(what)
+	if what == "" {
+		return fmt.Errorf("Schedule item needs a description")
+	}
+	at, err := time.Parse(scheduleTimeFormat, clock)
+	if err != nil {
+		return fmt.Errorf("%s is not a time, use HH:MM", clock)
+	}
+	race.Lock()
+	defer race.Unlock()
+	day := race.scheduleDay()
+	item := &ScheduleItem{At: time.Date(day.Year(), day.Month(), day.Day(), at.Hour(), at.Minute(), 0, 0, day.Location()), What: what}
+	race.schedule = append(race.schedule, item)
+	sort.SliceStable(race.schedule, func(i, j int) bool {
+		return race.schedule[i].At.Before(race.schedule[j].At)
+	})
+	log.Printf("Scheduled %s at %s", item.What, item.At.Format("3:04 PM"))
+	return nil
+}
+
+func (race *Race) RemoveScheduleItem(index int) error {
+	race.Lock()
+	defer race.Unlock()
+	if index < 0 || index >= len(race.schedule) {
+		return fmt.Errorf("Schedule item %d not found", index)
+	}
+	race.schedule = append(race.schedule[:index], race.schedule[index+1:]...)
+	return nil
+}
+
+// PostAnnouncement puts the text at the top of the info page and on the results screens
+func (race *Race) PostAnnouncement(text string) error {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return fmt.Errorf("Announcement is blank")
+	}
+	race.Lock()
+	defer race.Unlock()
+	race.announcements = append([]*Announcement{{Posted: race.GetTime(), Text: text}}, race.announcements...)
+	log.Printf("Announcement posted - %s", text)
+	return nil
+}
+
+func (race *Race) RemoveAnnouncement(index int) error {
+	race.Lock()
+	defer race.Unlock()
+	if index < 0 || index >= len(race.announcements) {
+		return fmt.Errorf("Announcement %d not found", index)
+	}
+	race.announcements = append(race.announcements[:index], race.announcements[index+1:]...)
+	return nil
+}
+
+// lockedNextScheduled is the next thing on the schedule, nil when nothing is left today
+func (race *Race) lockedNextScheduled(now time.Time) *ScheduleItem {
+	for _, item := range race.schedule {
+		if !item.At.Before(now) {
+			return item
+		}
+	}
+	return nil
+}
+
+func (race *Race) lockedLatestAnnouncement() *Announcement {
+	if len(race.announcements) == 0 {
+		return nil
+	}
+	return race.announcements[0]
+}
+
+func addScheduleHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	if err := race.AddScheduleItem(r.FormValue("at"), r.FormValue("what")); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/editInfo", 301)
+}
+
+func postAnnouncementHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	if err := race.PostAnnouncement(r.FormValue("text")); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/editInfo", 301)
+}
+
+func removeInfoHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	index, err := strconv.Atoi(r.FormValue("index"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting item", err)
+		return
+	}
+	if r.FormValue("type") == "schedule" {
+		err = race.RemoveScheduleItem(index)
+	} else {
+		err = race.RemoveAnnouncement(index)
+	}
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/editInfo", 301)
+}
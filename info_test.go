@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScheduleAndAnnouncements(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, item := range [][2]string{{"11:00", "Awards"}, {"08:00", "Packet pickup"}, {"09:30", "Start"}} {
+		if err := race.AddScheduleItem(item[0], item[1]); err != nil {
+			t.Fatalf("Unexpected error - %v", err)
+		}
+	}
+	if err := race.AddScheduleItem("noon", "Lunch"); err == nil {
+		t.Errorf("Expected error for a bad time")
+	}
+	if err := race.PostAnnouncement("  "); err == nil {
+		t.Errorf("Expected error for a blank announcement")
+	}
+	if err := race.PostAnnouncement("Park in the school lot"); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if race.schedule[0].What != "Packet pickup" || race.schedule[2].What != "Awards" {
+		t.Errorf("Expected the schedule in time order, got %v %v %v", race.schedule[0], race.schedule[1], race.schedule[2])
+	}
+	if next := race.lockedNextScheduled(race.GetTime()); next == nil || next.What != "Start" {
+		t.Errorf("Expected the start to be next, got %v", next)
+	}
+	if next := race.lockedNextScheduled(race.GetTime().Add(3 * time.Hour)); next != nil {
+		t.Errorf("Expected nothing left on the schedule, got %v", next)
+	}
+
+	r, _ := http.NewRequest("GET", "/results", nil)
+	w := httptest.NewRecorder()
+	handler(w, r, race)
+	if body := w.Body.String(); !strings.Contains(body, "Next: Start at 9:30 AM") || !strings.Contains(body, "Park in the school lot") {
+		t.Errorf("Expected the next event and announcement in the results footer - %s", body)
+	}
+
+	if err := race.RemoveScheduleItem(1); err != nil {
+		t.Errorf("Unexpected error - %v", err)
+	}
+	if err := race.RemoveAnnouncement(0); err != nil {
+		t.Errorf("Unexpected error - %v", err)
+	}
+	if err := race.RemoveAnnouncement(0); err == nil {
+		t.Errorf("Expected error removing a missing announcement")
+	}
+	if next := race.lockedNextScheduled(race.GetTime()); next == nil || next.What != "Awards" {
+		t.Errorf("Expected awards to be next once the start was removed, got %v", next)
+	}
+}
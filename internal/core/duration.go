@@ -0,0 +1,98 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type HumanDuration time.Duration
+
+func (hd HumanDuration) String() string {
+	if hd == 0 {
+		return "--"
+	}
+	seconds := time.Duration(hd).Seconds()
+	seconds -= float64(time.Duration(hd) / time.Minute * 60)
+	return fmt.Sprintf("%#02d:%#02d:%05.2f", time.Duration(hd)/time.Hour, time.Duration(hd)/time.Minute%60, seconds)
+}
+
+func (hd HumanDuration) Clock() string {
+	if hd == 0 {
+		return "--"
+	}
+	return fmt.Sprintf("%#02d:%#02d:%02d", time.Duration(hd)/time.Hour, time.Duration(hd)/time.Minute%60, time.Duration(hd)/time.Second%60)
+}
+
+// Pace is the time taken per mi or km to cover the given distance in meters
+func (hd HumanDuration) Pace(meters float64, unit string) string {
+	if hd <= 0 || meters <= 0 {
+		return "--"
+	}
+	pace := time.Duration(float64(hd) / (meters / DistanceUnits[unit])).Round(time.Second)
+	return fmt.Sprintf("%d:%02d/%s", pace/time.Minute, pace/time.Second%60, unit)
+}
+
+// DistanceUnits are the lengths in meters of the units a race distance may be given in
+var DistanceUnits = map[string]float64{
+	"m":     1,
+	"k":     1000,
+	"km":    1000,
+	"mi":    1609.344,
+	"mile":  1609.344,
+	"miles": 1609.344,
+}
+
+// ParseDistance parses a race distance like 5k, 10 km, 13.1mi or 400m into meters
+func ParseDistance(val string) (float64, error) {
+	val = strings.ToLower(strings.TrimSpace(val))
+	split := strings.IndexFunc(val, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if split <= 0 {
+		return 0, fmt.Errorf("%s is not a valid distance, must be a number followed by m, k, km or mi", val)
+	}
+	unit, ok := DistanceUnits[strings.TrimSpace(val[split:])]
+	if !ok {
+		return 0, fmt.Errorf("%s is not a valid distance unit, must be m, k, km or mi", val[split:])
+	}
+	amount, err := strconv.ParseFloat(val[:split], 64)
+	if err != nil {
+		return 0, fmt.Errorf("Error parsing distance - %s - %v", val, err)
+	}
+	return amount * unit, nil
+}
+
+func ParseHumanDuration(val string) (HumanDuration, error) {
+	var duration HumanDuration
+	if val == "--" || val == "" { // zero value case
+		return duration, nil
+	}
+	str := strings.Split(val, ":")
+	if len(str) < 3 {
+		return duration, fmt.Errorf("%s is not a valid race duration, must have two semicolons", val)
+	}
+	secs := strings.Split(str[2], ".")
+	if len(secs) < 2 {
+		return duration, fmt.Errorf("%s does not contain a valid seconds time, must have a decimal place", val)
+	}
+	hours, err := strconv.Atoi(str[0])
+	if err != nil {
+		return duration, fmt.Errorf("Error parsing hours - %s - %v", str[0], err)
+	}
+	minutes, err := strconv.Atoi(str[1])
+	if err != nil {
+		return duration, fmt.Errorf("Error parsing minutes - %s - %v", str[1], err)
+	}
+	seconds, err := strconv.Atoi(secs[0])
+	if err != nil {
+		return duration, fmt.Errorf("Error parsing seconds - %s - %v", secs[0], err)
+	}
+	hundredths, err := strconv.Atoi(secs[1])
+	if err != nil {
+		return duration, fmt.Errorf("Error parsing hundredths - %s - %v", secs[1], err)
+	}
+	duration = HumanDuration((time.Hour * time.Duration(hours)) + (time.Minute * time.Duration(minutes)) + (time.Second * time.Duration(seconds)) + (time.Millisecond * 10 * time.Duration(hundredths)))
+	return duration, nil
+}
@@ -0,0 +1,183 @@
+// Package core is the race data model, the entries and their results, and reading and writing them as CSV.
+// It knows nothing about HTTP or the running race so it can be tested on its own.  The Race itself, with the
+// timing, prizes and audit log, is still in package main.
+package core
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const NoBib Bib = -1
+
+type Bib int32
+
+func (b Bib) String() string {
+	if b < 0 {
+		return "--"
+	}
+	return strconv.Itoa(int(b))
+}
+
+type Place uint16
+
+func (p Place) String() string {
+	if p == 0 {
+		return "--"
+	}
+	return strconv.Itoa(int(p))
+}
+
+// Ordinal is the place as it's said out loud, e.g. 1st, 2nd, 3rd, 11th
+func (p Place) Ordinal() string {
+	if p == 0 {
+		return "--"
+	}
+	suffix := "th"
+	switch {
+	case p%100 >= 11 && p%100 <= 13:
+	case p%10 == 1:
+		suffix = "st"
+	case p%10 == 2:
+		suffix = "nd"
+	case p%10 == 3:
+		suffix = "rd"
+	}
+	return p.String() + suffix
+}
+
+type Prize struct {
+	Title       string
+	LowAge      uint
+	HighAge     uint
+	Gender      string   // M = only males, F = only Females, O = Overall
+	Amount      uint     // how many people win this prize?
+	WinAgain    bool     // if someone has already won another Prize, can they win this again?
+	Fundraising bool     // awarded to the biggest fundraisers instead of the fastest finishers
+	Event       string   // only racers in this event can win, blank for every event
+	Wave        string   // only racers in this wave can win, blank for every wave
+	Exclude     []string // racers with any of these eligibility flags can't win, like elite or out-of-region
+	Winners     []*Entry `json:"-"`
+}
+
+type Entry struct {
+	Bib           Bib
+	Fname         string
+	Lname         string
+	Male          bool
+	Age           uint
+	Optional      []string
+	Duration      HumanDuration
+	TimeFinished  time.Time
+	Confirmed     bool
+	Registration  RegistrationStatus
+	Raised        float64         // dollars raised for the race's charity
+	StartCrossing time.Time       // when the racer crossed the start mat, zero if they weren't seen crossing it
+	Adjustments   []Adjustment    // penalties and bonuses to their time
+	Points        int             // the points for the checkpoints they reached, when RACERGOCHECKPOINTS are worth points
+	Splits        []HumanDuration // when they finished each lap before the last, from the start
+	Status        ResultStatus    // DNS, DNF or DQ when they have no place
+}
+
+// used in html templates
+func (e Entry) Place(p int) int {
+	return p + 1
+}
+
+func (e Entry) Nonce() string {
+	s := md5.Sum([]byte(fmt.Sprintf("%d%d%t%d%s%s%t%s", e.Age, e.Bib, e.Confirmed, e.Duration, e.Fname, e.Lname, e.Male, e.Optional)))
+	return base64.StdEncoding.EncodeToString(s[:])
+}
+
+func (e Entry) HasFinished() bool {
+	return e.Duration > 0
+}
+
+func (e Entry) TimeFinishedString() string {
+	if e.HasFinished() {
+		return e.TimeFinished.Format(time.ANSIC)
+	}
+	return "--"
+}
+
+// Ranked is true when the entry has a place, they finished and weren't disqualified
+func (e Entry) Ranked() bool {
+	return e.HasFinished() && e.Status != StatusDQ
+}
+
+// TotalAdjustment is the entry's penalties less their bonuses
+func (e Entry) TotalAdjustment() HumanDuration {
+	var total HumanDuration
+	for _, a := range e.Adjustments {
+		total += a.Amount
+	}
+	return total
+}
+
+// AdjustedDuration is the gun time with the penalties and bonuses, finishers are placed by it
+func (e Entry) AdjustedDuration() HumanDuration {
+	if !e.HasFinished() {
+		return e.Duration
+	}
+	return e.Duration + e.TotalAdjustment()
+}
+
+// NetDuration is the racer's time from when they crossed the start mat, their gun time if they weren't seen crossing it,
+// with their penalties and bonuses
+func (e Entry) NetDuration() HumanDuration {
+	if !e.HasNetTime() {
+		return e.AdjustedDuration()
+	}
+	return HumanDuration(e.TimeFinished.Sub(e.StartCrossing)) + e.TotalAdjustment()
+}
+
+// HasNetTime is true when the racer finished after being seen crossing the start mat
+func (e Entry) HasNetTime() bool {
+	return e.HasFinished() && !e.StartCrossing.IsZero() && e.StartCrossing.Before(e.TimeFinished)
+}
+
+// LapTimes is how long each lap took, the last lap only once the racer has finished
+func (e Entry) LapTimes() []HumanDuration {
+	if len(e.Splits) == 0 {
+		return nil
+	}
+	laps := make([]HumanDuration, 0, len(e.Splits)+1)
+	var last HumanDuration
+	for _, split := range e.Splits {
+		laps = append(laps, split-last)
+		last = split
+	}
+	if e.HasFinished() {
+		laps = append(laps, e.Duration-last)
+	}
+	return laps
+}
+
+// Adjustment is a penalty added to or a bonus taken off a racer's time, e.g. for a missed obstacle
+type Adjustment struct {
+	Amount HumanDuration // positive for a penalty, negative for a bonus
+	Reason string
+}
+
+func (a Adjustment) String() string {
+	if a.Amount < 0 {
+		return fmt.Sprintf("-%s %s", -a.Amount, a.Reason)
+	}
+	return fmt.Sprintf("+%s %s", a.Amount, a.Reason)
+}
+
+type Audit struct {
+	Duration HumanDuration
+	Bib      Bib
+	Remove   bool
+}
+
+func Gender(male bool) string {
+	if male {
+		return "M"
+	}
+	return "F"
+}
@@ -0,0 +1,40 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlaceOrdinal(t *testing.T) {
+	for place, want := range map[Place]string{0: "--", 1: "1st", 2: "2nd", 3: "3rd", 4: "4th", 11: "11th", 12: "12th", 13: "13th", 21: "21st", 102: "102nd", 111: "111th"} {
+		if got := place.Ordinal(); got != want {
+			t.Errorf("Expected %s for %d, got %s", want, place, got)
+		}
+	}
+}
+
+func TestEntryTimes(t *testing.T) {
+	finished := time.Date(2014, 6, 1, 9, 20, 0, 0, time.UTC)
+	e := Entry{Duration: HumanDuration(20 * time.Minute), TimeFinished: finished, Adjustments: []Adjustment{{Amount: HumanDuration(time.Minute), Reason: "missed obstacle"}}}
+	if got := e.AdjustedDuration(); got != HumanDuration(21*time.Minute) {
+		t.Errorf("Expected the penalty added, got %s", got)
+	}
+	if e.HasNetTime() || e.NetDuration() != e.AdjustedDuration() {
+		t.Errorf("Expected the gun time without a start crossing, got %s", e.NetDuration())
+	}
+	e.StartCrossing = finished.Add(-18 * time.Minute)
+	if got := e.NetDuration(); got != HumanDuration(19*time.Minute) {
+		t.Errorf("Expected the chip time with the penalty, got %s", got)
+	}
+	if !e.Ranked() {
+		t.Errorf("Expected a finisher ranked")
+	}
+	e.Status = StatusDQ
+	if e.Ranked() {
+		t.Errorf("Expected a disqualified finisher not ranked")
+	}
+	e.Splits = []HumanDuration{HumanDuration(9 * time.Minute)}
+	if laps := e.LapTimes(); len(laps) != 2 || laps[0] != HumanDuration(9*time.Minute) || laps[1] != HumanDuration(11*time.Minute) {
+		t.Errorf("Expected laps of 9 and 11 minutes, got %v", laps)
+	}
+}
@@ -0,0 +1,33 @@
+package core
+
+import (
+	"strconv"
+	"time"
+)
+
+// Headers are the columns every results CSV starts with, a CSV written with them can be imported again
+var Headers = []string{"Fname", "Lname", "Age", "Gender", "Bib", "Overall Place", "Duration", "Time Finished", "Confirmed"}
+
+// StartedRow is the row after the Headers recording when the race started, in the Time Finished column
+func StartedRow(columns int, started time.Time) []string {
+	row := make([]string, columns)
+	row[7] = started.Format(time.ANSIC)
+	return row
+}
+
+// ResultRow is the entry's row under the Headers, the place left blank when they aren't ranked.  With netTimes
+// the gun and chip times follow, then with statuses their DNS, DNF or DQ.
+func ResultRow(entry *Entry, place Place, netTimes, statuses bool) []string {
+	overallPlace := ""
+	if place > 0 {
+		overallPlace = strconv.Itoa(int(place))
+	}
+	row := []string{entry.Fname, entry.Lname, strconv.Itoa(int(entry.Age)), Gender(entry.Male), entry.Bib.String(), overallPlace, entry.Duration.String(), entry.TimeFinishedString(), strconv.FormatBool(entry.Confirmed)}
+	if netTimes {
+		row = append(row, entry.Duration.String(), entry.NetDuration().String())
+	}
+	if statuses {
+		row = append(row, entry.Status.String())
+	}
+	return row
+}
@@ -0,0 +1,26 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestResultRow(t *testing.T) {
+	finished := time.Date(2014, 6, 1, 9, 20, 0, 0, time.UTC)
+	amy := &Entry{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 34, Duration: HumanDuration(20 * time.Minute), TimeFinished: finished, Confirmed: true, StartCrossing: finished.Add(-19 * time.Minute)}
+	want := []string{"Amy", "Brown", "34", "F", "1", "1", "00:20:00.00", finished.Format(time.ANSIC), "true", "00:20:00.00", "00:19:00.00"}
+	if got := ResultRow(amy, 1, true, false); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	amy.Status = StatusDQ
+	want = []string{"Amy", "Brown", "34", "F", "1", "", "00:20:00.00", finished.Format(time.ANSIC), "true", "DQ"}
+	if got := ResultRow(amy, 0, false, true); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected a disqualified racer without a place, %q, got %q", want, got)
+	}
+
+	if row := StartedRow(len(Headers), finished); len(row) != len(Headers) || row[7] != finished.Format(time.ANSIC) {
+		t.Errorf("Expected the start in the Time Finished column, got %q", row)
+	}
+}
@@ -0,0 +1,106 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseAmount reads a dollar amount as fundraising platforms tend to export them, e.g. "$1,250.00"
+func ParseAmount(val string) (float64, error) {
+	val = strings.NewReplacer("$", "", ",", "", " ", "").Replace(val)
+	amount, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return 0, err
+	}
+	if amount < 0 {
+		return 0, fmt.Errorf("%s is not a valid amount, must be >= 0", val)
+	}
+	return amount, nil
+}
+
+// ParseEntries reads the racers from the rows of a CSV file, the first row being the header.  Every required
+// field must be a column.  Columns that aren't Headers or reserved are the optional fields, returned in the
+// order they're kept in each entry's Optional.  Places and finish times are worked out again from the
+// durations so they're ignored.
+func ParseEntries(rows [][]string, required []string, reserved []string) ([]Entry, []string, error) {
+	if len(rows) <= 1 {
+		return nil, nil, fmt.Errorf("Either blank file or only supplied the header row")
+	}
+	optionalFields := make([]string, 0)
+	mandatoryFields := map[string]struct{}{}
+	for _, field := range required {
+		mandatoryFields[field] = struct{}{}
+	}
+	reservedFields := map[string]struct{}{}
+	for _, column := range append(Headers, reserved...) {
+		reservedFields[column] = struct{}{}
+	}
+	for col := range rows[0] {
+		if _, ok := mandatoryFields[rows[0][col]]; ok {
+			delete(mandatoryFields, rows[0][col])
+			continue
+		}
+		if _, ok := reservedFields[rows[0][col]]; !ok {
+			// optional field since it's not in the reserved list
+			optionalFields = append(optionalFields, rows[0][col])
+		}
+	}
+	if len(mandatoryFields) > 0 {
+		return nil, nil, fmt.Errorf("CSV file missing the following fields - %s", mandatoryFields)
+	}
+	bibs := make(map[Bib]struct{})
+	entries := make([]Entry, 0, len(rows)-1)
+	for row := 1; row < len(rows); row++ {
+		entry := Entry{Bib: NoBib}
+		entry.Optional = make([]string, 0)
+		for col := range rows[row] {
+			switch rows[0][col] {
+			case "Fname":
+				entry.Fname = rows[row][col]
+			case "Lname":
+				entry.Lname = rows[row][col]
+			case "Age":
+				tmpAge, _ := strconv.Atoi(rows[row][col])
+				entry.Age = uint(tmpAge)
+			case "Gender":
+				entry.Male = (rows[row][col] == "M")
+			case "Bib":
+				tmpBib, err := strconv.Atoi(rows[row][col])
+				if err != nil {
+					entry.Bib = NoBib
+				} else {
+					entry.Bib = Bib(tmpBib)
+				}
+			case "Overall Place":
+				// ignore since this will be calculated on sort
+			case "Duration":
+				var err error
+				entry.Duration, err = ParseHumanDuration(rows[row][col])
+				if err != nil {
+					return nil, nil, fmt.Errorf("Error parsing duration %s - %v.  Import failed.", rows[row][col], err)
+				}
+			case "Time Finished":
+			// ignore since Time Finished is based on Duration and race start time
+			case "Confirmed":
+				entry.Confirmed = rows[row][col] == "true"
+			case "Registration":
+				entry.Registration, _ = ParseRegistrationStatus(rows[row][col])
+			case "Raised":
+				entry.Raised, _ = ParseAmount(rows[row][col])
+			default:
+				if _, ok := reservedFields[rows[0][col]]; !ok {
+					entry.Optional = append(entry.Optional, rows[row][col])
+				}
+			}
+		}
+		if _, ok := bibs[entry.Bib]; ok {
+			return nil, nil, fmt.Errorf("Duplicate bib #%d detected in uploaded CSV file.  Import failed.", entry.Bib)
+		}
+		if entry.Bib >= 0 {
+			bibs[entry.Bib] = struct{}{}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, optionalFields, nil
+}
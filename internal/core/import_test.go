@@ -0,0 +1,53 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseEntries(t *testing.T) {
+	rows := [][]string{
+		{"Fname", "Lname", "Age", "Gender", "Bib", "Overall Place", "Duration", "TShirt", "Pace"},
+		{"Amy", "Brown", "34", "F", "1", "2", "00:21:00.50", "M", "6:45/mi"},
+		{"Bob", "Brown", "41", "M", "", "", "", "L", ""},
+	}
+	entries, optional, err := ParseEntries(rows, []string{"Fname", "Lname"}, []string{"Pace"})
+	if err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if len(optional) != 1 || optional[0] != "TShirt" {
+		t.Errorf("Expected TShirt as the only optional field, got %v", optional)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	amy := entries[0]
+	if amy.Fname != "Amy" || amy.Age != 34 || amy.Male || amy.Bib != 1 || amy.Duration != HumanDuration(21*time.Minute+500*time.Millisecond) || len(amy.Optional) != 1 || amy.Optional[0] != "M" {
+		t.Errorf("Amy not read correctly - %#v", amy)
+	}
+	if bob := entries[1]; !bob.Male || bob.Bib != NoBib || bob.HasFinished() {
+		t.Errorf("Expected Bob without a bib or a time - %#v", bob)
+	}
+
+	if _, _, err = ParseEntries(rows, []string{"Email"}, nil); err == nil {
+		t.Errorf("Expected an error when a required field is missing")
+	}
+	if _, _, err = ParseEntries(rows[:1], nil, nil); err == nil {
+		t.Errorf("Expected an error with only the header row")
+	}
+	if _, _, err = ParseEntries(append(rows, []string{"Cat", "Dog", "30", "F", "1"}), nil, nil); err == nil {
+		t.Errorf("Expected an error for a duplicate bib")
+	}
+	if _, _, err = ParseEntries([][]string{{"Bib", "Duration"}, {"3", "fast"}}, nil, nil); err == nil {
+		t.Errorf("Expected an error for a bad duration")
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	if amount, err := ParseAmount("$1,250.00"); err != nil || amount != 1250 {
+		t.Errorf("Expected 1250, got %v - %v", amount, err)
+	}
+	if _, err := ParseAmount("-5"); err == nil {
+		t.Errorf("Expected an error for a negative amount")
+	}
+}
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ResultStatus is why a racer has no place in the results
+type ResultStatus uint8
+
+const (
+	StatusOK  ResultStatus = iota // placed on their time, or still out on the course
+	StatusDNS                     // did not start
+	StatusDNF                     // started but did not finish
+	StatusDQ                      // disqualified, their time stands but they aren't placed or given prizes
+)
+
+func (rs ResultStatus) String() string {
+	switch rs {
+	case StatusDNS:
+		return "DNS"
+	case StatusDNF:
+		return "DNF"
+	case StatusDQ:
+		return "DQ"
+	}
+	return ""
+}
+
+// Title spells out the status for spectators
+func (rs ResultStatus) Title() string {
+	switch rs {
+	case StatusDNS:
+		return "Did Not Start"
+	case StatusDNF:
+		return "Did Not Finish"
+	case StatusDQ:
+		return "Disqualified"
+	}
+	return ""
+}
+
+func ParseResultStatus(s string) (ResultStatus, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "", "OK":
+		return StatusOK, nil
+	case "DNS":
+		return StatusDNS, nil
+	case "DNF":
+		return StatusDNF, nil
+	case "DQ", "DSQ":
+		return StatusDQ, nil
+	}
+	return StatusOK, fmt.Errorf("%s isn't a result status, use DNS, DNF, DQ or OK", s)
+}
+
+// RegistrationStatus tracks an entry through a lottery for oversubscribed races
+type RegistrationStatus uint8
+
+const (
+	Registered  RegistrationStatus = iota // signed up, no lottery has been run for them
+	Selected                              // won a spot in the lottery
+	NotSelected                           // lost the lottery
+	Waitlisted                            // signed up after the race was full, waiting for someone to withdraw
+	Withdrawn                             // gave up their spot
+)
+
+func (rs RegistrationStatus) String() string {
+	switch rs {
+	case Selected:
+		return "Selected"
+	case NotSelected:
+		return "Not Selected"
+	case Waitlisted:
+		return "Waitlisted"
+	case Withdrawn:
+		return "Withdrawn"
+	}
+	return "Registered"
+}
+
+// HasSpot is true when the entry is allowed to run the race
+func (rs RegistrationStatus) HasSpot() bool {
+	return rs == Registered || rs == Selected
+}
+
+func ParseRegistrationStatus(val string) (RegistrationStatus, error) {
+	for _, status := range []RegistrationStatus{Registered, Selected, NotSelected, Waitlisted, Withdrawn} {
+		if status.String() == val {
+			return status, nil
+		}
+	}
+	return Registered, fmt.Errorf("Unknown registration status %s", val)
+}
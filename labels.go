@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/mzimmerman/racergo/internal/core"
+)
+
+// Label is what's printed for a racer at packet pickup
+type Label struct {
+	Race     string `json:"race"`
+	Bib      Bib    `json:"bib"`
+	Fname    string `json:"fname"`
+	Lname    string `json:"lname"`
+	Gender   string `json:"gender"`
+	Age      uint   `json:"age"`
+	Division string `json:"division"`
+}
+
+// labels is the printer from RACERGOLABELPRINTER, nil when there isn't one
+var labels printer
+
+// escposLabel centers the race name, a big bib number and the racer's name and division, then cuts the paper
+func escposLabel(l Label) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("\x1b@")     // reset
+	buf.WriteString("\x1ba\x01") // center
+	fmt.Fprintf(&buf, "%s\n", l.Race)
+	buf.WriteString("\x1d!\x33") // quadruple size
+	if l.Bib >= 0 {
+		fmt.Fprintf(&buf, "%d\n", l.Bib)
+	} else {
+		buf.WriteString("NO BIB\n")
+	}
+	buf.WriteString("\x1d!\x11") // double size
+	fmt.Fprintf(&buf, "%s %s\n", l.Fname, l.Lname)
+	buf.WriteString("\x1d!\x00")
+	fmt.Fprintf(&buf, "%s %d  %s\n", l.Gender, l.Age, l.Division)
+	buf.WriteString("\x1dV\x42\x03") // feed a little and cut
+	return buf.Bytes()
+}
+
+// lockedLabel is the label for the entry
+func (race *Race) lockedLabel(e *Entry) Label {
+	return Label{Race: race.lockedName(), Bib: e.Bib, Fname: e.Fname, Lname: e.Lname, Gender: core.Gender(e.Male), Age: e.Age, Division: race.lockedDivisionOf(e)}
+}
+
+// printLabel prints the bib's label in the background when there's a label printer, the line at
+// packet pickup shouldn't wait on the printer
+func (race *Race) printLabel(bib Bib) {
+	if labels == nil || bib < 0 {
+		return
+	}
+	race.RLock()
+	entry, ok := race.bibbedEntries[bib]
+	var label Label
+	if ok {
+		label = race.lockedLabel(entry)
+	}
+	race.RUnlock()
+	if !ok {
+		return
+	}
+	go func() {
+		if err := labels(escposLabel(label), label); err != nil {
+			log.Printf("Error printing the label for bib #%d on %s - %v", bib, config.labelPrinter, err)
+		}
+	}()
+}
+
+// printLabelHandler prints a racer's label again, e.g. after the printer ran out of labels
+func printLabelHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	bib, err := strconv.Atoi(r.FormValue("bib"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting bib number", err)
+		return
+	}
+	if labels == nil {
+		showErrorForAdmin(w, r.Referer(), "There's no label printer, set RACERGOLABELPRINTER")
+		return
+	}
+	race.RLock()
+	entry, ok := race.bibbedEntries[Bib(bib)]
+	var label Label
+	if ok {
+		label = race.lockedLabel(entry)
+	}
+	race.RUnlock()
+	if !ok {
+		showErrorForAdmin(w, r.Referer(), "No racer with bib #%d", bib)
+		return
+	}
+	if err = labels(escposLabel(label), label); err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error printing the label for bib #%d - %v", bib, err)
+		return
+	}
+	http.Redirect(w, r, "/dayof", 301)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParsePrinter(t *testing.T) {
+	for _, spec := range []string{"escpos:192.168.1.50:9100", "http://localhost:8631/label"} {
+		if printer, err := parsePrinter(spec); err != nil || printer == nil {
+			t.Errorf("Expected %s to be a printer, got %v", spec, err)
+		}
+	}
+	for _, spec := range []string{"escpos:192.168.1.50", "dymo", "lpt1:"} {
+		if _, err := parsePrinter(spec); err == nil {
+			t.Errorf("Expected %s refused", spec)
+		}
+	}
+}
+
+func TestLabelPrinters(t *testing.T) {
+	label := Label{Race: "Turkey Trot", Bib: 42, Fname: "Amy", Lname: "Brown", Gender: "F", Age: 38, Division: "F 30-39"}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening - %v", err)
+	}
+	defer ln.Close()
+	printed := make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		data, _ := ioutil.ReadAll(conn)
+		conn.Close()
+		printed <- data
+	}()
+	if err = escposPrinter(ln.Addr().String())(escposLabel(label), label); err != nil {
+		t.Fatalf("Error printing - %v", err)
+	}
+	if data := <-printed; !bytes.HasPrefix(data, []byte("\x1b@")) || !bytes.Contains(data, []byte("42\n")) || !bytes.Contains(data, []byte("Amy Brown\n")) || !bytes.HasSuffix(data, []byte("\x1dV\x42\x03")) {
+		t.Errorf("Expected a reset, the bib, the name and a cut, got %q", data)
+	}
+
+	var got Label
+	bridge := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer bridge.Close()
+	if err = bridgePrinter(bridge.URL)(escposLabel(label), label); err != nil || got != label {
+		t.Errorf("Expected the label sent to the bridge, got %#v %v", got, err)
+	}
+	bridge.Config.Handler = http.NotFoundHandler()
+	if err = bridgePrinter(bridge.URL)(escposLabel(label), label); err == nil {
+		t.Errorf("Expected an error when the bridge fails")
+	}
+}
+
+func TestPrintLabelOnRegistration(t *testing.T) {
+	defer func(p printer) { labels = p }(labels)
+	printed := make(chan Label, 1)
+	labels = func(escpos []byte, doc interface{}) error {
+		printed <- doc.(Label)
+		return nil
+	}
+	race := NewRace()
+	r := httptest.NewRequest("POST", "/addEntry?Bib=7&Age=31&Fname=Bob&Lname=Adams&Male=M", nil)
+	r.Header.Set("Referer", "http://racergo/dayof")
+	addEntryHandler(httptest.NewRecorder(), r, race)
+	select {
+	case l := <-printed:
+		if l.Bib != 7 || l.Fname != "Bob" || l.Gender != "M" {
+			t.Errorf("Expected Bob's label, got %#v", l)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected a label printed for the on site registration")
+	}
+
+	w := httptest.NewRecorder()
+	printLabelHandler(w, httptest.NewRequest("POST", "/printLabel?bib=7", nil), race)
+	if w.Code != http.StatusMovedPermanently || (<-printed).Bib != 7 {
+		t.Errorf("Expected the label reprinted, got %d %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	printLabelHandler(w, httptest.NewRequest("POST", "/printLabel?bib=8", nil), race)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected no label for a bib nobody has, got %d", w.Code)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// lockedRecordLap records the crossing as a lap split while the racer has laps to go, returning false once the
+// crossing is their finish.  Crossings closer together than RACERGOMINLAP are the same crossing read again
+// and are ignored.
+func (race *Race) lockedRecordLap(entry *Entry, now time.Time, unassigned int) bool {
+	if config.laps <= 1 || entry.HasFinished() {
+		return false
+	}
+	split := HumanDuration(now.Sub(race.lockedStartOf(entry)))
+	if n := len(entry.Splits); n > 0 && split-entry.Splits[n-1] < HumanDuration(config.minLap) {
+		return true
+	}
+	if len(entry.Splits) >= config.laps-1 {
+		return false
+	}
+	entry.Splits = append(entry.Splits, split)
+	log.Printf("Bib #%d finished lap %d of %d at %s", entry.Bib, len(entry.Splits), config.laps, split)
+	race.lockedEntriesChanged()
+	race.lockedRecordEvent(Event{Kind: "link", Bib: entry.Bib, Time: now, Unassigned: unassigned})
+	return true
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLaps(t *testing.T) {
+	defer func(laps int, minLap time.Duration) {
+		config.laps, config.minLap = laps, minLap
+	}(config.laps, config.minLap)
+	config.laps, config.minLap = 3, time.Minute
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	race.AddEntry(Entry{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38})
+	startRace(race)
+	gun := race.started
+	for _, read := range []time.Duration{10 * time.Minute, 10*time.Minute + time.Second, 21 * time.Minute, 33 * time.Minute, 33*time.Minute + 2*time.Second} {
+		if err := race.RecordTagRead(TagRead{Bib: 1, Time: gun.Add(read)}); err != nil {
+			t.Fatalf("Error recording a read at %s - %v", read, err)
+		}
+	}
+	amy := race.bibbedEntries[1]
+	if len(amy.Splits) != 2 || amy.Splits[1] != HumanDuration(21*time.Minute) || amy.Duration != HumanDuration(33*time.Minute) {
+		t.Fatalf("Expected two splits then a finish on the third lap, got %v and %s", amy.Splits, amy.Duration)
+	}
+	laps := amy.LapTimes()
+	if len(laps) != 3 || laps[0] != HumanDuration(10*time.Minute) || laps[1] != HumanDuration(11*time.Minute) || laps[2] != HumanDuration(12*time.Minute) {
+		t.Errorf("Wrong lap times - %v", laps)
+	}
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	handler(w, r, race)
+	if page := w.Body.String(); !strings.Contains(page, "laps 00:10:00.00 00:11:00.00 00:12:00.00") {
+		t.Errorf("Expected the lap times on the results, got %s", page)
+	}
+
+	replayed := NewRace()
+	if err := replayed.Replay(race.events); err != nil {
+		t.Fatalf("Error replaying - %v", err)
+	}
+	if got := replayed.bibbedEntries[1]; len(got.Splits) != 2 || got.Duration != amy.Duration {
+		t.Errorf("Expected the laps replayed, got %v and %s", got.Splits, got.Duration)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// liveClockInterval is how often the race clock is pushed to keep the screens' ticking clocks in step, it also keeps proxies from closing the stream
+const liveClockInterval = 15 * time.Second
+
+// LiveResult is a recent finisher as it's pushed to the results screens
+type LiveResult struct {
+	Place         string
+	DivisionPlace string
+	Duration      string
+	Bib           string
+	Fname         string
+	Lname         string
+}
+
+// LiveClock is the race clock as it's pushed to the results screens
+type LiveClock struct {
+	Started   bool    `json:"started"`
+	Seconds   float64 `json:"seconds"`
+	Finalized bool    `json:"finalized"`
+}
+
+// Watch is told about every change to the racers or their times until stop is called, a change is dropped if the
+// last one hasn't been picked up yet since the watcher will read the latest anyway
+func (race *Race) Watch() (changed <-chan struct{}, stop func()) {
+	ch := make(chan struct{}, 1)
+	race.Lock()
+	if race.watchers == nil {
+		race.watchers = make(map[chan struct{}]bool)
+	}
+	race.watchers[ch] = true
+	race.Unlock()
+	return ch, func() {
+		race.Lock()
+		delete(race.watchers, ch)
+		race.Unlock()
+	}
+}
+
+func (race *Race) lockedNotifyWatchers() {
+	for ch := range race.watchers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (race *Race) lockedLiveResults() []LiveResult {
+	recent := race.lockedRecentRacers(10)
+	results := make([]LiveResult, len(recent))
+	for x, r := range recent {
+		division := r.DivisionPlace.String()
+		if r.DivisionPlace != 0 {
+			division = r.DivisionPlace.Ordinal() + " " + r.Division
+		}
+		results[x] = LiveResult{r.Place.String(), division, r.NetDuration().String(), r.Bib.String(), r.Fname, r.Lname}
+	}
+	return results
+}
+
+func (race *Race) lockedLiveClock() LiveClock {
+	clock := LiveClock{Started: !race.started.IsZero(), Finalized: race.finalized}
+	if clock.Started {
+		clock.Seconds = float64(int(race.GetTime().Sub(race.started).Seconds()))
+	}
+	return clock
+}
+
+func writeServerEvent(w http.ResponseWriter, event string, data interface{}) error {
+	js, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, js); err != nil {
+		return err
+	}
+	w.(http.Flusher).Flush()
+	return nil
+}
+
+// liveHandler pushes the recent finishers to the results screens as server-sent events whenever the racers or
+// their times change, and the race clock every liveClockInterval
+func liveHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	if _, ok := w.(http.Flusher); !ok {
+		http.Error(w, "Live updates aren't supported here", http.StatusNotImplemented)
+		return
+	}
+	changed, stop := race.Watch()
+	defer stop()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx would hold the events back otherwise
+	ticker := time.NewTicker(liveClockInterval)
+	defer ticker.Stop()
+	sendResults := true
+	for {
+		race.RLock()
+		clock := race.lockedLiveClock()
+		var results []LiveResult
+		if sendResults {
+			results = race.lockedLiveResults()
+		}
+		race.RUnlock()
+		if err := writeServerEvent(w, "clock", clock); err != nil {
+			return
+		}
+		if sendResults {
+			if err := writeServerEvent(w, "results", results); err != nil {
+				return
+			}
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-changed:
+			sendResults = true
+		case <-ticker.C:
+			sendResults = false
+		}
+	}
+}
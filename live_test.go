@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLiveResults(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	if err := race.AddEntry(Entry{Bib: 7, Fname: "Amy", Lname: "Brown", Age: 38}); err != nil {
+		t.Fatalf("Error adding entry - %v", err)
+	}
+	startRace(race)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		liveHandler(w, r, race)
+	}))
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Error connecting - %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected an event stream, got %s", ct)
+	}
+	events := bufio.NewReader(resp.Body)
+	next := func() (string, string) {
+		var event, data string
+		for {
+			line, err := events.ReadString('\n')
+			if err != nil {
+				t.Fatalf("Error reading the stream - %v", err)
+			}
+			line = strings.TrimSpace(line)
+			switch {
+			case line == "":
+				return event, data
+			case strings.HasPrefix(line, "event: "):
+				event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}
+	event, data := next()
+	var clock LiveClock
+	if err = json.Unmarshal([]byte(data), &clock); event != "clock" || err != nil || !clock.Started {
+		t.Errorf("Expected the running clock first, got %s %s - %v", event, data, err)
+	}
+	if event, data = next(); event != "results" || data != "[]" {
+		t.Errorf("Expected nobody finished yet, got %s %s", event, data)
+	}
+
+	*race.testingTime = race.testingTime.Add(21 * time.Minute)
+	linkBibTesting(t, race, 7, false)
+	next() // the clock comes with every push
+	event, data = next()
+	var results []LiveResult
+	if err = json.Unmarshal([]byte(data), &results); event != "results" || err != nil || len(results) != 1 {
+		t.Fatalf("Expected Amy pushed when she finished, got %s %s - %v", event, data, err)
+	}
+	if results[0].Bib != "7" || results[0].Duration != "00:21:00.00" || results[0].Place != "1" {
+		t.Errorf("Expected Amy first in 21:00, got %+v", results[0])
+	}
+}
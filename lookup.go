@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mzimmerman/racergo/internal/core"
+)
+
+// LookupResult is everything the public search is allowed to know about an Entry.
+// Anything not copied in here (e-mail addresses and the rest of the optional fields)
+// never leaves the server from the lookup page.
+type LookupResult struct {
+	Place       Place
+	Bib         Bib
+	Fname       string
+	Lname       string
+	Duration    HumanDuration // the net time
+	Gun         HumanDuration // the gun time when it differs from the net time
+	Hint        string        // only set when the name alone doesn't identify the entry
+	Adjustments []Adjustment  // the penalties and bonuses in the time
+	Status      ResultStatus
+}
+
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// disambiguationHint describes an entry using only the fields that are safe to show
+// to the public - age, gender, hometown and bib.
+func disambiguationHint(e *Entry, hometownIndex int) string {
+	hint := []string{fmt.Sprintf("%s%d", core.Gender(e.Male), e.Age)}
+	if hometownIndex >= 0 && hometownIndex < len(e.Optional) && e.Optional[hometownIndex] != "" {
+		hint = append(hint, e.Optional[hometownIndex])
+	}
+	if e.Bib >= 0 {
+		hint = append(hint, "Bib #"+e.Bib.String())
+	}
+	return strings.Join(hint, ", ")
+}
+
+// lockedLookup finds the entries matching the query by name or bib, adding a hint to
+// every result that shares its full name with another entry in the race
+func (race *Race) lockedLookup(query string) []LookupResult {
+	query = normalizeName(query)
+	hometownIndex := -1
+	for x, fn := range race.optionalEntryFields {
+		if fn == config.hometownField && x != race.optionalEmailIndex {
+			hometownIndex = x
+			break
+		}
+	}
+	results := make([]LookupResult, 0)
+	if query == "" {
+		return results
+	}
+	idx := race.lockedIndex()
+	matches := idx.nameMatches(query, race.allEntries)
+	if bib, err := strconv.Atoi(query); err == nil {
+		if e, ok := race.bibbedEntries[Bib(bib)]; ok && !strings.Contains(normalizeName(e.Fname+" "+e.Lname), query) {
+			matches = append(matches, e)
+			sort.Slice(matches, func(i, j int) bool { return idx.positions[matches[i]] < idx.positions[matches[j]] })
+		}
+	}
+	for _, e := range matches {
+		result := LookupResult{
+			Bib:         e.Bib,
+			Fname:       e.Fname,
+			Lname:       e.Lname,
+			Duration:    e.NetDuration(),
+			Adjustments: e.Adjustments,
+			Status:      e.Status,
+		}
+		if e.HasNetTime() {
+			result.Gun = e.Duration
+		}
+		result.Place = idx.places[e]
+		if len(idx.names[normalizeName(e.Fname+" "+e.Lname)]) > 1 {
+			result.Hint = disambiguationHint(e, hometownIndex)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func (race *Race) Lookup(query string) []LookupResult {
+	race.RLock()
+	defer race.RUnlock()
+	return race.lockedLookup(query)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func lookupTestRace(t *testing.T) *Race {
+	race := NewRace()
+	if err := race.SetOptionalFields([]string{"Email", "City"}); err != nil {
+		t.Fatalf("Error setting optional fields - %v", err)
+	}
+	entries := []Entry{
+		{Bib: 1, Fname: "John", Lname: "Smith", Male: true, Age: 34, Optional: []string{"john34@host.com", "Springfield"}},
+		{Bib: 2, Fname: "john", Lname: "smith", Male: true, Age: 61, Optional: []string{"john61@host.com", "Shelbyville"}},
+		{Bib: 3, Fname: "Jane", Lname: "Smithers", Male: false, Age: 28, Optional: []string{"jane@host.com", "Springfield"}},
+	}
+	for _, e := range entries {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	return race
+}
+
+func TestLookup(t *testing.T) {
+	race := lookupTestRace(t)
+	tests := []struct {
+		query string
+		hints []string
+	}{
+		{"", nil},
+		{"nobody", nil},
+		{"john smith", []string{"M34, Springfield, Bib #1", "M61, Shelbyville, Bib #2"}},
+		{"  JOHN   Smith ", []string{"M34, Springfield, Bib #1", "M61, Shelbyville, Bib #2"}},
+		{"smith", []string{"M34, Springfield, Bib #1", "M61, Shelbyville, Bib #2", ""}},
+		{"jane", []string{""}}, // unique name, no hint needed
+		{"3", []string{""}},
+	}
+	for _, test := range tests {
+		results := race.Lookup(test.query)
+		if len(results) != len(test.hints) {
+			t.Errorf("%q - expected %d results, got %d - %v", test.query, len(test.hints), len(results), results)
+			continue
+		}
+		for x := range results {
+			if results[x].Hint != test.hints[x] {
+				t.Errorf("%q - expected hint %q, got %q", test.query, test.hints[x], results[x].Hint)
+			}
+		}
+	}
+}
+
+func TestLookupHandlerHidesEmail(t *testing.T) {
+	race := lookupTestRace(t)
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/lookup?name=smith", nil)
+	handler(w, r, race)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected %d, got %d - %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	for _, want := range []string{"Shelbyville", "Bib #2", "Smithers"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected lookup page to contain %q", want)
+		}
+	}
+	if strings.Contains(body, "@host.com") {
+		t.Errorf("Lookup page exposed an e-mail address - %s", body)
+	}
+}
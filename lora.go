@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/hmac"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// LoRaWAN checkpoint readers send their readings to a network server like The Things Stack or ChirpStack, which posts
+// each uplink to the /lora webhook.  Readers at aid stations out of coverage hold their readings and send them in
+// batches when they can, so each reading carries the time it was seen rather than the time it arrives.
+
+// loraUplink holds the parts of a The Things Stack v3 or ChirpStack v4 uplink webhook we need
+type loraUplink struct {
+	EndDeviceIDs struct {
+		DeviceID string `json:"device_id"`
+	} `json:"end_device_ids"`
+	ReceivedAt    time.Time `json:"received_at"`
+	UplinkMessage struct {
+		FrmPayload     []byte          `json:"frm_payload"`
+		DecodedPayload json.RawMessage `json:"decoded_payload"`
+		RxMetadata     []struct {
+			Time *time.Time `json:"time"`
+		} `json:"rx_metadata"`
+	} `json:"uplink_message"`
+	DeviceInfo struct {
+		DeviceName string `json:"deviceName"`
+	} `json:"deviceInfo"`
+	Time   time.Time       `json:"time"`
+	Data   []byte          `json:"data"`
+	Object json.RawMessage `json:"object"`
+}
+
+// loraReading is a reading decoded by the network server's payload formatter.  Readers without a real time clock
+// send the Age in seconds before the uplink instead of a Timestamp.
+type loraReading struct {
+	CheckpointReading
+	Age float64 `json:"age"`
+}
+
+// parseLoRaUplink reads the readings out of an uplink.  The decoded payload is a reading or {"readings": [...]},
+// otherwise the raw payload is 6 bytes per reading, the bib as a big endian uint16 and a big endian uint32 that's
+// the Unix time seen, or the age in seconds if it's less than a day.
+func parseLoRaUplink(body []byte) ([]TagRead, error) {
+	var uplink loraUplink
+	if err := json.Unmarshal(body, &uplink); err != nil {
+		return nil, err
+	}
+	device, sent, payload, decoded := uplink.EndDeviceIDs.DeviceID, uplink.ReceivedAt, uplink.UplinkMessage.FrmPayload, uplink.UplinkMessage.DecodedPayload
+	if device == "" {
+		device, sent, payload, decoded = uplink.DeviceInfo.DeviceName, uplink.Time, uplink.Data, uplink.Object
+	}
+	for _, rx := range uplink.UplinkMessage.RxMetadata {
+		if rx.Time != nil && (sent.IsZero() || rx.Time.Before(sent)) {
+			sent = *rx.Time // the gateway's time is closer to when it was sent than the network server's
+		}
+	}
+	if device == "" {
+		return nil, fmt.Errorf("No device in the uplink")
+	}
+	if sent.IsZero() {
+		return nil, fmt.Errorf("No time in the uplink from %s", device)
+	}
+	var readings []loraReading
+	if len(decoded) > 0 && string(decoded) != "null" && string(decoded) != "{}" {
+		var batch struct {
+			Readings []loraReading `json:"readings"`
+		}
+		if err := json.Unmarshal(decoded, &batch); err != nil {
+			return nil, err
+		}
+		readings = batch.Readings
+		if readings == nil {
+			var reading loraReading
+			if err := json.Unmarshal(decoded, &reading); err != nil {
+				return nil, err
+			}
+			readings = []loraReading{reading}
+		}
+	} else {
+		if len(payload)%6 != 0 {
+			return nil, fmt.Errorf("%d byte payload from %s isn't 6 bytes per reading", len(payload), device)
+		}
+		for ; len(payload) > 0; payload = payload[6:] {
+			seen := binary.BigEndian.Uint32(payload[2:])
+			reading := loraReading{CheckpointReading: CheckpointReading{Bib: Bib(binary.BigEndian.Uint16(payload))}}
+			if seen < 24*60*60 {
+				reading.Age = float64(seen)
+			} else {
+				reading.Timestamp = float64(seen)
+			}
+			readings = append(readings, reading)
+		}
+	}
+	reads := make([]TagRead, 0, len(readings))
+	for _, reading := range readings {
+		at, err := reading.Time()
+		if err != nil {
+			return nil, err
+		}
+		if at.IsZero() {
+			at = sent.Add(-time.Duration(reading.Age * float64(time.Second)))
+		}
+		station := reading.Station
+		if station == "" {
+			station = device
+		}
+		reads = append(reads, TagRead{Bib: reading.Bib, Time: at, Checkpoint: strings.ToUpper(station), Reader: "lora " + device})
+	}
+	return reads, nil
+}
+
+// loraHandler is the uplink webhook for the LoRaWAN network server, which has to send RACERGOLORAKEY as a bearer token
+func loraHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	if config.loraKey == "" || !hmac.Equal([]byte(r.Header.Get("Authorization")), []byte("Bearer "+config.loraKey)) {
+		http.Error(w, "Invalid key", http.StatusForbidden)
+		return
+	}
+	var body json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	reads, err := parseLoRaUplink(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result := struct {
+		Accepted int `json:"accepted"`
+		Rejected int `json:"rejected"`
+	}{}
+	for _, tr := range reads {
+		// a retried uplink records the same readings again, which changes nothing
+		if err = race.RecordTagRead(tr); err != nil {
+			log.Printf("Reading of bib #%d from %s rejected - %v", tr.Bib, tr.Reader, err)
+			result.Rejected++
+			continue
+		}
+		result.Accepted++
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLoRaUplink(t *testing.T) {
+	sent := time.Date(2014, 6, 1, 13, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		name, body string
+		bibs       []Bib
+		checkpoint string
+		at         []time.Time
+	}{
+		{"decoded", `{"end_device_ids":{"device_id":"cp2"},"received_at":"2014-06-01T13:00:05Z","uplink_message":{"decoded_payload":{"bib":145,"timestamp":1401613200},"rx_metadata":[{"time":"2014-06-01T13:00:00Z"}]}}`,
+			[]Bib{145}, "CP2", []time.Time{time.Date(2014, 6, 1, 9, 0, 0, 0, time.UTC)}},
+		{"batch", `{"end_device_ids":{"device_id":"aid-3"},"received_at":"2014-06-01T13:00:00Z","uplink_message":{"decoded_payload":{"readings":[{"bib":145,"age":600,"station":"cp3"},{"bib":150,"age":60,"station":"cp3"}]}}}`,
+			[]Bib{145, 150}, "CP3", []time.Time{sent.Add(-10 * time.Minute), sent.Add(-time.Minute)}},
+		{"raw", `{"end_device_ids":{"device_id":"cp2"},"received_at":"2014-06-01T13:00:00Z","uplink_message":{"frm_payload":"AJFTiuuQAJYAAAA8"}}`,
+			[]Bib{145, 150}, "CP2", []time.Time{time.Date(2014, 6, 1, 9, 0, 0, 0, time.UTC), sent.Add(-time.Minute)}},
+		{"chirpstack", `{"deviceInfo":{"deviceName":"cp1"},"time":"2014-06-01T13:00:00Z","object":{"bib":145,"age":30}}`,
+			[]Bib{145}, "CP1", []time.Time{sent.Add(-30 * time.Second)}},
+	} {
+		reads, err := parseLoRaUplink([]byte(tt.body))
+		if err != nil || len(reads) != len(tt.bibs) {
+			t.Errorf("Expected %d reads from the %s uplink, got %v - %v", len(tt.bibs), tt.name, reads, err)
+			continue
+		}
+		for x, tr := range reads {
+			if tr.Bib != tt.bibs[x] || tr.Checkpoint != tt.checkpoint || !tr.Time.Equal(tt.at[x]) {
+				t.Errorf("Expected bib %d at %s %s from the %s uplink, got %#v", tt.bibs[x], tt.checkpoint, tt.at[x], tt.name, tr)
+			}
+		}
+	}
+	if _, err := parseLoRaUplink([]byte(`{"end_device_ids":{"device_id":"cp2"},"received_at":"2014-06-01T13:00:00Z","uplink_message":{"frm_payload":"AJE="}}`)); err == nil {
+		t.Errorf("Expected an error for a payload that isn't 6 bytes per reading")
+	}
+}
+
+func TestLoRaHandler(t *testing.T) {
+	defer func(key string) { config.loraKey = key }(config.loraKey)
+	config.loraKey = "secret"
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	if err := race.AddEntry(Entry{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38}); err != nil {
+		t.Fatalf("Error adding entry - %v", err)
+	}
+	startRace(race)
+	start := race.GetTime()
+	uplink := `{"end_device_ids":{"device_id":"cp1"},"received_at":"` + start.Add(time.Hour).Format(time.RFC3339) +
+		`","uplink_message":{"decoded_payload":{"readings":[{"bib":1,"age":3000},{"bib":9,"age":60}]}}}`
+	for _, key := range []string{"wrong", "secret"} {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("POST", "/lora", strings.NewReader(uplink))
+		r.Header.Set("Authorization", "Bearer "+key)
+		loraHandler(w, r, race)
+		if key == "wrong" {
+			if w.Code != http.StatusForbidden {
+				t.Errorf("Expected the wrong key to be refused, got %d", w.Code)
+			}
+			continue
+		}
+		if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `{"accepted":1,"rejected":1}` {
+			t.Errorf("Expected 1 accepted and 1 rejected, got %d %s", w.Code, w.Body)
+		}
+	}
+	race.RLock()
+	defer race.RUnlock()
+	splits := race.lockedSplits()
+	if len(splits) != 1 || splits[0].Checkpoint != "CP1" || splits[0].Split(start) != HumanDuration(10*time.Minute) {
+		t.Errorf("Expected Amy's CP1 split from when she was seen, got %v", splits)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/mzimmerman/racergo/internal/core"
+)
+
+// LotteryDraw records how a lottery was run so the draw can be reproduced if it's ever questioned
+type LotteryDraw struct {
+	Time        time.Time
+	Capacity    int
+	Bonus       float64
+	Seed        int64
+	Selected    int
+	NotSelected int
+}
+
+// lotteryWeight gives every entrant one ticket plus bonus tickets for each previous lottery they lost
+func (race *Race) lockedLotteryWeight(e *Entry, bonus float64) float64 {
+	for x, fn := range race.optionalEntryFields {
+		if fn == config.lotteryWeightField && x < len(e.Optional) {
+			losses, err := strconv.ParseFloat(e.Optional[x], 64)
+			if err == nil && losses > 0 {
+				return 1 + losses*bonus
+			}
+		}
+	}
+	return 1
+}
+
+// RunLottery fills the remaining spots up to capacity from the Registered entries with a weighted random draw
+// and marks everyone else as NotSelected.  Entries already Selected keep their spot.
+func (race *Race) RunLottery(capacity int, bonus float64, seed int64) (LotteryDraw, error) {
+	race.Lock()
+	defer race.Unlock()
+	if !race.started.IsZero() {
+		return LotteryDraw{}, fmt.Errorf("Race has already started, too late to run a lottery")
+	}
+	if capacity < 1 {
+		return LotteryDraw{}, fmt.Errorf("Capacity must be at least 1, not %d", capacity)
+	}
+	if bonus < 0 {
+		return LotteryDraw{}, fmt.Errorf("Bonus tickets per prior loss must not be negative, not %f", bonus)
+	}
+	spots := capacity
+	type ticket struct {
+		entry *Entry
+		key   float64
+	}
+	tickets := make([]ticket, 0, len(race.allEntries))
+	rnd := rand.New(rand.NewSource(seed))
+	for _, e := range race.allEntries {
+		switch e.Registration {
+		case Selected:
+			spots--
+		case Registered:
+			// Efraimidis-Spirakis weighted sampling, the highest keys win
+			tickets = append(tickets, ticket{e, math.Pow(rnd.Float64(), 1/race.lockedLotteryWeight(e, bonus))})
+		}
+	}
+	sort.SliceStable(tickets, func(i, j int) bool {
+		return tickets[i].key > tickets[j].key
+	})
+	draw := LotteryDraw{
+		Time:     race.GetTime(),
+		Capacity: capacity,
+		Bonus:    bonus,
+		Seed:     seed,
+	}
+	for x, t := range tickets {
+		if x < spots {
+			t.entry.Registration = Selected
+			draw.Selected++
+		} else {
+			t.entry.Registration = NotSelected
+			draw.NotSelected++
+		}
+	}
+	race.lotteryDraws = append(race.lotteryDraws, draw)
+	log.Printf("Lottery drawn with seed %d - %d selected, %d not selected", seed, draw.Selected, draw.NotSelected)
+	race.lockedRecordEvent(Event{Kind: "lottery", Draw: &draw})
+	return draw, nil
+}
+
+func (race *Race) lockedEntriesWithRegistration(status RegistrationStatus) []*Entry {
+	entries := make([]*Entry, 0)
+	for _, e := range race.allEntries {
+		if e.Registration == status {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+func lotteryHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	capacity, err := strconv.Atoi(r.FormValue("capacity"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting capacity", err)
+		return
+	}
+	bonus, err := strconv.ParseFloat(r.FormValue("bonus"), 64)
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting bonus tickets per prior loss", err)
+		return
+	}
+	seed := time.Now().UnixNano()
+	if r.FormValue("seed") != "" {
+		seed, err = strconv.ParseInt(r.FormValue("seed"), 10, 64)
+		if err != nil {
+			showErrorForAdmin(w, r.Referer(), "Error %s getting seed", err)
+			return
+		}
+	}
+	_, err = race.RunLottery(capacity, bonus, seed)
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/lottery", 301)
+}
+
+// lotteryBatchHandler downloads or e-mails the acceptance or decline batch for a lottery result
+func lotteryBatchHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	status, err := core.ParseRegistrationStatus(r.FormValue("status"))
+	if err != nil || status == Registered {
+		showErrorForAdmin(w, r.Referer(), "Can only send batches for the Selected or Not Selected entrants, not %s", r.FormValue("status"))
+		return
+	}
+	race.RLock()
+	entries := make([]Entry, 0)
+	for _, e := range race.lockedEntriesWithRegistration(status) {
+		entries = append(entries, *e)
+	}
+	emailIndex := race.optionalEmailIndex
+	name := race.lockedName()
+	race.RUnlock()
+	if r.FormValue("send") != "true" {
+		w.Header().Set("Content-type", "application/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s-lottery-%s.csv\"", config.webserverHostname, status))
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"Fname", "Lname", config.emailField, "Registration"})
+		for _, e := range entries {
+			email := ""
+			if emailIndex >= 0 && emailIndex < len(e.Optional) {
+				email = e.Optional[emailIndex]
+			}
+			writer.Write([]string{e.Fname, e.Lname, email, status.String()})
+		}
+		writer.Flush()
+		return
+	}
+	subject := fmt.Sprintf("%s Lottery Results", name)
+	for _, e := range entries {
+		text := fmt.Sprintf("Congratulations %s %s!  You have been selected in the %s lottery.  We'll see you on race day!\n\nAdd the race day schedule to your calendar - %s", e.Fname, e.Lname, name, race.calendarURL())
+		if status == NotSelected {
+			text = fmt.Sprintf("Sorry %s %s, you were not selected in the %s lottery this year.  Your odds will be better in next year's lottery!", e.Fname, e.Lname, name)
+		}
+		go sendEmail(e, emailIndex, subject, text)
+	}
+	log.Printf("Queued %d lottery %s e-mails", len(entries), status)
+	http.Redirect(w, r, "/lottery", 301)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func lotteryTestRace(t *testing.T) *Race {
+	race := NewRace()
+	if err := race.SetOptionalFields([]string{"Email", "Prior Losses"}); err != nil {
+		t.Fatalf("Error setting optional fields - %v", err)
+	}
+	for x := 0; x < 10; x++ {
+		losses := "0"
+		if x == 0 {
+			losses = "10"
+		}
+		e := Entry{Bib: NoBib, Fname: strconv.Itoa(x), Lname: "L", Age: 30, Optional: []string{strconv.Itoa(x) + "@host.com", losses}}
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	return race
+}
+
+func selectedNames(race *Race) string {
+	race.RLock()
+	defer race.RUnlock()
+	names := []string{}
+	for _, e := range race.lockedEntriesWithRegistration(Selected) {
+		names = append(names, e.Fname)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestLottery(t *testing.T) {
+	race := lotteryTestRace(t)
+	draw, err := race.RunLottery(4, 1, 42)
+	if err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if draw.Selected != 4 || draw.NotSelected != 6 {
+		t.Errorf("Expected 4 selected and 6 not, got %#v", draw)
+	}
+	// same seed, same draw
+	again := lotteryTestRace(t)
+	again.RunLottery(4, 1, 42)
+	if a, b := selectedNames(race), selectedNames(again); a != b {
+		t.Errorf("Same seed gave different draws - %s and %s", a, b)
+	}
+	// the only spots left are for those not yet drawn, and everyone has been drawn
+	draw, err = race.RunLottery(5, 1, 43)
+	if err != nil || draw.Selected != 0 || draw.NotSelected != 0 {
+		t.Errorf("Expected nobody to be drawn, got %#v - %v", draw, err)
+	}
+
+	// prior losses improve the odds
+	wins := 0
+	for seed := int64(0); seed < 200; seed++ {
+		race := lotteryTestRace(t)
+		race.RunLottery(1, 1, seed)
+		if selectedNames(race) == "0" {
+			wins++
+		}
+	}
+	if wins < 80 { // 11 of 20 tickets, expect around 110 wins
+		t.Errorf("Expected the entrant with prior losses to win most draws, won %d of 200", wins)
+	}
+
+	startRace(race)
+	if _, err := race.RunLottery(4, 1, 42); err == nil {
+		t.Errorf("Expected error running a lottery after the race started")
+	}
+}
+
+func TestLotteryBatch(t *testing.T) {
+	race := lotteryTestRace(t)
+	if _, err := race.RunLottery(9, 0, 7); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	race.RLock()
+	loser := race.lockedEntriesWithRegistration(NotSelected)[0]
+	race.RUnlock()
+	r, _ := http.NewRequest("GET", "/lotteryBatch?status=Not+Selected", nil)
+	w := httptest.NewRecorder()
+	lotteryBatchHandler(w, r, race)
+	want := "Fname,Lname,Email,Registration\n" + loser.Fname + ",L," + loser.Fname + "@host.com,Not Selected\n"
+	if w.Code != http.StatusOK || w.Body.String() != want {
+		t.Errorf("Wanted %q, got %d %q", want, w.Code, w.Body.String())
+	}
+	r, _ = http.NewRequest("GET", "/lotteryBatch?status=Registered", nil)
+	w = httptest.NewRecorder()
+	lotteryBatchHandler(w, r, race)
+	if w.Code != 409 {
+		t.Errorf("Expected 409 for a batch of undrawn entries, got %d", w.Code)
+	}
+	r, _ = http.NewRequest("GET", "/lottery", nil)
+	w = httptest.NewRecorder()
+	handler(w, r, race)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected %d, got %d - %s", http.StatusOK, w.Code, w.Body.String())
+	}
+}
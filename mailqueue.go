@@ -0,0 +1,195 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// SentMail is a recent e-mail for the dashboard, with the last error if it took retries to go through
+type SentMail struct {
+	To      string
+	Subject string
+	Queued  time.Time
+	Sent    time.Time
+	Retries int
+	Err     string
+}
+
+type queuedMail struct {
+	SentMail
+	mail *Mail
+}
+
+// MailStatus is how the queue is pacing itself, for the e-mail dashboard
+type MailStatus struct {
+	Pending     int
+	SentToday   int
+	DailyQuota  int // 0 is unlimited
+	PerMinute   int // 0 is unlimited
+	Drain       time.Duration
+	PausedUntil time.Time // set while waiting for the daily quota to reset
+	Sending     *SentMail // the e-mail being retried, nil if nothing is stuck
+	Recent      []SentMail
+}
+
+const recentMails = 20
+
+// MailQueue sends e-mails one at a time, at most perMinute a minute and dailyQuota a day, so a big race's result
+// e-mails don't trip the provider's rate limits
+type MailQueue struct {
+	sync.Mutex
+	pending     []*queuedMail
+	wake        chan struct{}
+	start       sync.Once
+	day         string
+	sentToday   int
+	lastSent    time.Time
+	pausedUntil time.Time
+	sending     *queuedMail
+	recent      []SentMail // newest first
+	perMinute   int
+	dailyQuota  int
+	deliver     func(*Mail) error
+	now         func() time.Time
+	sleep       func(time.Duration)
+}
+
+var emailQueue *MailQueue
+
+func NewMailQueue(perMinute, dailyQuota int) *MailQueue {
+	return &MailQueue{
+		wake:       make(chan struct{}, 1),
+		perMinute:  perMinute,
+		dailyQuota: dailyQuota,
+		deliver: func(m *Mail) error {
+			return mailTransport.Send(m)
+		},
+		now:   time.Now,
+		sleep: time.Sleep,
+	}
+}
+
+// Enqueue adds the e-mail to the back of the queue, starting the sender the first time
+func (q *MailQueue) Enqueue(to, subject string, m *Mail) {
+	q.Lock()
+	q.pending = append(q.pending, &queuedMail{SentMail: SentMail{To: to, Subject: subject, Queued: q.now()}, mail: m})
+	q.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	q.start.Do(func() {
+		go func() {
+			for {
+				q.step()
+			}
+		}()
+	})
+}
+
+// step waits until the next e-mail may go out under the rate and quota, then sends it, retrying until it goes through
+func (q *MailQueue) step() {
+	q.Lock()
+	for len(q.pending) == 0 {
+		q.Unlock()
+		<-q.wake
+		q.Lock()
+	}
+	now := q.now()
+	if day := now.Format("2006-01-02"); day != q.day {
+		q.day, q.sentToday, q.pausedUntil = day, 0, time.Time{}
+	}
+	if q.dailyQuota > 0 && q.sentToday >= q.dailyQuota {
+		year, month, day := now.Date()
+		q.pausedUntil = time.Date(year, month, day+1, 0, 0, 0, 0, now.Location())
+		q.Unlock()
+		log.Printf("Sent the daily quota of %d e-mails, waiting until %s", q.dailyQuota, q.pausedUntil.Format(time.ANSIC))
+		q.sleep(q.pausedUntil.Sub(now))
+		return
+	}
+	if wait := q.lockedInterval() - now.Sub(q.lastSent); wait > 0 && !q.lastSent.IsZero() {
+		q.Unlock()
+		q.sleep(wait)
+		return
+	}
+	m := q.pending[0]
+	q.pending = q.pending[1:]
+	q.sending = m
+	q.Unlock()
+
+	backoff := time.Second
+	for {
+		err := q.deliver(m.mail)
+		if err == nil {
+			break
+		}
+		backoff = backoff * 2
+		q.Lock()
+		m.Retries++
+		m.Err = err.Error()
+		q.Unlock()
+		log.Printf("Error sending mail to %s - %v, trying again in %s", m.To, err, backoff)
+		q.sleep(backoff)
+	}
+	log.Printf("Success sending %s to %s", m.Subject, m.To)
+	q.Lock()
+	defer q.Unlock()
+	q.sending = nil
+	q.sentToday++
+	q.lastSent = q.now()
+	m.Sent = q.lastSent
+	q.recent = append([]SentMail{m.SentMail}, q.recent...)
+	if len(q.recent) > recentMails {
+		q.recent = q.recent[:recentMails]
+	}
+}
+
+// SendNow delivers the e-mail once right away, ahead of the queue, counting it against the daily quota
+func (q *MailQueue) SendNow(to, subject string, m *Mail) error {
+	if err := q.deliver(m); err != nil {
+		return err
+	}
+	q.Lock()
+	defer q.Unlock()
+	now := q.now()
+	if day := now.Format("2006-01-02"); day != q.day {
+		q.day, q.sentToday, q.pausedUntil = day, 0, time.Time{}
+	}
+	q.sentToday++
+	q.recent = append([]SentMail{{To: to, Subject: subject, Queued: now, Sent: now}}, q.recent...)
+	if len(q.recent) > recentMails {
+		q.recent = q.recent[:recentMails]
+	}
+	return nil
+}
+
+func (q *MailQueue) lockedInterval() time.Duration {
+	if q.perMinute <= 0 {
+		return 0
+	}
+	return time.Minute / time.Duration(q.perMinute)
+}
+
+// Status describes the queue, the drain time counts the e-mails held back by the daily quota as waiting until tomorrow
+func (q *MailQueue) Status() MailStatus {
+	q.Lock()
+	defer q.Unlock()
+	status := MailStatus{
+		Pending:     len(q.pending),
+		SentToday:   q.sentToday,
+		DailyQuota:  q.dailyQuota,
+		PerMinute:   q.perMinute,
+		Drain:       time.Duration(len(q.pending)) * q.lockedInterval(),
+		PausedUntil: q.pausedUntil,
+		Recent:      q.recent,
+	}
+	if q.sending != nil && q.sending.Retries > 0 {
+		sending := q.sending.SentMail
+		status.Sending = &sending
+	}
+	if q.dailyQuota > 0 && q.sentToday+len(q.pending) > q.dailyQuota {
+		status.Drain += 24 * time.Hour * time.Duration((q.sentToday+len(q.pending)-1)/q.dailyQuota)
+	}
+	return status
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMailQueuePacing(t *testing.T) {
+	now := time.Date(2014, 6, 1, 23, 58, 0, 0, time.Local)
+	var slept []time.Duration
+	failures := 1
+	q := NewMailQueue(30, 3)
+	q.now = func() time.Time { return now }
+	q.sleep = func(d time.Duration) {
+		slept = append(slept, d)
+		now = now.Add(d)
+	}
+	q.deliver = func(*Mail) error {
+		if failures > 0 {
+			failures--
+			return errors.New("rate limited")
+		}
+		return nil
+	}
+	for _, to := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com"} {
+		q.pending = append(q.pending, &queuedMail{SentMail: SentMail{To: to, Subject: "Results", Queued: now}, mail: &Mail{}})
+	}
+	if status := q.Status(); status.Pending != 4 || status.Drain != 24*time.Hour+8*time.Second {
+		t.Errorf("Expected 4 pending draining past the quota into tomorrow, got %d and %s", status.Pending, status.Drain)
+	}
+	q.step()
+	if len(slept) != 1 || slept[0] != 2*time.Second {
+		t.Errorf("Expected one retry after 2s, got %v", slept)
+	}
+	q.step() // waits out the rate
+	q.step()
+	if len(slept) != 2 || slept[1] != 2*time.Second {
+		t.Errorf("Expected a 2s wait between e-mails at 30 a minute, got %v", slept)
+	}
+	q.step()
+	q.step()
+	q.step() // quota of 3 sent, waits for midnight
+	if status := q.Status(); status.SentToday != 3 || status.Pending != 1 || status.PausedUntil.IsZero() {
+		t.Errorf("Expected a pause after the daily quota, got %#v", status)
+	}
+	if len(q.recent) != 3 || q.recent[2].Retries != 1 || q.recent[0].To != "c@example.com" {
+		t.Errorf("Expected the 3 recent e-mails, newest first with the first retried once, got %#v", q.recent)
+	}
+	q.step()
+	if status := q.Status(); status.SentToday != 1 || status.Pending != 0 || !status.PausedUntil.IsZero() {
+		t.Errorf("Expected the last e-mail sent after midnight, got %#v", status)
+	}
+}
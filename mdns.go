@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+)
+
+// DNS record types and classes used to answer mDNS queries
+const (
+	dnsTypeA    = 1
+	dnsTypePTR  = 12
+	dnsTypeTXT  = 16
+	dnsTypeAAAA = 28
+	dnsTypeSRV  = 33
+	dnsTypeANY  = 255
+
+	dnsClassIN         = 1
+	dnsClassCacheFlush = 0x8000 // set on records only this host answers for
+	mdnsTTL            = 120
+)
+
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+type dnsQuestion struct {
+	Name string // lower case and fully qualified, e.g. racergo.local.
+	Type uint16
+}
+
+type dnsRecord struct {
+	Name  string
+	Type  uint16
+	Class uint16
+	Data  []byte
+}
+
+// mdnsResponder answers for the race's .local hostname and advertises it as a web server,
+// with the pages volunteers need listed in the TXT record
+type mdnsResponder struct {
+	host     string // e.g. racergo.local.
+	instance string // e.g. Orchard Run._http._tcp.local.
+	port     uint16
+	ips      []net.IP
+	txt      []string
+}
+
+const mdnsService = "_http._tcp.local."
+
+func newMDNSResponder(name string, port uint16, ips []net.IP) *mdnsResponder {
+	return &mdnsResponder{
+		host:     strings.ToLower(name) + ".local.",
+		instance: strings.Replace(config.raceName, ".", "", -1) + "." + mdnsService,
+		port:     port,
+		ips:      ips,
+		txt:      []string{"path=/", "admin=/admin", "dayof=/dayof", "results=/results", "lookup=/lookup"},
+	}
+}
+
+func writeDNSName(buf *bytes.Buffer, name string) {
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if len(label) > 63 {
+			label = label[:63]
+		}
+		buf.WriteByte(byte(len(label)))
+		buf.WriteString(label)
+	}
+	buf.WriteByte(0)
+}
+
+// readDNSName reads the name at offset, following compression pointers, returning the offset after it
+func readDNSName(msg []byte, offset int) (string, int, error) {
+	labels := []string{}
+	end := -1
+	for jumps := 0; ; {
+		if offset >= len(msg) {
+			return "", 0, fmt.Errorf("name runs past the end of the message")
+		}
+		length := int(msg[offset])
+		switch {
+		case length == 0:
+			if end < 0 {
+				end = offset + 1
+			}
+			return strings.ToLower(strings.Join(labels, ".")) + ".", end, nil
+		case length&0xC0 == 0xC0:
+			if offset+1 >= len(msg) || jumps > 10 {
+				return "", 0, fmt.Errorf("bad compression pointer")
+			}
+			if end < 0 {
+				end = offset + 2
+			}
+			offset = int(binary.BigEndian.Uint16(msg[offset:]) & 0x3FFF)
+			jumps++
+		default:
+			if offset+1+length > len(msg) {
+				return "", 0, fmt.Errorf("label runs past the end of the message")
+			}
+			labels = append(labels, string(msg[offset+1:offset+1+length]))
+			offset += 1 + length
+		}
+	}
+}
+
+// parseDNSQuestions returns the questions in a query, ignoring responses from other hosts
+func parseDNSQuestions(msg []byte) ([]dnsQuestion, error) {
+	if len(msg) < 12 {
+		return nil, fmt.Errorf("message too short")
+	}
+	if msg[2]&0x80 != 0 {
+		return nil, nil // a response, not a query
+	}
+	count := int(binary.BigEndian.Uint16(msg[4:]))
+	questions := make([]dnsQuestion, 0, count)
+	offset := 12
+	for x := 0; x < count; x++ {
+		name, next, err := readDNSName(msg, offset)
+		if err != nil {
+			return nil, err
+		}
+		if next+4 > len(msg) {
+			return nil, fmt.Errorf("question runs past the end of the message")
+		}
+		questions = append(questions, dnsQuestion{Name: name, Type: binary.BigEndian.Uint16(msg[next:])})
+		offset = next + 4
+	}
+	return questions, nil
+}
+
+func (m *mdnsResponder) addressRecords() []dnsRecord {
+	records := []dnsRecord{}
+	for _, ip := range m.ips {
+		if ip4 := ip.To4(); ip4 != nil {
+			records = append(records, dnsRecord{m.host, dnsTypeA, dnsClassIN | dnsClassCacheFlush, ip4})
+		} else {
+			records = append(records, dnsRecord{m.host, dnsTypeAAAA, dnsClassIN | dnsClassCacheFlush, ip.To16()})
+		}
+	}
+	return records
+}
+
+func (m *mdnsResponder) serviceRecords() []dnsRecord {
+	var ptr, srv, txt bytes.Buffer
+	writeDNSName(&ptr, m.instance)
+	binary.Write(&srv, binary.BigEndian, [3]uint16{0, 0, m.port}) // priority, weight, port
+	writeDNSName(&srv, m.host)
+	for _, t := range m.txt {
+		txt.WriteByte(byte(len(t)))
+		txt.WriteString(t)
+	}
+	return []dnsRecord{
+		{mdnsService, dnsTypePTR, dnsClassIN, ptr.Bytes()},
+		{m.instance, dnsTypeSRV, dnsClassIN | dnsClassCacheFlush, srv.Bytes()},
+		{m.instance, dnsTypeTXT, dnsClassIN | dnsClassCacheFlush, txt.Bytes()},
+	}
+}
+
+// answers are the records this host has for the questions, nil when the query is for someone else
+func (m *mdnsResponder) answers(questions []dnsQuestion) []dnsRecord {
+	answers := []dnsRecord{}
+	for _, q := range questions {
+		switch strings.ToLower(q.Name) {
+		case m.host:
+			for _, r := range m.addressRecords() {
+				if q.Type == r.Type || q.Type == dnsTypeANY {
+					answers = append(answers, r)
+				}
+			}
+		case mdnsService, strings.ToLower(m.instance):
+			answers = append(answers, m.serviceRecords()...)
+			answers = append(answers, m.addressRecords()...)
+		}
+	}
+	return answers
+}
+
+func buildDNSResponse(records []dnsRecord) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, [6]uint16{0, 0x8400, 0, uint16(len(records)), 0, 0}) // authoritative response
+	for _, r := range records {
+		writeDNSName(&buf, r.Name)
+		binary.Write(&buf, binary.BigEndian, r.Type)
+		binary.Write(&buf, binary.BigEndian, r.Class)
+		binary.Write(&buf, binary.BigEndian, uint32(mdnsTTL))
+		binary.Write(&buf, binary.BigEndian, uint16(len(r.Data)))
+		buf.Write(r.Data)
+	}
+	return buf.Bytes()
+}
+
+// localIPs are the addresses tablets on the race-day network can reach this laptop at
+func localIPs() []net.IP {
+	ips := []net.IP{}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return ips
+	}
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && !ipnet.IP.IsLoopback() && !ipnet.IP.IsLinkLocalUnicast() {
+			ips = append(ips, ipnet.IP)
+		}
+	}
+	return ips
+}
+
+// advertiseMDNS announces the race on the local network and answers queries for it until the program exits
+func advertiseMDNS(name string, port uint16) {
+	conn, err := net.ListenMulticastUDP("udp4", nil, mdnsGroup)
+	if err != nil {
+		log.Printf("Error listening for mDNS, not advertising %s.local - %v", name, err)
+		return
+	}
+	m := newMDNSResponder(name, port, localIPs())
+	conn.WriteToUDP(buildDNSResponse(append(m.serviceRecords(), m.addressRecords()...)), mdnsGroup)
+	log.Printf("Advertising http://%s:%d via mDNS", strings.TrimSuffix(m.host, "."), port)
+	buf := make([]byte, 9000)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			log.Printf("Error reading mDNS query - %v", err)
+			return
+		}
+		questions, err := parseDNSQuestions(buf[:n])
+		if err != nil {
+			continue
+		}
+		if answers := m.answers(questions); len(answers) > 0 {
+			conn.WriteToUDP(buildDNSResponse(answers), mdnsGroup)
+		}
+	}
+}
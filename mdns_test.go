@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+func dnsQuery(name string, qtype uint16) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, [6]uint16{0, 0, 1, 0, 0, 0})
+	writeDNSName(&buf, name)
+	binary.Write(&buf, binary.BigEndian, [2]uint16{qtype, dnsClassIN})
+	return buf.Bytes()
+}
+
+func TestMDNSAnswers(t *testing.T) {
+	m := newMDNSResponder("RacerGo", 8080, []net.IP{net.IPv4(192, 168, 1, 20), net.ParseIP("fd00::20")})
+	questions, err := parseDNSQuestions(dnsQuery("racergo.LOCAL", dnsTypeA))
+	if err != nil || len(questions) != 1 || questions[0].Name != "racergo.local." || questions[0].Type != dnsTypeA {
+		t.Fatalf("Unexpected questions %v - %v", questions, err)
+	}
+	answers := m.answers(questions)
+	if len(answers) != 1 || !bytes.Equal(answers[0].Data, []byte{192, 168, 1, 20}) {
+		t.Errorf("Expected just the IPv4 address, got %v", answers)
+	}
+	response := buildDNSResponse(answers)
+	if binary.BigEndian.Uint16(response[2:]) != 0x8400 || binary.BigEndian.Uint16(response[6:]) != 1 || !bytes.HasSuffix(response, []byte{192, 168, 1, 20}) {
+		t.Errorf("Unexpected response % x", response)
+	}
+
+	answers = m.answers([]dnsQuestion{{Name: mdnsService, Type: dnsTypePTR}})
+	if len(answers) != 5 {
+		t.Fatalf("Expected PTR, SRV, TXT and both addresses, got %d records", len(answers))
+	}
+	if srv := answers[1]; srv.Type != dnsTypeSRV || binary.BigEndian.Uint16(srv.Data[4:]) != 8080 {
+		t.Errorf("Expected the SRV record to point at port 8080, got % x", srv.Data)
+	}
+	if txt := answers[2]; !bytes.Contains(txt.Data, []byte("\x0cadmin=/admin")) {
+		t.Errorf("Expected the admin page in the TXT record, got %q", txt.Data)
+	}
+	if answers := m.answers([]dnsQuestion{{Name: "printer.local.", Type: dnsTypeA}}); len(answers) != 0 {
+		t.Errorf("Expected no answer for another host, got %v", answers)
+	}
+}
+
+func TestReadDNSName(t *testing.T) {
+	// racergo.local. followed by www pointing back at it
+	msg := []byte{7, 'r', 'a', 'c', 'e', 'r', 'g', 'o', 5, 'l', 'o', 'c', 'a', 'l', 0, 3, 'w', 'w', 'w', 0xC0, 0}
+	name, next, err := readDNSName(msg, 15)
+	if err != nil || name != "www.racergo.local." || next != len(msg) {
+		t.Errorf("Expected www.racergo.local. ending at %d, got %s %d %v", len(msg), name, next, err)
+	}
+	if _, _, err := readDNSName([]byte{0xC0, 0}, 0); err == nil {
+		t.Errorf("Expected error for a pointer loop")
+	}
+	if _, err := parseDNSQuestions([]byte{0, 0, 0, 0, 0, 1}); err == nil {
+		t.Errorf("Expected error for a short message")
+	}
+}
@@ -0,0 +1,11 @@
+package main
+
+import "net/http"
+
+// adminPage is where admin forms return to, the phone layout when the form was posted from it
+func adminPage(r *http.Request) string {
+	if r.FormValue("mobile") == "true" {
+		return "/m"
+	}
+	return "/admin"
+}
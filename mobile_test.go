@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMobileAdmin(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38},
+		{Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 31},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+
+	form := url.Values{"mobile": {"true"}}
+	r, _ := http.NewRequest("POST", "/start", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	startHandler(w, r, race)
+	if location := w.Header().Get("Location"); location != "/m" {
+		t.Errorf("Expected starting from the phone to return to /m, got %q", location)
+	}
+	*race.testingTime = race.testingTime.Add(20 * time.Minute)
+	linkBibTesting(t, race, 2, false)
+
+	r, _ = http.NewRequest("GET", "/m", nil)
+	w = httptest.NewRecorder()
+	handler(w, r, race)
+	body := w.Body.String()
+	if w.Code != http.StatusOK || !strings.Contains(body, "#2 Bob Adams") || !strings.Contains(body, `name="remove"`) {
+		t.Errorf("Expected bib 2 waiting for confirmation, got %d - %s", w.Code, body)
+	}
+
+	form = url.Values{"mobile": {"true"}, "bib": {"1"}}
+	r, _ = http.NewRequest("POST", "/checkBib", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	checkBibHandler(w, r, race)
+	if location := w.Header().Get("Location"); location != "/confirmBib?id=1&mobile=true" {
+		t.Fatalf("Expected the confirm page to return to the phone layout, got %q", location)
+	}
+	form = url.Values{"mobile": {"true"}, "id": {"1"}, "bib": {"1"}}
+	r, _ = http.NewRequest("POST", "/assignTime", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	assignTimeHandler(w, r, race)
+	if location := w.Header().Get("Location"); location != "/m" {
+		t.Errorf("Expected assigning from the phone to return to /m, got %q", location)
+	}
+
+	r, _ = http.NewRequest("GET", "/m/finishers", nil)
+	w = httptest.NewRecorder()
+	handler(w, r, race)
+	body = w.Body.String()
+	if strings.Contains(body, "<html") || !strings.Contains(body, "#1 Amy Brown") {
+		t.Errorf("Expected just the finisher list with bib 1, got %s", body)
+	}
+}
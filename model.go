@@ -0,0 +1,35 @@
+package main
+
+import "github.com/mzimmerman/racergo/internal/core"
+
+// The entry model lives in internal/core, these keep the names the rest of the server uses.  The Race and
+// everything that times, scores and audits it are still here in package main.
+
+type (
+	Bib                = core.Bib
+	Place              = core.Place
+	Prize              = core.Prize
+	Entry              = core.Entry
+	Audit              = core.Audit
+	Adjustment         = core.Adjustment
+	HumanDuration      = core.HumanDuration
+	ResultStatus       = core.ResultStatus
+	RegistrationStatus = core.RegistrationStatus
+)
+
+const NoBib = core.NoBib
+
+const (
+	StatusOK  = core.StatusOK
+	StatusDNS = core.StatusDNS
+	StatusDNF = core.StatusDNF
+	StatusDQ  = core.StatusDQ
+)
+
+const (
+	Registered  = core.Registered
+	Selected    = core.Selected
+	NotSelected = core.NotSelected
+	Waitlisted  = core.Waitlisted
+	Withdrawn   = core.Withdrawn
+)
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A small MQTT 3.1.1 client, just enough to subscribe to the checkpoint readers' topics on a broker, e.g.
+// RACERGOMQTT=tcp://broker.local:1883 with readers publishing to racergo/checkpoints/CP2
+
+// MQTT control packet types, see section 2.2.1 of the MQTT 3.1.1 spec
+const (
+	mqttConnect   = 1
+	mqttConnack   = 2
+	mqttPublish   = 3
+	mqttPuback    = 4
+	mqttSubscribe = 8
+	mqttSuback    = 9
+	mqttPingreq   = 12
+	mqttPingresp  = 13
+)
+
+// mqttKeepAlive is how often the broker hears from us, it drops the connection after 1.5 times this
+var mqttKeepAlive = 30 * time.Second
+
+// CheckpointReading is what a checkpoint reader publishes, the station comes from the end of the topic if it isn't set.
+// Timestamp is Unix seconds or milliseconds, or RFC 3339, and the time it arrives when not set.
+type CheckpointReading struct {
+	Bib       Bib         `json:"bib"`
+	Station   string      `json:"station"`
+	Timestamp interface{} `json:"timestamp"`
+}
+
+func mqttString(s string) []byte {
+	b := make([]byte, 2, 2+len(s))
+	binary.BigEndian.PutUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func writeMQTTPacket(w io.Writer, kind, flags byte, body []byte) error {
+	packet := []byte{kind<<4 | flags}
+	for length := len(body); ; {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		packet = append(packet, digit)
+		if length == 0 {
+			break
+		}
+	}
+	_, err := w.Write(append(packet, body...))
+	return err
+}
+
+func readMQTTPacket(r *bufio.Reader) (byte, byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for x := 0; ; x++ {
+		digit, err := r.ReadByte()
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		length += int(digit&0x7f) * multiplier
+		multiplier *= 128
+		if digit&0x80 == 0 {
+			break
+		}
+		if x == 3 {
+			return 0, 0, nil, fmt.Errorf("malformed remaining length")
+		}
+	}
+	body := make([]byte, length)
+	_, err = io.ReadFull(r, body)
+	return header >> 4, header & 0x0f, body, err
+}
+
+// dialMQTT connects to the broker and subscribes to the topic, returning the connection ready to read publishes
+func dialMQTT(broker *url.URL, topic, clientID string) (net.Conn, *bufio.Reader, error) {
+	conn, err := net.DialTimeout("tcp", broker.Host, 10*time.Second)
+	if err != nil {
+		return nil, nil, err
+	}
+	reader := bufio.NewReader(conn)
+	connect := append(mqttString("MQTT"), 4, 0x02) // protocol level 4, clean session
+	keepAlive := make([]byte, 2)
+	binary.BigEndian.PutUint16(keepAlive, uint16(mqttKeepAlive/time.Second))
+	connect = append(append(connect, keepAlive...), mqttString(clientID)...)
+	if broker.User != nil {
+		connect[7] |= 0x80
+		connect = append(connect, mqttString(broker.User.Username())...)
+		if password, ok := broker.User.Password(); ok {
+			connect[7] |= 0x40
+			connect = append(connect, mqttString(password)...)
+		}
+	}
+	fail := func(err error) (net.Conn, *bufio.Reader, error) {
+		conn.Close()
+		return nil, nil, err
+	}
+	if err = writeMQTTPacket(conn, mqttConnect, 0, connect); err != nil {
+		return fail(err)
+	}
+	kind, _, body, err := readMQTTPacket(reader)
+	if err != nil {
+		return fail(err)
+	}
+	if kind != mqttConnack || len(body) < 2 || body[1] != 0 {
+		return fail(fmt.Errorf("broker refused the connection - %v", body))
+	}
+	subscribe := append([]byte{0, 1}, mqttString(topic)...)
+	if err = writeMQTTPacket(conn, mqttSubscribe, 0x02, append(subscribe, 1)); err != nil { // QoS 1
+		return fail(err)
+	}
+	if kind, _, body, err = readMQTTPacket(reader); err != nil {
+		return fail(err)
+	}
+	if kind != mqttSuback || len(body) < 3 || body[2] == 0x80 {
+		return fail(fmt.Errorf("broker refused the subscription to %s", topic))
+	}
+	return conn, reader, nil
+}
+
+// parseCheckpointReading reads a JSON reading, or a text one like an SMS report, "145 150" or "CP2 145 150"
+func parseCheckpointReading(topic string, payload []byte, today time.Time) ([]TagRead, error) {
+	station := strings.ToUpper(topic[strings.LastIndex(topic, "/")+1:])
+	var reading CheckpointReading
+	if err := json.Unmarshal(payload, &reading); err != nil {
+		text := strings.TrimSpace(string(payload))
+		if fields := strings.Fields(text); len(fields) > 0 {
+			if _, err := strconv.Atoi(strings.TrimPrefix(fields[0], "#")); err == nil {
+				text = station + " " + text
+			}
+		}
+		checkpoint, at, bibs, err := parseSMSCommand(text, today)
+		if err != nil {
+			return nil, err
+		}
+		reads := make([]TagRead, len(bibs))
+		for x, bib := range bibs {
+			reads[x] = TagRead{Bib: bib, Time: at, Checkpoint: checkpoint, Reader: "mqtt " + topic}
+		}
+		return reads, nil
+	}
+	if reading.Station != "" {
+		station = strings.ToUpper(reading.Station)
+	}
+	at, err := reading.Time()
+	if err != nil {
+		return nil, err
+	}
+	return []TagRead{{Bib: reading.Bib, Time: at, Checkpoint: station, Reader: "mqtt " + topic}}, nil
+}
+
+// Time is when the reader saw the bib, the zero time when it didn't say
+func (reading CheckpointReading) Time() (time.Time, error) {
+	switch ts := reading.Timestamp.(type) {
+	case float64:
+		if ts > 1e12 {
+			return time.Unix(0, int64(ts)*int64(time.Millisecond)), nil
+		}
+		return time.Unix(0, int64(ts*float64(time.Second))), nil
+	case string:
+		at, err := time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%s is not an RFC 3339 timestamp", ts)
+		}
+		return at, nil
+	}
+	return time.Time{}, nil
+}
+
+// followMQTT records the readings published to RACERGOMQTTTOPIC, reconnecting to the broker if the connection drops
+func followMQTT(race *Race, broker *url.URL, topic string) {
+	for {
+		if err := race.readMQTT(broker, topic); err != nil {
+			log.Printf("Error with the MQTT broker %s, reconnecting - %v", broker.Host, err)
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
+
+func (race *Race) readMQTT(broker *url.URL, topic string) error {
+	conn, reader, err := dialMQTT(broker, topic, fmt.Sprintf("racergo-%d", time.Now().Unix()))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	log.Printf("Subscribed to %s on %s", topic, broker.Host)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(mqttKeepAlive):
+				writeMQTTPacket(conn, mqttPingreq, 0, nil)
+			}
+		}
+	}()
+	for {
+		conn.SetReadDeadline(time.Now().Add(mqttKeepAlive * 2))
+		kind, flags, body, err := readMQTTPacket(reader)
+		if err != nil {
+			return err
+		}
+		if kind != mqttPublish {
+			continue
+		}
+		if len(body) < 2 {
+			return fmt.Errorf("malformed publish")
+		}
+		topicLen := int(binary.BigEndian.Uint16(body))
+		if len(body) < 2+topicLen {
+			return fmt.Errorf("malformed publish")
+		}
+		name, payload := string(body[2:2+topicLen]), body[2+topicLen:]
+		if qos := flags >> 1 & 3; qos > 0 {
+			if len(payload) < 2 {
+				return fmt.Errorf("malformed publish")
+			}
+			writeMQTTPacket(conn, mqttPuback, 0, payload[:2])
+			payload = payload[2:]
+		}
+		reads, err := parseCheckpointReading(name, payload, time.Now())
+		if err != nil {
+			log.Printf("Ignoring the reading on %s - %v", name, err)
+			continue
+		}
+		for _, tr := range reads {
+			if err = race.RecordTagRead(tr); err != nil {
+				log.Printf("Reading of bib #%d on %s rejected - %v", tr.Bib, name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseCheckpointReading(t *testing.T) {
+	today := time.Date(2014, 6, 1, 9, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		topic, payload string
+		bibs           []Bib
+		checkpoint     string
+		at             time.Time
+	}{
+		{"racergo/checkpoints/cp2", `{"bib":145}`, []Bib{145}, "CP2", time.Time{}},
+		{"racergo/checkpoints/cp2", `{"bib":145,"station":"CP3","timestamp":1401613200}`, []Bib{145}, "CP3", today},
+		{"racergo/checkpoints/cp2", `{"bib":145,"timestamp":1401613200500}`, []Bib{145}, "CP2", today.Add(500 * time.Millisecond)},
+		{"racergo/checkpoints/cp2", `{"bib":145,"timestamp":"2014-06-01T09:00:00Z"}`, []Bib{145}, "CP2", today},
+		{"racergo/checkpoints/cp2", "145 150", []Bib{145, 150}, "CP2", time.Time{}},
+		{"racergo/checkpoints/cp2", "CP1 09:00 145", []Bib{145}, "CP1", today},
+	} {
+		reads, err := parseCheckpointReading(tt.topic, []byte(tt.payload), today)
+		if err != nil || len(reads) != len(tt.bibs) {
+			t.Errorf("Expected %d reads from %s, got %v - %v", len(tt.bibs), tt.payload, reads, err)
+			continue
+		}
+		for x, tr := range reads {
+			if tr.Bib != tt.bibs[x] || tr.Checkpoint != tt.checkpoint || !tr.Time.Equal(tt.at) {
+				t.Errorf("Expected bib %d at %s %s from %s, got %#v", tt.bibs[x], tt.checkpoint, tt.at, tt.payload, tr)
+			}
+		}
+	}
+	if _, err := parseCheckpointReading("racergo/checkpoints/cp2", []byte(`{"bib":145,"timestamp":"noon"}`), today); err == nil {
+		t.Errorf("Expected an error for a timestamp that isn't RFC 3339")
+	}
+}
+
+func TestReadMQTT(t *testing.T) {
+	defer func(checkpoints []string) { config.checkpoints = checkpoints }(config.checkpoints)
+	config.checkpoints = []string{"CP1", "CP2"}
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	if err := race.AddEntry(Entry{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38}); err != nil {
+		t.Fatalf("Error adding entry - %v", err)
+	}
+	startRace(race)
+	start := race.GetTime()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening - %v", err)
+	}
+	defer listener.Close()
+	pubacks := make(chan []byte, 1)
+	go func() { // a broker that accepts the subscription, publishes one reading and waits for the ack
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		if kind, _, body, err := readMQTTPacket(reader); err != nil || kind != mqttConnect || body[7] != 0xc2 {
+			t.Errorf("Expected a CONNECT with the user, got %d %q - %v", kind, body, err)
+			return
+		}
+		writeMQTTPacket(conn, mqttConnack, 0, []byte{0, 0})
+		kind, _, body, err := readMQTTPacket(reader)
+		if err != nil || kind != mqttSubscribe || string(body[4:len(body)-1]) != "racergo/checkpoints/#" {
+			t.Errorf("Expected a SUBSCRIBE, got %d %q - %v", kind, body, err)
+			return
+		}
+		writeMQTTPacket(conn, mqttSuback, 0, []byte{body[0], body[1], 1})
+		payload := `{"bib":1,"timestamp":"` + start.Add(10*time.Minute).Format(time.RFC3339) + `"}`
+		publish := append(mqttString("racergo/checkpoints/cp1"), 0, 7)
+		writeMQTTPacket(conn, mqttPublish, 0x02, append(publish, payload...))
+		if kind, _, body, err = readMQTTPacket(reader); err == nil && kind == mqttPuback {
+			pubacks <- body
+		}
+	}()
+	broker, _ := url.Parse("tcp://user:secret@" + listener.Addr().String())
+	if err := race.readMQTT(broker, "racergo/checkpoints/#"); err == nil {
+		t.Errorf("Expected an error when the broker hangs up")
+	}
+	select {
+	case body := <-pubacks:
+		if string(body) != "\x00\x07" {
+			t.Errorf("Expected the PUBACK for packet 7, got %q", body)
+		}
+	default:
+		t.Errorf("Expected the reading to be acknowledged")
+	}
+	race.RLock()
+	defer race.RUnlock()
+	splits := race.lockedSplits()
+	if len(splits) != 1 || splits[0].Checkpoint != "CP1" || splits[0].Split(start) != HumanDuration(10*time.Minute) {
+		t.Errorf("Expected Amy's CP1 split from the broker, got %v", splits)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// startCheckpoint is the checkpoint name of the start mat, a racer's read there is their start crossing
+const startCheckpoint = "START"
+
+// lockedNetTimes is true once anyone has been seen crossing the start mat
+func (race *Race) lockedNetTimes() bool {
+	for _, e := range race.allEntries {
+		if !e.StartCrossing.IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+// lockedRecordStartCrossing records when the bib crossed the start mat.  A racer is read many times as they
+// cross, and might walk over the mat again to warm up, so the first crossing after the gun is kept.
+func (race *Race) lockedRecordStartCrossing(bib Bib, at time.Time) error {
+	if race.started.IsZero() {
+		return fmt.Errorf("Race has not started yet, cannot record a start crossing")
+	}
+	entry, ok := race.bibbedEntries[bib]
+	if !ok {
+		return fmt.Errorf("Bib %d not found", bib)
+	}
+	gun := race.lockedStartOf(entry)
+	if at.Before(gun) {
+		return fmt.Errorf("Bib %d crossed the start at %s, before the gun", bib, at.Format("15:04:05.00"))
+	}
+	if !entry.StartCrossing.IsZero() && !at.Before(entry.StartCrossing) {
+		return nil
+	}
+	entry.StartCrossing = at
+	log.Printf("Bib #%d crossed the start %s after the gun", bib, HumanDuration(at.Sub(gun)))
+	race.lockedRecordEvent(Event{Kind: "startCrossing", Bib: bib, Time: at})
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNetTime(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38}, {Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 31}} {
+		race.AddEntry(e)
+	}
+	startRace(race)
+	gun := race.started
+	if err := race.RecordTagRead(TagRead{Bib: 1, Time: gun.Add(-time.Minute), Checkpoint: startCheckpoint}); err == nil {
+		t.Errorf("Expected a start crossing before the gun refused")
+	}
+	for _, read := range []TagRead{
+		{Bib: 1, Time: gun.Add(90 * time.Second), Checkpoint: startCheckpoint},
+		{Bib: 1, Time: gun.Add(91 * time.Second), Checkpoint: startCheckpoint},
+		{Bib: 1, Time: gun.Add(19 * time.Minute)},
+		{Bib: 2, Time: gun.Add(20 * time.Minute)},
+	} {
+		if err := race.RecordTagRead(read); err != nil {
+			t.Fatalf("Error recording %#v - %v", read, err)
+		}
+	}
+	amy, bob := race.bibbedEntries[1], race.bibbedEntries[2]
+	if race.allEntries[0] != amy || amy.Duration != HumanDuration(19*time.Minute) || amy.NetDuration() != HumanDuration(17*time.Minute+30*time.Second) {
+		t.Errorf("Expected Amy first on gun time with a 17:30 net time, got %s %s", amy.Duration, amy.NetDuration())
+	}
+	if bob.HasNetTime() || bob.NetDuration() != bob.Duration {
+		t.Errorf("Expected Bob's net time to be his gun time, got %s", bob.NetDuration())
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	race.WriteCSV(writer)
+	writer.Flush()
+	if !strings.HasPrefix(buf.String(), "Fname,Lname,Age,Gender,Bib,Overall Place,Duration,Time Finished,Confirmed,Gun Time,Chip Time\n") ||
+		!strings.Contains(buf.String(), "Amy,Brown,38,F,1,1,00:19:00.00,"+amy.TimeFinishedString()+",false,00:19:00.00,00:17:30.00\n") {
+		t.Errorf("Expected gun and chip times in the download, got %s", buf.String())
+	}
+	race.RLock()
+	chip := race.lockedColumnValue(amy, "Chip Time", race.lockedIndex())
+	lookup := race.lockedLookup("amy")
+	finisher, _ := race.lockedFinisher(1)
+	race.RUnlock()
+	if chip != "00:17:30.00" {
+		t.Errorf("Expected Amy's chip time exported, got %s", chip)
+	}
+	if len(lookup) != 1 || lookup[0].Duration != amy.NetDuration() || lookup[0].Gun != amy.Duration {
+		t.Errorf("Expected the lookup to show Amy's net and gun times, got %#v", lookup)
+	}
+	if finisher.Duration != amy.NetDuration() || finisher.Gun != amy.Duration {
+		t.Errorf("Expected Amy's finisher page to show her net and gun times, got %#v", finisher)
+	}
+
+	replayed := NewRace()
+	if err := replayed.Replay(race.events); err != nil {
+		t.Fatalf("Error replaying - %v", err)
+	}
+	if got := replayed.bibbedEntries[1].NetDuration(); got != amy.NetDuration() {
+		t.Errorf("Expected the start crossing replayed, got a net time of %s", got)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// hostnames are the names the race is served under, RACERGOHOSTNAME then any RACERGOHOSTALIASES
+func hostnames() []string {
+	return append([]string{config.webserverHostname}, config.hostAliases...)
+}
+
+func stripPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// allowedHost is true for any host unless RACERGORESTRICTHOSTS is set, then only for the race's hostnames.
+// Ports are ignored so the same names work whether the race is on port 80 or 8080.
+func allowedHost(r *http.Request) bool {
+	if !config.restrictHosts {
+		return true
+	}
+	host := stripPort(requestHost(r))
+	for _, name := range hostnames() {
+		if strings.EqualFold(stripPort(name), host) {
+			return true
+		}
+	}
+	return false
+}
+
+// handle registers the handler for the path whatever host it's reached by, so volunteers can type
+// the laptop's IP, logging the requests that change anything
+func handle(path string, h http.Handler) {
+	http.Handle(path, logRequests(h))
+}
+
+// clientIP is the address of whoever made the request, taken from X-Forwarded-For when behind a trusted proxy
+func clientIP(r *http.Request) string {
+	if config.trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// requestScheme is http or https as the browser sees it, which a trusted proxy passes in X-Forwarded-Proto
+func requestScheme(r *http.Request) string {
+	if config.trustProxy {
+		if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+			return proto
+		}
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// requestHost is the host the browser asked for, which a trusted proxy passes in X-Forwarded-Host
+func requestHost(r *http.Request) string {
+	if config.trustProxy {
+		if host := r.Header.Get("X-Forwarded-Host"); host != "" {
+			return host
+		}
+	}
+	return r.Host
+}
+
+func logRequests(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowedHost(r) {
+			redirectToHostname(w, r)
+			return
+		}
+		if !authorized(w, r) || !withinRateLimit(w, r) {
+			return
+		}
+		if r.Method != "GET" && r.Method != "HEAD" {
+			if user := requestUser(r); user != "" {
+				log.Printf("%s %s from %s by %s", r.Method, r.URL.Path, clientIP(r), user)
+			} else {
+				log.Printf("%s %s from %s", r.Method, r.URL.Path, clientIP(r))
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// redirectToHostname sends requests for a host that isn't allowed to the same page on RACERGOHOSTNAME
+func redirectToHostname(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Redirecting %s%s from %s to %s", r.Host, r.URL.Path, clientIP(r), config.webserverHostname)
+	http.Redirect(w, r, requestScheme(r)+"://"+config.webserverHostname+r.URL.RequestURI(), 307)
+}
+
+// listen opens every address in RACERGOLISTEN, or port 80 falling back to 8080 if none are set.
+// Addresses without an IP listen on both IPv4 and IPv6, use [::]:80 or 0.0.0.0:80 for just one.
+func listen() ([]net.Listener, error) {
+	if len(config.listenAddrs) == 0 {
+		listener, err := net.Listen("tcp", ":80")
+		if err != nil {
+			log.Printf("Error listening on port 80, trying 8080 instead! - %s\n", err)
+			listener, err = net.Listen("tcp", ":8080")
+			if err != nil {
+				return nil, err
+			}
+		}
+		return []net.Listener{listener}, nil
+	}
+	listeners := make([]net.Listener, 0, len(config.listenAddrs))
+	for _, addr := range config.listenAddrs {
+		network := "tcp"
+		if strings.HasPrefix(addr, "[") {
+			network = "tcp6"
+		} else if host, _, err := net.SplitHostPort(addr); err == nil && net.ParseIP(host).To4() != nil {
+			network = "tcp4"
+		}
+		listener, err := net.Listen(network, addr)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
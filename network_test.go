@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProxyHeaders(t *testing.T) {
+	defer func(trust bool) { config.trustProxy = trust }(config.trustProxy)
+	r, _ := http.NewRequest("GET", "/results?x=1", nil)
+	r.RemoteAddr = "[fe80::1]:5123"
+	r.Host = "10.0.0.2"
+	r.Header.Set("X-Forwarded-For", "203.0.113.9, 10.0.0.1")
+	r.Header.Set("X-Forwarded-Proto", "https")
+	r.Header.Set("X-Forwarded-Host", "results.example.com")
+
+	config.trustProxy = false
+	if ip, scheme, host := clientIP(r), requestScheme(r), requestHost(r); ip != "fe80::1" || scheme != "http" || host != "10.0.0.2" {
+		t.Errorf("Expected forwarded headers ignored without a trusted proxy, got %s %s %s", ip, scheme, host)
+	}
+	config.trustProxy = true
+	if ip, scheme, host := clientIP(r), requestScheme(r), requestHost(r); ip != "203.0.113.9" || scheme != "https" || host != "results.example.com" {
+		t.Errorf("Expected forwarded headers used behind a trusted proxy, got %s %s %s", ip, scheme, host)
+	}
+
+	w := httptest.NewRecorder()
+	redirectToHostname(w, r)
+	if location := w.Header().Get("Location"); location != "https://"+config.webserverHostname+"/results?x=1" {
+		t.Errorf("Expected a redirect to the same page on the race hostname, got %s", location)
+	}
+}
+
+func TestListen(t *testing.T) {
+	defer func(addrs []string) { config.listenAddrs = addrs }(config.listenAddrs)
+	config.listenAddrs = []string{"127.0.0.1:0", "localhost:0"}
+	listeners, err := listen()
+	if err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	for _, l := range listeners {
+		l.Close()
+	}
+	if len(listeners) != 2 {
+		t.Errorf("Expected 2 listeners, got %d", len(listeners))
+	}
+	config.listenAddrs = []string{"127.0.0.1:0", "not an address"}
+	if _, err := listen(); err == nil {
+		t.Errorf("Expected error listening on a bad address")
+	}
+}
+
+func TestAllowedHost(t *testing.T) {
+	defer func(restrict bool, aliases []string) {
+		config.restrictHosts, config.hostAliases = restrict, aliases
+	}(config.restrictHosts, config.hostAliases)
+	config.hostAliases = []string{"192.168.1.20"}
+	served := false
+	h := logRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { served = true }))
+	for _, test := range []struct {
+		host     string
+		restrict bool
+		served   bool
+	}{
+		{"10.0.0.7:8080", false, true},
+		{"10.0.0.7:8080", true, false},
+		{"192.168.1.20", true, true},
+		{stripPort(config.webserverHostname) + ":80", true, true},
+	} {
+		config.restrictHosts = test.restrict
+		served = false
+		r, _ := http.NewRequest("GET", "/admin", nil)
+		r.Host = test.host
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if served != test.served {
+			t.Errorf("Expected served %t for %s when restricted is %t, got %d", test.served, test.host, test.restrict, w.Code)
+		}
+		if !served && w.Code != 307 {
+			t.Errorf("Expected a redirect for %s, got %d", test.host, w.Code)
+		}
+	}
+}
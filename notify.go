@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Notification is a rendered e-mail or text as a racer would get it
+type Notification struct {
+	Name    string
+	SMS     bool
+	Subject string
+	Text    string
+	HTML    template.HTML
+}
+
+func (race *Race) lockedSurveyEmail(e Entry) (string, string) {
+	return fmt.Sprintf("%s Survey", race.lockedName()), fmt.Sprintf("Hi %s %s,\n\nThanks for running the %s!  Tell us how it went so we can make next year's race even better - %s", e.Fname, e.Lname, race.lockedName(), race.surveyLink(e.Bib))
+}
+
+func (race *Race) lockedWaitlistEmail(e Entry) (string, string) {
+	return fmt.Sprintf("%s Waitlist", race.lockedName()), fmt.Sprintf("Good news %s %s!  A spot has opened up in the %s and you've been moved off the waitlist.  We'll see you on race day!\n\nAdd the race day schedule to your calendar - %s", e.Fname, e.Lname, race.lockedName(), race.calendarURL())
+}
+
+func (race *Race) lockedResultsFinalEmail(e Entry, result string) (string, string) {
+	return fmt.Sprintf("%s Final Results", race.lockedName()), fmt.Sprintf("Hi %s %s,\n\nThanks for letting us know about your result.  The %s results are now final and yours is:\n\n%s\n\nSee the full results at %s", e.Fname, e.Lname, race.lockedName(), result, race.raceURL("/"))
+}
+
+func (race *Race) lockedCutoffSMS(text string) string {
+	return fmt.Sprintf("%s: %s", race.lockedName(), text)
+}
+
+// sampleFinisher is who the previews and test messages are addressed to
+var sampleFinisher = Entry{Bib: 123, Fname: "Sample", Lname: "Finisher", Age: 35}
+
+// lockedSampleResult is the sample finisher's results e-mail as the race would send it
+func (race *Race) lockedSampleResult() ResultSummary {
+	return ResultSummary{
+		Finisher:       Finisher{Bib: 123, Fname: "Sample", Lname: "Finisher", Duration: HumanDuration(45*time.Minute + 12*time.Second), Place: 3, Division: "35-39", DivisionPlace: 1, RaceName: race.lockedName()},
+		Standing:       "3rd overall and 1st in 35-39",
+		Pace:           "7:16/mi",
+		Prizes:         []string{"1st in Masters Women"},
+		ResultURL:      race.finisherURL(123),
+		CertificateURL: race.badgeURL(123),
+	}
+}
+
+// lockedPreviewNotifications renders every message racers can be sent, using the sample finisher
+func (race *Race) lockedPreviewNotifications() []Notification {
+	previews := []Notification{}
+	sampleResult := race.lockedSampleResult()
+	subject, text, html := resultsEmail(sampleResult)
+	previews = append(previews, Notification{Name: "Results", Subject: subject, Text: text, HTML: template.HTML(html)})
+	subject, text = race.lockedWaitlistEmail(sampleFinisher)
+	previews = append(previews, Notification{Name: "Off the waitlist", Subject: subject, Text: text})
+	subject, text = race.lockedSurveyEmail(sampleFinisher)
+	previews = append(previews, Notification{Name: "Survey", Subject: subject, Text: text})
+	subject, text = race.lockedResultsFinalEmail(sampleFinisher, fmt.Sprintf("%s, %s", sampleResult.Duration, sampleResult.Standing))
+	previews = append(previews, Notification{Name: "Results final, to racers who sent in a claim", Subject: subject, Text: text})
+	previews = append(previews, Notification{Name: "Course cutoff warning", SMS: true, Text: race.lockedCutoffSMS(fmt.Sprintf("Course closes in %s", humanMinutes(15*time.Minute)))})
+	return previews
+}
+
+// SendTestEmail sends the sample results e-mail straight to the address, skipping the queue so a provider error shows right away
+func (race *Race) SendTestEmail(to string) error {
+	if err := mailTransport.Configured(); err != nil {
+		return err
+	}
+	if _, err := mail.ParseAddress(to); err != nil {
+		return fmt.Errorf("%s is not an e-mail address - %v", to, err)
+	}
+	race.RLock()
+	subject, text, html := resultsEmail(race.lockedSampleResult())
+	race.RUnlock()
+	subject = "Test - " + subject
+	m := NewMail(to, subject, text)
+	m.HTML = html
+	return emailQueue.SendNow(to, subject, m)
+}
+
+// SendTestSMS texts the sample cutoff warning to the number, returning Twilio's error if it wasn't sent
+func (race *Race) SendTestSMS(to string) error {
+	if config.twilioAccountSID == "" || config.twilioFrom == "" {
+		return fmt.Errorf("Texting isn't configured, set RACERGOTWILIOACCOUNTSID, RACERGOTWILIOAUTHTOKEN and RACERGOTWILIOFROM")
+	}
+	race.RLock()
+	text := race.lockedCutoffSMS(fmt.Sprintf("Course closes in %s", humanMinutes(15*time.Minute)))
+	race.RUnlock()
+	return textSMS(to, "Test - "+text)
+}
+
+// testNotificationHandler sends a test e-mail and/or text to the admin, reporting back on the notifications page
+func testNotificationHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	email, phone := strings.TrimSpace(r.FormValue("email")), strings.TrimSpace(r.FormValue("phone"))
+	if email == "" && phone == "" {
+		showErrorForAdmin(w, r.Referer(), "Enter an e-mail address or phone number to send the test to")
+		return
+	}
+	sent := []string{}
+	if email != "" {
+		if err := race.SendTestEmail(email); err != nil {
+			showErrorForAdmin(w, r.Referer(), "Test e-mail not sent - %v", err)
+			return
+		}
+		sent = append(sent, email)
+	}
+	if phone != "" {
+		if err := race.SendTestSMS(phone); err != nil {
+			showErrorForAdmin(w, r.Referer(), "Test text not sent - %v", err)
+			return
+		}
+		sent = append(sent, phone)
+	}
+	http.Redirect(w, r, "/notifications?sent="+url.QueryEscape(strings.Join(sent, " and ")), 301)
+}
+
+// sendDirectorReports e-mails the race day archive and awards report to the race directors once the results are final
+func (race *Race) sendDirectorReports() {
+	if len(config.directorEmails) == 0 {
+		return
+	}
+	var archive, awards, records bytes.Buffer
+	if err := race.WriteArchive(&archive); err != nil {
+		log.Printf("Error writing the archive for the race directors - %v", err)
+		return
+	}
+	writer := csv.NewWriter(&awards)
+	race.RLock()
+	race.lockedWriteAwards(writer)
+	writer.Flush()
+	candidates := len(race.recordCandidates)
+	writer = csv.NewWriter(&records)
+	race.lockedWriteRecordCandidates(writer)
+	name := race.lockedName()
+	race.RUnlock()
+	writer.Flush()
+	text := fmt.Sprintf("The %s results were finalized at %s.  Attached are the official results archive and the awards report.", name, race.GetTime().Format("3:04 PM"))
+	if candidates > 0 {
+		text += fmt.Sprintf("\n\n%d finishes may have set a record, see records.csv for the paperwork.", candidates)
+	}
+	subject := fmt.Sprintf("%s Official Results", name)
+	for _, to := range config.directorEmails {
+		m := NewMail(to, subject, text)
+		m.Attach(archiveName(), archive.Bytes())
+		m.Attach("awards.csv", awards.Bytes())
+		if candidates > 0 {
+			m.Attach("records.csv", records.Bytes())
+		}
+		emailQueue.Enqueue(to, subject, m)
+	}
+	log.Printf("Sent the official results to %s", strings.Join(config.directorEmails, ", "))
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotificationPreview(t *testing.T) {
+	race := NewRace()
+	r, _ := http.NewRequest("GET", "/notifications", nil)
+	w := httptest.NewRecorder()
+	handler(w, r, race)
+	body := w.Body.String()
+	for _, expected := range []string{"Congratulations Sample Finisher!", "Course closes in 15 minutes", "Thanks for running the"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected the preview to contain %q - %s", expected, body)
+		}
+	}
+
+	user := config.sendgriduser
+	defer func() { config.sendgriduser = user }()
+	config.sendgriduser = SENDGRIDUSER
+	if err := race.SendTestEmail("me@example.com"); err == nil {
+		t.Errorf("Expected an error sending without e-mail configured")
+	}
+	config.sendgriduser = "racer"
+	queue := emailQueue
+	defer func() { emailQueue = queue }()
+	emailQueue = NewMailQueue(60, 0)
+	emailQueue.now = func() time.Time { return time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local) }
+	var delivered *Mail
+	emailQueue.deliver = func(m *Mail) error {
+		delivered = m
+		return nil
+	}
+	if err := race.SendTestEmail("not an address"); err == nil {
+		t.Errorf("Expected an error sending to a bad address")
+	}
+	if err := race.SendTestEmail("me@example.com"); err != nil || delivered == nil {
+		t.Fatalf("Expected the test e-mail delivered - %v", err)
+	}
+	if status := emailQueue.Status(); status.SentToday != 1 || status.Recent[0].To != "me@example.com" {
+		t.Errorf("Expected the test e-mail on the dashboard, got %#v", status)
+	}
+}
+
+func TestDirectorReports(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	if err := race.AddEntry(Entry{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 28}); err != nil {
+		t.Fatalf("Error adding entry - %v", err)
+	}
+	race.SetPrizes([]Prize{{Title: "Women's Overall", HighAge: 100, Gender: "F", Amount: 1}})
+	startRace(race)
+	*race.testingTime = race.testingTime.Add(20 * time.Minute)
+	linkBibTesting(t, race, 1, false)
+	linkBibTesting(t, race, 1, false)
+
+	var awards bytes.Buffer
+	writer := csv.NewWriter(&awards)
+	race.RLock()
+	race.lockedWriteAwards(writer)
+	race.RUnlock()
+	writer.Flush()
+	if expected := "Prize,Place,Bib,Fname,Lname,Age,Duration\nWomen's Overall,1,1,Amy,Brown,28,00:20:00.00\n"; awards.String() != expected {
+		t.Errorf("Expected awards report %q, got %q", expected, awards.String())
+	}
+
+	directors := config.directorEmails
+	defer func() { config.directorEmails = directors }()
+	config.directorEmails = []string{"director@example.com", "timer@example.com"}
+	queue := emailQueue
+	defer func() { emailQueue = queue }()
+	emailQueue = NewMailQueue(0, 0)
+	delivered := make(chan *Mail, 2)
+	emailQueue.deliver = func(m *Mail) error {
+		delivered <- m
+		return nil
+	}
+	for x := 0; race.ReviewAnomaly(x, false) == nil; x++ {
+	}
+	if err := race.Finalize(false); err != nil {
+		t.Fatalf("Unexpected error finalizing - %v", err)
+	}
+	for x := 0; x < 2; x++ {
+		select {
+		case <-delivered:
+		case <-time.After(time.Second):
+			t.Fatalf("Expected both race directors e-mailed, got %d", x)
+		}
+	}
+	if status := emailQueue.Status(); len(status.Recent) != 2 || status.Recent[1].To != "director@example.com" {
+		t.Errorf("Expected the official results sent to the directors, got %#v", status.Recent)
+	}
+}
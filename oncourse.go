@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OnCourse is a racer who hasn't finished, with the last place they were seen
+type OnCourse struct {
+	*Entry
+	CheckedIn      time.Time
+	LastCheckpoint *Passing         // nil if they haven't been reported at a checkpoint
+	Position       *TrackerPosition // nil if they don't have a satellite tracker or it hasn't reported
+}
+
+// CheckInRacer records the racer as picked up their bib and at the start, so they're counted as on the course
+func (race *Race) CheckInRacer(bib Bib) error {
+	race.Lock()
+	defer race.Unlock()
+	entry, ok := race.bibbedEntries[bib]
+	if !ok {
+		return fmt.Errorf("Bib %d not found", bib)
+	}
+	if !entry.Registration.HasSpot() {
+		return fmt.Errorf("Bib #%d is %s and doesn't have a spot in the race", bib, entry.Registration)
+	}
+	if race.checkedIn == nil {
+		race.checkedIn = make(map[Bib]time.Time)
+	}
+	if _, ok := race.checkedIn[bib]; !ok {
+		race.checkedIn[bib] = race.GetTime()
+		log.Printf("Checked in bib #%d", bib)
+	}
+	return nil
+}
+
+// AccountFor takes the racer off the still on course list without a finish, e.g. they dropped out and got a ride back
+func (race *Race) AccountFor(bib Bib, note string) error {
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return fmt.Errorf("Note how bib #%d was accounted for", bib)
+	}
+	race.Lock()
+	defer race.Unlock()
+	if _, ok := race.bibbedEntries[bib]; !ok {
+		return fmt.Errorf("Bib %d not found", bib)
+	}
+	if race.accountedFor == nil {
+		race.accountedFor = make(map[Bib]string)
+	}
+	race.accountedFor[bib] = note
+	log.Printf("Bib #%d accounted for - %s", bib, note)
+	return nil
+}
+
+// lockedStillOnCourse lists the racers known to have started, by check in or a checkpoint report, who haven't finished
+// or been accounted for, along with how many racers with a spot never checked in.  If nobody has been checked in,
+// every racer with a spot who hasn't finished is listed since there's no telling who started.
+func (race *Race) lockedStillOnCourse() ([]OnCourse, int) {
+	lastSeen := make(map[Bib]*Passing)
+	for _, p := range race.passings {
+		if last, ok := lastSeen[p.Bib]; !ok || p.Time.After(last.Time) {
+			lastSeen[p.Bib] = p
+		}
+	}
+	var onCourse []OnCourse
+	notCheckedIn := 0
+	for _, e := range race.lockedOnCourse() {
+		checkedIn, ok := race.checkedIn[e.Bib]
+		if !ok && lastSeen[e.Bib] == nil && len(race.checkedIn) > 0 {
+			notCheckedIn++
+			continue
+		}
+		oc := OnCourse{Entry: e, CheckedIn: checkedIn, LastCheckpoint: lastSeen[e.Bib]}
+		if tp, ok := race.positions[e.Bib]; ok {
+			oc.Position = &tp
+		}
+		onCourse = append(onCourse, oc)
+	}
+	return onCourse, notCheckedIn
+}
+
+func checkInRacerHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	bib, err := strconv.Atoi(r.FormValue("bib"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting bib number", err)
+		return
+	}
+	if err = race.CheckInRacer(Bib(bib)); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/onCourse", 301)
+}
+
+func accountForHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	bib, err := strconv.Atoi(r.FormValue("bib"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting bib number", err)
+		return
+	}
+	if err = race.AccountFor(Bib(bib), r.FormValue("note")); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/onCourse", 301)
+}
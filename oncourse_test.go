@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStillOnCourse(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 8, 45, 0, 0, time.Local)
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38},
+		{Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 31},
+		{Bib: 3, Fname: "Cal", Lname: "Cole", Male: true, Age: 52},
+		{Bib: 4, Fname: "Dee", Lname: "Dunn", Age: 44},
+		{Bib: 5, Fname: "Eve", Lname: "Earl", Age: 29, Registration: Waitlisted},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	race.RLock()
+	onCourse, _ := race.lockedStillOnCourse()
+	race.RUnlock()
+	if len(onCourse) != 4 {
+		t.Errorf("Expected everyone with a spot listed before anyone checks in, got %d", len(onCourse))
+	}
+	for _, bib := range []Bib{1, 2} {
+		if err := race.CheckInRacer(bib); err != nil {
+			t.Fatalf("Unexpected error - %v", err)
+		}
+	}
+	if err := race.CheckInRacer(5); err == nil {
+		t.Errorf("Expected error checking in a waitlisted racer")
+	}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	startRace(race)
+	*race.testingTime = race.testingTime.Add(10 * time.Minute)
+	race.Lock()
+	for _, p := range []Passing{{Checkpoint: "CP1", Bib: 2}, {Checkpoint: "CP1", Bib: 3}} {
+		p.Time = race.GetTime()
+		if err := race.lockedRecordPassing(p); err != nil {
+			t.Fatalf("Unexpected error - %v", err)
+		}
+	}
+	race.Unlock()
+	*race.testingTime = race.testingTime.Add(10 * time.Minute)
+	linkBibTesting(t, race, 1, false)
+	if err := race.AccountFor(3, ""); err == nil {
+		t.Errorf("Expected error accounting for a racer without a note")
+	}
+
+	race.RLock()
+	onCourse, notCheckedIn := race.lockedStillOnCourse()
+	race.RUnlock()
+	// Amy finished, Cal was seen at CP1 without checking in, Dee never checked in
+	if len(onCourse) != 2 || onCourse[0].Bib != 2 || onCourse[1].Bib != 3 || notCheckedIn != 1 {
+		t.Fatalf("Expected Bob and Cal still out with Dee not checked in, got %v and %d", onCourse, notCheckedIn)
+	}
+	if onCourse[0].LastCheckpoint == nil || onCourse[0].LastCheckpoint.Checkpoint != "CP1" || onCourse[1].CheckedIn.IsZero() == false {
+		t.Errorf("Expected Bob last seen at CP1 and Cal never checked in, got %#v", onCourse)
+	}
+
+	if err := race.AccountFor(3, "Dropped at CP1, got a ride back"); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	r, _ := http.NewRequest("GET", "/onCourse", nil)
+	w := httptest.NewRecorder()
+	handler(w, r, race)
+	body := w.Body.String()
+	if !strings.Contains(body, "CP1 at 00:10:00.00") || !strings.Contains(body, "Bib #3 - Dropped at CP1") || strings.Contains(body, "<td>Cal Cole</td>") {
+		t.Errorf("Expected Bob still out and Cal accounted for - %s", body)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/mzimmerman/racergo/internal/core"
+)
+
+// maxEventCompetitors is how many of the top finishers are listed in the results page's structured data, search
+// engines only show a few and the whole field would bloat every page load
+const maxEventCompetitors = 10
+
+// iofResultList is an IOF XML 3.0 ResultList, the format orienteering and timing software exchange results in,
+// see https://orienteering.sport/iof/it/data-standard-3-0/
+type iofResultList struct {
+	XMLName    xml.Name         `xml:"http://www.orienteering.org/datastandard/3.0 ResultList"`
+	IOFVersion string           `xml:"iofVersion,attr"`
+	CreateTime string           `xml:"createTime,attr"`
+	Creator    string           `xml:"creator,attr"`
+	Status     string           `xml:"status,attr"`
+	Event      iofEvent         `xml:"Event"`
+	Classes    []iofClassResult `xml:"ClassResult"`
+}
+
+type iofEvent struct {
+	Name      string          `xml:"Name"`
+	StartTime *iofDateAndTime `xml:"StartTime,omitempty"`
+}
+
+type iofDateAndTime struct {
+	Date string `xml:"Date"`
+	Time string `xml:"Time"`
+}
+
+type iofClassResult struct {
+	Class   iofClass          `xml:"Class"`
+	Results []iofPersonResult `xml:"PersonResult"`
+}
+
+type iofClass struct {
+	Name string `xml:"Name"`
+}
+
+type iofPersonResult struct {
+	Person iofPerson `xml:"Person"`
+	Result iofResult `xml:"Result"`
+}
+
+type iofPerson struct {
+	Sex  string  `xml:"sex,attr"`
+	Name iofName `xml:"Name"`
+}
+
+type iofName struct {
+	Family string `xml:"Family"`
+	Given  string `xml:"Given"`
+}
+
+// iofResult is in the element order the schema requires
+type iofResult struct {
+	BibNumber  string         `xml:"BibNumber,omitempty"`
+	StartTime  string         `xml:"StartTime,omitempty"`
+	FinishTime string         `xml:"FinishTime,omitempty"`
+	Time       string         `xml:"Time,omitempty"`
+	Position   Place          `xml:"Position,omitempty"`
+	Status     string         `xml:"Status"`
+	SplitTimes []iofSplitTime `xml:"SplitTime"`
+}
+
+type iofSplitTime struct {
+	ControlCode string `xml:"ControlCode"`
+	Time        string `xml:"Time"`
+}
+
+func iofSeconds(d HumanDuration) string {
+	return strconv.FormatFloat(time.Duration(d).Seconds(), 'f', -1, 64)
+}
+
+// iofStatus is the entry's competitor status in IOF terms
+func iofStatus(e *Entry) string {
+	switch e.Status {
+	case StatusDNS:
+		return "DidNotStart"
+	case StatusDNF:
+		return "DidNotFinish"
+	case StatusDQ:
+		return "Disqualified"
+	}
+	return "OK"
+}
+
+// lockedIOFResultList has a class for each division with the racers who finished or were marked DNS, DNF or DQ,
+// racers still on the course are left out until they have a result.  The list is a snapshot until the results
+// are finalized.
+func (race *Race) lockedIOFResultList() iofResultList {
+	list := iofResultList{IOFVersion: "3.0", CreateTime: race.GetTime().Format(time.RFC3339), Creator: "racergo", Status: "Snapshot", Event: iofEvent{Name: race.lockedName()}}
+	if race.finalized {
+		list.Status = "Complete"
+	}
+	if !race.started.IsZero() {
+		list.Event.StartTime = &iofDateAndTime{Date: race.started.Format("2006-01-02"), Time: race.started.Format("15:04:05Z07:00")}
+	}
+	divisionPlaces := race.lockedDivisionPlaces()
+	classes := make(map[string]*iofClassResult)
+	for _, e := range race.allEntries {
+		if !e.HasFinished() && e.Status == StatusOK {
+			continue
+		}
+		division := race.lockedDivisionOf(e)
+		class, ok := classes[division]
+		if !ok {
+			class = &iofClassResult{Class: iofClass{Name: division}}
+			classes[division] = class
+		}
+		result := iofResult{Status: iofStatus(e)}
+		if e.Bib >= 0 {
+			result.BibNumber = e.Bib.String()
+		}
+		if e.HasFinished() {
+			result.StartTime = race.lockedStartOf(e).Format(time.RFC3339)
+			result.FinishTime = e.TimeFinished.Format(time.RFC3339)
+			result.Time = iofSeconds(e.NetDuration())
+			for x, split := range e.Splits {
+				result.SplitTimes = append(result.SplitTimes, iofSplitTime{ControlCode: fmt.Sprintf("LAP%d", x+1), Time: iofSeconds(split)})
+			}
+		}
+		if e.Ranked() {
+			result.Position = divisionPlaces[e]
+		}
+		class.Results = append(class.Results, iofPersonResult{Person: iofPerson{Sex: core.Gender(e.Male), Name: iofName{Family: e.Lname, Given: e.Fname}}, Result: result})
+	}
+	names := make([]string, 0, len(classes))
+	for name := range classes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		list.Classes = append(list.Classes, *classes[name])
+	}
+	return list
+}
+
+// lockedWriteIOF writes the results as an IOF XML ResultList
+func (race *Race) lockedWriteIOF(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(race.lockedIOFResultList())
+}
+
+// lockedSportsEvent describes the race as a schema.org SportsEvent for search engines, with the top finishers
+func (race *Race) lockedSportsEvent() map[string]interface{} {
+	event := map[string]interface{}{
+		"@context": "https://schema.org",
+		"@type":    "SportsEvent",
+		"name":     race.lockedName(),
+		"sport":    "Running",
+		"url":      race.raceURL("/"),
+	}
+	if start := race.started; !start.IsZero() {
+		event["startDate"] = start.Format(time.RFC3339)
+	} else if !config.raceDate.IsZero() {
+		event["startDate"] = config.raceDate.Format("2006-01-02")
+	}
+	competitors := []map[string]interface{}{}
+	for _, row := range race.lockedFinishers() {
+		if len(competitors) == maxEventCompetitors {
+			break
+		}
+		competitors = append(competitors, map[string]interface{}{
+			"@type": "Person",
+			"name":  row.Fname + " " + row.Lname,
+			"url":   race.raceURL(fmt.Sprintf("/finisher?bib=%d", row.Bib)),
+		})
+	}
+	if len(competitors) > 0 {
+		event["competitor"] = competitors
+	}
+	return event
+}
+
+func iofHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"%s-results.iof.xml\"", config.webserverHostname))
+	race.RLock()
+	defer race.RUnlock()
+	race.lockedWriteIOF(w)
+}
+
+func jsonLDHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	race.RLock()
+	event := race.lockedSportsEvent()
+	race.RUnlock()
+	w.Header().Set("Content-Type", "application/ld+json")
+	json.NewEncoder(w).Encode(event)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIOFResultList(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38}, {Bib: 2, Fname: "Bea", Lname: "Adams", Age: 31}, {Bib: 3, Fname: "Cal", Lname: "Cole", Male: true, Age: 44}, {Bib: 4, Fname: "Dan", Lname: "Dunn", Male: true, Age: 40}} {
+		race.AddEntry(e)
+	}
+	startRace(race)
+	for x, bib := range []int{2, 1} {
+		*race.testingTime = race.started.Add(time.Duration(20+x) * time.Minute)
+		linkBibTesting(t, race, bib, false)
+		linkBibTesting(t, race, bib, false)
+	}
+	race.SetStatus(3, StatusDNF)
+
+	var buf bytes.Buffer
+	race.RLock()
+	race.lockedWriteIOF(&buf)
+	race.RUnlock()
+	var list iofResultList
+	if err := xml.Unmarshal(buf.Bytes(), &list); err != nil {
+		t.Fatalf("Error reading the IOF XML - %v\n%s", err, buf.String())
+	}
+	if list.Status != "Snapshot" || list.Event.StartTime == nil || list.Event.StartTime.Date != "2014-06-01" {
+		t.Errorf("Wrong list details - %#v", list)
+	}
+	got := map[string][]string{}
+	for _, class := range list.Classes {
+		for _, pr := range class.Results {
+			got[class.Class.Name] = append(got[class.Class.Name], pr.Person.Name.Given+" "+pr.Result.Status+" "+pr.Result.Position.String()+" "+pr.Result.Time)
+		}
+	}
+	if women, men := strings.Join(got["F30-39"], ", "), strings.Join(got["M40-49"], ", "); women != "Bea OK 1 1200, Amy OK 2 1260" || men != "Cal DidNotFinish -- " {
+		t.Errorf("Wrong results - %v", got)
+	}
+	if strings.Contains(buf.String(), "Dan") {
+		t.Errorf("Expected Dan left out while he's on the course")
+	}
+	if !strings.Contains(buf.String(), `<ResultList xmlns="http://www.orienteering.org/datastandard/3.0" iofVersion="3.0"`) {
+		t.Errorf("Expected an IOF 3.0 ResultList, got %s", buf.String())
+	}
+}
+
+func TestSportsEventJSONLD(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	race.AddEntry(Entry{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38})
+	startRace(race)
+	*race.testingTime = race.started.Add(20 * time.Minute)
+	linkBibTesting(t, race, 1, false)
+	linkBibTesting(t, race, 1, false)
+
+	r, _ := http.NewRequest("GET", "/results.jsonld", nil)
+	w := httptest.NewRecorder()
+	jsonLDHandler(w, r, race)
+	var event map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &event); err != nil {
+		t.Fatalf("Error reading the JSON-LD - %v", err)
+	}
+	competitors, _ := event["competitor"].([]interface{})
+	if event["@type"] != "SportsEvent" || len(competitors) != 1 || competitors[0].(map[string]interface{})["name"] != "Amy Brown" {
+		t.Errorf("Wrong event - %v", event)
+	}
+
+	get := func() string {
+		r, _ := http.NewRequest("GET", "/", nil)
+		w := httptest.NewRecorder()
+		handler(w, r, race)
+		return w.Body.String()
+	}
+	if strings.Contains(get(), "application/ld+json") {
+		t.Errorf("Expected the structured data left off until the results are final")
+	}
+	for x := 0; race.ReviewAnomaly(x, false) == nil; x++ {
+	}
+	if err := race.Finalize(false); err != nil {
+		t.Fatalf("Error finalizing - %v", err)
+	}
+	if page := get(); !strings.Contains(page, `<script type="application/ld+json">{"@context":"https://schema.org","@type":"SportsEvent"`) {
+		t.Errorf("Expected the structured data on the final results, got %s", page)
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PaperImport is a manual backup being reviewed before it's applied: a sheet of finish times
+// written down in order, and the pull tags torn off the bibs in the order runners came through the chute
+type PaperImport struct {
+	Times []HumanDuration
+	Bibs  []Bib
+}
+
+// PaperRow is one line of the zipped sheets, a missing time or bib is zero or NoBib
+type PaperRow struct {
+	Position int
+	Time     HumanDuration
+	Bib      Bib
+	Problem  string
+}
+
+// parsePaperTime reads a hand written elapsed time like 19:42, 19:42.3 or 1:02:15
+func parsePaperTime(val string) (HumanDuration, error) {
+	parts := strings.Split(strings.TrimSpace(val), ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("%s is not a time, use MM:SS or H:MM:SS", val)
+	}
+	seconds, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil || seconds < 0 || seconds >= 60 {
+		return 0, fmt.Errorf("%s is not a time, use MM:SS or H:MM:SS", val)
+	}
+	duration := time.Duration(seconds * float64(time.Second))
+	for x, unit := range []time.Duration{time.Minute, time.Hour} {
+		if x+2 > len(parts) {
+			break
+		}
+		n, err := strconv.Atoi(parts[len(parts)-2-x])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("%s is not a time, use MM:SS or H:MM:SS", val)
+		}
+		duration += time.Duration(n) * unit
+	}
+	return HumanDuration(duration), nil
+}
+
+// paperColumn reads the first column of a sheet, skipping blank lines and a header row
+func paperColumn(data []byte, parse func(string) error) error {
+	rows, _, err := readCSV(data)
+	if err != nil {
+		return err
+	}
+	for x, row := range rows {
+		if len(row) == 0 || strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+		if err = parse(row[0]); err != nil {
+			if x == 0 {
+				continue // header
+			}
+			return fmt.Errorf("Line %d - %v", x+1, err)
+		}
+	}
+	return nil
+}
+
+func parsePaperImport(times, bibs []byte) (*PaperImport, error) {
+	pi := &PaperImport{}
+	err := paperColumn(times, func(val string) error {
+		t, err := parsePaperTime(val)
+		if err == nil {
+			pi.Times = append(pi.Times, t)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Times sheet %v", err)
+	}
+	err = paperColumn(bibs, func(val string) error {
+		bib, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(val), "#"))
+		if err != nil || bib < 0 {
+			return fmt.Errorf("%s is not a bib number", val)
+		}
+		pi.Bibs = append(pi.Bibs, Bib(bib))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Bibs sheet %v", err)
+	}
+	return pi, nil
+}
+
+// lockedPaperRows zips the sheets together, describing anything that needs a look before applying
+func (race *Race) lockedPaperRows(pi *PaperImport) []PaperRow {
+	if pi == nil {
+		return nil
+	}
+	count := len(pi.Times)
+	if len(pi.Bibs) > count {
+		count = len(pi.Bibs)
+	}
+	rows := make([]PaperRow, count)
+	for x := range rows {
+		row := PaperRow{Position: x + 1, Bib: NoBib}
+		if x < len(pi.Times) {
+			row.Time = pi.Times[x]
+		}
+		if x < len(pi.Bibs) {
+			row.Bib = pi.Bibs[x]
+		}
+		switch entry, ok := race.bibbedEntries[row.Bib]; {
+		case row.Time == 0:
+			row.Problem = "No time"
+		case row.Bib == NoBib:
+			row.Problem = "No bib"
+		case !ok:
+			row.Problem = fmt.Sprintf("Bib #%d not found", row.Bib)
+		case entry.HasFinished():
+			row.Problem = fmt.Sprintf("Already finished in %s", entry.Duration)
+		}
+		rows[x] = row
+	}
+	return rows
+}
+
+// Edit lines the sheets back up where a time or pull tag was missed or doubled,
+// removing or inserting a blank time or bib at the position
+func (pi *PaperImport) Edit(action string, position int) error {
+	x := position - 1
+	switch action {
+	case "deleteTime", "insertTime":
+		if x < 0 || x > len(pi.Times) || (action == "deleteTime" && x == len(pi.Times)) {
+			return fmt.Errorf("No time at position %d", position)
+		}
+		if action == "deleteTime" {
+			pi.Times = append(pi.Times[:x], pi.Times[x+1:]...)
+		} else {
+			pi.Times = append(pi.Times[:x], append([]HumanDuration{0}, pi.Times[x:]...)...)
+		}
+	case "deleteBib", "insertBib":
+		if x < 0 || x > len(pi.Bibs) || (action == "deleteBib" && x == len(pi.Bibs)) {
+			return fmt.Errorf("No bib at position %d", position)
+		}
+		if action == "deleteBib" {
+			pi.Bibs = append(pi.Bibs[:x], pi.Bibs[x+1:]...)
+		} else {
+			pi.Bibs = append(pi.Bibs[:x], append([]Bib{NoBib}, pi.Bibs[x:]...)...)
+		}
+	default:
+		return fmt.Errorf("Unknown paper backup action %s", action)
+	}
+	return nil
+}
+
+func (race *Race) EditPaperImport(action string, position int) error {
+	race.Lock()
+	defer race.Unlock()
+	if race.paperImport == nil {
+		return fmt.Errorf("No paper backup has been uploaded")
+	}
+	return race.paperImport.Edit(action, position)
+}
+
+// ApplyPaperImport gives every runner on the sheets without a finish time their paper time from their wave's start,
+// rows with a problem are left alone.  The rows are checked before any are applied and then applied as one batch,
+// so nothing is recorded if one can't be.  Returns how many times were recorded.
+func (race *Race) ApplyPaperImport() (int, error) {
+	race.Lock()
+	defer race.Unlock()
+	if race.paperImport == nil {
+		return 0, fmt.Errorf("No paper backup has been uploaded")
+	}
+	if race.started.IsZero() {
+		return 0, fmt.Errorf("Race has not started yet, cannot record paper times")
+	}
+	ops := []BatchOp{}
+	positions := []int{}
+	seen := make(map[Bib]int)
+	for _, row := range race.lockedPaperRows(race.paperImport) {
+		if row.Problem != "" {
+			continue
+		}
+		if first, ok := seen[row.Bib]; ok {
+			return 0, fmt.Errorf("Bib #%d is on the sheets at %d and %d", row.Bib, first, row.Position)
+		}
+		seen[row.Bib] = row.Position
+		ops = append(ops, BatchOp{Op: "setTime", Bib: row.Bib, Duration: row.Time.String(), Confirm: true})
+		positions = append(positions, row.Position)
+	}
+	if len(ops) > 0 {
+		if batchErr := race.lockedApplyBatch(ops); batchErr != nil {
+			if batchErr.Operation < 0 {
+				return 0, fmt.Errorf("%s", batchErr.Error)
+			}
+			return 0, fmt.Errorf("Position %d - %s", positions[batchErr.Operation], batchErr.Error)
+		}
+	}
+	log.Printf("Applied %d times from the paper backup", len(ops))
+	race.paperImport = nil
+	return len(ops), nil
+}
+
+func readUpload(r *http.Request, name string) ([]byte, error) {
+	file, _, err := r.FormFile(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ioutil.ReadAll(file)
+}
+
+func uploadPaperBackupHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error getting Reader - %s", err)
+		return
+	}
+	times, err := readUpload(r, "times")
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error reading the times sheet - %v", err)
+		return
+	}
+	bibs, err := readUpload(r, "bibs")
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error reading the bibs sheet - %v", err)
+		return
+	}
+	pi, err := parsePaperImport(times, bibs)
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	race.Lock()
+	race.paperImport = pi
+	race.Unlock()
+	http.Redirect(w, r, "/paperBackup", 301)
+}
+
+func paperBackupActionHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	var err error
+	switch action := r.FormValue("action"); action {
+	case "apply":
+		_, err = race.ApplyPaperImport()
+	case "discard":
+		race.Lock()
+		race.paperImport = nil
+		race.Unlock()
+	default:
+		var position int
+		if position, err = strconv.Atoi(r.FormValue("position")); err == nil {
+			err = race.EditPaperImport(action, position)
+		}
+	}
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/paperBackup", 301)
+}
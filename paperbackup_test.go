@@ -0,0 +1,121 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePaperTime(t *testing.T) {
+	for val, expected := range map[string]time.Duration{
+		"19:42":    19*time.Minute + 42*time.Second,
+		" 19:42.5": 19*time.Minute + 42500*time.Millisecond,
+		"1:02:15":  time.Hour + 2*time.Minute + 15*time.Second,
+	} {
+		if d, err := parsePaperTime(val); err != nil || d != HumanDuration(expected) {
+			t.Errorf("Expected %q to be %s, got %s - %v", val, expected, d, err)
+		}
+	}
+	for _, val := range []string{"1942", "19:75", "a:10", "1:2:3:4"} {
+		if _, err := parsePaperTime(val); err == nil {
+			t.Errorf("Expected error parsing %q", val)
+		}
+	}
+}
+
+func TestPaperImport(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 34},
+		{Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 41},
+		{Bib: 3, Fname: "Cal", Lname: "Cole", Male: true, Age: 29},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	if _, err := parsePaperImport([]byte("Time\n19:00\nbad\n"), []byte("1\n")); err == nil {
+		t.Errorf("Expected error for a bad time after the header")
+	}
+	// the timer missed nobody but the chute volunteer pulled 9 by mistake
+	pi, err := parsePaperImport([]byte("Time\n19:00\n\n20:30\n21:15\n"), []byte("Bib\n2\n9\n#1\n3\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	startRace(race)
+	*race.testingTime = race.testingTime.Add(25 * time.Minute)
+	linkBibTesting(t, race, 3, false)
+	race.paperImport = pi
+	rows := race.lockedPaperRows(race.paperImport)
+	if len(rows) != 4 || rows[1].Problem != "Bib #9 not found" || rows[3].Problem != "No time" {
+		t.Fatalf("Unexpected rows %v", rows)
+	}
+	if err = race.EditPaperImport("deleteBib", 2); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if err = race.EditPaperImport("deleteBib", 4); err == nil {
+		t.Errorf("Expected error deleting past the end")
+	}
+	rows = race.lockedPaperRows(race.paperImport)
+	if len(rows) != 3 || rows[0].Problem != "" || rows[1].Problem != "" || rows[2].Problem != "Already finished in 00:25:00.00" {
+		t.Fatalf("Unexpected rows after deleting the stray tag %v", rows)
+	}
+	r, _ := http.NewRequest("GET", "/paperBackup", nil)
+	w := httptest.NewRecorder()
+	handler(w, r, race)
+	if body := w.Body.String(); w.Code != http.StatusOK || !strings.Contains(body, "Already finished") || strings.Contains(body, "pull tags, insert") {
+		t.Errorf("Expected the review table without a count warning, got %d - %s", w.Code, body)
+	}
+	applied, err := race.ApplyPaperImport()
+	if err != nil || applied != 2 {
+		t.Fatalf("Expected 2 times applied, got %d - %v", applied, err)
+	}
+	if amy := race.bibbedEntries[1]; amy.Duration != HumanDuration(20*time.Minute+30*time.Second) || !amy.Confirmed {
+		t.Errorf("Expected Amy confirmed at 20:30, got %#v", amy)
+	}
+	if cal := race.bibbedEntries[3]; cal.Duration != HumanDuration(25*time.Minute) {
+		t.Errorf("Expected Cal's electronic time kept, got %s", cal.Duration)
+	}
+	if race.paperImport != nil {
+		t.Errorf("Expected the import cleared once applied")
+	}
+}
+
+func TestPaperImportAllOrNothing(t *testing.T) {
+	defer func(waves []string, starts []Wave) {
+		config.waves, config.waveStarts = waves, starts
+	}(config.waves, config.waveStarts)
+	config.waveStarts, _ = parseWaves([]string{"Elite@1-9", "Open=+10m@10-99"})
+	config.waves = []string{"Elite", "Open"}
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38}, {Bib: 10, Fname: "Bob", Lname: "Adams", Male: true, Age: 31}} {
+		race.AddEntry(e)
+	}
+	startRace(race)
+	var err error
+	// the chute volunteer pulled Amy's tag twice
+	if race.paperImport, err = parsePaperImport([]byte("19:00\n20:00\n21:00\n"), []byte("1\n10\n1\n")); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if _, err = race.ApplyPaperImport(); err == nil || !strings.Contains(err.Error(), "Bib #1") {
+		t.Errorf("Expected the doubled tag refused, got %v", err)
+	}
+	if race.bibbedEntries[1].HasFinished() || race.bibbedEntries[10].HasFinished() || race.paperImport == nil {
+		t.Fatalf("Expected nothing applied when a row is refused")
+	}
+	if err = race.EditPaperImport("deleteBib", 3); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if applied, err := race.ApplyPaperImport(); err != nil || applied != 2 {
+		t.Fatalf("Expected 2 times applied, got %d - %v", applied, err)
+	}
+	if bob := race.bibbedEntries[10]; bob.Duration != HumanDuration(20*time.Minute) || !bob.TimeFinished.Equal(race.started.Add(30*time.Minute)) || !bob.Confirmed {
+		t.Errorf("Expected Bob's paper time from the Open wave's start, got %#v", bob)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CameraTime is one finish read off the photo finish camera
+type CameraTime struct {
+	Bib  Bib
+	Lane string
+	Time HumanDuration
+}
+
+// CameraRow is a camera time next to the time recorded at the line, a Problem keeps it from being applied
+type CameraRow struct {
+	CameraTime
+	Name       string
+	Manual     HumanDuration
+	Difference HumanDuration
+	Problem    string
+	Disagrees  bool // the times differ by more than RACERGOCAMERATHRESHOLD, the camera time can still be used by hand
+}
+
+// cameraNonFinishes are the statuses a photo finish system writes in place of a time
+var cameraNonFinishes = map[string]bool{"DNF": true, "DNS": true, "DQ": true, "NT": true, "SCR": true}
+
+// parseCameraTimes reads a photo finish export.  A file with a header row naming Bib (or ID) and Time columns is
+// read by those, otherwise it's read as a FinishLynx .lif: an event line then Place,ID,Lane,Last,First,Affiliation,Time.
+func parseCameraTimes(data []byte) ([]CameraTime, error) {
+	rows, _, err := readCSVFields(data, -1) // a .lif event line is shorter than the results
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("Photo finish file is blank")
+	}
+	bibCol, laneCol, timeCol := 1, 2, 6 // FinishLynx .lif
+	columns := make(map[string]int)
+	for x, h := range rows[0] {
+		columns[strings.ToLower(strings.TrimSpace(h))] = x
+	}
+	if _, ok := columns["time"]; ok {
+		bibCol, laneCol, timeCol = -1, -1, columns["time"]
+		for _, name := range []string{"bib", "id"} {
+			if x, ok := columns[name]; ok {
+				bibCol = x
+			}
+		}
+		if x, ok := columns["lane"]; ok {
+			laneCol = x
+		}
+		if bibCol < 0 {
+			return nil, fmt.Errorf("Photo finish file has a Time column but no Bib or ID column")
+		}
+	}
+	times := []CameraTime{}
+	for x, row := range rows[1:] {
+		if len(row) <= bibCol || len(row) <= timeCol || strings.TrimSpace(row[bibCol]) == "" {
+			continue
+		}
+		val := strings.TrimSpace(row[timeCol])
+		if cameraNonFinishes[strings.ToUpper(val)] {
+			continue
+		}
+		bib, err := strconv.Atoi(strings.TrimSpace(row[bibCol]))
+		if err != nil || bib < 0 {
+			return nil, fmt.Errorf("Line %d - %s is not a bib number", x+2, row[bibCol])
+		}
+		t, err := parsePaperTime(val)
+		if err != nil {
+			return nil, fmt.Errorf("Line %d - %v", x+2, err)
+		}
+		ct := CameraTime{Bib: Bib(bib), Time: t}
+		if laneCol >= 0 && laneCol < len(row) {
+			ct.Lane = strings.TrimSpace(row[laneCol])
+		}
+		times = append(times, ct)
+	}
+	return times, nil
+}
+
+// lockedCameraRows compares the camera times with the recorded results
+func (race *Race) lockedCameraRows(times []CameraTime) []CameraRow {
+	rows := make([]CameraRow, 0, len(times))
+	for _, ct := range times {
+		row := CameraRow{CameraTime: ct}
+		entry, ok := race.bibbedEntries[ct.Bib]
+		if !ok {
+			row.Problem = fmt.Sprintf("Bib #%d not found", ct.Bib)
+			rows = append(rows, row)
+			continue
+		}
+		row.Name = entry.Fname + " " + entry.Lname
+		if entry.HasFinished() {
+			row.Manual = entry.Duration
+			row.Difference = ct.Time - entry.Duration
+			diff := row.Difference
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff > HumanDuration(config.cameraThreshold) {
+				row.Disagrees = true
+				row.Problem = fmt.Sprintf("Camera and line times differ by %s", diff)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// ApplyCameraImport gives every runner the camera time, except where the camera disagrees with the line or
+// the bib is unknown.  With bibs, only those runners get their camera time, disagreeing or not.
+func (race *Race) ApplyCameraImport(bibs ...Bib) (int, error) {
+	race.Lock()
+	defer race.Unlock()
+	if race.cameraImport == nil {
+		return 0, fmt.Errorf("No photo finish file has been uploaded")
+	}
+	only := make(map[Bib]bool)
+	for _, bib := range bibs {
+		only[bib] = true
+	}
+	ops := []BatchOp{}
+	for _, row := range race.lockedCameraRows(race.cameraImport) {
+		if (len(only) > 0 && (!only[row.Bib] || row.Name == "")) || (len(only) == 0 && row.Problem != "") || row.Time == row.Manual {
+			continue
+		}
+		ops = append(ops, BatchOp{Op: "setTime", Bib: row.Bib, Duration: row.Time.String()})
+	}
+	if len(ops) == 0 {
+		return 0, nil
+	}
+	if batchErr := race.lockedApplyBatch(ops); batchErr != nil {
+		if batchErr.Operation < 0 {
+			return 0, fmt.Errorf("%s", batchErr.Error)
+		}
+		return 0, fmt.Errorf("Bib #%d - %s", ops[batchErr.Operation].Bib, batchErr.Error)
+	}
+	log.Printf("Applied %d photo finish times", len(ops))
+	return len(ops), nil
+}
+
+func uploadPhotoFinishHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error getting Reader - %s", err)
+		return
+	}
+	data, err := readUpload(r, "camera")
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error reading the photo finish file - %v", err)
+		return
+	}
+	times, err := parseCameraTimes(data)
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	race.Lock()
+	race.cameraImport = times
+	race.Unlock()
+	http.Redirect(w, r, "/photoFinish", 301)
+}
+
+func photoFinishActionHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	var err error
+	switch r.FormValue("action") {
+	case "apply":
+		_, err = race.ApplyCameraImport()
+	case "useCamera":
+		var bib int
+		if bib, err = strconv.Atoi(r.FormValue("bib")); err == nil {
+			_, err = race.ApplyCameraImport(Bib(bib))
+		}
+	case "discard":
+		race.Lock()
+		race.cameraImport = nil
+		race.Unlock()
+	default:
+		err = fmt.Errorf("Unknown photo finish action %s", r.FormValue("action"))
+	}
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/photoFinish", 301)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPhotoFinish(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 28},
+		{Bib: 2, Fname: "Bea", Lname: "Adams", Age: 31},
+		{Bib: 3, Fname: "Cat", Lname: "Cole", Age: 52},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	startRace(race)
+	for _, bib := range []int{1, 2} {
+		*race.testingTime = race.testingTime.Add(20 * time.Minute)
+		linkBibTesting(t, race, bib, false)
+	}
+
+	lif := "1,1,1,5K Women\n" +
+		"1,1,3,Brown,Amy,,20:00.31\n" +
+		"2,2,4,Adams,Bea,,41:30.00\n" +
+		"3,3,5,Cole,Cat,,45:12.50\n" +
+		",9,6,Nobody,,,DNF\n" +
+		"4,7,7,Unknown,,,50:00.00\n"
+	times, err := parseCameraTimes([]byte(lif))
+	if err != nil || len(times) != 4 || times[0].Lane != "3" || times[0].Time != HumanDuration(20*time.Minute+310*time.Millisecond) {
+		t.Fatalf("Expected 4 camera times from the .lif, got %v - %v", times, err)
+	}
+	if csvTimes, err := parseCameraTimes([]byte("Time,Bib\n20:00.31,1\n")); err != nil || len(csvTimes) != 1 || csvTimes[0].Bib != 1 {
+		t.Errorf("Expected a camera time from the CSV, got %v - %v", csvTimes, err)
+	}
+	if _, err := parseCameraTimes([]byte("1,1,1,5K\n1,x,3,Brown,Amy,,20:00.31\n")); err == nil {
+		t.Errorf("Expected an error for a bad bib")
+	}
+
+	race.cameraImport = times
+	rows := race.lockedCameraRows(times)
+	if rows[0].Problem != "" || !rows[1].Disagrees || rows[2].Problem != "" || rows[3].Problem != "Bib #7 not found" {
+		t.Errorf("Expected Bea flagged a minute and a half off the line and bib 7 unknown, got %#v", rows)
+	}
+	r, _ := http.NewRequest("GET", "/photoFinish", nil)
+	w := httptest.NewRecorder()
+	handler(w, r, race)
+	if body := w.Body.String(); !strings.Contains(body, "Camera and line times differ by 00:01:30.00") {
+		t.Errorf("Expected the disagreement on the page - %s", body)
+	}
+
+	if applied, err := race.ApplyCameraImport(); err != nil || applied != 2 {
+		t.Fatalf("Expected Amy and Cat's camera times applied, got %d - %v", applied, err)
+	}
+	if race.bibbedEntries[1].Duration != HumanDuration(20*time.Minute+310*time.Millisecond) || race.bibbedEntries[3].Duration != HumanDuration(45*time.Minute+12*time.Second+500*time.Millisecond) {
+		t.Errorf("Expected the camera times preferred, got %s and %s", race.bibbedEntries[1].Duration, race.bibbedEntries[3].Duration)
+	}
+	if race.bibbedEntries[2].Duration != HumanDuration(40*time.Minute) {
+		t.Errorf("Expected Bea's disagreeing time left alone, got %s", race.bibbedEntries[2].Duration)
+	}
+	if applied, err := race.ApplyCameraImport(2); err != nil || applied != 1 || race.bibbedEntries[2].Duration != HumanDuration(41*time.Minute+30*time.Second) {
+		t.Errorf("Expected Bea's camera time used by hand, got %d %s - %v", applied, race.bibbedEntries[2].Duration, err)
+	}
+}
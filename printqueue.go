@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// printTimeout is how long a print can take before the printer is given up on, the racer is already registered or finished by then
+const printTimeout = 5 * time.Second
+
+// printer prints a document, ESC/POS printers are sent the escpos bytes and print bridges the document as JSON
+type printer func(escpos []byte, doc interface{}) error
+
+// parsePrinter reads RACERGOLABELPRINTER or RACERGOTICKETPRINTER, one of
+//
+//	escpos:<address>  an ESC/POS receipt or label printer's raw port, e.g. escpos:192.168.1.50:9100
+//	<url>             a local print bridge taking the document as JSON, e.g. http://localhost:8631/label for a DYMO
+func parsePrinter(spec string) (printer, error) {
+	if strings.HasPrefix(spec, "escpos:") {
+		addr := strings.TrimPrefix(spec, "escpos:")
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return nil, fmt.Errorf("%s is not a printer address like 192.168.1.50:9100 - %v", addr, err)
+		}
+		return escposPrinter(addr), nil
+	}
+	if strings.HasPrefix(spec, "http://") || strings.HasPrefix(spec, "https://") {
+		return bridgePrinter(spec), nil
+	}
+	return nil, fmt.Errorf("%s is not a printer, expected escpos:<address> or a print bridge URL", spec)
+}
+
+func escposPrinter(addr string) printer {
+	return func(escpos []byte, doc interface{}) error {
+		conn, err := net.DialTimeout("tcp", addr, printTimeout)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(printTimeout))
+		_, err = conn.Write(escpos)
+		return err
+	}
+}
+
+func bridgePrinter(url string) printer {
+	client := &http.Client{Timeout: printTimeout}
+	return func(escpos []byte, doc interface{}) error {
+		body, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			return fmt.Errorf("the print bridge answered %s", resp.Status)
+		}
+		return nil
+	}
+}
+
+// Ticket is the slip handed to a finisher at the line
+type Ticket struct {
+	Race          string        `json:"race"`
+	Place         Place         `json:"place"`
+	Bib           Bib           `json:"bib"`
+	Fname         string        `json:"fname"`
+	Lname         string        `json:"lname"`
+	Time          HumanDuration `json:"time"`          // the net time
+	Gun           HumanDuration `json:"gun,omitempty"` // the gun time when it differs from the net time
+	Division      string        `json:"division"`
+	DivisionPlace Place         `json:"divisionPlace"`
+}
+
+// escposTicket is the place in big type with the bib, name and time under it, then a cut
+func escposTicket(t Ticket) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("\x1b@")     // reset
+	buf.WriteString("\x1ba\x01") // center
+	fmt.Fprintf(&buf, "%s\n", t.Race)
+	buf.WriteString("\x1d!\x33") // quadruple size
+	fmt.Fprintf(&buf, "%s\n", t.Place.Ordinal())
+	buf.WriteString("\x1d!\x11") // double size
+	fmt.Fprintf(&buf, "%s\n", t.Time)
+	buf.WriteString("\x1d!\x00")
+	fmt.Fprintf(&buf, "#%d %s %s\n", t.Bib, t.Fname, t.Lname)
+	if t.Gun > 0 {
+		fmt.Fprintf(&buf, "Gun time %s\n", t.Gun)
+	}
+	if t.DivisionPlace > 0 {
+		fmt.Fprintf(&buf, "%s in %s\n", t.DivisionPlace.Ordinal(), t.Division)
+	}
+	buf.WriteString("Unofficial\n")
+	buf.WriteString("\x1dV\x42\x03") // feed a little and cut
+	return buf.Bytes()
+}
+
+// PrintJob is a ticket in the print queue, kept after it prints so it can be printed again
+type PrintJob struct {
+	ID      int
+	Ticket  Ticket
+	Queued  time.Time
+	Printed time.Time // zero until it prints
+	Error   string    // why the last try failed, it waits for a reprint
+	tried   bool
+}
+
+// PrintQueue prints the tickets one at a time in the order they were queued, a slow or jammed printer
+// holds up the queue but never the finish line
+type PrintQueue struct {
+	sync.Mutex
+	printer printer
+	jobs    []*PrintJob
+	wake    chan struct{}
+}
+
+// tickets is the finish ticket queue for RACERGOTICKETPRINTER, nil when there isn't a ticket printer
+var tickets *PrintQueue
+
+func NewPrintQueue(p printer) *PrintQueue {
+	q := &PrintQueue{printer: p, wake: make(chan struct{}, 1)}
+	go q.run()
+	return q
+}
+
+// Queue adds the ticket to the end of the queue, safe to call with the race locked
+func (q *PrintQueue) Queue(t Ticket) {
+	q.Lock()
+	q.jobs = append(q.jobs, &PrintJob{ID: len(q.jobs) + 1, Ticket: t, Queued: time.Now()})
+	q.Unlock()
+	q.signal()
+}
+
+// Reprint queues the job to print again
+func (q *PrintQueue) Reprint(id int) error {
+	q.Lock()
+	defer q.Unlock()
+	if id < 1 || id > len(q.jobs) {
+		return fmt.Errorf("There's no ticket %d", id)
+	}
+	job := q.jobs[id-1]
+	job.tried, job.Error = false, ""
+	q.signal()
+	return nil
+}
+
+// Jobs is a copy of every ticket queued, newest first
+func (q *PrintQueue) Jobs() []PrintJob {
+	q.Lock()
+	defer q.Unlock()
+	jobs := make([]PrintJob, len(q.jobs))
+	for x, job := range q.jobs {
+		jobs[len(jobs)-1-x] = *job
+	}
+	return jobs
+}
+
+func (q *PrintQueue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *PrintQueue) next() *PrintJob {
+	q.Lock()
+	defer q.Unlock()
+	for _, job := range q.jobs {
+		if !job.tried {
+			job.tried = true
+			return job
+		}
+	}
+	return nil
+}
+
+func (q *PrintQueue) run() {
+	for range q.wake {
+		for job := q.next(); job != nil; job = q.next() {
+			err := q.printer(escposTicket(job.Ticket), job.Ticket)
+			q.Lock()
+			if err != nil {
+				job.Error = err.Error()
+				log.Printf("Error printing the finish ticket for bib #%d - %v", job.Ticket.Bib, err)
+			} else {
+				job.Printed, job.Error = time.Now(), ""
+			}
+			q.Unlock()
+		}
+	}
+}
+
+// lockedQueueTicket queues the finisher's ticket when there's a ticket printer
+func (race *Race) lockedQueueTicket(e *Entry) {
+	if tickets == nil {
+		return
+	}
+	row := race.lockedResultRow(race.lockedIndex(), e)
+	ticket := Ticket{Race: race.lockedName(), Place: row.Place, Bib: e.Bib, Fname: e.Fname, Lname: e.Lname, Time: e.NetDuration(),
+		Division: row.Division, DivisionPlace: row.DivisionPlace}
+	if e.HasNetTime() {
+		ticket.Gun = e.Duration
+	}
+	tickets.Queue(ticket)
+}
+
+func reprintTicketHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting the ticket", err)
+		return
+	}
+	if tickets == nil {
+		showErrorForAdmin(w, r.Referer(), "There's no ticket printer, set RACERGOTICKETPRINTER")
+		return
+	}
+	if err = tickets.Reprint(id); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/tickets", 301)
+}
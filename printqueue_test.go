@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitForJobs waits until every ticket has been tried
+func waitForJobs(t *testing.T, q *PrintQueue, done func([]PrintJob) bool) []PrintJob {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		jobs := q.Jobs()
+		if done(jobs) {
+			return jobs
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting on the print queue, have %#v", jobs)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestFinishTickets(t *testing.T) {
+	defer func(q *PrintQueue) { tickets = q }(tickets)
+	var lock sync.Mutex
+	var printed []Ticket
+	jammed := true
+	tickets = NewPrintQueue(func(escpos []byte, doc interface{}) error {
+		lock.Lock()
+		defer lock.Unlock()
+		if jammed {
+			jammed = false
+			return fmt.Errorf("out of paper")
+		}
+		if !strings.Contains(string(escpos), doc.(Ticket).Time.String()) {
+			return fmt.Errorf("no time on the ticket")
+		}
+		printed = append(printed, doc.(Ticket))
+		return nil
+	})
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{{Bib: 1, Fname: "Amy", Lname: "Brown"}, {Bib: 2, Fname: "Bob", Lname: "Adams", Male: true}} {
+		race.AddEntry(e)
+	}
+	startRace(race)
+	*race.testingTime = race.testingTime.Add(18 * time.Minute)
+	linkBibTesting(t, race, 2, false)
+	*race.testingTime = race.testingTime.Add(time.Minute)
+	linkBibTesting(t, race, 1, false)
+	if jobs := tickets.Jobs(); len(jobs) != 0 {
+		t.Errorf("Expected no tickets until the finishes are confirmed, got %d", len(jobs))
+	}
+	linkBibTesting(t, race, 2, false)
+	linkBibTesting(t, race, 1, false)
+
+	jobs := waitForJobs(t, tickets, func(jobs []PrintJob) bool {
+		return len(jobs) == 2 && (jobs[0].Error != "" || !jobs[0].Printed.IsZero()) && (jobs[1].Error != "" || !jobs[1].Printed.IsZero())
+	})
+	if jobs[1].Ticket.Bib != 2 || jobs[1].Ticket.Place != 1 || jobs[1].Error != "out of paper" {
+		t.Errorf("Expected Bob's ticket first and jammed, got %#v", jobs[1])
+	}
+	if jobs[0].Ticket.Bib != 1 || jobs[0].Ticket.Place != 2 || jobs[0].Ticket.Time != HumanDuration(19*time.Minute) || jobs[0].Printed.IsZero() {
+		t.Errorf("Expected Amy's ticket printed, got %#v", jobs[0])
+	}
+
+	w := httptest.NewRecorder()
+	reprintTicketHandler(w, httptest.NewRequest("POST", "/reprintTicket?id=1", nil), race)
+	if w.Code != http.StatusMovedPermanently {
+		t.Fatalf("Expected Bob's ticket reprinted, got %d %s", w.Code, w.Body.String())
+	}
+	waitForJobs(t, tickets, func(jobs []PrintJob) bool { return !jobs[1].Printed.IsZero() })
+	lock.Lock()
+	if len(printed) != 2 || printed[1].Bib != 2 {
+		t.Errorf("Expected Amy's then Bob's ticket printed, got %#v", printed)
+	}
+	lock.Unlock()
+	if err := tickets.Reprint(3); err == nil {
+		t.Errorf("Expected no ticket 3")
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/tickets", nil), race)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Bob Adams") {
+		t.Errorf("Expected the tickets page to list Bob's ticket, got %d", w.Code)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// prizeScope finds the event, wave and eligibility flags of an entry from its optional fields, so scoped
+// prizes only draw winners from their own event or wave and skip racers flagged as ineligible
+type prizeScope struct {
+	event int // index into the optional fields, -1 if the field wasn't imported
+	wave  int
+	flags int
+}
+
+var unscoped = prizeScope{event: -1, wave: -1, flags: -1}
+
+func (race *Race) lockedPrizeScope() prizeScope {
+	scope := unscoped
+	for x, field := range race.optionalEntryFields {
+		switch field {
+		case config.eventField:
+			scope.event = x
+		case config.waveField:
+			scope.wave = x
+		case config.flagsField:
+			scope.flags = x
+		}
+	}
+	return scope
+}
+
+func (ps prizeScope) matches(p Prize, e *Entry) bool {
+	field := func(x int) string {
+		if x < 0 || x >= len(e.Optional) {
+			return ""
+		}
+		return strings.TrimSpace(e.Optional[x])
+	}
+	if p.Event != "" && !strings.EqualFold(field(ps.event), p.Event) {
+		return false
+	}
+	if p.Wave != "" && !strings.EqualFold(field(ps.wave), p.Wave) {
+		return false
+	}
+	if len(p.Exclude) > 0 {
+		for _, flag := range parseFieldList(field(ps.flags)) {
+			for _, excluded := range p.Exclude {
+				if strings.EqualFold(flag, excluded) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// validatePrizeScopes checks every scoped prize names one of the events in RACERGOEVENTS or waves in RACERGOWAVES
+func validatePrizeScopes(prizes []Prize) error {
+	known := func(name string, names []string) bool {
+		for _, n := range names {
+			if strings.EqualFold(n, name) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, p := range prizes {
+		if p.Event != "" && !known(p.Event, config.events) {
+			return fmt.Errorf("Prize %s is for event %s, which isn't one of the race's events %s", p.Title, p.Event, strings.Join(config.events, ","))
+		}
+		if p.Wave != "" && !known(p.Wave, config.waves) {
+			return fmt.Errorf("Prize %s is for wave %s, which isn't one of the race's waves %s", p.Title, p.Wave, strings.Join(config.waves, ","))
+		}
+	}
+	return nil
+}
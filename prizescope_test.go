@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPrizeScopes(t *testing.T) {
+	defer func(events []string) { config.events = events }(config.events)
+	config.events = []string{"5k", "10k"}
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	if err := race.SetOptionalFields([]string{"Event"}); err != nil {
+		t.Fatalf("Error setting optional fields - %v", err)
+	}
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38, Optional: []string{"5k"}},
+		{Bib: 2, Fname: "Bea", Lname: "Adams", Age: 31, Optional: []string{"10k"}},
+		{Bib: 3, Fname: "Cat", Lname: "Cole", Age: 52, Optional: []string{"5K"}},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	if err := race.SetPrizes([]Prize{{Title: "Marathon Women", HighAge: 100, Gender: "F", Amount: 1, Event: "Marathon"}}); err == nil {
+		t.Errorf("Expected a prize for an unknown event to be refused")
+	}
+	err := race.SetPrizes([]Prize{
+		{Title: "5k Women", HighAge: 100, Gender: "F", Amount: 2, Event: "5k"},
+		{Title: "10k Women", HighAge: 100, Gender: "F", Amount: 2, Event: "10k"},
+		{Title: "Women", HighAge: 100, Gender: "F", Amount: 1, WinAgain: true},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	startRace(race)
+	for _, bib := range []int{2, 1, 3} {
+		*race.testingTime = race.testingTime.Add(time.Minute)
+		linkBibTesting(t, race, bib, false)
+		linkBibTesting(t, race, bib, false)
+	}
+	race.RLock()
+	defer race.RUnlock()
+	if w := race.prizes[0].Winners; len(w) != 2 || w[0].Bib != 1 || w[1].Bib != 3 {
+		t.Errorf("Expected Amy and Cat to win the 5k, got %v", w)
+	}
+	if w := race.prizes[1].Winners; len(w) != 1 || w[0].Bib != 2 {
+		t.Errorf("Expected Bea to win the 10k, got %v", w)
+	}
+	if w := race.prizes[2].Winners; len(w) != 1 || w[0].Bib != 2 {
+		t.Errorf("Expected the unscoped prize to draw from every event, got %v", w)
+	}
+}
+
+func TestPrizeEligibility(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	if err := race.SetOptionalFields([]string{"Flags"}); err != nil {
+		t.Fatalf("Error setting optional fields - %v", err)
+	}
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 28, Optional: []string{"Elite"}},
+		{Bib: 2, Fname: "Bea", Lname: "Adams", Age: 31, Optional: []string{"out-of-region"}},
+		{Bib: 3, Fname: "Cat", Lname: "Cole", Age: 52, Optional: []string{""}},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	race.SetPrizes([]Prize{
+		{Title: "Women's Overall", HighAge: 100, Gender: "F", Amount: 1},
+		{Title: "Top Local Woman", HighAge: 100, Gender: "F", Amount: 1, WinAgain: true, Exclude: []string{"elite", "out-of-region"}},
+	})
+	startRace(race)
+	for _, bib := range []int{1, 2, 3} {
+		*race.testingTime = race.testingTime.Add(time.Minute)
+		linkBibTesting(t, race, bib, false)
+		linkBibTesting(t, race, bib, false)
+	}
+	race.RLock()
+	defer race.RUnlock()
+	if w := race.prizes[0].Winners; len(w) != 1 || w[0].Bib != 1 {
+		t.Errorf("Expected the elite Amy to win overall, got %v", w)
+	}
+	if w := race.prizes[1].Winners; len(w) != 1 || w[0].Bib != 3 {
+		t.Errorf("Expected Cat as the top local finisher, got %v", w)
+	}
+}
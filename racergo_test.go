@@ -12,6 +12,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/mzimmerman/racergo/internal/core"
 )
 
 func startRace(race *Race) {
@@ -31,7 +33,7 @@ func modifyTestEntry(race *Race, t *testing.T, place Place, e *Entry, optionalEn
 	values.Add("Fname", e.Fname)
 	values.Add("Lname", e.Lname)
 	values.Add("Duration", e.Duration.String())
-	values.Add("Male", gender(e.Male))
+	values.Add("Male", core.Gender(e.Male))
 	for x, o := range e.Optional {
 		values.Add(optionalEntryFields[x], o)
 	}
@@ -52,7 +54,7 @@ func addTestEntry(race *Race, t *testing.T, e *Entry, optionalEntryFields []stri
 	values.Add("Age", strconv.Itoa(int(e.Age)))
 	values.Add("Fname", e.Fname)
 	values.Add("Lname", e.Lname)
-	values.Add("Male", gender(e.Male))
+	values.Add("Male", core.Gender(e.Male))
 	for x, o := range e.Optional {
 		values.Add(optionalEntryFields[x], o)
 	}
@@ -98,10 +100,10 @@ func TestDownloadAndAudit(t *testing.T) {
 	}
 
 	users := []Entry{
-		Entry{1, "A", "B", true, 15, []string{"userA@host.com", "Large"}, HumanDuration(time.Second), raceStart.Add(time.Second), true},
-		Entry{2, "C", "D", false, 25, []string{"userC@host.com", "Medium"}, HumanDuration(time.Minute), raceStart.Add(time.Minute), true},
-		Entry{3, "E", "F", true, 30, []string{"userE@host.com", "Small"}, HumanDuration(time.Hour), raceStart.Add(time.Hour), true},
-		Entry{4, "G", "H", false, 35, []string{"userG@host.com", "XSmall"}, HumanDuration(time.Millisecond * 10), raceStart.Add(time.Millisecond * 10), true},
+		Entry{Bib: 1, Fname: "A", Lname: "B", Male: true, Age: 15, Optional: []string{"userA@host.com", "Large"}, Duration: HumanDuration(time.Second), TimeFinished: raceStart.Add(time.Second), Confirmed: true},
+		Entry{Bib: 2, Fname: "C", Lname: "D", Male: false, Age: 25, Optional: []string{"userC@host.com", "Medium"}, Duration: HumanDuration(time.Minute), TimeFinished: raceStart.Add(time.Minute), Confirmed: true},
+		Entry{Bib: 3, Fname: "E", Lname: "F", Male: true, Age: 30, Optional: []string{"userE@host.com", "Small"}, Duration: HumanDuration(time.Hour), TimeFinished: raceStart.Add(time.Hour), Confirmed: true},
+		Entry{Bib: 4, Fname: "G", Lname: "H", Male: false, Age: 35, Optional: []string{"userG@host.com", "XSmall"}, Duration: HumanDuration(time.Millisecond * 10), TimeFinished: raceStart.Add(time.Millisecond * 10), Confirmed: true},
 	}
 	for _, u := range users {
 		addTestEntry(race, t, &u, optionalEntryFields)
@@ -109,10 +111,10 @@ func TestDownloadAndAudit(t *testing.T) {
 	downloadUploadCompareDownload(t, race)
 	validateDownload(t, race, 1, fmt.Sprintf(`Fname,Lname,Age,Gender,Bib,Overall Place,Duration,Time Finished,Confirmed,Email,T-Shirt
 ,,,,,,,%s,,Email,T-Shirt
-A,B,15,M,1,1,--,--,false,userA@host.com,Large
-C,D,25,F,2,2,--,--,false,userC@host.com,Medium
-E,F,30,M,3,3,--,--,false,userE@host.com,Small
-G,H,35,F,4,4,--,--,false,userG@host.com,XSmall
+A,B,15,M,1,,--,--,false,userA@host.com,Large
+C,D,25,F,2,,--,--,false,userC@host.com,Medium
+E,F,30,M,3,,--,--,false,userE@host.com,Small
+G,H,35,F,4,,--,--,false,userG@host.com,XSmall
 `,
 		raceStart.Format(time.ANSIC),
 	))
@@ -176,8 +178,8 @@ E,F,30,M,3,4,01:00:00.00,%s,true,userE@host.com,ET
 ,,,,,,,%s,,Email,T-Shirt
 A,B,15,M,1,1,00:00:01.00,%s,true,userA@host.com,AT
 E,F,30,M,3,2,01:00:00.00,%s,true,userE@host.com,ET
-C,D,25,F,2,3,--,--,false,userC@host.com,CT
-G,H,35,F,4,4,--,--,false,userG@host.com,GT
+C,D,25,F,2,,--,--,false,userC@host.com,CT
+G,H,35,F,4,,--,--,false,userG@host.com,GT
 `,
 		raceStart.Format(time.ANSIC),
 		raceStart.Add(time.Second).Format(time.ANSIC),
@@ -280,7 +282,7 @@ func TestRestoreTime(t *testing.T) {
 	now := time.Now().Round(time.Second)
 	race := NewRace()
 	race.testingTime = &now
-	want := fmt.Sprintf("%s\n", strings.Join(headers, ","))
+	want := fmt.Sprintf("%s\n", strings.Join(core.Headers, ","))
 	got := downloadCurrent(t, race)
 	f, err := ioutil.TempFile("/tmp", "racergorestoretime")
 	if err != nil {
@@ -306,7 +308,7 @@ func TestRestoreTime(t *testing.T) {
 	*race.testingTime = race.testingTime.Add(time.Minute)
 	race.RecordTimeForBib(1)
 	race.RecordTimeForBib(1)
-	want = fmt.Sprintf("%s\n,,,,,,,%s,\nmatt,z,34,M,1,1,00:01:00.00,%s,true\n", strings.Join(headers, ","), now.Add(-time.Minute).Format(time.ANSIC), now.Format(time.ANSIC))
+	want = fmt.Sprintf("%s\n,,,,,,,%s,\nmatt,z,34,M,1,1,00:01:00.00,%s,true\n", strings.Join(core.Headers, ","), now.Add(-time.Minute).Format(time.ANSIC), now.Format(time.ANSIC))
 	got = downloadCurrent(t, race)
 	f, err = ioutil.TempFile("/tmp", "racergorestoretime")
 	if err != nil {
@@ -339,7 +341,7 @@ func TestRestoreTime(t *testing.T) {
 	testUploadRacersHelper(t, startedOutput, 301, race)
 	race.Lock()
 	now = now.Add(-time.Minute)
-	if race.started != now {
+	if !race.started.Equal(now) {
 		t.Errorf("Race should be started and equal now! - got %s, want %s", race.started, now)
 	}
 	race.Unlock()
@@ -539,6 +541,23 @@ func TestLoadRacers(t *testing.T) {
 
 }
 
+func TestImportEntries(t *testing.T) {
+	race := NewRace()
+	if err := race.ImportEntries([]byte("Fname,Lname,Age,Gender,Bib,Duration,Hometown\nAmy,Brown,38,F,1,,Erie\nCal,Cole,44,M,2,,Troy\n")); err != nil {
+		t.Fatalf("Error importing racers - %v", err)
+	}
+	if len(race.allEntries) != 2 || race.bibbedEntries[2].Lname != "Cole" || race.GetOptionalFields()[0] != "Hometown" {
+		t.Errorf("Wrong racers imported - %v %v", race.allEntries, race.GetOptionalFields())
+	}
+	race = NewRace()
+	if err := race.ImportEntries([]byte("Fname,Lname,Age,Gender,Bib,Duration\nAmy,Brown,38,F,1,soon\n")); err == nil {
+		t.Errorf("Expected a bad duration to fail the import")
+	}
+	if len(race.allEntries) != 0 {
+		t.Errorf("Expected nothing imported from a bad file, got %v", race.allEntries)
+	}
+}
+
 func TestTemplates(t *testing.T) {
 	race := NewRace()
 	urls := []string{
@@ -562,10 +581,10 @@ func TestTemplates(t *testing.T) {
 		t.Errorf("Nil expected, got %v", err)
 	}
 	users := []Entry{
-		Entry{-1, "A", "B", true, 15, []string{"userA@host.com", "Large"}, 0, time.Time{}, true},
-		Entry{-1, "C", "D", false, 25, []string{"userC@host.com", "Medium"}, 0, time.Time{}, true},
-		Entry{-1, "E", "F", true, 30, []string{"userE@host.com", "Small"}, 0, time.Time{}, true},
-		Entry{5, "G", "H", false, 35, []string{"userG@host.com", "XSmall"}, 0, time.Time{}, true},
+		Entry{Bib: -1, Fname: "A", Lname: "B", Male: true, Age: 15, Optional: []string{"userA@host.com", "Large"}, Duration: 0, TimeFinished: time.Time{}, Confirmed: true},
+		Entry{Bib: -1, Fname: "C", Lname: "D", Male: false, Age: 25, Optional: []string{"userC@host.com", "Medium"}, Duration: 0, TimeFinished: time.Time{}, Confirmed: true},
+		Entry{Bib: -1, Fname: "E", Lname: "F", Male: true, Age: 30, Optional: []string{"userE@host.com", "Small"}, Duration: 0, TimeFinished: time.Time{}, Confirmed: true},
+		Entry{Bib: 5, Fname: "G", Lname: "H", Male: false, Age: 35, Optional: []string{"userG@host.com", "XSmall"}, Duration: 0, TimeFinished: time.Time{}, Confirmed: true},
 	}
 	for _, u := range users {
 		t.Logf("Adding entry - %v", u)
@@ -591,10 +610,10 @@ func TestTemplates(t *testing.T) {
 		}
 	}
 	users = []Entry{
-		Entry{1, "H", "I", true, 15, []string{"userA@host.com", "Large"}, 0, time.Time{}, true},
-		Entry{2, "J", "K", false, 25, []string{"userC@host.com", "Medium"}, 0, time.Time{}, true},
-		Entry{3, "L", "M", true, 30, []string{"userE@host.com", "Small"}, 0, time.Time{}, true},
-		Entry{4, "N", "O", false, 35, []string{"userG@host.com", "XSmall"}, 0, time.Time{}, true},
+		Entry{Bib: 1, Fname: "H", Lname: "I", Male: true, Age: 15, Optional: []string{"userA@host.com", "Large"}, Duration: 0, TimeFinished: time.Time{}, Confirmed: true},
+		Entry{Bib: 2, Fname: "J", Lname: "K", Male: false, Age: 25, Optional: []string{"userC@host.com", "Medium"}, Duration: 0, TimeFinished: time.Time{}, Confirmed: true},
+		Entry{Bib: 3, Fname: "L", Lname: "M", Male: true, Age: 30, Optional: []string{"userE@host.com", "Small"}, Duration: 0, TimeFinished: time.Time{}, Confirmed: true},
+		Entry{Bib: 4, Fname: "N", Lname: "O", Male: false, Age: 35, Optional: []string{"userG@host.com", "XSmall"}, Duration: 0, TimeFinished: time.Time{}, Confirmed: true},
 	}
 	for _, u := range users {
 		t.Logf("Adding entry - %v", u)
@@ -853,7 +872,7 @@ func TestHumanDuration(t *testing.T) {
 		if val.duration.Clock() != val.clock {
 			t.Errorf("Expected %s, got %d", val.clock, val.duration.Clock())
 		}
-		newDuration, err := ParseHumanDuration(val.time)
+		newDuration, err := core.ParseHumanDuration(val.time)
 		if err != nil {
 			t.Errorf("Unexpected error - %v", err)
 		}
@@ -862,3 +881,24 @@ func TestHumanDuration(t *testing.T) {
 		}
 	}
 }
+
+func TestPlaceOrdinal(t *testing.T) {
+	tests := map[Place]string{
+		0:   "--",
+		1:   "1st",
+		2:   "2nd",
+		3:   "3rd",
+		4:   "4th",
+		11:  "11th",
+		12:  "12th",
+		13:  "13th",
+		21:  "21st",
+		102: "102nd",
+		111: "111th",
+	}
+	for place, want := range tests {
+		if got := place.Ordinal(); got != want {
+			t.Errorf("Expected %s, got %s", want, got)
+		}
+	}
+}
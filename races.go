@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// hostedRaces are the races run alongside the main race, like a kids' fun run before the 5k, by slug in
+// RACERGORACES order.  Each has its own racers, clock, results and event log, served under /races/{slug}/
+var hostedRaces []*Race
+
+var raceSlugRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+type raceContextKey struct{}
+
+// parseHostedRace reads one RACERGORACES entry, kids=Kids Fun Run
+func parseHostedRace(spec string) (slug, title string, err error) {
+	parts := strings.SplitN(spec, "=", 2)
+	slug = strings.ToLower(strings.TrimSpace(parts[0]))
+	if !raceSlugRegexp.MatchString(slug) {
+		return "", "", fmt.Errorf("race id %q must be lowercase letters, numbers and dashes", parts[0])
+	}
+	title = slug
+	if len(parts) == 2 && strings.TrimSpace(parts[1]) != "" {
+		title = strings.TrimSpace(parts[1])
+	}
+	return slug, title, nil
+}
+
+// AddHostedRace creates another race served under /races/{slug}/
+func AddHostedRace(slug, title string) (*Race, error) {
+	if hostedRace(slug) != nil {
+		return nil, fmt.Errorf("race id %s is listed twice", slug)
+	}
+	race := NewRace()
+	race.slug = slug
+	race.title = title
+	hostedRaces = append(hostedRaces, race)
+	return race, nil
+}
+
+func hostedRace(slug string) *Race {
+	for _, race := range hostedRaces {
+		if race.slug == slug {
+			return race
+		}
+	}
+	return nil
+}
+
+// Path is the prefix the race's pages are served under, blank for the main race
+func (race *Race) Path() string {
+	if race.slug == "" {
+		return ""
+	}
+	return "/races/" + race.slug
+}
+
+// lockedName is the title on the race's pages, RACERGORACENAME for the main race
+func (race *Race) lockedName() string {
+	if race.title != "" {
+		return race.title
+	}
+	return config.raceName
+}
+
+// raceFile gives a hosted race its own copy of a file the main race keeps, racergo-events.jsonl becomes racergo-events-kids.jsonl
+func raceFile(path, slug string) string {
+	if path == "" || slug == "" {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + slug + ext
+}
+
+// startHostedRaces recovers each hosted race from its own event log or snapshot and keeps it saved,
+// the checkpoint feeds, trackers and mirroring only follow the main race
+func startHostedRaces() {
+	for _, race := range hostedRaces {
+		snapshot := raceFile(config.snapshotFile, race.slug)
+		if config.eventLog != "" {
+			eventLog := raceFile(config.eventLog, race.slug)
+			if err := openEventLog(race, eventLog); err != nil {
+				log.Printf("Error with the event log %s - %v", eventLog, err)
+				os.Exit(exitCantCreate)
+			}
+		} else {
+			recoverRace(race, snapshot)
+		}
+		go snapshotRace(race, snapshot, config.snapshotInterval)
+	}
+}
+
+// raceFromRequest is the race a request was routed to under /races/{slug}/, the main race otherwise
+func raceFromRequest(r *http.Request) *Race {
+	if race, ok := r.Context().Value(raceContextKey{}).(*Race); ok {
+		return race
+	}
+	return globalRace
+}
+
+// prefixedRedirects points the redirects of a hosted race's handlers back under /races/{slug}/
+type prefixedRedirects struct {
+	http.ResponseWriter
+	prefix string
+}
+
+func (w prefixedRedirects) WriteHeader(code int) {
+	if location := w.Header().Get("Location"); strings.HasPrefix(location, "/") && !strings.HasPrefix(location, "//") &&
+		!strings.HasPrefix(location, w.prefix+"/") {
+		w.Header().Set("Location", w.prefix+location)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w prefixedRedirects) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// racesHandler lists the races on /races/ and serves each hosted race's pages under /races/{slug}/ with the
+// same handlers as the main race
+func racesHandler(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/races/")
+	if rest == "" {
+		racesListHandler(w, r)
+		return
+	}
+	parts := strings.SplitN(rest, "/", 2)
+	race := hostedRace(parts[0])
+	if race == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if len(parts) == 1 {
+		http.Redirect(w, r, race.Path()+"/", 301)
+		return
+	}
+	routed := r.WithContext(context.WithValue(r.Context(), raceContextKey{}, race))
+	u := *r.URL
+	u.Path = "/" + parts[1]
+	u.RawPath = ""
+	routed.URL = &u
+	handler, pattern := http.DefaultServeMux.Handler(routed)
+	if pattern == "/races/" {
+		http.NotFound(w, r)
+		return
+	}
+	handler.ServeHTTP(prefixedRedirects{w, race.Path()}, routed)
+}
+
+func racesListHandler(w http.ResponseWriter, r *http.Request) {
+	type raceLink struct {
+		Name string
+		Path string
+	}
+	globalRace.RLock()
+	races := []raceLink{{globalRace.lockedName(), "/"}}
+	globalRace.RUnlock()
+	for _, race := range hostedRaces {
+		races = append(races, raceLink{race.title, race.Path() + "/"})
+	}
+	if err := raceResultsTemplate.ExecuteTemplate(w, "races", map[string]interface{}{"Races": races}); err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error rendering the races - %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseHostedRace(t *testing.T) {
+	tests := []struct {
+		spec, slug, title string
+		err               bool
+	}{
+		{"kids=Kids Fun Run", "kids", "Kids Fun Run", false},
+		{"10k", "10k", "10k", false},
+		{" Half = Half Marathon ", "half", "Half Marathon", false},
+		{"fun run=Fun Run", "", "", true},
+		{"=Nameless", "", "", true},
+	}
+	for _, test := range tests {
+		slug, title, err := parseHostedRace(test.spec)
+		if (err != nil) != test.err {
+			t.Errorf("%q - expected error %t, got %v", test.spec, test.err, err)
+			continue
+		}
+		if slug != test.slug || title != test.title {
+			t.Errorf("%q - expected %s/%s, got %s/%s", test.spec, test.slug, test.title, slug, title)
+		}
+	}
+	if got := raceFile("racergo-events.jsonl", "kids"); got != "racergo-events-kids.jsonl" {
+		t.Errorf("Expected the kids race to get its own event log, got %s", got)
+	}
+	if got := raceFile("racergo-events.jsonl", ""); got != "racergo-events.jsonl" {
+		t.Errorf("Expected the main race to keep the event log, got %s", got)
+	}
+}
+
+func TestHostedRaces(t *testing.T) {
+	defer func(races []*Race) { hostedRaces = races }(hostedRaces)
+	hostedRaces = nil
+	kids, err := AddHostedRace("kids", "Kids Fun Run")
+	if err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if _, err = AddHostedRace("kids", "Again"); err == nil {
+		t.Errorf("Expected an error hosting the same race twice")
+	}
+	if err = kids.AddEntry(Entry{Bib: 1, Fname: "Tiny", Lname: "Tot", Age: 6}); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	http.DefaultServeMux.ServeHTTP(w, httptest.NewRequest("GET", "/races/kids/admin", nil))
+	body := w.Body.String()
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the kids admin page, got %d - %s", w.Code, body)
+	}
+	if !strings.Contains(body, "Tot") {
+		t.Errorf("Expected the kids racer on the kids admin page")
+	}
+	if !strings.Contains(body, `href="/races/kids/admin`) || strings.Contains(body, `href="/admin`) {
+		t.Errorf("Expected the links on the kids admin page to stay in the kids race")
+	}
+
+	w = httptest.NewRecorder()
+	http.DefaultServeMux.ServeHTTP(w, httptest.NewRequest("POST", "/races/kids/setTheme?theme="+displayThemes[1].Name, nil))
+	if location := w.Header().Get("Location"); location != "/races/kids/admin" {
+		t.Errorf("Expected to be sent back to the kids admin page, got %q", location)
+	}
+	kids.RLock()
+	theme := kids.theme
+	kids.RUnlock()
+	if theme != displayThemes[1].Name {
+		t.Errorf("Expected the theme set on the kids race, got %q", theme)
+	}
+
+	w = httptest.NewRecorder()
+	http.DefaultServeMux.ServeHTTP(w, httptest.NewRequest("GET", "/races/", nil))
+	if body = w.Body.String(); !strings.Contains(body, "Kids Fun Run") || !strings.Contains(body, `href="/races/kids/"`) {
+		t.Errorf("Expected the kids race listed - %s", body)
+	}
+
+	w = httptest.NewRecorder()
+	http.DefaultServeMux.ServeHTTP(w, httptest.NewRequest("GET", "/races/relay/admin", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected an unknown race not to be found, got %d", w.Code)
+	}
+}
+
+func TestHostedRaceLinks(t *testing.T) {
+	defer func(races []*Race) { hostedRaces = races }(hostedRaces)
+	hostedRaces = nil
+	kids, err := AddHostedRace("kids", "Kids Fun Run")
+	if err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	kids.testingTime = &time.Time{}
+	*kids.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	if err = kids.AddEntry(Entry{Bib: 1, Fname: "Tiny", Lname: "Tot", Age: 6}); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	startRace(kids)
+	*kids.testingTime = kids.testingTime.Add(20 * time.Minute)
+	linkBibTesting(t, kids, 1, false)
+	linkBibTesting(t, kids, 1, false)
+
+	kids.RLock()
+	subject, text, html := resultsEmail(kids.lockedResultSummary(kids.allEntries[0]))
+	badge := kids.badgeURL(1)
+	kids.RUnlock()
+	if !strings.Contains(subject, "Kids Fun Run") || !strings.Contains(text, "Kids Fun Run") {
+		t.Errorf("Expected the e-mail to name the kids race - %s\n%s", subject, text)
+	}
+	if !strings.Contains(text, "/races/kids/finisher?bib=1") || !strings.Contains(html, "/races/kids/finisher?bib=1") {
+		t.Errorf("Expected the e-mail to link to the kids result - %s\n%s", text, html)
+	}
+	if !strings.Contains(badge, "/races/kids/badge.png?bib=1") {
+		t.Errorf("Expected the badge in the kids race, got %s", badge)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIKey is a third party integration's key from RACERGOAPIKEYS, with its own allowance separate from anonymous pollers
+type APIKey struct {
+	Name      string
+	Key       string
+	PerMinute int // 0 is unlimited
+	Burst     int // requests allowed at once before the per minute rate applies
+}
+
+// parseAPIKeys reads RACERGOAPIKEYS entries like runsignup:0123abcd:600 or runsignup:0123abcd:600:100, keyed by the key
+func parseAPIKeys(list []string) (map[string]APIKey, error) {
+	keys := make(map[string]APIKey)
+	for _, spec := range list {
+		parts := strings.Split(spec, ":")
+		if len(parts) < 3 || len(parts) > 4 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("API keys must be like name:key:requests a minute[:burst], not %s", spec)
+		}
+		key := APIKey{Name: parts[0], Key: parts[1]}
+		var err error
+		if key.PerMinute, err = strconv.Atoi(parts[2]); err != nil || key.PerMinute < 0 {
+			return nil, fmt.Errorf("%s's requests a minute must be a number, 0 for unlimited, not %s", key.Name, parts[2])
+		}
+		key.Burst = key.PerMinute
+		if len(parts) == 4 {
+			if key.Burst, err = strconv.Atoi(parts[3]); err != nil || key.Burst < 1 {
+				return nil, fmt.Errorf("%s's burst must be a number of requests, not %s", key.Name, parts[3])
+			}
+		}
+		if _, ok := keys[key.Key]; ok {
+			return nil, fmt.Errorf("%s's key is used twice", key.Name)
+		}
+		keys[key.Key] = key
+	}
+	return keys, nil
+}
+
+// maxRateBuckets is how many clients are tracked before the ones that have stopped polling are forgotten
+const maxRateBuckets = 10000
+
+type rateBucket struct {
+	tokens    float64
+	perMinute int
+	burst     int
+	last      time.Time
+}
+
+func (b *rateBucket) refill(now time.Time) {
+	b.tokens = math.Min(float64(b.burst), b.tokens+now.Sub(b.last).Minutes()*float64(b.perMinute))
+	b.last = now
+}
+
+// RateLimiter is a token bucket for each client, a client can make burst requests at once and then perMinute a minute
+type RateLimiter struct {
+	sync.Mutex
+	buckets map[string]*rateBucket
+	now     func() time.Time
+}
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{buckets: make(map[string]*rateBucket), now: time.Now}
+}
+
+var publicLimiter = NewRateLimiter()
+
+// Allow takes a request from the client's bucket, or says how long until there's one to take
+func (l *RateLimiter) Allow(client string, perMinute, burst int) (bool, time.Duration) {
+	if perMinute <= 0 {
+		return true, 0
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	l.Lock()
+	defer l.Unlock()
+	now := l.now()
+	b, ok := l.buckets[client]
+	if !ok {
+		if len(l.buckets) >= maxRateBuckets {
+			l.lockedForget(now)
+		}
+		b = &rateBucket{tokens: float64(burst), last: now}
+		l.buckets[client] = b
+	}
+	b.perMinute, b.burst = perMinute, burst
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / float64(perMinute) * float64(time.Minute))
+}
+
+// lockedForget drops the clients whose buckets have filled back up, they haven't asked for anything in a while
+func (l *RateLimiter) lockedForget(now time.Time) {
+	for client, b := range l.buckets {
+		if b.refill(now); b.tokens >= float64(b.burst) {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// rateLimitedPath is true for the public pages and feeds, not their stylesheets and images or anything needing a sign in.
+// A hosted race's pages are counted once they're routed to the race.
+func rateLimitedPath(path string) bool {
+	switch subtree(path) {
+	case "/static/", "/fonts/", "/sponsors/", "/races/":
+		return false
+	}
+	return pathRole("GET", path) == rolePublic
+}
+
+// requestAPIKey is the key an integration sent in the X-API-Key header or the apiKey parameter
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	return r.URL.Query().Get("apiKey")
+}
+
+// withinRateLimit answers 429 Too Many Requests with a Retry-After once a client has used up its allowance.
+// Keyed integrations get their key's allowance, everyone else RACERGORATELIMIT a minute from each address.
+// Signed in timers and admins are never limited.
+func withinRateLimit(w http.ResponseWriter, r *http.Request) bool {
+	if !rateLimitedPath(r.URL.Path) {
+		return true
+	}
+	if _, ok := authenticate(r); ok {
+		return true
+	}
+	client, perMinute, burst := "ip "+clientIP(r), config.rateLimit, config.rateBurst
+	if given := requestAPIKey(r); given != "" {
+		key, ok := config.apiKeys[given]
+		if !ok {
+			http.Error(w, "Unknown API key", http.StatusUnauthorized)
+			return false
+		}
+		client, perMinute, burst = "key "+key.Name, key.PerMinute, key.Burst
+	}
+	ok, wait := publicLimiter.Allow(client, perMinute, burst)
+	if ok {
+		return true
+	}
+	log.Printf("Rate limited %s %s from %s", r.Method, r.URL.Path, client)
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	http.Error(w, "Too many requests, slow down", http.StatusTooManyRequests)
+	return false
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	limiter := NewRateLimiter()
+	limiter.now = func() time.Time { return now }
+	for x := 0; x < 3; x++ {
+		if ok, _ := limiter.Allow("poller", 60, 3); !ok {
+			t.Fatalf("Expected the burst of 3 allowed, stopped at %d", x)
+		}
+	}
+	ok, wait := limiter.Allow("poller", 60, 3)
+	if ok || wait != time.Second {
+		t.Errorf("Expected to wait a second after the burst, got %t %s", ok, wait)
+	}
+	if ok, _ = limiter.Allow("other", 60, 3); !ok {
+		t.Errorf("Expected another client to have its own allowance")
+	}
+	now = now.Add(time.Second)
+	if ok, _ = limiter.Allow("poller", 60, 3); !ok {
+		t.Errorf("Expected a request allowed a second later")
+	}
+	if ok, _ = limiter.Allow("anything", 0, 0); !ok {
+		t.Errorf("Expected no limit at 0 a minute")
+	}
+}
+
+func TestParseAPIKeys(t *testing.T) {
+	keys, err := parseAPIKeys([]string{"runsignup:abc:600", "scoreboard:def:60:5"})
+	if err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if keys["abc"].Burst != 600 || keys["def"].Name != "scoreboard" || keys["def"].Burst != 5 {
+		t.Errorf("Expected both keys with their bursts, got %v", keys)
+	}
+	for _, bad := range []string{"runsignup:abc", "runsignup:abc:lots", "runsignup:abc:60:0", ":abc:60"} {
+		if _, err = parseAPIKeys([]string{bad}); err == nil {
+			t.Errorf("Expected an error for %s", bad)
+		}
+	}
+	if _, err = parseAPIKeys([]string{"one:abc:60", "two:abc:60"}); err == nil {
+		t.Errorf("Expected an error for a key used twice")
+	}
+}
+
+func TestWithinRateLimit(t *testing.T) {
+	defer func(limit, burst int, keys map[string]APIKey, users map[string]User, limiter *RateLimiter) {
+		config.rateLimit, config.rateBurst, config.apiKeys, config.users, publicLimiter = limit, burst, keys, users, limiter
+	}(config.rateLimit, config.rateBurst, config.apiKeys, config.users, publicLimiter)
+	publicLimiter = NewRateLimiter()
+	config.rateLimit, config.rateBurst = 30, 2
+	config.apiKeys, _ = parseAPIKeys([]string{"scoreboard:def:600:10"})
+	config.users, _ = parseUsers([]string{"amy:secret:admin"})
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	get := func(path string, setup func(*http.Request)) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		if setup != nil {
+			setup(r)
+		}
+		w := httptest.NewRecorder()
+		logRequests(ok).ServeHTTP(w, r)
+		return w
+	}
+	for x := 0; x < 2; x++ {
+		if w := get("/stats.json", nil); w.Code != http.StatusOK {
+			t.Fatalf("Expected the burst allowed, got %d", w.Code)
+		}
+	}
+	w := get("/stats.json", nil)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "2" {
+		t.Errorf("Expected 429 with a 2 second Retry-After, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w = get("/static/bootstrap.min.css", nil); w.Code != http.StatusOK {
+		t.Errorf("Expected stylesheets not limited, got %d", w.Code)
+	}
+	if w = get("/stats.json", func(r *http.Request) { r.Header.Set("X-API-Key", "def") }); w.Code != http.StatusOK {
+		t.Errorf("Expected the keyed integration allowed, got %d", w.Code)
+	}
+	if w = get("/stats.json?apiKey=nope", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected an unknown key refused, got %d", w.Code)
+	}
+	if w = get("/stats.json", func(r *http.Request) { r.SetBasicAuth("amy", "secret") }); w.Code != http.StatusOK {
+		t.Errorf("Expected a signed in admin not limited, got %d", w.Code)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Record is a standing record a finisher could break, e.g. the course record or a state age group record
+type Record struct {
+	Standard string // whose record it is, e.g. Course, Colorado or USATF
+	Gender   string // M, F or blank for either
+	MinAge   int
+	MaxAge   int // 0 for no upper limit
+	Time     HumanDuration
+	Holder   string
+	Year     string
+}
+
+// Division describes who the record is for, e.g. F 40-44
+func (r Record) Division() string {
+	gender := r.Gender
+	if gender == "" {
+		gender = "Open"
+	}
+	switch {
+	case r.MinAge == 0 && r.MaxAge == 0:
+		return gender
+	case r.MaxAge == 0:
+		return fmt.Sprintf("%s %d+", gender, r.MinAge)
+	}
+	return fmt.Sprintf("%s %d-%d", gender, r.MinAge, r.MaxAge)
+}
+
+func (r Record) matches(e *Entry) bool {
+	if (r.Gender == "M" && !e.Male) || (r.Gender == "F" && e.Male) {
+		return false
+	}
+	return int(e.Age) >= r.MinAge && (r.MaxAge == 0 || int(e.Age) <= r.MaxAge)
+}
+
+// parseRecords reads the records table, a CSV with Standard, Gender, MinAge, MaxAge, Time, Holder and Year columns.
+// Times are like 15:42.1 or 1:02:15.
+func parseRecords(data []byte) ([]Record, error) {
+	rows, _, err := readCSV(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("Records table is blank")
+	}
+	header := make(map[string]int)
+	for x, h := range rows[0] {
+		header[strings.ToLower(strings.TrimSpace(h))] = x
+	}
+	for _, required := range []string{"standard", "time"} {
+		if _, ok := header[required]; !ok {
+			return nil, fmt.Errorf("Records table is missing the %s column", required)
+		}
+	}
+	column := func(row []string, name string) string {
+		if x, ok := header[name]; ok && x < len(row) {
+			return strings.TrimSpace(row[x])
+		}
+		return ""
+	}
+	records := make([]Record, 0, len(rows)-1)
+	for x, row := range rows[1:] {
+		record := Record{Standard: column(row, "standard"), Gender: strings.ToUpper(column(row, "gender")), Holder: column(row, "holder"), Year: column(row, "year")}
+		if record.Gender != "" && record.Gender != "M" && record.Gender != "F" {
+			return nil, fmt.Errorf("Row %d gender %s must be M, F or blank", x+2, record.Gender)
+		}
+		for _, age := range []struct {
+			name string
+			dst  *int
+		}{{"minage", &record.MinAge}, {"maxage", &record.MaxAge}} {
+			if val := column(row, age.name); val != "" {
+				if *age.dst, err = strconv.Atoi(val); err != nil {
+					return nil, fmt.Errorf("Row %d %s is not an age", x+2, val)
+				}
+			}
+		}
+		if record.Time, err = parsePaperTime(column(row, "time")); err != nil {
+			return nil, fmt.Errorf("Row %d - %v", x+2, err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// RecordCandidate is a finish that may have set a record and needs the paperwork sent in
+type RecordCandidate struct {
+	Record
+	*Entry
+	Margin HumanDuration // how far under the record, 0 for a tie
+}
+
+// lockedRecordCandidates lists every finish at or under a record the finisher is eligible for
+func (race *Race) lockedRecordCandidates() []RecordCandidate {
+	var candidates []RecordCandidate
+	for _, record := range race.records {
+		for _, e := range race.allEntries {
+			if e.HasFinished() && e.Registration.HasSpot() && e.Duration > 0 && e.Duration <= record.Time && record.matches(e) {
+				candidates = append(candidates, RecordCandidate{Record: record, Entry: e, Margin: record.Time - e.Duration})
+			}
+		}
+	}
+	return candidates
+}
+
+// SetRecords replaces the records table
+func (race *Race) SetRecords(records []Record) {
+	race.Lock()
+	defer race.Unlock()
+	race.records = records
+	log.Printf("Loaded %d records", len(records))
+}
+
+// lockedFlagRecords logs the possible records when the results are finalized
+func (race *Race) lockedFlagRecords() {
+	race.recordCandidates = race.lockedRecordCandidates()
+	for _, c := range race.recordCandidates {
+		log.Printf("Possible %s record for %s - bib #%d %s %s in %s, the record is %s", c.Standard, c.Division(), c.Bib, c.Fname, c.Lname, c.Duration, c.Record.Time)
+	}
+}
+
+// lockedWriteRecordCandidates writes what the record keepers ask for with each possible record.
+// Confidential fields like the emergency contacts and medical notes are left out.
+func (race *Race) lockedWriteRecordCandidates(writer *csv.Writer) {
+	var optional []int
+	header := []string{"Standard", "Division", "Bib", "Fname", "Lname", "Gender", "Age", "Time", "Record", "Record Holder", "Record Year", "Margin", "Race", "Date", "Start"}
+	for x, field := range race.optionalEntryFields {
+		if !restrictedField(field) {
+			optional = append(optional, x)
+			header = append(header, field)
+		}
+	}
+	writer.Write(header)
+	start := "--"
+	if !race.started.IsZero() {
+		start = race.started.Format("3:04:05 PM")
+	}
+	for _, c := range race.recordCandidates {
+		gender := "F"
+		if c.Male {
+			gender = "M"
+		}
+		row := []string{c.Standard, c.Division(), c.Bib.String(), c.Fname, c.Lname, gender, fmt.Sprintf("%d", c.Age), c.Duration.String(), c.Record.Time.String(),
+			c.Holder, c.Year, c.Margin.String(), config.raceName, race.scheduleDay().Format("2006-01-02"), start}
+		for _, x := range optional {
+			if x < len(c.Optional) {
+				row = append(row, c.Optional[x])
+			} else {
+				row = append(row, "")
+			}
+		}
+		writer.Write(row)
+	}
+}
+
+func uploadRecordsHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error getting Reader - %s", err)
+		return
+	}
+	data, err := readUpload(r, "records")
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error reading the records table - %v", err)
+		return
+	}
+	records, err := parseRecords(data)
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	race.SetRecords(records)
+	http.Redirect(w, r, "/admin", 301)
+}
+
+func recordsHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	w.Header().Set("Content-type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s-%s-records.csv\"", config.webserverHostname, time.Now().In(time.Local).Format("2006-01-02")))
+	writer := csv.NewWriter(w)
+	race.RLock()
+	race.lockedWriteRecordCandidates(writer)
+	race.RUnlock()
+	writer.Flush()
+}
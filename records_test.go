@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRecords = `Standard,Gender,MinAge,MaxAge,Time,Holder,Year
+Course,F,,,18:30,Jo Fast,2009
+Course,M,,,16:05.2,Al Quick,2011
+Colorado,F,40,44,19:10,Di Steady,2012
+Colorado,M,50,54,17:00,Ed Old,2010
+`
+
+func TestParseRecords(t *testing.T) {
+	records, err := parseRecords([]byte(testRecords))
+	if err != nil || len(records) != 4 {
+		t.Fatalf("Expected 4 records, got %v - %v", records, err)
+	}
+	if records[1].Time != HumanDuration(16*time.Minute+5200*time.Millisecond) || records[2].Division() != "F 40-44" || records[0].Division() != "F" {
+		t.Errorf("Expected the records read, got %#v", records)
+	}
+	for _, bad := range []string{
+		"Standard,Gender\nCourse,F\n",
+		"Standard,Gender,Time\nCourse,X,18:30\n",
+		"Standard,MinAge,Time\nCourse,forty,18:30\n",
+		"Standard,Time\nCourse,soon\n",
+	} {
+		if _, err := parseRecords([]byte(bad)); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestRecordCandidates(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	if err := race.SetOptionalFields([]string{"Email", "Medical Notes"}); err != nil {
+		t.Fatalf("Error setting fields - %v", err)
+	}
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 42, Optional: []string{"amy@host.com", "Asthma"}},
+		{Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 52, Optional: []string{"bob@host.com", ""}},
+		{Bib: 3, Fname: "Cal", Lname: "Cole", Male: true, Age: 31, Optional: []string{"cal@host.com", ""}},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	records, _ := parseRecords([]byte(testRecords))
+	race.SetRecords(records)
+	startRace(race)
+	for _, finish := range []struct {
+		bib   int
+		after time.Duration
+	}{{3, 16*time.Minute + 30*time.Second}, {2, 17 * time.Minute}, {1, 18 * time.Minute}} {
+		*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local).Add(finish.after)
+		linkBibTesting(t, race, finish.bib, false)
+		linkBibTesting(t, race, finish.bib, false)
+	}
+	for x := 0; race.ReviewAnomaly(x, false) == nil; x++ {
+	}
+	if err := race.Finalize(false); err != nil {
+		t.Fatalf("Unexpected error finalizing - %v", err)
+	}
+	race.RLock()
+	defer race.RUnlock()
+	// Amy breaks the course and her age group records, Bob ties his age group record, Cal is too slow for the course record
+	var found []string
+	for _, c := range race.recordCandidates {
+		found = append(found, c.Standard+" "+c.Division()+" "+c.Fname+" "+c.Margin.String())
+	}
+	expected := []string{"Course F Amy 00:00:30.00", "Colorado F 40-44 Amy 00:01:10.00", "Colorado M 50-54 Bob --"}
+	if strings.Join(found, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected %v, got %v", expected, found)
+	}
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	race.lockedWriteRecordCandidates(writer)
+	writer.Flush()
+	if rows, _, err := readCSV(buf.Bytes()); err != nil || len(rows) != 4 || rows[0][len(rows[0])-1] != "Email" || rows[1][len(rows[1])-1] != "amy@host.com" {
+		t.Errorf("Expected the paperwork without the medical notes, got %v - %v", rows, err)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// defaultRefresh is how often each page reloads itself in seconds, without needing any JavaScript
+var defaultRefresh = map[string]int{
+	"results":     3,
+	"default":     30,
+	"info":        30,
+	"fundraising": 60,
+	"results.txt": 30,
+}
+
+// parseRefresh reads page=seconds pairs, e.g. results=10,default=60, over the defaults.  0 turns refreshing off for the page.
+func parseRefresh(setting string) (map[string]int, error) {
+	refresh := make(map[string]int, len(defaultRefresh))
+	for page, seconds := range defaultRefresh {
+		refresh[page] = seconds
+	}
+	for _, pair := range parseFieldList(setting) {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("expected page=seconds, not %s", pair)
+		}
+		page := strings.TrimSpace(parts[0])
+		if _, ok := defaultRefresh[page]; !ok {
+			return nil, fmt.Errorf("%s doesn't refresh, must be one of results, default, info, fundraising or results.txt", page)
+		}
+		seconds, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || seconds < 0 {
+			return nil, fmt.Errorf("%s is not a number of seconds for %s", parts[1], page)
+		}
+		refresh[page] = seconds
+	}
+	return refresh, nil
+}
+
+// resultsTextHandler is the results as plain text for old phones and venues with barely any signal,
+// refreshed with the Refresh header since there's no markup to put a meta refresh in
+func resultsTextHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	race.RLock()
+	defer race.RUnlock()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	if seconds := config.refresh["results.txt"]; seconds > 0 && !race.lockedPreferences(r).LiveUpdatesOff {
+		w.Header().Set("Refresh", strconv.Itoa(seconds))
+	}
+	fmt.Fprintln(w, race.lockedName())
+	if race.started.IsZero() {
+		fmt.Fprintln(w, "Not started yet")
+		return
+	}
+	status := "Unofficial"
+	if race.finalized {
+		status = "Official"
+	}
+	fmt.Fprintf(w, "%s results as of %s\n\n", status, race.GetTime().Format("3:04:05 PM"))
+	writeResultsText(w, "Overall", race.lockedFinishers(), false)
+	for _, division := range race.lockedDivisionResults() {
+		fmt.Fprintln(w)
+		writeResultsText(w, division.Division, division.Rows, true)
+	}
+}
+
+// writeResultsText writes the rows as a fixed-width table under the title, placed within the division or overall
+func writeResultsText(w io.Writer, title string, rows []ResultRow, byDivision bool) {
+	fmt.Fprintln(w, title)
+	fmt.Fprintln(w, strings.Repeat("=", len(title)))
+	tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+	fmt.Fprintln(tw, "Place\tTime\tBib\tName")
+	for _, row := range rows {
+		place := row.Place
+		if byDivision {
+			place = row.DivisionPlace
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s %s\n", place, row.NetDuration(), row.Bib, row.Fname, row.Lname)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRefresh(t *testing.T) {
+	refresh, err := parseRefresh("results=10, default=0")
+	if err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if refresh["results"] != 10 || refresh["default"] != 0 || refresh["fundraising"] != 60 {
+		t.Errorf("Expected results and default overridden and the rest left alone, got %v", refresh)
+	}
+	for _, setting := range []string{"results", "admin=5", "results=-1", "results=soon"} {
+		if _, err := parseRefresh(setting); err == nil {
+			t.Errorf("Expected error parsing %s", setting)
+		}
+	}
+}
+
+func TestResultsText(t *testing.T) {
+	defer func(refresh map[string]int) { config.refresh = refresh }(config.refresh)
+	config.refresh, _ = parseRefresh("default=0,results.txt=15")
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38},
+		{Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 31},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	startRace(race)
+	*race.testingTime = race.testingTime.Add(20 * time.Minute)
+	linkBibTesting(t, race, 2, false)
+
+	r, _ := http.NewRequest("GET", "/results.txt", nil)
+	w := httptest.NewRecorder()
+	resultsTextHandler(w, r, race)
+	body := w.Body.String()
+	if w.Header().Get("Refresh") != "15" || !strings.Contains(body, "1     00:20:00.00 2   Bob Adams") || strings.Contains(body, "Amy") || strings.Contains(body, "<") {
+		t.Errorf("Expected Bob in plain text refreshing every 15 seconds, got %v - %s", w.Header(), body)
+	}
+
+	r, _ = http.NewRequest("GET", "/", nil)
+	w = httptest.NewRecorder()
+	handler(w, r, race)
+	if strings.Contains(w.Body.String(), `http-equiv="refresh"`) {
+		t.Errorf("Expected refresh turned off for the default page - %s", w.Body.String())
+	}
+}
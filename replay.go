@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// replayPages are served by the replay command, every page that only shows the race
+var replayPages = []string{"/", "/admin", "/audit", "/results", "/results/print", "/lookup", "/finisher", "/splits", "/events"}
+
+// eventsUntil returns the events up to and including the sequence number, all of them for 0
+func eventsUntil(events []Event, until int) []Event {
+	if until <= 0 {
+		return events
+	}
+	for x, ev := range events {
+		if ev.Seq > until {
+			return events[:x]
+		}
+	}
+	return events
+}
+
+// lockedEventsFor returns the events that touched the bib, all of them for NoBib
+func (race *Race) lockedEventsFor(bib Bib) []Event {
+	if bib == NoBib {
+		return race.events
+	}
+	events := make([]Event, 0)
+	for _, ev := range race.events {
+		touched := ev.Bib == bib || (ev.Entry != nil && ev.Entry.Bib == bib)
+		for _, a := range ev.Audit {
+			touched = touched || a.Bib == bib
+		}
+		for _, op := range ev.Ops {
+			touched = touched || op.Bib == bib || (op.Op == "assignBib" && op.NewBib == bib)
+		}
+		if touched {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+// replayCommand rebuilds the race from an event file up to a point and serves it read-only,
+// for working out how the results came to be
+//
+//	racergo replay race.events --until 120
+func replayCommand(args []string) int {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	until := flags.Int("until", 0, "replay the events up to and including this sequence number, 0 for all of them")
+	addr := flags.String("listen", "localhost:8081", "address to serve the replayed race on")
+	file := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		file, args = args[0], args[1:]
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if file == "" {
+		file = flags.Arg(0)
+	}
+	if file == "" {
+		fmt.Fprintf(os.Stderr, "Usage: racergo replay <event file> [--until <seq>] [--listen <addr>]\n")
+		return exitUsage
+	}
+	fd, err := os.Open(file)
+	if err != nil {
+		log.Printf("Error opening %s - %v", file, err)
+		return exitDataErr
+	}
+	events, err := ReadEvents(fd)
+	fd.Close()
+	if err != nil {
+		log.Printf("Error reading %s - %v", file, err)
+		return exitDataErr
+	}
+	events = eventsUntil(events, *until)
+	if err = globalRace.Replay(events); err != nil {
+		log.Printf("%v", err)
+		return exitDataErr
+	}
+	mux := http.NewServeMux()
+	for _, page := range replayPages {
+		mux.Handle(page, logRequests(RaceHandler(handler)))
+	}
+	mux.Handle("/static/", staticHandler("static/"))
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Printf("Error listening on %s - %v", *addr, err)
+		return exitUnavailable
+	}
+	log.Printf("Replayed %d events from %s, read-only at http://%s/admin and http://%s/events?bib=", len(events), file, listener.Addr(), listener.Addr())
+	if err = http.Serve(listener, mux); err != nil {
+		log.Printf("Error serving the replayed race - %v", err)
+		return exitUnavailable
+	}
+	return exitOK
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplayUntil(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38},
+		{Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 31},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	startRace(race)
+	*race.testingTime = race.testingTime.Add(20 * time.Minute)
+	linkBibTesting(t, race, 1, false)
+	*race.testingTime = race.testingTime.Add(time.Minute)
+	linkBibTesting(t, race, 2, false)
+	if batchErr := race.ApplyBatch([]BatchOp{{Op: "assignBib", Bib: 2, NewBib: 5}}); batchErr != nil {
+		t.Fatalf("Unexpected error - %v", batchErr.Error)
+	}
+
+	// replay to just before Bob finished
+	until := 0
+	for _, ev := range race.events {
+		if ev.Kind == "link" && ev.Bib == 2 {
+			until = ev.Seq - 1
+		}
+	}
+	replayed := NewRace()
+	if err := replayed.Replay(eventsUntil(race.events, until)); err != nil {
+		t.Fatalf("Error replaying - %v", err)
+	}
+	if amy, bob := replayed.bibbedEntries[1], replayed.bibbedEntries[2]; !amy.HasFinished() || bob.HasFinished() {
+		t.Errorf("Expected only Amy finished at event %d", until)
+	}
+
+	race.RLock()
+	bobEvents := race.lockedEventsFor(5)
+	race.RUnlock()
+	if len(bobEvents) != 1 || bobEvents[0].Kind != "batch" {
+		t.Errorf("Expected only the batch to touch bib 5, got %v", bobEvents)
+	}
+	r, _ := http.NewRequest("GET", "/events?bib=2", nil)
+	w := httptest.NewRecorder()
+	handler(w, r, race)
+	if body := w.Body.String(); !strings.Contains(body, "bib 2 linked at 9:21:00 AM") || strings.Contains(body, "bib 1 linked") {
+		t.Errorf("Expected only Bob's events - %s", body)
+	}
+	if code := replayCommand(nil); code != exitUsage {
+		t.Errorf("Expected usage error without an event file, got %d", code)
+	}
+	if code := replayCommand([]string{"missing.events", "--until", "3"}); code != exitDataErr {
+		t.Errorf("Expected data error for a missing event file, got %d", code)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log"
+	"strings"
+)
+
+// ResultSummary is everything in a finisher's results e-mail
+type ResultSummary struct {
+	Finisher
+	Standing       string
+	Pace           string
+	Prizes         []string
+	ResultURL      string
+	CertificateURL string
+	Cheers         []string // approved cheers from spectators
+}
+
+func (race *Race) lockedResultSummary(entry *Entry) ResultSummary {
+	rs := ResultSummary{
+		Finisher:       Finisher{Bib: entry.Bib, Fname: entry.Fname, Lname: entry.Lname, Duration: entry.NetDuration(), RaceName: race.lockedName()},
+		Standing:       race.lockedStanding(entry),
+		Pace:           entry.NetDuration().Pace(config.raceDistance, config.paceUnit),
+		ResultURL:      race.finisherURL(entry.Bib),
+		CertificateURL: race.badgeURL(entry.Bib),
+	}
+	if finisher, err := race.lockedFinisher(entry.Bib); err == nil {
+		rs.Finisher = finisher
+	}
+	rs.Cheers = race.lockedCheersFor(entry.Bib)
+	for _, prize := range race.prizes {
+		for x, winner := range prize.Winners {
+			if winner == entry {
+				rs.Prizes = append(rs.Prizes, fmt.Sprintf("%s in %s", Place(x+1).Ordinal(), prize.Title))
+			}
+		}
+	}
+	return rs
+}
+
+var resultEmailTemplate = template.Must(template.New("resultEmail").Funcs(template.FuncMap{
+	"ordinal": func(p Place) string {
+		return p.Ordinal()
+	},
+}).Parse(`<html>
+<body style="font-family: Helvetica, Arial, sans-serif; color: #333">
+	<h2>Congratulations {{.Fname}} {{.Lname}}!</h2>
+	<p>You finished the {{.RaceName}}!</p>
+	<table cellpadding="6" style="border-collapse: collapse; border: 1px solid #ddd">
+		<tr><th align="left">Time</th><td>{{.Duration}}</td></tr>
+		{{if .Gun}}<tr><th align="left">Gun Time</th><td>{{.Gun}}</td></tr>{{end}}
+		<tr><th align="left">Pace</th><td>{{.Pace}}</td></tr>
+		{{if .Place}}<tr><th align="left">Overall</th><td>{{ordinal .Place}}</td></tr>{{end}}
+		{{if and .DivisionPlace (ne .Division "Overall")}}<tr><th align="left">{{.Division}}</th><td>{{ordinal .DivisionPlace}}</td></tr>{{end}}
+		{{range .Prizes}}<tr><th align="left">Prize</th><td>{{.}}</td></tr>{{end}}
+	</table>
+	{{with .Cheers}}
+		<h3>Cheers from your supporters</h3>
+		{{range .}}<p style="font-style: italic">{{.}}</p>{{end}}
+	{{end}}
+	<p><a href="{{.ResultURL}}">See your result page</a> | <a href="{{.CertificateURL}}">Download your finisher certificate</a></p>
+</body>
+</html>
+`))
+
+// resultsEmail is the subject, plain text and HTML parts of a finisher's results e-mail
+func resultsEmail(rs ResultSummary) (string, string, string) {
+	result := rs.Duration.String()
+	if rs.Standing != "" {
+		result += ", " + rs.Standing
+	}
+	text := fmt.Sprintf("Congratulations %s %s!  You finished the %s in %s!\n\nPace: %s\n", rs.Fname, rs.Lname, rs.RaceName, result, rs.Pace)
+	if rs.Gun > 0 {
+		text += fmt.Sprintf("Gun time: %s\n", rs.Gun)
+	}
+	if len(rs.Prizes) > 0 {
+		text += fmt.Sprintf("Prizes: %s\n", strings.Join(rs.Prizes, ", "))
+	}
+	if len(rs.Cheers) > 0 {
+		text += fmt.Sprintf("\nCheers from your supporters:\n%s\n", strings.Join(rs.Cheers, "\n"))
+	}
+	text += fmt.Sprintf("\nShare your finish - %s\nYour finisher certificate - %s", rs.ResultURL, rs.CertificateURL)
+	var html bytes.Buffer
+	if err := resultEmailTemplate.Execute(&html, rs); err != nil {
+		log.Printf("Error rendering the results e-mail for bib #%d, sending only text - %v", rs.Bib, err)
+		html.Reset()
+	}
+	return fmt.Sprintf("%s Results", rs.RaceName), text, html.String()
+}
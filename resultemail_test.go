@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResultEmail(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 28},
+		{Bib: 2, Fname: "Bea", Lname: "Adams", Age: 31},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	race.SetPrizes([]Prize{{Title: "Women's Overall", HighAge: 100, Gender: "F", Amount: 1}})
+	startRace(race)
+	for _, bib := range []int{1, 2} {
+		*race.testingTime = race.testingTime.Add(20 * time.Minute)
+		linkBibTesting(t, race, bib, false)
+		linkBibTesting(t, race, bib, false)
+	}
+	race.RLock()
+	winner, second := race.lockedResultSummary(race.bibbedEntries[1]), race.lockedResultSummary(race.bibbedEntries[2])
+	race.RUnlock()
+	if winner.Place != 1 || len(winner.Prizes) != 1 || winner.Prizes[0] != "1st in Women's Overall" {
+		t.Errorf("Expected Amy 1st winning Women's Overall, got %#v", winner)
+	}
+	if second.Place != 2 || len(second.Prizes) != 0 {
+		t.Errorf("Expected Bea 2nd without a prize, got %#v", second)
+	}
+	_, text, html := resultsEmail(winner)
+	for _, expected := range []string{"00:20:00", winner.Pace, "1st in Women&#39;s Overall", "/finisher?bib=1", "/badge.png?bib=1"} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("Expected the HTML part to contain %q - %s", expected, html)
+		}
+	}
+	if !strings.Contains(text, "Prizes: 1st in Women's Overall") || !strings.Contains(text, "/badge.png?bib=1") {
+		t.Errorf("Expected the text part to have the prize and certificate - %s", text)
+	}
+	if _, text, _ = resultsEmail(second); strings.Contains(text, "Prizes") {
+		t.Errorf("Expected no prizes in Bea's e-mail - %s", text)
+	}
+}
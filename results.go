@@ -0,0 +1,104 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// ResultRow is an entry along with the places it has been computed to hold, used for displaying results
+type ResultRow struct {
+	*Entry
+	Place         Place
+	Division      string
+	DivisionPlace Place
+	Estimate      *Estimate // nil unless the time was estimated for a racer the line missed
+}
+
+// resultSorts are the orders spectators can view the results in, keyed by the sort form value
+var resultSorts = map[string]func(a, b ResultRow) bool{
+	"name": func(a, b ResultRow) bool {
+		if al, bl := strings.ToLower(a.Lname), strings.ToLower(b.Lname); al != bl {
+			return al < bl
+		}
+		return strings.ToLower(a.Fname) < strings.ToLower(b.Fname)
+	},
+	"bib": func(a, b ResultRow) bool {
+		return a.Bib < b.Bib
+	},
+	"division": func(a, b ResultRow) bool {
+		if a.Division != b.Division {
+			return a.Division < b.Division
+		}
+		return finishedFirst(a, b, a.DivisionPlace < b.DivisionPlace)
+	},
+	"recent": func(a, b ResultRow) bool {
+		return finishedFirst(a, b, a.TimeFinished.After(b.TimeFinished))
+	},
+}
+
+// finishedFirst orders finishers before non-finishers, using less when both have finished
+func finishedFirst(a, b ResultRow, less bool) bool {
+	if a.HasFinished() != b.HasFinished() {
+		return a.HasFinished()
+	}
+	return a.HasFinished() && less
+}
+
+// lockedResults returns every entry in the requested order, overall place if the order is unknown.
+// The sort is stable so ties keep their overall place order between refreshes.
+func (race *Race) lockedResults(sortBy string) []ResultRow {
+	idx := race.lockedIndex()
+	rows := make([]ResultRow, len(race.allEntries))
+	for x, e := range race.allEntries {
+		rows[x] = race.lockedResultRow(idx, e)
+	}
+	if less, ok := resultSorts[sortBy]; ok {
+		sort.SliceStable(rows, func(i, j int) bool {
+			return less(rows[i], rows[j])
+		})
+	}
+	return rows
+}
+
+// DivisionResults are a division's finishers in division place order
+type DivisionResults struct {
+	Division string
+	Rows     []ResultRow
+}
+
+// lockedFinishers returns the finishers in overall place order
+func (race *Race) lockedFinishers() []ResultRow {
+	rows := race.lockedResults("")
+	for x, row := range rows {
+		if row.Place == 0 {
+			return rows[:x]
+		}
+	}
+	return rows
+}
+
+// lockedDivisionResults groups the finishers by division, none if the race isn't split into divisions
+func (race *Race) lockedDivisionResults() []DivisionResults {
+	idx := race.lockedIndex()
+	names := make([]string, 0, len(idx.divisions))
+	for name := range idx.divisions {
+		if name != "Overall" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var divisions []DivisionResults
+	for _, name := range names {
+		division := DivisionResults{Division: name}
+		for _, e := range idx.divisions[name] {
+			if !e.Ranked() {
+				break // in allEntries order, nobody after this has a place either
+			}
+			division.Rows = append(division.Rows, race.lockedResultRow(idx, e))
+		}
+		if len(division.Rows) > 0 {
+			divisions = append(divisions, division)
+		}
+	}
+	return divisions
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResultSorts(t *testing.T) {
+	race := NewRace()
+	raceStart := time.Now().Add(-time.Hour).Round(time.Second)
+	race.testingTime = &time.Time{}
+	*race.testingTime = raceStart
+	for _, e := range []Entry{
+		{Bib: 4, Fname: "Zed", Lname: "Adams", Male: true, Age: 34},
+		{Bib: 3, Fname: "Amy", Lname: "Brown", Male: false, Age: 38},
+		{Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 31},
+		{Bib: 1, Fname: "Cal", Lname: "Cole", Male: true, Age: 52},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	startRace(race)
+	for x, bib := range []Bib{4, 3, 2} {
+		*race.testingTime = raceStart.Add(time.Duration(x+20) * time.Minute)
+		if err := race.RecordTimeForBib(bib); err != nil {
+			t.Errorf("Error linking bib - %v", err)
+		}
+	}
+	tests := []struct {
+		sort string
+		bibs []Bib
+	}{
+		{"", []Bib{4, 3, 2, 1}},
+		{"unknown", []Bib{4, 3, 2, 1}},
+		{"name", []Bib{2, 4, 3, 1}},
+		{"bib", []Bib{1, 2, 3, 4}},
+		{"division", []Bib{3, 4, 2, 1}},
+		{"recent", []Bib{2, 3, 4, 1}},
+	}
+	race.RLock()
+	for _, test := range tests {
+		rows := race.lockedResults(test.sort)
+		for x := range rows {
+			if rows[x].Bib != test.bibs[x] {
+				t.Errorf("%q - expected bib %s in row %d, got %s", test.sort, test.bibs[x], x, rows[x].Bib)
+			}
+		}
+	}
+	rows := race.lockedResults("division")
+	race.RUnlock()
+	if rows[2].Division != "M30-39" || rows[2].DivisionPlace != 2 || rows[2].Place != 3 {
+		t.Errorf("Wrong places computed - %#v", rows[2])
+	}
+	if rows[3].Place != 0 || rows[3].DivisionPlace != 0 {
+		t.Errorf("Non-finisher should not be placed - %#v", rows[3])
+	}
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/?sort=division", nil)
+	handler(w, r, race)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected %d, got %d - %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("GET", "/results.txt", nil)
+	resultsTextHandler(w, r, race)
+	text := w.Body.String()
+	if !strings.Contains(text, "M30-39\n======\nPlace Time        Bib Name\n1     00:20:00.00 4   Zed Adams\n2     00:22:00.00 2   Bob Adams\n") || strings.Contains(text, "Cal Cole") {
+		t.Errorf("Expected the M30-39 division in fixed-width text without non-finishers - %s", text)
+	}
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("GET", "/results/print", nil)
+	handler(w, r, race)
+	page := w.Body.String()
+	if w.Code != http.StatusOK || strings.Count(page, `<section class="division">`) != 2 || strings.Contains(page, "<script") {
+		t.Errorf("Expected a printable page with the F30-39 and M30-39 divisions and no scripts, got %d - %s", w.Code, page)
+	}
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("GET", "/results/divisions?division=M30-39", nil)
+	handler(w, r, race)
+	page = w.Body.String()
+	if w.Code != http.StatusOK || !strings.Contains(page, "<h2>M30-39 <small>2 finishers</small></h2>") || strings.Contains(page, "<h2>F30-39") {
+		t.Errorf("Expected only the M30-39 division, got %d - %s", w.Code, page)
+	}
+}
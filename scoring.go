@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Scorer ranks the finishers.  Timed races are ranked by time, rogaines and adventure races by the
+// points for the checkpoints each racer reached and then by time.
+type Scorer interface {
+	// Compare is negative when finisher a ranks ahead of finisher b, positive when behind and 0 when tied
+	Compare(a, b *Entry) int
+}
+
+type timeScorer struct{}
+
+func (timeScorer) Compare(a, b *Entry) int {
+	return compareDurations(a.AdjustedDuration(), b.AdjustedDuration())
+}
+
+type pointsScorer struct{}
+
+func (pointsScorer) Compare(a, b *Entry) int {
+	if a.Points != b.Points {
+		return b.Points - a.Points
+	}
+	return compareDurations(a.AdjustedDuration(), b.AdjustedDuration())
+}
+
+func compareDurations(a, b HumanDuration) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// scorers are the RACERGOSCORING modes
+var scorers = map[string]Scorer{
+	"time":   timeScorer{},
+	"points": pointsScorer{},
+}
+
+// scorer is how the race is ranked, from RACERGOSCORING
+var scorer Scorer = timeScorer{}
+
+// parseCheckpoints reads RACERGOCHECKPOINTS, each checkpoint optionally followed by the points it's worth, e.g. CP1=10,CP2=20
+func parseCheckpoints(list []string) ([]string, map[string]int, error) {
+	names := make([]string, len(list))
+	points := make(map[string]int)
+	for x, cp := range list {
+		names[x] = cp
+		if eq := strings.Index(cp, "="); eq >= 0 {
+			names[x] = strings.TrimSpace(cp[:eq])
+			worth, err := strconv.Atoi(strings.TrimSpace(cp[eq+1:]))
+			if err != nil || worth < 0 {
+				return nil, nil, fmt.Errorf("checkpoint %s must be worth a number of points", names[x])
+			}
+			points[names[x]] = worth
+		}
+	}
+	return names, points, nil
+}
+
+// lockedScorePassing adds the checkpoint's points to the racer the first time they pass it and ranks them again
+func (race *Race) lockedScorePassing(passing Passing) {
+	worth := config.checkpointPoints[passing.Checkpoint]
+	entry, ok := race.bibbedEntries[passing.Bib]
+	if worth == 0 || !ok {
+		return
+	}
+	entry.Points += worth
+	if entry.HasFinished() {
+		race.lockedSortEntries()
+		race.lockedRecomputePrizes()
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCheckpoints(t *testing.T) {
+	names, points, err := parseCheckpoints([]string{"CP1=10", "CP2 = 25", "WATER"})
+	if err != nil {
+		t.Fatalf("Error parsing checkpoints - %v", err)
+	}
+	if len(names) != 3 || names[0] != "CP1" || names[1] != "CP2" || names[2] != "WATER" || points["CP1"] != 10 || points["CP2"] != 25 || points["WATER"] != 0 {
+		t.Errorf("Wrong checkpoints parsed - %v %v", names, points)
+	}
+	if _, _, err = parseCheckpoints([]string{"CP1=lots"}); err == nil {
+		t.Errorf("Expected points that aren't a number refused")
+	}
+}
+
+func TestPointsScoring(t *testing.T) {
+	defer func(checkpoints []string, points map[string]int, s Scorer) {
+		config.checkpoints, config.checkpointPoints, scorer = checkpoints, points, s
+	}(config.checkpoints, config.checkpointPoints, scorer)
+	config.checkpoints, config.checkpointPoints, _ = parseCheckpoints([]string{"CP1=10", "CP2=20", "CP3=30"})
+	scorer = scorers["points"]
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38}, {Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 31}, {Bib: 3, Fname: "Cal", Lname: "Cole", Male: true, Age: 44}} {
+		race.AddEntry(e)
+	}
+	startRace(race)
+	race.Lock()
+	for _, p := range []Passing{
+		{Checkpoint: "CP1", Bib: 1, Time: race.started.Add(10 * time.Minute)},
+		{Checkpoint: "CP1", Bib: 1, Time: race.started.Add(11 * time.Minute)},
+		{Checkpoint: "CP3", Bib: 2, Time: race.started.Add(30 * time.Minute)},
+		{Checkpoint: "CP3", Bib: 3, Time: race.started.Add(35 * time.Minute)},
+	} {
+		if err := race.lockedRecordPassing(p); err != nil {
+			t.Fatalf("Error recording %#v - %v", p, err)
+		}
+	}
+	race.Unlock()
+	for x, bib := range []int{1, 3, 2} {
+		*race.testingTime = race.started.Add(time.Duration(60+x) * time.Minute)
+		linkBibTesting(t, race, bib, false)
+	}
+	if race.bibbedEntries[1].Points != 10 {
+		t.Errorf("Expected a checkpoint passed twice scored once, got %d points", race.bibbedEntries[1].Points)
+	}
+	if race.allEntries[0].Bib != 3 || race.allEntries[1].Bib != 2 || race.allEntries[2].Bib != 1 {
+		t.Errorf("Expected 30 points ahead of 10 and ties broken by time, got %d %d %d", race.allEntries[0].Bib, race.allEntries[1].Bib, race.allEntries[2].Bib)
+	}
+	race.Lock()
+	race.lockedRecordPassing(Passing{Checkpoint: "CP2", Bib: 1, Time: race.started.Add(40 * time.Minute)})
+	race.Unlock()
+	if race.allEntries[0].Bib != 1 || race.allEntries[1].Bib != 3 || race.allEntries[2].Bib != 2 {
+		t.Errorf("Expected Amy's late report to tie everyone on 30 points and her time to put her first, got %d %d %d", race.allEntries[0].Bib, race.allEntries[1].Bib, race.allEntries[2].Bib)
+	}
+}
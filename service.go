@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// exit codes, following sysexits.h so the service manager can tell a bad setup from a crash
+const (
+	exitOK          = 0
+	exitUsage       = 64 // unknown subcommand
+	exitDataErr     = 65 // the event file to replay couldn't be read or replayed
+	exitUnavailable = 69 // couldn't listen for connections
+	exitCantCreate  = 73 // couldn't install or uninstall the service
+)
+
+const serviceName = "racergo"
+
+// SaveSnapshot writes the race in the /download format to the file, through a temporary file
+// so a crash part way through never leaves a half written snapshot behind
+func (race *Race) SaveSnapshot(path string) error {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := race.WriteCSV(writer); err != nil {
+		return err
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// RestoreSnapshot loads a snapshot written by SaveSnapshot as if it had been uploaded, restarting the clock
+// at the original start time so finish times carry on where they left off
+func (race *Race) RestoreSnapshot(path string) error {
+	req, err := uploadFile(path)
+	if err != nil {
+		return err
+	}
+	resp := httptest.NewRecorder()
+	uploadRacersHandler(resp, req, race)
+	if resp.Code != 301 {
+		return fmt.Errorf("%s", resp.Body.String())
+	}
+	return nil
+}
+
+// snapshotRace saves the race every interval, skipping the write when nothing has changed
+func snapshotRace(race *Race, path string, interval time.Duration) {
+	var last []byte
+	for range time.Tick(interval) {
+		var buf bytes.Buffer
+		writer := csv.NewWriter(&buf)
+		race.WriteCSV(writer)
+		writer.Flush()
+		if bytes.Equal(buf.Bytes(), last) {
+			continue
+		}
+		if err := race.SaveSnapshot(path); err != nil {
+			log.Printf("Error saving snapshot to %s - %v", path, err)
+			continue
+		}
+		last = buf.Bytes()
+	}
+}
+
+// recoverRace reloads the last snapshot, if there is one, when racergo comes back up after a crash or reboot
+func recoverRace(race *Race, path string) {
+	if _, err := os.Stat(path); err != nil {
+		return
+	}
+	if err := race.RestoreSnapshot(path); err != nil {
+		log.Printf("Error recovering the race from %s - %v", path, err)
+		return
+	}
+	log.Printf("Recovered the race from %s", path)
+}
+
+// serviceEnvironment is the RACERGO settings in effect now, so the service runs with the same configuration
+func serviceEnvironment() []string {
+	env := []string{}
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, "RACERGO") {
+			env = append(env, kv)
+		}
+	}
+	sort.Strings(env)
+	return env
+}
+
+// systemdUnit restarts racergo within seconds of a crash, but not when it exits because it was set up wrong
+func systemdUnit(exe, dir string, env []string) string {
+	var unit bytes.Buffer
+	fmt.Fprintf(&unit, "[Unit]\nDescription=racergo race results\nAfter=network-online.target\nWants=network-online.target\n\n")
+	fmt.Fprintf(&unit, "[Service]\nExecStart=%q run\nWorkingDirectory=%s\n", exe, dir)
+	for _, kv := range env {
+		fmt.Fprintf(&unit, "Environment=%q\n", kv)
+	}
+	fmt.Fprintf(&unit, "Restart=always\nRestartSec=2\nRestartPreventExitStatus=%d\n\n", exitUsage)
+	fmt.Fprintf(&unit, "[Install]\nWantedBy=multi-user.target\n")
+	return unit.String()
+}
+
+func runCommands(commands [][]string) error {
+	for _, c := range commands {
+		out, err := exec.Command(c[0], c[1:]...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("%s - %v: %s", strings.Join(c, " "), err, out)
+		}
+	}
+	return nil
+}
+
+// installService starts racergo at boot from the current directory with the current settings.
+// On Windows it's a task run at startup, elsewhere a systemd unit that also restarts it after a crash.
+func installService() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if runtime.GOOS == "windows" {
+		for _, kv := range serviceEnvironment() {
+			parts := strings.SplitN(kv, "=", 2)
+			if err = runCommands([][]string{{"setx", "/M", parts[0], parts[1]}}); err != nil {
+				return err
+			}
+		}
+		return runCommands([][]string{{"schtasks", "/Create", "/F", "/TN", serviceName, "/SC", "ONSTART", "/RU", "SYSTEM",
+			"/TR", fmt.Sprintf(`cmd /c "cd /d "%s" && "%s" run"`, dir, exe)}})
+	}
+	unitPath := filepath.Join("/etc/systemd/system", serviceName+".service")
+	if err = ioutil.WriteFile(unitPath, []byte(systemdUnit(exe, dir, serviceEnvironment())), 0644); err != nil {
+		return err
+	}
+	return runCommands([][]string{{"systemctl", "daemon-reload"}, {"systemctl", "enable", "--now", serviceName}})
+}
+
+func uninstallService() error {
+	if runtime.GOOS == "windows" {
+		return runCommands([][]string{{"schtasks", "/Delete", "/F", "/TN", serviceName}})
+	}
+	if err := runCommands([][]string{{"systemctl", "disable", "--now", serviceName}}); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join("/etc/systemd/system", serviceName+".service")); err != nil {
+		return err
+	}
+	return runCommands([][]string{{"systemctl", "daemon-reload"}})
+}
+
+// serviceCommand handles the install and uninstall subcommands, returning false to carry on and run the race
+func serviceCommand(args []string) (bool, int) {
+	if len(args) == 0 || args[0] == "run" {
+		return false, exitOK
+	}
+	var err error
+	switch args[0] {
+	case "install":
+		err = installService()
+	case "uninstall":
+		err = uninstallService()
+	case "replay":
+		return true, replayCommand(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Usage: %s [run|install|uninstall|replay]\n", filepath.Base(os.Args[0]))
+		return true, exitUsage
+	}
+	if err != nil {
+		log.Printf("Error with %s - %v", args[0], err)
+		return true, exitCantCreate
+	}
+	log.Printf("Service %sed", args[0])
+	return true, exitOK
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshotRecovery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatalf("Error creating directory - %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "snapshot.csv")
+
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 34},
+		{Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 41},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	startRace(race)
+	*race.testingTime = race.testingTime.Add(20 * time.Minute)
+	linkBibTesting(t, race, 2, false)
+	if err = race.SaveSnapshot(path); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if _, err = os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("Expected the temporary snapshot to be renamed into place")
+	}
+
+	recovered := NewRace()
+	recoverRace(recovered, filepath.Join(dir, "missing.csv"))
+	if len(recovered.allEntries) != 0 {
+		t.Errorf("Expected nothing recovered without a snapshot")
+	}
+	if err = recovered.RestoreSnapshot(path); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if !recovered.started.Equal(race.started) {
+		t.Errorf("Expected the race started at %s, got %s", race.started, recovered.started)
+	}
+	if len(recovered.allEntries) != 2 || recovered.allEntries[0].Bib != 2 || recovered.allEntries[0].Duration != HumanDuration(20*time.Minute) {
+		t.Errorf("Expected Bob's finish recovered, got %#v", recovered.allEntries[0])
+	}
+}
+
+func TestSystemdUnit(t *testing.T) {
+	unit := systemdUnit("/opt/racergo/racergo", "/opt/racergo", []string{"RACERGORACENAME=Orchard Run"})
+	for _, line := range []string{
+		`ExecStart="/opt/racergo/racergo" run`,
+		"WorkingDirectory=/opt/racergo",
+		`Environment="RACERGORACENAME=Orchard Run"`,
+		"Restart=always",
+		"RestartPreventExitStatus=64",
+	} {
+		if !strings.Contains(unit, line+"\n") {
+			t.Errorf("Expected %s in the unit - %s", line, unit)
+		}
+	}
+	if done, code := serviceCommand([]string{"run"}); done || code != exitOK {
+		t.Errorf("Expected run to carry on running the race")
+	}
+	if done, code := serviceCommand([]string{"bogus"}); !done || code != exitUsage {
+		t.Errorf("Expected a usage error for an unknown subcommand, got %d", code)
+	}
+}
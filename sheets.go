@@ -0,0 +1,130 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Sheet is a page of results for the results board, places First through Last
+type Sheet struct {
+	Number      int
+	First, Last Place
+	Printed     time.Time
+	Posted      time.Time
+	fingerprint string // what was on the sheet when it was printed
+	Changed     bool   // the results on the sheet have been corrected since it was printed
+}
+
+func (s Sheet) Status() string {
+	switch {
+	case s.Changed:
+		return "Reprint, results changed"
+	case !s.Posted.IsZero():
+		return "Posted"
+	case !s.Printed.IsZero():
+		return "Printed"
+	}
+	return "Ready"
+}
+
+// lockedConfirmedPlaces counts the finishers from first place on that are all confirmed, the places that can go on the board
+func (race *Race) lockedConfirmedPlaces() Place {
+	for x, e := range race.allEntries {
+		if !e.Ranked() || !e.Confirmed {
+			return Place(x)
+		}
+	}
+	return Place(len(race.allEntries))
+}
+
+// lockedSheetFingerprint summarizes everything printed on the sheet so corrections can be noticed
+func (race *Race) lockedSheetFingerprint(first, last Place) string {
+	hash := md5.New()
+	for place := first; place <= last && int(place) <= len(race.allEntries); place++ {
+		hash.Write([]byte(race.allEntries[place-1].Nonce()))
+	}
+	return base64.StdEncoding.EncodeToString(hash.Sum(nil))
+}
+
+// lockedSheets lists every sheet that can be printed, a sheet is ready once all its places are confirmed.
+// The last partial sheet is only ready once the results are final.
+func (race *Race) lockedSheets() []Sheet {
+	size := Place(config.sheetSize)
+	confirmed := race.lockedConfirmedPlaces()
+	var sheets []Sheet
+	for first := Place(1); first <= confirmed; first += size {
+		last := first + size - 1
+		if last > confirmed {
+			if !race.finalized {
+				break
+			}
+			last = confirmed
+		}
+		sheet := Sheet{Number: len(sheets) + 1, First: first, Last: last}
+		if len(race.sheets) > len(sheets) && !race.sheets[len(sheets)].Printed.IsZero() {
+			printed := race.sheets[len(sheets)]
+			sheet.Printed = printed.Printed
+			sheet.Posted = printed.Posted
+			sheet.fingerprint = printed.fingerprint
+			sheet.Changed = printed.Last != last || printed.fingerprint != race.lockedSheetFingerprint(first, last)
+		}
+		sheets = append(sheets, sheet)
+	}
+	// sheets printed before a correction removed finishers from the board need taking down
+	for x := len(sheets); x < len(race.sheets); x++ {
+		if !race.sheets[x].Printed.IsZero() {
+			gone := *race.sheets[x]
+			gone.Changed = true
+			sheets = append(sheets, gone)
+		}
+	}
+	return sheets
+}
+
+// lockedPrintSheet returns the rows for the sheet and records it as printed
+func (race *Race) lockedPrintSheet(number int) (Sheet, []ResultRow, error) {
+	sheets := race.lockedSheets()
+	if number < 1 || number > len(sheets) || sheets[number-1].Last > race.lockedConfirmedPlaces() {
+		return Sheet{}, nil, fmt.Errorf("Sheet %d isn't ready, not all of its places are confirmed", number)
+	}
+	sheet := sheets[number-1]
+	sheet.Printed = race.GetTime()
+	sheet.Posted = time.Time{}
+	sheet.Changed = false
+	sheet.fingerprint = race.lockedSheetFingerprint(sheet.First, sheet.Last)
+	for len(race.sheets) < number {
+		race.sheets = append(race.sheets, &Sheet{Number: len(race.sheets) + 1})
+	}
+	*race.sheets[number-1] = sheet
+	log.Printf("Printed results sheet %d, places %d-%d", number, sheet.First, sheet.Last)
+	return sheet, race.lockedFinishers()[sheet.First-1 : sheet.Last], nil
+}
+
+// PostSheet records the printed sheet as put up on the results board
+func (race *Race) PostSheet(number int) error {
+	race.Lock()
+	defer race.Unlock()
+	if number < 1 || number > len(race.sheets) || race.sheets[number-1].Printed.IsZero() {
+		return fmt.Errorf("Sheet %d hasn't been printed yet", number)
+	}
+	race.sheets[number-1].Posted = race.GetTime()
+	return nil
+}
+
+func postSheetHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	number, err := strconv.Atoi(r.FormValue("sheet"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting sheet number", err)
+		return
+	}
+	if err = race.PostSheet(number); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/sheets", 301)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResultsBoardSheets(t *testing.T) {
+	defer func(size int) { config.sheetSize = size }(config.sheetSize)
+	config.sheetSize = 2
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38},
+		{Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 31},
+		{Bib: 3, Fname: "Cal", Lname: "Cole", Male: true, Age: 52},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	startRace(race)
+	for _, bib := range []int{2, 1, 3} {
+		*race.testingTime = race.testingTime.Add(time.Minute)
+		linkBibTesting(t, race, bib, false)
+	}
+	for _, bib := range []int{2, 1} {
+		linkBibTesting(t, race, bib, false) // confirm
+	}
+
+	race.Lock()
+	sheets := race.lockedSheets()
+	race.Unlock()
+	if len(sheets) != 1 || sheets[0].First != 1 || sheets[0].Last != 2 || sheets[0].Status() != "Ready" {
+		t.Fatalf("Expected only the first full sheet ready, got %#v", sheets)
+	}
+
+	r, _ := http.NewRequest("GET", "/sheet?sheet=2", nil)
+	w := httptest.NewRecorder()
+	handler(w, r, race)
+	if !strings.Contains(w.Body.String(), "Sheet 2 isn&#39;t ready") {
+		t.Errorf("Expected an error printing a sheet that isn't ready - %s", w.Body.String())
+	}
+	r, _ = http.NewRequest("GET", "/sheet?sheet=1", nil)
+	w = httptest.NewRecorder()
+	handler(w, r, race)
+	if body := w.Body.String(); !strings.Contains(body, "Places 1 to 2") || !strings.Contains(body, "Bob Adams") || strings.Contains(body, "Cal Cole") {
+		t.Errorf("Expected Bob and Amy on sheet 1 - %s", body)
+	}
+	if err := race.PostSheet(1); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+
+	race.Lock()
+	race.bibbedEntries[2].Fname = "Rob" // a registration correction
+	sheets = race.lockedSheets()
+	race.Unlock()
+	if sheets[0].Status() != "Reprint, results changed" {
+		t.Errorf("Expected the posted sheet flagged after a correction, got %s", sheets[0].Status())
+	}
+
+	linkBibTesting(t, race, 3, false)
+	race.Lock()
+	race.finalized = true
+	sheets = race.lockedSheets()
+	race.Unlock()
+	if len(sheets) != 2 || sheets[1].First != 3 || sheets[1].Last != 3 {
+		t.Errorf("Expected the partial last sheet once finalized, got %#v", sheets)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseSMSCommand reads a checkpoint report texted in by a volunteer, e.g. "CP2 145 150 152" or
+// "CP2 10:32 145 150" when the texts are sent after the fact.  Times are on the same day as the race start.
+func parseSMSCommand(body string, started time.Time) (string, time.Time, []Bib, error) {
+	fields := strings.FieldsFunc(body, func(r rune) bool {
+		return r == ' ' || r == ',' || r == '\n' || r == '\r' || r == '\t'
+	})
+	if len(fields) < 2 {
+		return "", time.Time{}, nil, fmt.Errorf("Send the checkpoint followed by bibs, e.g. CP2 145 150 152")
+	}
+	checkpoint := strings.ToUpper(fields[0])
+	at := time.Time{}
+	if strings.Contains(fields[1], ":") {
+		var clock time.Time
+		var err error
+		for _, layout := range []string{"15:04:05", "15:04"} {
+			if clock, err = time.Parse(layout, fields[1]); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return "", time.Time{}, nil, fmt.Errorf("%s is not a time, use HH:MM or HH:MM:SS", fields[1])
+		}
+		at = time.Date(started.Year(), started.Month(), started.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, started.Location())
+		fields = fields[1:]
+	}
+	bibs := make([]Bib, 0, len(fields)-1)
+	for _, f := range fields[1:] {
+		bib, err := strconv.Atoi(strings.TrimPrefix(f, "#"))
+		if err != nil || bib < 0 {
+			return "", time.Time{}, nil, fmt.Errorf("%s is not a bib number", f)
+		}
+		bibs = append(bibs, Bib(bib))
+	}
+	if len(bibs) == 0 {
+		return "", time.Time{}, nil, fmt.Errorf("No bibs found after %s", checkpoint)
+	}
+	return checkpoint, at, bibs, nil
+}
+
+// validTwilioSignature checks the request was signed by Twilio with our auth token
+func validTwilioSignature(r *http.Request, authToken string) bool {
+	signed := requestScheme(r) + "://" + requestHost(r) + r.URL.RequestURI()
+	keys := make([]string, 0, len(r.PostForm))
+	for key := range r.PostForm {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		signed += key + r.PostForm.Get(key)
+	}
+	mac := hmac.New(sha1.New, []byte(authToken))
+	mac.Write([]byte(signed))
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(r.Header.Get("X-Twilio-Signature")))
+}
+
+// RecordSMS records the passings in a volunteer's text, returning the reply to text back
+func (race *Race) RecordSMS(from, body string) string {
+	race.Lock()
+	defer race.Unlock()
+	if race.started.IsZero() {
+		return "Race has not started yet, passings not recorded"
+	}
+	checkpoint, at, bibs, err := parseSMSCommand(body, race.started)
+	if err != nil {
+		return err.Error()
+	}
+	if !knownCheckpoint(checkpoint) {
+		return fmt.Sprintf("Unknown checkpoint %s, expected one of %s", checkpoint, strings.Join(config.checkpoints, " "))
+	}
+	if at.IsZero() {
+		at = race.GetTime()
+	}
+	recorded, failed, medical := []string{}, []string{}, false
+	for _, bib := range bibs {
+		if err := race.lockedRecordPassing(Passing{Checkpoint: checkpoint, Bib: bib, Time: at, Source: from}); err != nil {
+			failed = append(failed, bib.String())
+			continue
+		}
+		recorded = append(recorded, bib.String())
+		medical = medical || race.lockedMedicalFlag(bib)
+	}
+	reply := fmt.Sprintf("%s: recorded %d", checkpoint, len(recorded))
+	if len(failed) > 0 {
+		reply += ", unknown bibs " + strings.Join(failed, " ")
+	}
+	if medical { // anyone can text in, so which racers have notes is left for the medical lookup
+		reply += ", medical has notes, check the medical lookup"
+	}
+	return reply
+}
+
+// smsHandler is the inbound message webhook for Twilio, replying to the volunteer with what was recorded.  Messages
+// are refused unless they're signed with RACERGOTWILIOAUTHTOKEN
+func smsHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	r.ParseForm()
+	if config.twilioAuthToken == "" || !validTwilioSignature(r, config.twilioAuthToken) {
+		http.Error(w, "Invalid signature", http.StatusForbidden)
+		return
+	}
+	reply := race.RecordSMS(r.PostFormValue("From"), r.PostFormValue("Body"))
+	w.Header().Set("Content-type", "text/xml")
+	fmt.Fprint(w, xml.Header)
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Response"`
+		Message string
+	}{Message: reply})
+}
+
+// sendSMS texts the message through Twilio, doing nothing unless RACERGOTWILIOACCOUNTSID and RACERGOTWILIOFROM are set
+func sendSMS(to, body string) {
+	if config.twilioAccountSID == "" || config.twilioFrom == "" {
+		return
+	}
+	if err := textSMS(to, body); err != nil {
+		log.Printf("Error texting %s - %v", to, err)
+	}
+}
+
+// textSMS sends the message through Twilio, returning why it wasn't sent
+func textSMS(to, body string) error {
+	form := url.Values{"From": {config.twilioFrom}, "To": {to}, "Body": {body}}
+	req, err := http.NewRequest("POST", fmt.Sprintf("https://api.twilio.com/2010-04-01/Accounts/%s/Messages.json", config.twilioAccountSID), strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(config.twilioAccountSID, config.twilioAuthToken)
+	resp, err := syncClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Twilio returned %s", resp.Status)
+	}
+	return nil
+}
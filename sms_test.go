@@ -0,0 +1,73 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSMSCommand(t *testing.T) {
+	started := time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	checkpoint, at, bibs, err := parseSMSCommand("cp2 145, 150 #152", started)
+	if err != nil || checkpoint != "CP2" || !at.IsZero() || len(bibs) != 3 || bibs[2] != 152 {
+		t.Errorf("Unexpected parse - %s %s %v %v", checkpoint, at, bibs, err)
+	}
+	_, at, bibs, err = parseSMSCommand("CP1 9:32:15 7", started)
+	if err != nil || !at.Equal(started.Add(32*time.Minute+15*time.Second)) || len(bibs) != 1 {
+		t.Errorf("Unexpected parse with a time - %s %v %v", at, bibs, err)
+	}
+	for _, body := range []string{"", "CP2", "CP2 10:15", "CP2 abc", "CP2 25:99 1"} {
+		if _, _, _, err := parseSMSCommand(body, started); err == nil {
+			t.Errorf("Expected error parsing %q", body)
+		}
+	}
+}
+
+func TestSMSWebhook(t *testing.T) {
+	defer func(token string) { config.twilioAuthToken = token }(config.twilioAuthToken)
+	config.twilioAuthToken = "secret"
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{{Bib: 145, Fname: "Amy", Lname: "Brown"}, {Bib: 150, Fname: "Bob", Lname: "Adams"}} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	startRace(race)
+
+	form := url.Values{"From": {"+15555550100"}, "Body": {"CP2 145 150 999"}}
+	send := func(signature string) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest("POST", "http://example.com/sms", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Header.Set("X-Twilio-Signature", signature)
+		w := httptest.NewRecorder()
+		smsHandler(w, r, race)
+		return w
+	}
+	if w := send("forged"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected a forged signature to be refused, got %d", w.Code)
+	}
+	config.twilioAuthToken = ""
+	if w := send(""); w.Code != http.StatusForbidden {
+		t.Errorf("Expected an unsigned message to be refused without an auth token, got %d", w.Code)
+	}
+	config.twilioAuthToken = "secret"
+	mac := hmac.New(sha1.New, []byte("secret"))
+	mac.Write([]byte("http://example.com/smsBodyCP2 145 150 999From+15555550100"))
+	w := send(base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	if !strings.Contains(w.Body.String(), "<Response><Message>CP2: recorded 2, unknown bibs 999</Message></Response>") {
+		t.Errorf("Unexpected reply - %d %s", w.Code, w.Body.String())
+	}
+	race.RLock()
+	defer race.RUnlock()
+	if splits := race.lockedSplits(); len(splits) != 2 || splits[0].Source != "+15555550100" {
+		t.Errorf("Expected 2 passings from the volunteer's phone, got %v", splits)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// Passing is a bib seen going by a checkpoint on the course
+type Passing struct {
+	Checkpoint string
+	Bib        Bib
+	Time       time.Time
+	Source     string // where the passing came from, e.g. the phone number that texted it in
+}
+
+func (p Passing) Split(started time.Time) HumanDuration {
+	return HumanDuration(p.Time.Sub(started))
+}
+
+// knownCheckpoint is true for checkpoints listed in RACERGOCHECKPOINTS, or any checkpoint if none are listed
+func knownCheckpoint(checkpoint string) bool {
+	if len(config.checkpoints) == 0 {
+		return true
+	}
+	for _, cp := range config.checkpoints {
+		if cp == checkpoint {
+			return true
+		}
+	}
+	return false
+}
+
+// lockedRecordPassing merges the passing into the race's splits.  A bib reported at the same checkpoint
+// more than once keeps the earliest time, since reports sent in late shouldn't push a split back.
+func (race *Race) lockedRecordPassing(passing Passing) error {
+	if race.started.IsZero() {
+		return fmt.Errorf("Race has not started yet, cannot record a passing")
+	}
+	if !knownCheckpoint(passing.Checkpoint) {
+		return fmt.Errorf("Unknown checkpoint %s", passing.Checkpoint)
+	}
+	if _, ok := race.bibbedEntries[passing.Bib]; !ok {
+		return fmt.Errorf("Bib %d not found", passing.Bib)
+	}
+	for _, p := range race.passings {
+		if p.Checkpoint == passing.Checkpoint && p.Bib == passing.Bib {
+			if passing.Time.Before(p.Time) {
+				p.Time = passing.Time
+				p.Source = passing.Source
+				race.lockedRecordEvent(Event{Kind: "passing", Passing: &passing})
+			}
+			return nil
+		}
+	}
+	log.Printf("Bib #%d passed %s at %s", passing.Bib, passing.Checkpoint, passing.Split(race.started))
+	race.passings = append(race.passings, &passing)
+	race.lockedScorePassing(passing)
+	recorded := passing // the split can still move earlier, the event keeps this report
+	race.lockedRecordEvent(Event{Kind: "passing", Passing: &recorded})
+	return nil
+}
+
+// lockedSplits returns the passings in checkpoint then time order for display
+func (race *Race) lockedSplits() []*Passing {
+	splits := make([]*Passing, len(race.passings))
+	copy(splits, race.passings)
+	sort.SliceStable(splits, func(i, j int) bool {
+		if splits[i].Checkpoint != splits[j].Checkpoint {
+			return splits[i].Checkpoint < splits[j].Checkpoint
+		}
+		return splits[i].Time.Before(splits[j].Time)
+	})
+	return splits
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordPassing(t *testing.T) {
+	defer func(checkpoints []string) { config.checkpoints = checkpoints }(config.checkpoints)
+	config.checkpoints = []string{"CP1", "CP2"}
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	if err := race.AddEntry(Entry{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38}); err != nil {
+		t.Fatalf("Error adding entry - %v", err)
+	}
+	race.Lock()
+	if err := race.lockedRecordPassing(Passing{Checkpoint: "CP1", Bib: 1}); err == nil {
+		t.Errorf("Expected error recording a passing before the start")
+	}
+	race.Unlock()
+	startRace(race)
+	start := race.GetTime()
+	race.Lock()
+	defer race.Unlock()
+	if err := race.lockedRecordPassing(Passing{Checkpoint: "CP9", Bib: 1, Time: start}); err == nil {
+		t.Errorf("Expected error for an unknown checkpoint")
+	}
+	if err := race.lockedRecordPassing(Passing{Checkpoint: "CP1", Bib: 2, Time: start}); err == nil {
+		t.Errorf("Expected error for an unknown bib")
+	}
+	for _, p := range []Passing{
+		{Checkpoint: "CP2", Bib: 1, Time: start.Add(20 * time.Minute), Source: "late"},
+		{Checkpoint: "CP1", Bib: 1, Time: start.Add(10 * time.Minute), Source: "a"},
+		{Checkpoint: "CP2", Bib: 1, Time: start.Add(19 * time.Minute), Source: "b"},
+	} {
+		if err := race.lockedRecordPassing(p); err != nil {
+			t.Errorf("Unexpected error - %v", err)
+		}
+	}
+	splits := race.lockedSplits()
+	if len(splits) != 2 {
+		t.Fatalf("Expected 2 splits, got %d", len(splits))
+	}
+	if splits[0].Checkpoint != "CP1" || splits[1].Split(start) != HumanDuration(19*time.Minute) || splits[1].Source != "b" {
+		t.Errorf("Expected the earliest CP2 report to be kept, got %#v %#v", splits[0], splits[1])
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Sponsor is a logo rotated through the public pages, shown in proportion to its Weight
+// while inside its From/Until window (zero times leave that side of the window open)
+type Sponsor struct {
+	Name        string
+	Image       string // file name within config.sponsorDir
+	Weight      uint
+	From        time.Time
+	Until       time.Time
+	Impressions uint64
+}
+
+const sponsorTimeFormat = "2006-01-02T15:04" // what a datetime-local input sends
+
+func (s *Sponsor) Active(now time.Time) bool {
+	return (s.From.IsZero() || !now.Before(s.From)) && (s.Until.IsZero() || now.Before(s.Until))
+}
+
+func (race *Race) AddSponsor(sponsor Sponsor) error {
+	if sponsor.Name == "" {
+		return fmt.Errorf("Sponsor needs a name")
+	}
+	if sponsor.Weight == 0 {
+		return fmt.Errorf("Sponsor %s needs a weight of at least 1", sponsor.Name)
+	}
+	if !sponsor.Until.IsZero() && !sponsor.Until.After(sponsor.From) {
+		return fmt.Errorf("Sponsor %s is scheduled to stop before it starts", sponsor.Name)
+	}
+	race.Lock()
+	defer race.Unlock()
+	for _, s := range race.sponsors {
+		if s.Name == sponsor.Name {
+			return fmt.Errorf("Sponsor %s already exists", sponsor.Name)
+		}
+	}
+	race.sponsors = append(race.sponsors, &sponsor)
+	return nil
+}
+
+func (race *Race) RemoveSponsor(name string) error {
+	race.Lock()
+	defer race.Unlock()
+	for x, s := range race.sponsors {
+		if s.Name == name {
+			race.sponsors = append(race.sponsors[:x], race.sponsors[x+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("Sponsor %s not found", name)
+}
+
+// lockedSponsorCounts copies the sponsors so their impressions can be shown while spectators' pages keep counting them
+func (race *Race) lockedSponsorCounts() []Sponsor {
+	race.statsLock.Lock()
+	defer race.statsLock.Unlock()
+	sponsors := make([]Sponsor, len(race.sponsors))
+	for x, s := range race.sponsors {
+		sponsors[x] = *s
+	}
+	return sponsors
+}
+
+// lockedNextSponsor picks a sponsor to show by weight from those currently scheduled and counts the impression
+func (race *Race) lockedNextSponsor(now time.Time) *Sponsor {
+	total := uint(0)
+	for _, s := range race.sponsors {
+		if s.Active(now) {
+			total += s.Weight
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+	pick := uint(rand.Int63n(int64(total)))
+	for _, s := range race.sponsors {
+		if !s.Active(now) {
+			continue
+		}
+		if pick < s.Weight {
+			race.statsLock.Lock()
+			s.Impressions++
+			race.statsLock.Unlock()
+			return s
+		}
+		pick -= s.Weight
+	}
+	return nil
+}
+
+func parseSponsorTime(val string) (time.Time, error) {
+	if val == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation(sponsorTimeFormat, val, time.Local)
+}
+
+func uploadSponsorHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	err := r.ParseMultipartForm(1 << 20)
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error getting Reader - %s", err)
+		return
+	}
+	sponsor := Sponsor{Name: r.FormValue("name")}
+	weight, err := strconv.Atoi(r.FormValue("weight"))
+	if err != nil || weight < 1 {
+		showErrorForAdmin(w, r.Referer(), "%s is not a valid weight, must be at least 1", r.FormValue("weight"))
+		return
+	}
+	sponsor.Weight = uint(weight)
+	if sponsor.From, err = parseSponsorTime(r.FormValue("from")); err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error parsing from time - %v", err)
+		return
+	}
+	if sponsor.Until, err = parseSponsorTime(r.FormValue("until")); err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error parsing until time - %v", err)
+		return
+	}
+	file, header, err := r.FormFile("logo")
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error getting logo - %s", err)
+		return
+	}
+	defer file.Close()
+	sponsor.Image = filepath.Base(header.Filename)
+	if err = os.MkdirAll(config.sponsorDir, 0755); err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error creating sponsor directory - %v", err)
+		return
+	}
+	out, err := os.Create(filepath.Join(config.sponsorDir, sponsor.Image))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error saving logo - %v", err)
+		return
+	}
+	_, err = io.Copy(out, file)
+	out.Close()
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error saving logo - %v", err)
+		return
+	}
+	if err = race.AddSponsor(sponsor); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/sponsors", 301)
+}
+
+func removeSponsorHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	if err := race.RemoveSponsor(r.FormValue("name")); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/sponsors", 301)
+}
+
+// sponsorReportHandler downloads the impression counts for the post-race sponsor report
+func sponsorReportHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	w.Header().Set("Content-type", "application/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s-sponsors.csv\"", config.webserverHostname))
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"Sponsor", "Weight", "Impressions"})
+	race.RLock()
+	for _, s := range race.lockedSponsorCounts() {
+		writer.Write([]string{s.Name, strconv.Itoa(int(s.Weight)), strconv.FormatUint(s.Impressions, 10)})
+	}
+	race.RUnlock()
+	writer.Flush()
+}
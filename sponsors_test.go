@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSponsorRotation(t *testing.T) {
+	race := NewRace()
+	now := time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	if err := race.AddSponsor(Sponsor{Name: "Always", Image: "always.png", Weight: 3}); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if err := race.AddSponsor(Sponsor{Name: "Morning", Image: "morning.png", Weight: 1, Until: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if err := race.AddSponsor(Sponsor{Name: "Later", Image: "later.png", Weight: 100, From: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if err := race.AddSponsor(Sponsor{Name: "Always", Weight: 1}); err == nil {
+		t.Errorf("Expected error adding a duplicate sponsor")
+	}
+	if err := race.AddSponsor(Sponsor{Name: "Nothing", Weight: 0}); err == nil {
+		t.Errorf("Expected error adding a sponsor with no weight")
+	}
+	if err := race.AddSponsor(Sponsor{Name: "Backwards", Weight: 1, From: now, Until: now.Add(-time.Hour)}); err == nil {
+		t.Errorf("Expected error adding a sponsor scheduled to stop before it starts")
+	}
+
+	race.Lock()
+	for x := 0; x < 400; x++ {
+		race.lockedNextSponsor(now)
+	}
+	race.Unlock()
+	counts := map[string]uint64{}
+	for _, s := range race.sponsors {
+		counts[s.Name] = s.Impressions
+	}
+	if counts["Always"]+counts["Morning"] != 400 || counts["Later"] != 0 {
+		t.Errorf("Expected 400 impressions among the scheduled sponsors, got %v", counts)
+	}
+	if counts["Always"] < 250 || counts["Morning"] < 50 { // expect 300 to 100
+		t.Errorf("Expected impressions in proportion to weight, got %v", counts)
+	}
+
+	race.Lock()
+	if s := race.lockedNextSponsor(now.Add(2 * time.Hour)); s == nil || s.Name == "Morning" {
+		t.Errorf("Expected a sponsor other than Morning after its window closed, got %v", s)
+	}
+	race.Unlock()
+
+	if err := race.RemoveSponsor("Morning"); err != nil {
+		t.Errorf("Unexpected error - %v", err)
+	}
+	if err := race.RemoveSponsor("Morning"); err == nil {
+		t.Errorf("Expected error removing a missing sponsor")
+	}
+
+	r, _ := http.NewRequest("GET", "/sponsorReport", nil)
+	w := httptest.NewRecorder()
+	sponsorReportHandler(w, r, race)
+	want := "Sponsor,Weight,Impressions\n"
+	for _, s := range race.sponsors {
+		want += s.Name + "," + strconv.Itoa(int(s.Weight)) + "," + strconv.FormatUint(s.Impressions, 10) + "\n"
+	}
+	if w.Body.String() != want {
+		t.Errorf("Wanted %q, got %q", want, w.Body.String())
+	}
+}
+
+func TestUploadSponsor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sponsors")
+	if err != nil {
+		t.Fatalf("Error creating temp dir - %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(old string) { config.sponsorDir = old }(config.sponsorDir)
+	config.sponsorDir = dir
+
+	race := NewRace()
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	mw.WriteField("name", "Local Bank")
+	mw.WriteField("weight", "2")
+	mw.WriteField("from", "2014-06-01T08:00")
+	fw, _ := mw.CreateFormFile("logo", "../bank.png")
+	fw.Write([]byte("not really a png"))
+	mw.Close()
+	r, _ := http.NewRequest("POST", "/uploadSponsor", body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	uploadSponsorHandler(w, r, race)
+	if w.Code != 301 {
+		t.Fatalf("Expected 301, got %d - %s", w.Code, w.Body.String())
+	}
+	if len(race.sponsors) != 1 || race.sponsors[0].Image != "bank.png" || race.sponsors[0].Weight != 2 || race.sponsors[0].From.Hour() != 8 {
+		t.Errorf("Sponsor not added as expected - %#v", race.sponsors)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "bank.png")); err != nil {
+		t.Errorf("Expected logo saved in the sponsor directory - %v", err)
+	}
+
+	r, _ = http.NewRequest("GET", "/sponsors", nil)
+	w = httptest.NewRecorder()
+	handler(w, r, race)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected %d, got %d - %s", http.StatusOK, w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// StartCapture is one press of the start, by hand or from the start trigger
+type StartCapture struct {
+	At     time.Time
+	Source string
+}
+
+// CaptureStart starts the race, or once it's started records the extra press so the admin can pick the right one
+func (race *Race) CaptureStart(t *time.Time, source string) error {
+	race.Lock()
+	defer race.Unlock()
+	if race.started.IsZero() {
+		return race.lockedStart(t, source)
+	}
+	at := race.GetTime()
+	if t != nil {
+		at = withMonotonic(*t)
+	}
+	race.startCaptures = append(race.startCaptures, StartCapture{At: at, Source: source})
+	race.lockedRecordEvent(Event{Kind: "startCapture", Time: at, Source: source})
+	log.Printf("Start captured again by %s, %s after the start in use", source, HumanDuration(at.Sub(race.started)))
+	return nil
+}
+
+// SelectStart makes the capture the start of the race, moving every finish time by the difference
+func (race *Race) SelectStart(index int) error {
+	race.Lock()
+	defer race.Unlock()
+	if race.finalized {
+		return fmt.Errorf("Results have been finalized, cannot change the start")
+	}
+	if index < 0 || index >= len(race.startCaptures) {
+		return fmt.Errorf("Start capture %d not found", index)
+	}
+	previous := race.started
+	race.started = race.startCaptures[index].At
+	race.startCapture = index
+	shift := HumanDuration(previous.Sub(race.started))
+	for _, entry := range race.allEntries {
+		if entry.HasFinished() {
+			entry.Duration = HumanDuration(entry.TimeFinished.Sub(race.lockedStartOf(entry)))
+		}
+	}
+	for x := range race.chute.Times {
+		race.chute.Times[x] += shift
+	}
+	race.lockedRecomputePrizes()
+	if race.startRaceChan != nil {
+		race.startRaceChan <- race.started
+	}
+	race.lockedRecordEvent(Event{Kind: "selectStart", Capture: index})
+	log.Printf("Start changed to %s from %s, finish times moved by %s", race.started.Format("15:04:05.00"), race.startCaptures[index].Source, shift)
+	return nil
+}
+
+func selectStartHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	index, err := strconv.Atoi(r.FormValue("capture"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting the start capture", err)
+		return
+	}
+	if err = race.SelectStart(index); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/admin", 301)
+}
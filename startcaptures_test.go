@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStartCaptures(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	if err := race.AddEntry(Entry{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 28}); err != nil {
+		t.Fatalf("Error adding entry - %v", err)
+	}
+	if err := race.CaptureStart(nil, "Start button"); err != nil {
+		t.Fatalf("Unexpected error starting - %v", err)
+	}
+	gun := race.testingTime.Add(1500 * time.Millisecond)
+	if err := race.CaptureStart(&gun, "udp::9999"); err != nil {
+		t.Fatalf("Unexpected error capturing the start again - %v", err)
+	}
+	if len(race.startCaptures) != 2 || !race.started.Equal(*race.testingTime) {
+		t.Fatalf("Expected the first press to start the race and the second captured, got %v", race.startCaptures)
+	}
+	*race.testingTime = race.testingTime.Add(20 * time.Minute)
+	linkBibTesting(t, race, 1, false)
+	linkBibTesting(t, race, 1, false)
+
+	r, _ := http.NewRequest("GET", "/admin", nil)
+	w := httptest.NewRecorder()
+	handler(w, r, race)
+	if body := w.Body.String(); !strings.Contains(body, "The start was captured 2 times") || !strings.Contains(body, "udp::9999") {
+		t.Errorf("Expected both start captures on the admin page - %s", body)
+	}
+
+	if err := race.SelectStart(2); err == nil {
+		t.Errorf("Expected an error selecting a missing capture")
+	}
+	if err := race.SelectStart(1); err != nil {
+		t.Fatalf("Unexpected error selecting the start - %v", err)
+	}
+	if expected := HumanDuration(20*time.Minute - 1500*time.Millisecond); race.bibbedEntries[1].Duration != expected {
+		t.Errorf("Expected Amy's time moved to %s, got %s", expected, race.bibbedEntries[1].Duration)
+	}
+
+	replayed := NewRace()
+	if err := replayed.Replay(race.events); err != nil {
+		t.Fatalf("Unexpected error replaying - %v", err)
+	}
+	if replayed.startCapture != 1 || !replayed.started.Equal(gun) || replayed.bibbedEntries[1].Duration != race.bibbedEntries[1].Duration {
+		t.Errorf("Expected the selected start kept in the event log, got capture %d at %s", replayed.startCapture, replayed.started)
+	}
+	if err := race.Undo(); err != nil {
+		t.Fatalf("Unexpected error undoing - %v", err)
+	}
+	if race.startCapture != 0 || race.bibbedEntries[1].Duration != HumanDuration(20*time.Minute) {
+		t.Errorf("Expected undo to go back to the first start, got capture %d and %s", race.startCapture, race.bibbedEntries[1].Duration)
+	}
+}
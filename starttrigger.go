@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// gpioRoot is where Linux exposes GPIO pins, e.g. on a Raspberry Pi
+var gpioRoot = "/sys/class/gpio"
+
+// gpioPoll is how often a GPIO pin is read, well under the hundredths race times are shown in
+var gpioPoll = time.Millisecond
+
+// startTrigger waits for the gun, returning the moment it went off
+type startTrigger func() (time.Time, error)
+
+// parseStartTrigger reads RACERGOSTARTTRIGGER, one of
+//
+//	udp:<listen address>  a datagram from the start horn system, e.g. udp::9999
+//	serial:<device>       any byte from a USB serial device, e.g. serial:/dev/ttyUSB0, set the baud rate with stty first
+//	gpio:<pin>            the pin going high, e.g. gpio:17 on a Raspberry Pi
+//
+// When RACERGOSTARTTRIGGERSECRET is set, a datagram has to contain it to start the race.
+func parseStartTrigger(spec string) (startTrigger, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, fmt.Errorf("%s is not a start trigger, expected udp:<address>, serial:<device> or gpio:<pin>", spec)
+	}
+	switch parts[0] {
+	case "udp":
+		conn, err := net.ListenPacket("udp", parts[1])
+		if err != nil {
+			return nil, err
+		}
+		return udpTrigger(conn, config.startTriggerSecret), nil
+	case "serial":
+		return serialTrigger(parts[1]), nil
+	case "gpio":
+		return gpioTrigger(parts[1]), nil
+	}
+	return nil, fmt.Errorf("Unknown start trigger %s, expected udp, serial or gpio", parts[0])
+}
+
+func udpTrigger(conn net.PacketConn, secret string) startTrigger {
+	buf := make([]byte, 1500)
+	return func() (time.Time, error) {
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			at := time.Now()
+			if err != nil {
+				return at, err
+			}
+			if secret != "" && !bytes.Contains(buf[:n], []byte(secret)) {
+				log.Printf("Ignoring a start signal from %s without the secret", from)
+				continue
+			}
+			return at, nil
+		}
+	}
+}
+
+func serialTrigger(device string) startTrigger {
+	return func() (time.Time, error) {
+		port, err := os.Open(device)
+		if err != nil {
+			return time.Time{}, err
+		}
+		defer port.Close()
+		buf := make([]byte, 1)
+		_, err = port.Read(buf)
+		return time.Now(), err
+	}
+}
+
+// gpioTrigger exports the pin as an input if it isn't already, then waits for it to go from low to high
+func gpioTrigger(pin string) startTrigger {
+	dir := filepath.Join(gpioRoot, "gpio"+pin)
+	return func() (time.Time, error) {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			if err = ioutil.WriteFile(filepath.Join(gpioRoot, "export"), []byte(pin), 0200); err != nil {
+				return time.Time{}, err
+			}
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "direction"), []byte("in"), 0644); err != nil {
+			return time.Time{}, err
+		}
+		wasLow := false
+		for {
+			value, err := ioutil.ReadFile(filepath.Join(dir, "value"))
+			at := time.Now()
+			if err != nil {
+				return at, err
+			}
+			high := strings.TrimSpace(string(value)) == "1"
+			if high && wasLow {
+				return at, nil
+			}
+			wasLow = !high
+			time.Sleep(gpioPoll)
+		}
+	}
+}
+
+// watchStartTrigger starts the race at the moment the trigger fires, retrying a second later if the device goes away
+func watchStartTrigger(race *Race, trigger startTrigger) {
+	for {
+		at, err := trigger()
+		if err != nil {
+			log.Printf("Error waiting for the start trigger %s - %v", config.startTrigger, err)
+			time.Sleep(time.Second)
+			continue
+		}
+		if err = race.CaptureStart(&at, config.startTrigger); err != nil {
+			log.Printf("Ignoring the start trigger - %v", err)
+			continue
+		}
+		log.Printf("Start captured by %s at %s", config.startTrigger, at.Format("15:04:05.000"))
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUDPStartTrigger(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening - %v", err)
+	}
+	defer conn.Close()
+	trigger := udpTrigger(conn, "horn-7")
+	sender, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Error dialing - %v", err)
+	}
+	defer sender.Close()
+	sender.Write([]byte("GO"))
+	sender.Write([]byte("GO horn-7"))
+	before := time.Now()
+	at, err := trigger()
+	if err != nil || at.Before(before) {
+		t.Fatalf("Expected the signed datagram to fire the trigger, got %s - %v", at, err)
+	}
+
+	race := NewRace()
+	err = race.Start(&at)
+	if drift := race.started.Round(0).Sub(at.Round(0)); err != nil || drift > time.Millisecond || drift < -time.Millisecond {
+		t.Errorf("Expected the race started at the trigger, got %s - %v", race.started, err)
+	}
+	if _, err = parseStartTrigger("horn:1"); err == nil {
+		t.Errorf("Expected an error for an unknown trigger")
+	}
+}
+
+func TestGPIOStartTrigger(t *testing.T) {
+	root, err := ioutil.TempDir("", "gpio")
+	if err != nil {
+		t.Fatalf("Error making a temp dir - %v", err)
+	}
+	defer os.RemoveAll(root)
+	defer func(old string) { gpioRoot = old }(gpioRoot)
+	gpioRoot = root
+	pin := filepath.Join(root, "gpio17")
+	os.Mkdir(pin, 0755)
+	value := filepath.Join(pin, "value")
+	ioutil.WriteFile(value, []byte("1\n"), 0644) // a pin left high doesn't start the race, only a rising edge
+
+	fired := make(chan time.Time)
+	go func() {
+		at, err := gpioTrigger("17")()
+		if err != nil {
+			t.Errorf("Unexpected error - %v", err)
+		}
+		fired <- at
+	}()
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case <-fired:
+		t.Fatalf("Expected no start while the pin stays high")
+	default:
+	}
+	ioutil.WriteFile(value, []byte("0\n"), 0644)
+	time.Sleep(20 * time.Millisecond)
+	ioutil.WriteFile(value, []byte("1\n"), 0644)
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the rising edge to fire the trigger")
+	}
+	if direction, _ := ioutil.ReadFile(filepath.Join(pin, "direction")); string(direction) != "in" {
+		t.Errorf("Expected the pin set as an input, got %q", direction)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"path/filepath"
+)
+
+// staticVersions maps each file in static/ to a hash of its contents, worked out at startup
+var staticVersions map[string]string
+
+// versionStaticFiles hashes every file in the directory so asset URLs change whenever a file does
+func versionStaticFiles(dir string) map[string]string {
+	versions := make(map[string]string)
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		log.Printf("Error reading static files, assets won't be cached - %v", err)
+		return versions
+	}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			log.Printf("Error reading %s, it won't be cached - %v", f.Name(), err)
+			continue
+		}
+		sum := sha256.Sum256(data)
+		versions[f.Name()] = hex.EncodeToString(sum[:4])
+	}
+	return versions
+}
+
+// assetURL is the versioned URL for a file in static/.  The version goes in the query rather
+// than the path so the relative font URLs in bootstrap's CSS keep working.
+func assetURL(name string) string {
+	if version, ok := staticVersions[name]; ok {
+		return "/static/" + name + "?v=" + version
+	}
+	return "/static/" + name
+}
+
+// staticHandler caches versioned assets for a year, since a changed file gets a new URL,
+// and makes browsers check back for anything requested without the current version
+func staticHandler(dir string) http.Handler {
+	files := http.StripPrefix("/static/", http.FileServer(http.Dir(dir)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if version, ok := staticVersions[filepath.Base(r.URL.Path)]; ok && r.FormValue("v") == version {
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		} else {
+			w.Header().Set("Cache-Control", "no-cache")
+		}
+		files.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStaticVersions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "static")
+	if err != nil {
+		t.Fatalf("Error creating directory - %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err = ioutil.WriteFile(filepath.Join(dir, "site.css"), []byte("body {}"), 0644); err != nil {
+		t.Fatalf("Error writing file - %v", err)
+	}
+	defer func(versions map[string]string) { staticVersions = versions }(staticVersions)
+	staticVersions = versionStaticFiles(dir)
+	url := assetURL("site.css")
+	if !strings.HasPrefix(url, "/static/site.css?v=") || len(url) != len("/static/site.css?v=")+8 {
+		t.Errorf("Expected a versioned URL, got %s", url)
+	}
+	if url := assetURL("missing.css"); url != "/static/missing.css" {
+		t.Errorf("Expected an unversioned URL for an unknown file, got %s", url)
+	}
+
+	for path, cache := range map[string]string{
+		url:                    "public, max-age=31536000, immutable",
+		"/static/site.css":     "no-cache",
+		"/static/site.css?v=0": "no-cache",
+	} {
+		r, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		staticHandler(dir).ServeHTTP(w, r)
+		if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != cache || w.Body.String() != "body {}" {
+			t.Errorf("Expected %s cached with %q, got %d %q", path, cache, w.Code, w.Header().Get("Cache-Control"))
+		}
+	}
+
+	if err = ioutil.WriteFile(filepath.Join(dir, "site.css"), []byte("body { color: red; }"), 0644); err != nil {
+		t.Fatalf("Error writing file - %v", err)
+	}
+	staticVersions = versionStaticFiles(dir)
+	if assetURL("site.css") == url {
+		t.Errorf("Expected a new URL once the file changed")
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mzimmerman/racergo/internal/core"
+)
+
+// PastRace is an earlier year's finish times, loaded from its archive zip or results CSV to compare against
+type PastRace struct {
+	Year      string
+	Name      string // from the archive's race.csv, blank for a results CSV or an older archive
+	Durations []HumanDuration
+	Finishers []PastFinisher // in finish order
+	Weather   []Conditions   // from the archive's weather.csv
+}
+
+// PastFinisher is a finisher in an earlier year's results
+type PastFinisher struct {
+	Name     string
+	Male     bool
+	Duration HumanDuration
+}
+
+// previousYears are set from RACERGOPREVIOUSRESULTS, oldest first
+var previousYears []PastRace
+
+// histogramWidths are the bucket widths tried, the first to fit the times in histogramBuckets is used
+var histogramWidths = []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 30 * time.Minute, time.Hour}
+
+const histogramBuckets = 30
+
+// loadPastRace reads the results.csv from an archive zip, or a results CSV, as written by /download
+func loadPastRace(year, path string) (PastRace, error) {
+	past := PastRace{Year: year}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return past, err
+	}
+	if strings.HasSuffix(strings.ToLower(path), ".zip") {
+		archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return past, err
+		}
+		files := make(map[string][]byte)
+		for _, f := range archive.File {
+			switch f.Name {
+			case "results.csv", "race.csv", "weather.csv":
+				rc, err := f.Open()
+				if err != nil {
+					return past, err
+				}
+				files[f.Name], err = ioutil.ReadAll(rc)
+				rc.Close()
+				if err != nil {
+					return past, err
+				}
+			}
+		}
+		if data = files["results.csv"]; data == nil {
+			return past, fmt.Errorf("No results.csv in %s", path)
+		}
+		if err = past.readArchiveDetails(files["race.csv"], files["weather.csv"]); err != nil {
+			return past, fmt.Errorf("%s - %v", path, err)
+		}
+	}
+	rows, _, err := readCSV(data)
+	if err != nil {
+		return past, err
+	}
+	if len(rows) == 0 {
+		return past, fmt.Errorf("%s is blank", path)
+	}
+	columns := make(map[string]int)
+	for x, h := range rows[0] {
+		columns[h] = x
+	}
+	column, ok := columns["Duration"]
+	if !ok {
+		return past, fmt.Errorf("No Duration column in %s", path)
+	}
+	field := func(row []string, name string) string {
+		if x, ok := columns[name]; ok && x < len(row) {
+			return row[x]
+		}
+		return ""
+	}
+	for _, row := range rows[1:] {
+		if column >= len(row) {
+			continue
+		}
+		if d, err := core.ParseHumanDuration(row[column]); err == nil && d > 0 {
+			past.Durations = append(past.Durations, d)
+			past.Finishers = append(past.Finishers, PastFinisher{
+				Name:     strings.TrimSpace(field(row, "Fname") + " " + field(row, "Lname")),
+				Male:     field(row, "Gender") == core.Gender(true),
+				Duration: d,
+			})
+		}
+	}
+	return past, nil
+}
+
+// DistributionRow is how many finished in each bucket for one group of racers
+type DistributionRow struct {
+	Name   string
+	Counts []int
+	Total  int
+	Max    int // the busiest bucket, what the heat is relative to
+}
+
+// Distribution is the finish times bucketed for the stats heat map, each bucket starts at the time in its label
+type Distribution struct {
+	Width   HumanDuration
+	Buckets []HumanDuration
+	Rows    []DistributionRow
+}
+
+// lockedDistribution buckets the finishers overall, by gender and by division, along with the previous years.
+// The bucket width is the smallest that fits every time in histogramBuckets.
+func (race *Race) lockedDistribution() Distribution {
+	var dist Distribution
+	type group struct {
+		name      string
+		durations []HumanDuration
+	}
+	groups := []*group{{name: "Overall"}, {name: "Women"}, {name: "Men"}}
+	divisions := make(map[string]*group)
+	var divisionGroups []*group
+	for _, row := range race.lockedFinishers() {
+		groups[0].durations = append(groups[0].durations, row.Duration)
+		if row.Male {
+			groups[2].durations = append(groups[2].durations, row.Duration)
+		} else {
+			groups[1].durations = append(groups[1].durations, row.Duration)
+		}
+		if row.Division == "Overall" {
+			continue
+		}
+		if divisions[row.Division] == nil {
+			divisions[row.Division] = &group{name: row.Division}
+			divisionGroups = append(divisionGroups, divisions[row.Division])
+		}
+		divisions[row.Division].durations = append(divisions[row.Division].durations, row.Duration)
+	}
+	sort.Slice(divisionGroups, func(i, j int) bool { // finish order isn't a useful order for divisions
+		return divisionGroups[i].name < divisionGroups[j].name
+	})
+	groups = append(groups, divisionGroups...)
+	for x := len(previousYears) - 1; x >= 0; x-- {
+		groups = append(groups, &group{name: previousYears[x].Year, durations: previousYears[x].Durations})
+	}
+	var low, high HumanDuration
+	for _, g := range groups {
+		for _, d := range g.durations {
+			if low == 0 || d < low {
+				low = d
+			}
+			if d > high {
+				high = d
+			}
+		}
+	}
+	if high == 0 {
+		return dist
+	}
+	width := histogramWidths[len(histogramWidths)-1]
+	for _, w := range histogramWidths {
+		if time.Duration(high)/w-time.Duration(low)/w < histogramBuckets {
+			width = w
+			break
+		}
+	}
+	first := time.Duration(low) / width
+	count := int(time.Duration(high)/width-first) + 1
+	dist.Width = HumanDuration(width)
+	for x := 0; x < count; x++ {
+		dist.Buckets = append(dist.Buckets, HumanDuration((first+time.Duration(x))*width))
+	}
+	for _, g := range groups {
+		row := DistributionRow{Name: g.name, Counts: make([]int, count), Total: len(g.durations)}
+		for _, d := range g.durations {
+			bucket := int(time.Duration(d)/width - first)
+			row.Counts[bucket]++
+			if row.Counts[bucket] > row.Max {
+				row.Max = row.Counts[bucket]
+			}
+		}
+		dist.Rows = append(dist.Rows, row)
+	}
+	return dist
+}
+
+// statsJSONHandler serves the finish time distribution for charting elsewhere, e.g. the race's own website
+func statsJSONHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	race.RLock()
+	dist := race.lockedDistribution()
+	race.RUnlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dist)
+}
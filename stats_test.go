@@ -0,0 +1,92 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func finishedRace(t *testing.T, finishes ...time.Duration) *Race {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	start := time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	*race.testingTime = start
+	for x := range finishes {
+		if err := race.AddEntry(Entry{Bib: Bib(x + 1), Fname: "Racer", Lname: string(rune('A' + x)), Male: x%2 == 1, Age: uint(20 + 10*x)}); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	startRace(race)
+	for x, after := range finishes {
+		*race.testingTime = start.Add(after)
+		linkBibTesting(t, race, x+1, false)
+	}
+	return race
+}
+
+func TestDistribution(t *testing.T) {
+	defer func(years []PastRace) { previousYears = years }(previousYears)
+	dir, err := ioutil.TempDir("", "stats")
+	if err != nil {
+		t.Fatalf("Error making a temp dir - %v", err)
+	}
+	defer os.RemoveAll(dir)
+	archive, err := os.Create(filepath.Join(dir, "2013.zip"))
+	if err != nil {
+		t.Fatalf("Error creating the archive - %v", err)
+	}
+	if err = finishedRace(t, 21*time.Minute, 35*time.Minute).WriteArchive(archive); err != nil {
+		t.Fatalf("Error writing the archive - %v", err)
+	}
+	archive.Close()
+	past, err := loadPastRace("2013", filepath.Join(dir, "2013.zip"))
+	if err != nil || len(past.Durations) != 2 || past.Durations[1] != HumanDuration(35*time.Minute) {
+		t.Fatalf("Expected 2013's two finishers, got %v - %v", past, err)
+	}
+	previousYears = []PastRace{past}
+
+	race := finishedRace(t, 20*time.Minute, 20*time.Minute+30*time.Second, 24*time.Minute)
+	race.RLock()
+	dist := race.lockedDistribution()
+	race.RUnlock()
+	// 20:00 to 35:00 fits in 30 one minute buckets
+	if dist.Width != HumanDuration(time.Minute) || len(dist.Buckets) != 16 || dist.Buckets[0] != HumanDuration(20*time.Minute) {
+		t.Fatalf("Expected 16 one minute buckets from 20:00, got %s %v", dist.Width, dist.Buckets)
+	}
+	names := make([]string, len(dist.Rows))
+	for x, row := range dist.Rows {
+		names[x] = row.Name
+	}
+	if len(dist.Rows) < 4 || names[0] != "Overall" || names[1] != "Women" || names[2] != "Men" || names[len(names)-1] != "2013" {
+		t.Fatalf("Expected overall, gender, division and 2013 rows, got %v", names)
+	}
+	if overall := dist.Rows[0]; overall.Counts[0] != 2 || overall.Counts[4] != 1 || overall.Max != 2 || overall.Total != 3 {
+		t.Errorf("Expected 2 finishers in the first minute and 1 in the fifth, got %v", overall)
+	}
+	if last := dist.Rows[len(dist.Rows)-1]; last.Counts[1] != 1 || last.Counts[15] != 1 {
+		t.Errorf("Expected 2013's finishers at 21 and 35 minutes, got %v", last.Counts)
+	}
+
+	for _, page := range []string{"/stats", "/admin/stats"} {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", page, nil)
+		handler(w, r, race)
+		body := w.Body.String()
+		if w.Code != http.StatusOK || !strings.Contains(body, `<td data-count="2">2</td>`) {
+			t.Errorf("Expected the heat map on %s, got %d %s", page, w.Code, body)
+		}
+		if admin := strings.Contains(body, `href="/admin">Back`); admin != (page == "/admin/stats") {
+			t.Errorf("Expected the way back to the admin page only on /admin/stats")
+		}
+	}
+	w := httptest.NewRecorder()
+	statsJSONHandler(w, nil, race)
+	if !strings.Contains(w.Body.String(), `"Name":"2013"`) {
+		t.Errorf("Expected the distribution as JSON, got %s", w.Body)
+	}
+}
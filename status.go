@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/mzimmerman/racergo/internal/core"
+)
+
+// lockedStatuses is true once anyone has been marked DNS, DNF or DQ
+func (race *Race) lockedStatuses() bool {
+	for _, e := range race.allEntries {
+		if e.Status != StatusOK {
+			return true
+		}
+	}
+	return false
+}
+
+// SetStatus marks the bib DNS, DNF or DQ, or clears the mark with StatusOK
+func (race *Race) SetStatus(bib Bib, status ResultStatus) error {
+	race.Lock()
+	defer race.Unlock()
+	if race.finalized {
+		return fmt.Errorf("Results have been finalized, cannot change a racer's status")
+	}
+	entry, ok := race.bibbedEntries[bib]
+	if !ok {
+		return fmt.Errorf("Bib %d not found", bib)
+	}
+	if (status == StatusDNS || status == StatusDNF) && entry.HasFinished() {
+		return fmt.Errorf("Bib #%d has a finish time, remove it before marking them %s", bib, status)
+	}
+	entry.Status = status
+	race.lockedSortEntries()
+	race.lockedRecomputePrizes()
+	log.Printf("Bib #%d status set to %q", bib, status)
+	race.lockedRecordEvent(Event{Kind: "status", Bib: bib, Status: status.String()})
+	return nil
+}
+
+func setStatusHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	bib, err := strconv.Atoi(r.FormValue("bib"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting bib number", err)
+		return
+	}
+	status, err := core.ParseResultStatus(r.FormValue("status"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	if err = race.SetStatus(Bib(bib), status); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/admin", 301)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResultStatus(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38}, {Bib: 2, Fname: "Bea", Lname: "Adams", Age: 31}, {Bib: 3, Fname: "Cal", Lname: "Cole", Male: true, Age: 44}} {
+		race.AddEntry(e)
+	}
+	race.SetPrizes([]Prize{{Title: "Overall", HighAge: ^uint(0), Gender: "O", Amount: 1}})
+	startRace(race)
+	for x, bib := range []int{1, 2} {
+		*race.testingTime = race.started.Add(time.Duration(20+x) * time.Minute)
+		linkBibTesting(t, race, bib, false)
+		linkBibTesting(t, race, bib, false)
+	}
+	if err := race.SetStatus(1, StatusDNF); err == nil {
+		t.Errorf("Expected a finisher refused as DNF")
+	}
+	r, _ := http.NewRequest("POST", "/setStatus?bib=1&status=dq", nil)
+	w := httptest.NewRecorder()
+	setStatusHandler(w, r, race)
+	if w.Code != 301 {
+		t.Fatalf("Expected Amy disqualified, got %d - %s", w.Code, w.Body.String())
+	}
+	if err := race.SetStatus(3, StatusDNS); err != nil {
+		t.Fatalf("Error marking Cal DNS - %v", err)
+	}
+	race.RLock()
+	finishers := race.lockedFinishers()
+	winners := race.prizes[0].Winners
+	race.RUnlock()
+	if len(finishers) != 1 || finishers[0].Bib != 2 || finishers[0].Place != 1 {
+		t.Errorf("Expected Bea alone in first with Amy disqualified, got %#v", finishers)
+	}
+	if len(winners) != 1 || winners[0].Bib != 2 {
+		t.Errorf("Expected the prize to go to Bea, got %v", winners)
+	}
+	if onCourse := race.lockedOnCourse(); len(onCourse) != 0 {
+		t.Errorf("Expected nobody left on course once Cal is DNS, got %v", onCourse)
+	}
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("GET", "/", nil)
+	handler(w, r, race)
+	if page := w.Body.String(); !strings.Contains(page, `<abbr title="Disqualified">DQ</abbr>`) || !strings.Contains(page, `<abbr title="Did Not Start">DNS</abbr>`) {
+		t.Errorf("Expected the statuses in the results, got %s", page)
+	}
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	race.WriteCSV(writer)
+	writer.Flush()
+	if !strings.Contains(buf.String(), ",Confirmed,Status\n") || !strings.Contains(buf.String(), "Cal,Cole,44,M,3,,--,--,false,DNS\n") {
+		t.Errorf("Expected the statuses in the download, got %s", buf.String())
+	}
+
+	replayed := NewRace()
+	if err := replayed.Replay(race.events); err != nil {
+		t.Fatalf("Error replaying - %v", err)
+	}
+	if replayed.bibbedEntries[1].Status != StatusDQ || replayed.bibbedEntries[3].Status != StatusDNS {
+		t.Errorf("Expected the statuses replayed")
+	}
+}
+
+func TestDisqualifiedHaveNoPlace(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38}, {Bib: 2, Fname: "Bea", Lname: "Adams", Age: 31}, {Bib: 3, Fname: "Cal", Lname: "Cole", Male: true, Age: 44}} {
+		race.AddEntry(e)
+	}
+	startRace(race)
+	for x, bib := range []int{1, 2, 3} {
+		*race.testingTime = race.started.Add(time.Duration(20+x) * time.Minute)
+		linkBibTesting(t, race, bib, false)
+		linkBibTesting(t, race, bib, false)
+	}
+	if err := race.SetStatus(2, StatusDQ); err != nil {
+		t.Fatalf("Error disqualifying Bea - %v", err)
+	}
+
+	export := downloadWith(t, race, url.Values{"preset": {"results"}}).Body.String()
+	if !strings.Contains(export, "\n2,3,Cal,Cole,") || !strings.Contains(export, "\n--,2,Bea,Adams,F,31,F30-39,--,") || !strings.HasSuffix(strings.SplitAfter(export, "Bea,Adams")[1], ",DQ\n") {
+		t.Errorf("Expected Bea without a place and marked DQ in the export, got %s", export)
+	}
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	race.WriteCSV(writer)
+	writer.Flush()
+	if download := buf.String(); !strings.Contains(download, "Bea,Adams,31,F,2,,") || !strings.Contains(download, "Cal,Cole,44,M,3,2,") {
+		t.Errorf("Expected Bea without a place in the download, got %s", download)
+	}
+
+	race.RLock()
+	bea, cal := race.lockedAPIEntry(race.bibbedEntries[2]), race.lockedAPIEntry(race.bibbedEntries[3])
+	recent := race.lockedRecentRacers(3)
+	standing := race.lockedStanding(race.bibbedEntries[2])
+	race.RUnlock()
+	if bea.Place != 0 || bea.Duration == "" || cal.Place != 2 {
+		t.Errorf("Expected Bea's time without a place and Cal second in the API, got %#v and %#v", bea, cal)
+	}
+	if len(recent) != 3 || recent[0].Bib != 2 || recent[0].Place != 0 || recent[1].Bib != 3 || recent[1].Place != 2 {
+		t.Errorf("Expected Bea without a place in the recent racers, got %v", recent)
+	}
+	if standing != "" {
+		t.Errorf("Expected Bea without a standing, got %q", standing)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel() // the stream sends what there is then notices the decoder is gone
+	r, _ := http.NewRequestWithContext(ctx, "POST", "/racergo.Timing/StreamResults", nil)
+	w := httptest.NewRecorder()
+	race.grpcStreamResults(w, r)
+	places := make(map[uint64]uint64)
+	for {
+		msg, err := readGRPCMessage(w.Body)
+		if err != nil {
+			break
+		}
+		fields, _ := decodeProto(msg)
+		var place, bib uint64
+		for _, f := range fields {
+			switch f.num {
+			case 1:
+				place = f.varint
+			case 2:
+				bib = f.varint
+			}
+		}
+		places[bib] = place
+	}
+	if len(places) != 3 || places[1] != 1 || places[2] != 0 || places[3] != 2 {
+		t.Errorf("Expected Bea streamed without a place, got %v", places)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// parseStopwatchDump reads the memory of a lap-memory stopwatch or the tape of a printing timer.
+// Watches record either split times (elapsed since the start) or lap times (since the previous lap),
+// and printing timers such as the Seiko S149 print both for each finish.  Splits are used when present,
+// otherwise laps are added up.  Lines that aren't a recorded time, like the start time of day, are skipped.
+func parseStopwatchDump(data []byte) ([]HumanDuration, error) {
+	text, _ := decodeText(data)
+	var splits, laps, plain []HumanDuration
+	for _, line := range strings.Split(strings.Replace(text, "\r", "\n", -1), "\n") {
+		upper := strings.ToUpper(line)
+		if strings.Contains(upper, "AM") || strings.Contains(upper, "PM") || strings.Contains(upper, "START") || strings.Contains(upper, "DATE") {
+			continue
+		}
+		var recorded HumanDuration
+		found := false
+		for _, token := range strings.FieldsFunc(line, func(r rune) bool { return r == ' ' || r == '\t' || r == ',' || r == ';' }) {
+			if !strings.Contains(token, ":") {
+				continue
+			}
+			if t, err := parsePaperTime(token); err == nil {
+				recorded, found = t, true
+			}
+		}
+		if !found {
+			continue
+		}
+		switch {
+		case strings.Contains(upper, "SPLIT") || strings.Contains(upper, "TOTAL"):
+			splits = append(splits, recorded)
+		case strings.Contains(upper, "LAP"):
+			laps = append(laps, recorded)
+		default:
+			plain = append(plain, recorded)
+		}
+	}
+	switch {
+	case len(splits) > 0:
+		return splits, nil
+	case len(laps) > 0:
+		var elapsed HumanDuration
+		times := make([]HumanDuration, len(laps))
+		for x, lap := range laps {
+			elapsed += lap
+			times[x] = elapsed
+		}
+		return times, nil
+	case len(plain) > 0:
+		return plain, nil
+	}
+	return nil, fmt.Errorf("No times found in the stopwatch memory")
+}
+
+// uploadStopwatchHandler lines a stopwatch's times up with the pull tag order for review on the paper backup page
+func uploadStopwatchHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error getting Reader - %s", err)
+		return
+	}
+	dump, err := readUpload(r, "dump")
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error reading the stopwatch memory - %v", err)
+		return
+	}
+	bibs, err := readUpload(r, "bibs")
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error reading the bibs sheet - %v", err)
+		return
+	}
+	times, err := parseStopwatchDump(dump)
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	pi, err := parsePaperImport(nil, bibs)
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	pi.Times = times
+	race.Lock()
+	race.paperImport = pi
+	race.Unlock()
+	http.Redirect(w, r, "/paperBackup", 301)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseStopwatchDump(t *testing.T) {
+	minutes := func(m, s float64) HumanDuration {
+		return HumanDuration(time.Duration(m*float64(time.Minute) + s*float64(time.Second)))
+	}
+	for name, test := range map[string]struct {
+		dump     string
+		expected []HumanDuration
+	}{
+		"printer tape with splits and laps": {
+			"SEIKO S149\r\nDATE 06/01/14\r\nSTART 09:00:00 AM\r\nSPLIT 001  0:19:42.31\r\n  LAP 001    19:42.31\r\nSPLIT 002  0:20:30.12\r\n  LAP 002     0:47.81\r\n",
+			[]HumanDuration{minutes(19, 42.31), minutes(20, 30.12)},
+		},
+		"lap memory": {
+			"MEM  LAP\n01   LAP 19:42.3\n02   LAP 0:48.0\n03   LAP 1:10.0\n",
+			[]HumanDuration{minutes(19, 42.3), minutes(20, 30.3), minutes(21, 40.3)},
+		},
+		"plain export": {
+			"No,Time\n1,19:42.31\n2,20:30.12\n",
+			[]HumanDuration{minutes(19, 42.31), minutes(20, 30.12)},
+		},
+	} {
+		times, err := parseStopwatchDump([]byte(test.dump))
+		if err != nil {
+			t.Errorf("%s - unexpected error %v", name, err)
+			continue
+		}
+		if len(times) != len(test.expected) {
+			t.Errorf("%s - expected %v, got %v", name, test.expected, times)
+			continue
+		}
+		for x := range times {
+			if diff := times[x] - test.expected[x]; diff > HumanDuration(time.Millisecond) || diff < -HumanDuration(time.Millisecond) {
+				t.Errorf("%s - expected %s at %d, got %s", name, test.expected[x], x, times[x])
+			}
+		}
+	}
+	if _, err := parseStopwatchDump([]byte("SEIKO S149\nSTART 09:00:00 AM\n")); err == nil {
+		t.Errorf("Expected error for a dump without any times")
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+)
+
+// streamChunk is how many result rows are rendered at a time when the results are streamed
+const streamChunk = 500
+
+// lockedStreamedResults is true when the public results page is big enough to send in pieces, unless the race has its own results page
+func (race *Race) lockedStreamedResults(name string, results []ResultRow) bool {
+	if name != "default" || config.streamRows <= 0 || len(results) <= config.streamRows {
+		return false
+	}
+	_, custom := race.templateOverrides["default"]
+	return !custom
+}
+
+// lockedStreamResults sends the results page a piece at a time, the top of the page first so the browser can start
+// drawing and then streamChunk rows at a time.  The race's read lock is let go while each piece is sent so a slow
+// phone can't hold up the finish line, the rows stay in the order they were in when the page was asked for.
+func (race *Race) lockedStreamResults(tmpl *template.Template, data map[string]interface{}, results []ResultRow, w io.Writer) error {
+	buf := tmplPool.Get()
+	defer tmplPool.Put(buf)
+	if err := tmpl.ExecuteTemplate(buf, "resultsTop", data); err != nil {
+		return err
+	}
+	send := func() error {
+		race.RUnlock()
+		defer race.RLock()
+		_, err := io.Copy(w, buf)
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		return err
+	}
+	if err := send(); err != nil {
+		return nil // the browser went away
+	}
+	// the status has been sent, errors from here on can only be logged
+	for x := 0; x < len(results); x += streamChunk {
+		end := x + streamChunk
+		if end > len(results) {
+			end = len(results)
+		}
+		if err := tmpl.ExecuteTemplate(buf, "resultRows", results[x:end]); err != nil {
+			log.Printf("Error executing template - %v", err)
+			return nil
+		}
+		if err := send(); err != nil {
+			return nil
+		}
+	}
+	if err := tmpl.ExecuteTemplate(buf, "resultsBottom", data); err != nil {
+		log.Printf("Error executing template - %v", err)
+		return nil
+	}
+	send()
+	return nil
+}
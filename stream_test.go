@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// unlockedWriter fails the test if anything is written while the race is locked
+type unlockedWriter struct {
+	*httptest.ResponseRecorder
+	t       *testing.T
+	race    *Race // checked when set
+	flushes int
+}
+
+func (w *unlockedWriter) Write(p []byte) (int, error) {
+	if w.race != nil {
+		if !w.race.TryLock() {
+			w.t.Errorf("Expected the race unlocked while the page is sent")
+		} else {
+			w.race.Unlock()
+		}
+	}
+	return w.ResponseRecorder.Write(p)
+}
+
+func (w *unlockedWriter) Flush() {
+	w.flushes++
+}
+
+func TestStreamResults(t *testing.T) {
+	defer func(rows int) { config.streamRows = rows }(config.streamRows)
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	finishers := streamChunk + 10
+	for x := 1; x <= finishers; x++ {
+		if err := race.AddEntry(Entry{Bib: Bib(x), Fname: "Racer", Lname: fmt.Sprintf("Number%d", x), Age: 30}); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	startRace(race)
+	for x := 1; x <= finishers; x++ {
+		*race.testingTime = race.testingTime.Add(time.Second)
+		linkBibTesting(t, race, x, false)
+	}
+	get := func(locked *Race) *unlockedWriter {
+		w := &unlockedWriter{ResponseRecorder: httptest.NewRecorder(), t: t, race: locked}
+		if err := race.GenerateTemplate(templateRequest{name: "", writer: w, request: httptest.NewRequest("GET", "/", nil)}); err != nil {
+			t.Fatalf("Error generating the results - %v", err)
+		}
+		return w
+	}
+
+	config.streamRows = finishers
+	w := get(nil)
+	if w.flushes != 0 {
+		t.Errorf("Expected the results sent whole up to RACERGOSTREAMROWS, flushed %d times", w.flushes)
+	}
+	whole := w.Body.String()
+
+	config.streamRows = 100
+	w = get(race)
+	// the top of the page, two chunks of rows and the bottom
+	if w.flushes != 4 {
+		t.Errorf("Expected the results sent in 4 pieces, got %d", w.flushes)
+	}
+	streamed := w.Body.String()
+	// the clock at the top of the page ticks between the two, compare from the results table down
+	table := func(page string) string {
+		return strings.Join(strings.Fields(page[strings.Index(page, `id="results"`):]), " ")
+	}
+	if table(streamed) != table(whole) {
+		t.Errorf("Expected the same results streamed as sent whole")
+	}
+	if !strings.Contains(streamed, fmt.Sprintf("Number%d", finishers)) || !strings.HasSuffix(strings.TrimSpace(streamed), "</html>") {
+		t.Errorf("Expected every finisher and the end of the page")
+	}
+}
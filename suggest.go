@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"unicode/utf8"
+)
+
+// maxSuggestions keeps the autocomplete payload small, a search box only shows a handful anyway
+const maxSuggestions = 10
+
+// Suggestions is an OpenSearch suggestions response, the racers' names with a description and a link for each
+type Suggestions struct {
+	Query        string
+	Completions  []string
+	Descriptions []string
+	URLs         []string
+}
+
+// MarshalJSON writes the suggestions as the array the OpenSearch suggestions extension expects
+func (s Suggestions) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{s.Query, s.Completions, s.Descriptions, s.URLs})
+}
+
+// openSearchDescription tells browsers and club sites where to search the race and get suggestions
+type openSearchDescription struct {
+	XMLName       xml.Name        `xml:"http://a9.com/-/spec/opensearch/1.1/ OpenSearchDescription"`
+	ShortName     string          `xml:"ShortName"`
+	Description   string          `xml:"Description"`
+	InputEncoding string          `xml:"InputEncoding"`
+	URLs          []openSearchURL `xml:"Url"`
+}
+
+type openSearchURL struct {
+	Type     string `xml:"type,attr"`
+	Template string `xml:"template,attr"`
+}
+
+// raceURL is where the race's page is for links that leave the site, like feeds and search boxes
+func (race *Race) raceURL(path string) string {
+	return fmt.Sprintf("http://%s%s%s", config.webserverHostname, race.Path(), path)
+}
+
+// lockedSuggest completes the query to racers' names, matching the same way the lookup page does.  Only what the
+// lookup page shows is used so the suggestions never give away more about a racer than searching for them would.
+func (race *Race) lockedSuggest(query string) Suggestions {
+	suggestions := Suggestions{Query: query, Completions: []string{}, Descriptions: []string{}, URLs: []string{}}
+	for _, result := range race.lockedLookup(query) {
+		if len(suggestions.Completions) == maxSuggestions {
+			break
+		}
+		description := result.Hint
+		if description == "" {
+			description = "Bib #" + result.Bib.String()
+		}
+		link := race.raceURL("/lookup?name=" + url.QueryEscape(result.Fname+" "+result.Lname))
+		switch {
+		case result.Status != StatusOK:
+			description += ", " + result.Status.Title()
+		case result.Place != 0:
+			description += fmt.Sprintf(", %s in %s", result.Place.Ordinal(), result.Duration)
+			link = race.raceURL(fmt.Sprintf("/finisher?bib=%d", result.Bib))
+		}
+		suggestions.Completions = append(suggestions.Completions, result.Fname+" "+result.Lname)
+		suggestions.Descriptions = append(suggestions.Descriptions, description)
+		suggestions.URLs = append(suggestions.URLs, link)
+	}
+	return suggestions
+}
+
+// suggestHandler answers search boxes as the spectator types, anyone's site can ask since the results are public
+func suggestHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	race.RLock()
+	suggestions := race.lockedSuggest(r.FormValue("q"))
+	race.RUnlock()
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/x-suggestions+json")
+	json.NewEncoder(w).Encode(suggestions)
+}
+
+// openSearchHandler writes the OpenSearch description so browsers can add the race as a search engine
+func openSearchHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	race.RLock()
+	name := race.lockedName()
+	race.RUnlock()
+	short := name
+	if utf8.RuneCountInString(short) > 16 { // the most OpenSearch allows
+		short = string([]rune(short)[:16])
+	}
+	description := openSearchDescription{
+		ShortName:     short,
+		Description:   "Find a racer in the " + name + " results",
+		InputEncoding: "UTF-8",
+		URLs: []openSearchURL{
+			{Type: "text/html", Template: race.raceURL("/lookup?name={searchTerms}")},
+			{Type: "application/x-suggestions+json", Template: race.raceURL("/api/suggest?q={searchTerms}")},
+		},
+	}
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/opensearchdescription+xml")
+	fmt.Fprint(w, xml.Header)
+	xml.NewEncoder(w).Encode(description)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSuggest(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38}, {Bib: 2, Fname: "Amos", Lname: "Adams", Male: true, Age: 31}, {Bib: 3, Fname: "Cal", Lname: "Cole", Male: true, Age: 44}} {
+		race.AddEntry(e)
+	}
+	startRace(race)
+	*race.testingTime = race.started.Add(20 * time.Minute)
+	linkBibTesting(t, race, 1, false)
+	if err := race.SetStatus(2, StatusDNS); err != nil {
+		t.Fatalf("Error marking Amos DNS - %v", err)
+	}
+
+	r, _ := http.NewRequest("GET", "/api/suggest?q=am", nil)
+	w := httptest.NewRecorder()
+	suggestHandler(w, r, race)
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("Expected other sites allowed to ask for suggestions")
+	}
+	var got []interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("Error reading the suggestions %s - %v", w.Body.String(), err)
+	}
+	want := `["am",["Amy Brown","Amos Adams"],["Bib #1, 1st in 00:20:00.00","Bib #2, Did Not Start"],["http://localhost:8080/finisher?bib=1","http://localhost:8080/lookup?name=Amos+Adams"]]`
+	if body := strings.TrimSpace(w.Body.String()); body != want {
+		t.Errorf("Wrong suggestions\nwant %s\ngot  %s", want, body)
+	}
+
+	r, _ = http.NewRequest("GET", "/api/suggest?q=zz", nil)
+	w = httptest.NewRecorder()
+	suggestHandler(w, r, race)
+	if body := strings.TrimSpace(w.Body.String()); body != `["zz",[],[],[]]` {
+		t.Errorf("Expected empty lists for no matches, got %s", body)
+	}
+
+	r, _ = http.NewRequest("GET", "/opensearch.xml", nil)
+	w = httptest.NewRecorder()
+	openSearchHandler(w, r, race)
+	if body := w.Body.String(); !strings.Contains(body, `template="http://localhost:8080/api/suggest?q={searchTerms}"`) || !strings.Contains(body, `template="http://localhost:8080/lookup?name={searchTerms}"`) {
+		t.Errorf("Expected the search and suggestion urls in the description, got %s", body)
+	}
+}
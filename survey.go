@@ -0,0 +1,161 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SurveyResponse is a finisher's answers to the post-race survey, a 1 to 5 rating for each question
+type SurveyResponse struct {
+	Bib       Bib
+	Submitted time.Time
+	Ratings   []int
+	Comments  string
+}
+
+// SurveyQuestion is a question's results for the race committee
+type SurveyQuestion struct {
+	Question  string
+	Responses int
+	Average   float64
+	Counts    [5]int // how many answered 1 through 5
+}
+
+var surveyRatings = []int{1, 2, 3, 4, 5}
+
+// surveyToken signs the bib so only the finisher the survey was sent to can answer it
+func surveyToken(bib Bib) string {
+	secret := transferSecret
+	if config.transferSecret != "" {
+		secret = []byte(config.transferSecret)
+	}
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "survey|%d", bib)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:18])
+}
+
+// surveyLink is the survey a finisher is sent, the hosted survey with their bib filled in if RACERGOSURVEYURL is set
+func (race *Race) surveyLink(bib Bib) string {
+	if config.surveyURL != "" {
+		return strings.Replace(config.surveyURL, "{bib}", strconv.Itoa(int(bib)), -1)
+	}
+	return race.raceURL(fmt.Sprintf("/survey?bib=%d&token=%s", bib, surveyToken(bib)))
+}
+
+// SendSurvey e-mails the survey link to every finisher with an e-mail address once the results are final, returning how many were sent
+func (race *Race) SendSurvey() (int, error) {
+	race.Lock()
+	defer race.Unlock()
+	if !race.finalized {
+		return 0, fmt.Errorf("Results aren't final yet, the survey goes out after finalizing")
+	}
+	if !race.surveySent.IsZero() {
+		return 0, fmt.Errorf("The survey was already sent at %s", race.surveySent.Format("3:04 PM"))
+	}
+	sent := 0
+	for _, e := range race.allEntries {
+		if !e.HasFinished() || race.lockedEmailOf(e) == "" {
+			continue
+		}
+		subject, text := race.lockedSurveyEmail(*e)
+		go sendEmail(*e, race.optionalEmailIndex, subject, text)
+		sent++
+	}
+	race.surveySent = race.GetTime()
+	log.Printf("Sent the survey to %d finishers", sent)
+	return sent, nil
+}
+
+// lockedSurveyEntry returns the entry for the bib if the token is a valid survey link for it
+func (race *Race) lockedSurveyEntry(bib Bib, token string) (*Entry, error) {
+	entry, ok := race.bibbedEntries[bib]
+	if !ok || !hmac.Equal([]byte(token), []byte(surveyToken(bib))) {
+		return nil, fmt.Errorf("This survey link is not valid")
+	}
+	return entry, nil
+}
+
+// RecordSurvey saves the finisher's answers, replacing any they sent before
+func (race *Race) RecordSurvey(bib Bib, token string, ratings []int, comments string) error {
+	if len(ratings) != len(config.surveyQuestions) {
+		return fmt.Errorf("Please answer all %d questions", len(config.surveyQuestions))
+	}
+	for _, rating := range ratings {
+		if rating < 1 || rating > 5 {
+			return fmt.Errorf("Ratings are from 1 to 5, not %d", rating)
+		}
+	}
+	race.Lock()
+	defer race.Unlock()
+	if _, err := race.lockedSurveyEntry(bib, token); err != nil {
+		return err
+	}
+	if race.surveyResponses == nil {
+		race.surveyResponses = make(map[Bib]*SurveyResponse)
+	}
+	race.surveyResponses[bib] = &SurveyResponse{Bib: bib, Submitted: race.GetTime(), Ratings: ratings, Comments: strings.TrimSpace(comments)}
+	return nil
+}
+
+// lockedSurveyReport totals the ratings for each question
+func (race *Race) lockedSurveyReport() []SurveyQuestion {
+	report := make([]SurveyQuestion, len(config.surveyQuestions))
+	for x, question := range config.surveyQuestions {
+		report[x].Question = question
+		total := 0
+		for _, response := range race.surveyResponses {
+			if x < len(response.Ratings) {
+				report[x].Responses++
+				report[x].Counts[response.Ratings[x]-1]++
+				total += response.Ratings[x]
+			}
+		}
+		if report[x].Responses > 0 {
+			report[x].Average = float64(total) / float64(report[x].Responses)
+		}
+	}
+	return report
+}
+
+// lockedSurveyComments returns the comments left with the survey, by bib
+func (race *Race) lockedSurveyComments() []*SurveyResponse {
+	var comments []*SurveyResponse
+	for _, e := range race.allEntries {
+		if response, ok := race.surveyResponses[e.Bib]; ok && response.Comments != "" {
+			comments = append(comments, response)
+		}
+	}
+	return comments
+}
+
+func sendSurveyHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	if _, err := race.SendSurvey(); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/surveyReport", 301)
+}
+
+func submitSurveyHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	bib, err := strconv.Atoi(r.FormValue("bib"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting bib number", err)
+		return
+	}
+	ratings := make([]int, len(config.surveyQuestions))
+	for x := range ratings {
+		ratings[x], _ = strconv.Atoi(r.FormValue(fmt.Sprintf("q%d", x)))
+	}
+	if err = race.RecordSurvey(Bib(bib), r.FormValue("token"), ratings, r.FormValue("comments")); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/survey?thanks=true", 301)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSurvey(t *testing.T) {
+	defer func(questions []string) { config.surveyQuestions = questions }(config.surveyQuestions)
+	config.surveyQuestions = []string{"Course?", "Organization?"}
+	race := transferTestRace(t)
+	startRace(race)
+	*race.testingTime = race.testingTime.Add(20 * time.Minute)
+	linkBibTesting(t, race, 1, false)
+	linkBibTesting(t, race, 1, false)
+	if _, err := race.SendSurvey(); err == nil {
+		t.Errorf("Expected error sending the survey before the results are final")
+	}
+	race.Lock()
+	race.finalized = true
+	race.Unlock()
+	if sent, err := race.SendSurvey(); err != nil || sent != 1 {
+		t.Errorf("Expected the survey sent to Amy only, got %d - %v", sent, err)
+	}
+	if _, err := race.SendSurvey(); err == nil {
+		t.Errorf("Expected error sending the survey twice")
+	}
+
+	if err := race.RecordSurvey(1, "forged", []int{5, 4}, ""); err == nil {
+		t.Errorf("Expected error with a forged token")
+	}
+	if err := race.RecordSurvey(1, surveyToken(1), []int{5}, ""); err == nil {
+		t.Errorf("Expected error leaving a question unanswered")
+	}
+	if err := race.RecordSurvey(1, surveyToken(1), []int{5, 6}, ""); err == nil {
+		t.Errorf("Expected error with a rating out of range")
+	}
+	if err := race.RecordSurvey(1, surveyToken(1), []int{3, 4}, ""); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if err := race.RecordSurvey(1, surveyToken(1), []int{5, 4}, "Great water stops"); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if err := race.RecordSurvey(2, surveyToken(2), []int{2, 2}, ""); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	race.RLock()
+	report := race.lockedSurveyReport()
+	race.RUnlock()
+	if report[0].Responses != 2 || report[0].Average != 3.5 || report[0].Counts != [5]int{0, 1, 0, 0, 1} {
+		t.Errorf("Expected Amy's second answers to replace her first, got %#v", report[0])
+	}
+
+	r, _ := http.NewRequest("GET", "/surveyReport", nil)
+	w := httptest.NewRecorder()
+	handler(w, r, race)
+	if !strings.Contains(w.Body.String(), "Great water stops") || !strings.Contains(w.Body.String(), "<td>3.5</td>") {
+		t.Errorf("Expected the comments and averages in the report - %s", w.Body.String())
+	}
+	r, _ = http.NewRequest("GET", "/survey?bib=2&token="+surveyToken(2), nil)
+	w = httptest.NewRecorder()
+	handler(w, r, race)
+	if !strings.Contains(w.Body.String(), `name="q1" value="5"`) || !strings.Contains(w.Body.String(), "already answered") {
+		t.Errorf("Expected the survey form for Bob - %s", w.Body.String())
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mzimmerman/racergo/internal/core"
+)
+
+// SyncRole is how a laptop takes part in primary/backup replication
+type SyncRole uint8
+
+const (
+	SyncPrimary  SyncRole = iota // the default, records the race and is mirrored by any backups
+	SyncBackup                   // mirrors the primary, anything changed locally is overwritten
+	SyncPromoted                 // a backup that has taken over from the primary
+)
+
+func (sr SyncRole) String() string {
+	switch sr {
+	case SyncBackup:
+		return "Backup"
+	case SyncPromoted:
+		return "Promoted Backup"
+	}
+	return "Primary"
+}
+
+// snapshot is a race as written by /download
+type snapshot struct {
+	started time.Time
+	fields  []string
+	entries []Entry
+}
+
+// parseSnapshot reads the /download format, finish times are made absolute so they can be
+// compared between laptops that didn't start the race at exactly the same moment
+func parseSnapshot(data []byte) (snapshot, error) {
+	var snap snapshot
+	rows, _, err := readCSV(data)
+	if err != nil {
+		return snap, err
+	}
+	if len(rows) == 0 {
+		return snap, fmt.Errorf("Snapshot is blank")
+	}
+	reserved := make(map[string]bool)
+	for _, h := range append(core.Headers, computedColumns...) {
+		reserved[h] = true
+	}
+	header := make(map[string]int)
+	for x, h := range rows[0] {
+		header[h] = x
+		if !reserved[h] {
+			snap.fields = append(snap.fields, h)
+		}
+	}
+	rows = rows[1:]
+	if len(rows) > 0 && len(rows[0]) > 7 && strings.Join(rows[0][:6], "") == "" {
+		if snap.started, err = time.ParseInLocation(time.ANSIC, rows[0][7], time.Local); err != nil {
+			return snap, fmt.Errorf("Error parsing start time %s - %v", rows[0][7], err)
+		}
+		rows = rows[1:]
+	}
+	for _, row := range rows {
+		value := func(column string) string {
+			if x, ok := header[column]; ok && x < len(row) {
+				return row[x]
+			}
+			return ""
+		}
+		bib, err := strconv.Atoi(value("Bib"))
+		if err != nil {
+			continue // only entries with bibs can be matched up between laptops
+		}
+		age, _ := strconv.Atoi(value("Age"))
+		entry := Entry{Bib: Bib(bib), Fname: value("Fname"), Lname: value("Lname"), Age: uint(age), Male: value("Gender") == "M", Confirmed: value("Confirmed") == "true"}
+		if entry.Duration, err = core.ParseHumanDuration(value("Duration")); err != nil {
+			return snap, err
+		}
+		if entry.HasFinished() {
+			entry.TimeFinished = snap.started.Add(time.Duration(entry.Duration))
+		}
+		for _, f := range snap.fields {
+			entry.Optional = append(entry.Optional, value(f))
+		}
+		snap.entries = append(snap.entries, entry)
+	}
+	return snap, nil
+}
+
+// MergeSnapshot brings in the entries and finish times from another laptop.  A backup mirroring its
+// primary takes the primary's word for everything.  Otherwise, after a split-brain, the earliest time
+// for each bib wins and a time confirmed on either laptop stays confirmed, so merging in either
+// direction, or more than once, ends up with the same results.
+func (race *Race) MergeSnapshot(data []byte, mirror bool) (int, error) {
+	snap, err := parseSnapshot(data)
+	if err != nil {
+		return 0, err
+	}
+	race.RLock()
+	started := race.started
+	race.RUnlock()
+	if started.IsZero() && !snap.started.IsZero() {
+		if err = race.Start(&snap.started); err != nil {
+			return 0, err
+		}
+	}
+	race.Lock()
+	defer race.Unlock()
+	merge := Event{Kind: "merge"}
+	if mirror && !snap.started.IsZero() && !race.started.Equal(snap.started) {
+		merge.Started = &snap.started
+	}
+	if len(race.allEntries) == 0 {
+		merge.Fields = snap.fields
+	}
+	for _, remote := range snap.entries {
+		if merge.Fields == nil {
+			remote.Optional = race.lockedMatchFields(snap.fields, remote.Optional)
+		}
+		local, ok := race.bibbedEntries[remote.Bib]
+		if !ok {
+			entry := remote
+			if entry.HasFinished() {
+				entry.Duration = HumanDuration(entry.TimeFinished.Sub(race.lockedStartOf(&entry)))
+			}
+			merge.Entries = append(merge.Entries, entry)
+			continue
+		}
+		merged := *local
+		if mirror {
+			merged.Fname, merged.Lname, merged.Age, merged.Male, merged.Optional = remote.Fname, remote.Lname, remote.Age, remote.Male, remote.Optional
+			merged.Duration, merged.TimeFinished, merged.Confirmed = remote.Duration, remote.TimeFinished, remote.Confirmed
+		} else if remote.HasFinished() {
+			if !local.HasFinished() || remote.TimeFinished.Before(local.TimeFinished) {
+				merged.TimeFinished = remote.TimeFinished
+				merged.Duration = HumanDuration(remote.TimeFinished.Sub(race.lockedStartOf(local)))
+			}
+			merged.Confirmed = local.Confirmed || remote.Confirmed
+		}
+		if merged.Duration.String() != local.Duration.String() || !merged.TimeFinished.Equal(local.TimeFinished) || merged.Confirmed != local.Confirmed ||
+			merged.Fname != local.Fname || merged.Lname != local.Lname || merged.Age != local.Age || merged.Male != local.Male || !equalStringSlices(merged.Optional, local.Optional) {
+			merge.Entries = append(merge.Entries, merged)
+		}
+	}
+	if merge.Started == nil && merge.Fields == nil && len(merge.Entries) == 0 {
+		return 0, nil
+	}
+	race.lockedApplyMerge(merge)
+	return len(merge.Entries), nil
+}
+
+// lockedApplyMerge puts in the entries as they were after merging, adding the ones this race didn't have.
+// The merge is logged with its outcome so a backup that's promoted can replay it.
+func (race *Race) lockedApplyMerge(merge Event) {
+	if merge.Started != nil {
+		race.started = withMonotonic(*merge.Started)
+	}
+	if merge.Fields != nil {
+		race.optionalEntryFields = merge.Fields
+		for x, fn := range merge.Fields {
+			if fn == config.emailField {
+				race.optionalEmailIndex = x
+			}
+		}
+	}
+	for _, merged := range merge.Entries {
+		entry := merged
+		entry.Optional = append([]string(nil), merged.Optional...) // the logged event keeps its own copy
+		if local, ok := race.bibbedEntries[entry.Bib]; ok {
+			*local = entry
+			continue
+		}
+		race.allEntries = append(race.allEntries, &entry)
+		race.bibbedEntries[entry.Bib] = &entry
+	}
+	race.lockedSortEntries()
+	race.lockedRecomputePrizes()
+	race.lockedRecordEvent(merge)
+}
+
+// lockedMatchFields reorders another laptop's optional fields to match this race's
+func (race *Race) lockedMatchFields(fields, values []string) []string {
+	matched := make([]string, len(race.optionalEntryFields))
+	for x, field := range race.optionalEntryFields {
+		for y, f := range fields {
+			if f == field && y < len(values) {
+				matched[x] = values[y]
+			}
+		}
+	}
+	return matched
+}
+
+var syncClient = &http.Client{Timeout: 5 * time.Second}
+
+// syncSnapshotHandler serves the race to the backups with the emergency contact details left in, unlike /download
+func syncSnapshotHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	w.Header().Set("Content-type", "application/csv")
+	writer := csv.NewWriter(w)
+	race.WriteCSV(writer)
+	writer.Flush()
+}
+
+// fetchSnapshot downloads another laptop's race, signing in as RACERGOPRIMARYUSER since the snapshot is admin only
+func fetchSnapshot(url string) ([]byte, error) {
+	req, err := http.NewRequest("GET", strings.TrimSuffix(url, "/")+"/syncSnapshot", nil)
+	if err != nil {
+		return nil, err
+	}
+	if user := strings.SplitN(config.primaryUser, ":", 2); len(user) == 2 {
+		req.SetBasicAuth(user[0], user[1])
+	}
+	resp, err := syncClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (race *Race) setSyncStatus(format string, args ...interface{}) {
+	race.Lock()
+	race.syncStatus = fmt.Sprintf("%s - ", race.GetTime().Format("3:04:05 PM")) + fmt.Sprintf(format, args...)
+	race.Unlock()
+}
+
+// followPrimary mirrors the primary every interval until this backup is promoted
+func followPrimary(race *Race, url string, interval time.Duration) {
+	race.Lock()
+	race.syncRole = SyncBackup
+	race.Unlock()
+	log.Printf("Mirroring the primary at %s", url)
+	for range time.Tick(interval) {
+		race.RLock()
+		role := race.syncRole
+		race.RUnlock()
+		if role != SyncBackup {
+			log.Printf("Promoted, no longer mirroring %s", url)
+			return
+		}
+		data, err := fetchSnapshot(url)
+		if err != nil {
+			race.setSyncStatus("Error reaching the primary at %s - %v", url, err)
+			continue
+		}
+		changed, err := race.MergeSnapshot(data, true)
+		if err != nil {
+			race.setSyncStatus("Error mirroring the primary - %v", err)
+			continue
+		}
+		race.setSyncStatus("Mirrored the primary, %d entries changed", changed)
+	}
+}
+
+// Promote makes this backup the primary, it stops mirroring and records the race from here on
+func (race *Race) Promote() error {
+	race.Lock()
+	defer race.Unlock()
+	if race.syncRole != SyncBackup {
+		return fmt.Errorf("Only a backup can be promoted, this laptop is the %s", race.syncRole)
+	}
+	race.syncRole = SyncPromoted
+	log.Printf("Promoted to primary")
+	return nil
+}
+
+func promoteHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	if err := race.Promote(); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/admin", 301)
+}
+
+// mergeFromHandler merges in the finishes recorded on another laptop, e.g. the old primary once it's back
+func mergeFromHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	url := r.FormValue("url")
+	if url == "" {
+		url = config.primaryURL
+	}
+	if url == "" {
+		showErrorForAdmin(w, r.Referer(), "No laptop to merge from, enter its address")
+		return
+	}
+	data, err := fetchSnapshot(url)
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error reaching %s - %v", url, err)
+		return
+	}
+	changed, err := race.MergeSnapshot(data, false)
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error merging from %s - %v", url, err)
+		return
+	}
+	race.setSyncStatus("Merged %d entries from %s", changed, url)
+	http.Redirect(w, r, "/admin", 301)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func snapshotOf(t *testing.T, race *Race) []byte {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := race.WriteCSV(writer); err != nil {
+		t.Fatalf("Error writing snapshot - %v", err)
+	}
+	writer.Flush()
+	return buf.Bytes()
+}
+
+func TestMirrorAndMerge(t *testing.T) {
+	primary := NewRace()
+	primary.testingTime = &time.Time{}
+	*primary.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	if err := primary.SetOptionalFields([]string{"Email"}); err != nil {
+		t.Fatalf("Error setting optional fields - %v", err)
+	}
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 34, Optional: []string{"amy@host.com"}},
+		{Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 41, Optional: []string{""}},
+		{Bib: 3, Fname: "Cal", Lname: "Cole", Male: true, Age: 29, Optional: []string{""}},
+	} {
+		if err := primary.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	startRace(primary)
+	*primary.testingTime = primary.testingTime.Add(20 * time.Minute)
+	linkBibTesting(t, primary, 2, false)
+
+	backup := NewRace()
+	backup.testingTime = &time.Time{}
+	*backup.testingTime = *primary.testingTime
+	backup.syncRole = SyncBackup
+	changed, err := backup.MergeSnapshot(snapshotOf(t, primary), true)
+	if err != nil || changed != 3 {
+		t.Fatalf("Expected 3 entries mirrored, got %d - %v", changed, err)
+	}
+	if !backup.started.Equal(primary.started) || backup.optionalEmailIndex != 0 || backup.bibbedEntries[2].Duration.String() != "00:20:00.00" {
+		t.Errorf("Expected the backup to match the primary, got %s %#v", backup.started, backup.bibbedEntries[2])
+	}
+	if changed, _ = backup.MergeSnapshot(snapshotOf(t, primary), true); changed != 0 {
+		t.Errorf("Expected nothing to change mirroring again, got %d", changed)
+	}
+
+	// the primary drops off the network, the backup is promoted and both keep recording
+	if err = backup.Promote(); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if err = backup.Promote(); err == nil {
+		t.Errorf("Expected error promoting twice")
+	}
+	*primary.testingTime = primary.testingTime.Add(time.Minute)
+	linkBibTesting(t, primary, 1, false)
+	linkBibTesting(t, primary, 2, false) // confirm
+	*backup.testingTime = backup.testingTime.Add(50 * time.Second)
+	linkBibTesting(t, backup, 1, false)
+	*backup.testingTime = backup.testingTime.Add(time.Minute)
+	linkBibTesting(t, backup, 3, false)
+
+	fromBackup := snapshotOf(t, backup)
+	if _, err = backup.MergeSnapshot(snapshotOf(t, primary), false); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if _, err = primary.MergeSnapshot(fromBackup, false); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if a, b := string(snapshotOf(t, primary)), string(snapshotOf(t, backup)); a != b {
+		t.Errorf("Expected both laptops to agree after merging\n%s\n%s", a, b)
+	}
+	if amy := primary.bibbedEntries[1]; amy.Duration.String() != "00:20:50.00" {
+		t.Errorf("Expected Amy's earlier backup time to win, got %s", amy.Duration)
+	}
+	if bob := backup.bibbedEntries[2]; !bob.Confirmed {
+		t.Errorf("Expected Bob's confirmation on the primary to carry over")
+	}
+	if cal := primary.bibbedEntries[3]; !cal.HasFinished() || primary.allEntries[2] != cal {
+		t.Errorf("Expected Cal's finish from the backup in third, got %#v", cal)
+	}
+}
+
+func TestFetchSnapshotSignsIn(t *testing.T) {
+	defer func(users map[string]User, primaryUser string) { config.users, config.primaryUser = users, primaryUser }(config.users, config.primaryUser)
+	config.users = map[string]User{"amy": {"amy", "secret", roleAdmin}}
+	primary := NewRace()
+	primary.AddEntry(Entry{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 34})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authorized(w, r) {
+			syncSnapshotHandler(w, r, primary)
+		}
+	}))
+	defer server.Close()
+
+	config.primaryUser = ""
+	if _, err := fetchSnapshot(server.URL); err == nil {
+		t.Errorf("Expected the primary to refuse a download without signing in")
+	}
+	config.primaryUser = "amy:secret"
+	data, err := fetchSnapshot(server.URL)
+	if err != nil || !strings.Contains(string(data), "Amy") {
+		t.Errorf("Expected the primary's racers signing in as RACERGOPRIMARYUSER, got %q - %v", data, err)
+	}
+}
+
+func TestPromotedBackupReplaysMerges(t *testing.T) {
+	primary := NewRace()
+	primary.testingTime = &time.Time{}
+	*primary.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 34},
+		{Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 41},
+	} {
+		if err := primary.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	startRace(primary)
+	*primary.testingTime = primary.testingTime.Add(20 * time.Minute)
+	linkBibTesting(t, primary, 2, false)
+
+	backup := NewRace()
+	backup.testingTime = &time.Time{}
+	*backup.testingTime = *primary.testingTime
+	backup.syncRole = SyncBackup
+	if _, err := backup.MergeSnapshot(snapshotOf(t, primary), true); err != nil {
+		t.Fatalf("Error mirroring - %v", err)
+	}
+	linkBibTesting(t, primary, 2, false)
+	if _, err := backup.MergeSnapshot(snapshotOf(t, primary), true); err != nil {
+		t.Fatalf("Error mirroring - %v", err)
+	}
+	if err := backup.Promote(); err != nil {
+		t.Fatalf("Error promoting - %v", err)
+	}
+	*backup.testingTime = backup.testingTime.Add(time.Minute)
+	linkBibTesting(t, backup, 1, false)
+
+	// the promoted backup restarts from its event log
+	replayed := replayLogged(t, backup)
+	if a, b := string(snapshotOf(t, backup)), string(snapshotOf(t, replayed)); a != b {
+		t.Errorf("Expected the replayed backup to match\n%s\n%s", a, b)
+	}
+	if bob := replayed.bibbedEntries[2]; !bob.Confirmed || bob.Duration.String() != "00:20:00.00" {
+		t.Errorf("Expected Bob's mirrored result replayed, got %#v", bob)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"html/template"
+)
+
+// TemplateFunc documents a helper available to custom templates
+type TemplateFunc struct {
+	Name        string
+	Usage       string
+	Description string
+}
+
+// templateFuncDocs is served at /admin/templates, keep it in step with templateFuncs and lockedTemplateFuncs
+var templateFuncDocs = []TemplateFunc{
+	{"textequal", `{{if textequal .sort "name"}}`, "True if the two strings are the same"},
+	{"formatPace", `{{formatPace .Entry.Duration}}`, "Pace per mile or km over the race distance, e.g. 8:03/mi"},
+	{"ordinal", `{{ordinal .Place}}`, "A place as it's said out loud, e.g. 1st, 2nd, 3rd"},
+	{"asset", `<link rel="stylesheet" href="{{asset "custom.css"}}">`, "Versioned URL for a file in static/, browsers cache it until the file changes"},
+	{"genderDisplay", `{{genderDisplay .Entry.Male}}`, "Male or Female"},
+	{"ageGroupOf", `{{ageGroupOf .Entry}}`, "The age category the racer is in, e.g. 30-39, blank if the race doesn't collect age"},
+	{"divisionOf", `{{divisionOf .Entry}}`, "The division the racer is placed in, e.g. F30-39"},
+	{"divisionPlaceOf", `{{divisionPlaceOf .Entry}}`, "The racer's place in their division, -- if they haven't finished"},
+	{"racePath", `<a href="{{racePath}}/results">`, "Where the race's pages are, /races/kids for a race hosted with RACERGORACES, blank for the main race"},
+}
+
+// templateFuncs are the helpers that don't need the race, available when the template is parsed
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"textequal": func(a, b string) bool {
+			return a == b
+		},
+		"formatPace": func(hd HumanDuration) string {
+			return hd.Pace(config.raceDistance, config.paceUnit)
+		},
+		"ordinal": func(p Place) string {
+			return p.Ordinal()
+		},
+		"asset":           assetURL,
+		"restrictedField": restrictedField,
+		"genderDisplay": func(male bool) string {
+			if male {
+				return "Male"
+			}
+			return "Female"
+		},
+		// placeholders so templates parse, replaced by lockedTemplateFuncs before executing
+		"ageGroupOf":      func(e *Entry) string { return "" },
+		"divisionOf":      func(e *Entry) string { return "" },
+		"divisionPlaceOf": func(e *Entry) Place { return 0 },
+		"racePath":        func() string { return "" },
+	}
+}
+
+// lockedTemplateFuncs are the helpers that look up the racer in the race, division places
+// are only worked out if a template asks for one
+func (race *Race) lockedTemplateFuncs() template.FuncMap {
+	var divisionPlaces map[*Entry]Place
+	return template.FuncMap{
+		"ageGroupOf": func(e *Entry) string {
+			if !race.lockedRequires("Age") {
+				return ""
+			}
+			for _, c := range race.categories {
+				if e.Age >= c.LowAge && e.Age <= c.HighAge {
+					return c.Name
+				}
+			}
+			return ""
+		},
+		"divisionOf": func(e *Entry) string {
+			return race.lockedDivisionOf(e)
+		},
+		"divisionPlaceOf": func(e *Entry) Place {
+			if divisionPlaces == nil {
+				divisionPlaces = race.lockedDivisionPlaces()
+			}
+			return divisionPlaces[e]
+		},
+		"racePath": race.Path,
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 34},
+		{Bib: 2, Fname: "Bea", Lname: "Cole", Age: 38},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	startRace(race)
+	*race.testingTime = race.testingTime.Add(25 * time.Minute)
+	linkBibTesting(t, race, 2, false)
+	*race.testingTime = race.testingTime.Add(time.Minute)
+	linkBibTesting(t, race, 1, false)
+
+	tmpl, err := template.New("custom").Funcs(templateFuncs()).Parse(`{{range .}}{{.Bib}} {{genderDisplay .Male}} {{ageGroupOf .}} {{ordinal (divisionPlaceOf .)}} {{divisionOf .}} {{formatPace .Duration}};{{end}}`)
+	if err != nil {
+		t.Fatalf("Error parsing template - %v", err)
+	}
+	var buf bytes.Buffer
+	race.Lock()
+	err = tmpl.Funcs(race.lockedTemplateFuncs()).Execute(&buf, race.allEntries)
+	race.Unlock()
+	if err != nil {
+		t.Fatalf("Error executing template - %v", err)
+	}
+	expected := "2 Female 30-39 1st F30-39 " + HumanDuration(25*time.Minute).Pace(config.raceDistance, config.paceUnit) + ";" +
+		"1 Female 30-39 2nd F30-39 " + HumanDuration(26*time.Minute).Pace(config.raceDistance, config.paceUnit) + ";"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+
+	r, _ := http.NewRequest("GET", "/admin/templates", nil)
+	w := httptest.NewRecorder()
+	handler(w, r, race)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "divisionPlaceOf") {
+		t.Errorf("Expected the template function docs, got %d - %s", w.Code, w.Body.String())
+	}
+}
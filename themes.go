@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// DisplayTheme is a look for the public screens, applied by the theme-Name class on the page
+type DisplayTheme struct {
+	Name        string
+	Description string
+}
+
+var displayThemes = []DisplayTheme{
+	{"default", "Standard"},
+	{"contrast", "High contrast, readable in direct sunlight"},
+	{"dark", "Dark, for evening races"},
+}
+
+// SetTheme switches the public screens to the theme, connected displays pick it up within a few seconds
+func (race *Race) SetTheme(name string) error {
+	for _, theme := range displayThemes {
+		if theme.Name == name {
+			race.Lock()
+			defer race.Unlock()
+			race.theme = name
+			log.Printf("Display theme set to %s", name)
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown display theme %s", name)
+}
+
+func (race *Race) lockedTheme() string {
+	if race.theme == "" {
+		return "default"
+	}
+	return race.theme
+}
+
+// themeHandler tells the displays which theme to show so they can switch without waiting for a refresh
+func themeHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	race.RLock()
+	theme := race.lockedTheme()
+	race.RUnlock()
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, theme)
+}
+
+func setThemeHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	if err := race.SetTheme(r.FormValue("theme")); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/admin", 301)
+}
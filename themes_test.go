@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDisplayTheme(t *testing.T) {
+	race := NewRace()
+	if err := race.SetTheme("neon"); err == nil {
+		t.Errorf("Expected error for an unknown theme")
+	}
+	if err := race.SetTheme("dark"); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	r, _ := http.NewRequest("GET", "/theme", nil)
+	w := httptest.NewRecorder()
+	themeHandler(w, r, race)
+	if w.Body.String() != "dark" {
+		t.Errorf("Expected the displays told dark, got %q", w.Body.String())
+	}
+	r, _ = http.NewRequest("GET", "/results", nil)
+	w = httptest.NewRecorder()
+	handler(w, r, race)
+	if !strings.Contains(w.Body.String(), `class="theme-dark"`) || !strings.Contains(w.Body.String(), `$.get("/theme"`) {
+		t.Errorf("Expected the results page in the dark theme and following changes - %s", w.Body.String())
+	}
+	r, _ = http.NewRequest("GET", "/admin", nil)
+	w = httptest.NewRecorder()
+	handler(w, r, race)
+	if !strings.Contains(w.Body.String(), `<option value="dark" selected>`) {
+		t.Errorf("Expected the current theme selected on the admin page - %s", w.Body.String())
+	}
+}
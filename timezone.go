@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// loadTimezone finds the race's timezone by its IANA name, e.g. America/Denver, the server's own timezone if blank.
+// It becomes time.Local so every displayed and exported wall clock time is in the race's timezone,
+// which matters when the server is hosted somewhere other than the race.
+func loadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %s, expected a name like America/New_York - %v", name, err)
+	}
+	return location, nil
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadTimezone(t *testing.T) {
+	if location, err := loadTimezone(""); err != nil || location != time.Local {
+		t.Errorf("Expected the server's timezone when not set, got %v - %v", location, err)
+	}
+	if _, err := loadTimezone("Mars/Olympus_Mons"); err == nil {
+		t.Errorf("Expected error for an unknown timezone")
+	}
+	location, err := loadTimezone("UTC")
+	if err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if zone := time.Date(2014, 6, 1, 9, 0, 0, 0, location).Format("MST"); zone != "UTC" {
+		t.Errorf("Expected UTC, got %s", zone)
+	}
+}
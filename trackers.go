@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Racers in an ultra often carry a satellite tracker, and their shared feeds are polled for where the racers are.
+// The tracker's ID, the SPOT messenger ID/ESN or the inReach IMEI, is in the RACERGOTRACKERFIELD column of the racers.
+
+// TrackerPosition is the last place a racer's tracker reported from
+type TrackerPosition struct {
+	Tracker   string
+	Latitude  float64
+	Longitude float64
+	At        time.Time
+	Message   string // the tracker's message type, e.g. TRACK, OK, HELP or SOS
+}
+
+// Distress is true for a help or SOS message, the racer needs someone to go out to them
+func (tp TrackerPosition) Distress() bool {
+	message := strings.ToUpper(tp.Message)
+	return message == "HELP" || message == "SOS" || message == "EMERGENCY" || strings.Contains(message, "DECLARE")
+}
+
+// MapURL shows the position on OpenStreetMap
+func (tp TrackerPosition) MapURL() string {
+	return fmt.Sprintf("https://www.openstreetmap.org/?mlat=%.5f&mlon=%.5f#map=14/%.5f/%.5f", tp.Latitude, tp.Longitude, tp.Latitude, tp.Longitude)
+}
+
+// parseSPOTFeed reads a SPOT shared page's message.json, which has a single message as an object rather than a list
+func parseSPOTFeed(data []byte) ([]TrackerPosition, error) {
+	var feed struct {
+		Response struct {
+			FeedMessageResponse struct {
+				Messages struct {
+					Message json.RawMessage `json:"message"`
+				} `json:"messages"`
+			} `json:"feedMessageResponse"`
+			Errors *struct {
+				Error struct {
+					Description string `json:"description"`
+				} `json:"error"`
+			} `json:"errors"`
+		} `json:"response"`
+	}
+	if err := json.Unmarshal(data, &feed); err != nil {
+		return nil, err
+	}
+	if feed.Response.Errors != nil { // also what SPOT sends when there's been no messages in the last week
+		return nil, fmt.Errorf("SPOT feed error - %s", feed.Response.Errors.Error.Description)
+	}
+	type spotMessage struct {
+		MessengerID string  `json:"messengerId"`
+		UnixTime    int64   `json:"unixTime"`
+		MessageType string  `json:"messageType"`
+		Latitude    float64 `json:"latitude"`
+		Longitude   float64 `json:"longitude"`
+	}
+	raw := feed.Response.FeedMessageResponse.Messages.Message
+	var messages []spotMessage
+	if err := json.Unmarshal(raw, &messages); err != nil {
+		var message spotMessage
+		if err = json.Unmarshal(raw, &message); err != nil {
+			return nil, err
+		}
+		messages = []spotMessage{message}
+	}
+	positions := make([]TrackerPosition, 0, len(messages))
+	for _, m := range messages {
+		positions = append(positions, TrackerPosition{Tracker: m.MessengerID, Latitude: m.Latitude, Longitude: m.Longitude, At: time.Unix(m.UnixTime, 0), Message: m.MessageType})
+	}
+	return positions, nil
+}
+
+// parseInReachFeed reads a Garmin MapShare KML feed
+func parseInReachFeed(data []byte) ([]TrackerPosition, error) {
+	var kml struct {
+		Placemarks []struct {
+			When string `xml:"TimeStamp>when"`
+			Data []struct {
+				Name  string `xml:"name,attr"`
+				Value string `xml:"value"`
+			} `xml:"ExtendedData>Data"`
+			Coordinates string `xml:"Point>coordinates"`
+		} `xml:"Document>Folder>Placemark"`
+	}
+	if err := xml.Unmarshal(data, &kml); err != nil {
+		return nil, err
+	}
+	var positions []TrackerPosition
+	for _, p := range kml.Placemarks {
+		if p.Coordinates == "" {
+			continue // the track line joining the points
+		}
+		tp := TrackerPosition{}
+		var err error
+		if tp.At, err = time.Parse(time.RFC3339, p.When); err != nil {
+			return nil, fmt.Errorf("%s is not a time in the inReach feed", p.When)
+		}
+		coordinates := strings.Split(strings.TrimSpace(p.Coordinates), ",")
+		if len(coordinates) < 2 {
+			return nil, fmt.Errorf("%s are not coordinates in the inReach feed", p.Coordinates)
+		}
+		if tp.Longitude, err = strconv.ParseFloat(coordinates[0], 64); err == nil {
+			tp.Latitude, err = strconv.ParseFloat(coordinates[1], 64)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s are not coordinates in the inReach feed", p.Coordinates)
+		}
+		for _, d := range p.Data {
+			switch d.Name {
+			case "IMEI":
+				tp.Tracker = d.Value
+			case "Event":
+				tp.Message = d.Value
+			case "In Emergency":
+				if d.Value == "True" {
+					tp.Message = "SOS"
+				}
+			}
+		}
+		positions = append(positions, tp)
+	}
+	return positions, nil
+}
+
+func fetchTrackerFeed(url string) ([]TrackerPosition, error) {
+	resp, err := syncClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "<") {
+		return parseInReachFeed(data)
+	}
+	return parseSPOTFeed(data)
+}
+
+// UpdatePositions keeps the latest position of each racer's tracker, returning how many racers moved.
+// Positions from trackers no racer has are ignored.
+func (race *Race) UpdatePositions(positions []TrackerPosition) int {
+	race.Lock()
+	defer race.Unlock()
+	trackerIndex := -1
+	for x, field := range race.optionalEntryFields {
+		if strings.EqualFold(field, config.trackerField) {
+			trackerIndex = x
+		}
+	}
+	if trackerIndex < 0 {
+		return 0
+	}
+	bibs := make(map[string]Bib)
+	for _, e := range race.allEntries {
+		if trackerIndex < len(e.Optional) && strings.TrimSpace(e.Optional[trackerIndex]) != "" {
+			bibs[strings.TrimSpace(e.Optional[trackerIndex])] = e.Bib
+		}
+	}
+	if race.positions == nil {
+		race.positions = make(map[Bib]TrackerPosition)
+	}
+	moved := 0
+	for _, tp := range positions {
+		bib, ok := bibs[tp.Tracker]
+		if !ok {
+			continue
+		}
+		if last, ok := race.positions[bib]; ok && !tp.At.After(last.At) {
+			continue
+		}
+		if tp.Distress() {
+			log.Printf("Bib #%d's tracker sent %s from %.5f,%.5f", bib, tp.Message, tp.Latitude, tp.Longitude)
+		}
+		race.positions[bib] = tp
+		moved++
+	}
+	return moved
+}
+
+// followTrackers polls the tracker feeds every interval, SPOT asks for no more than every 2.5 minutes
+func followTrackers(race *Race, feeds []string, interval time.Duration) {
+	for {
+		for _, url := range feeds {
+			positions, err := fetchTrackerFeed(url)
+			if err != nil {
+				log.Printf("Error reading the tracker feed %s - %v", url, err)
+				continue
+			}
+			race.UpdatePositions(positions)
+		}
+		time.Sleep(interval)
+	}
+}
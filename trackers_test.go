@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSPOTFeed = `{"response":{"feedMessageResponse":{"count":2,"messages":{"message":[
+	{"messengerId":"0-2345678","unixTime":1401627600,"messageType":"TRACK","latitude":39.9,"longitude":-105.3},
+	{"messengerId":"0-2345678","unixTime":1401624000,"messageType":"TRACK","latitude":39.8,"longitude":-105.2}]}}}}`
+
+const testInReachFeed = `<?xml version="1.0" encoding="utf-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2"><Document><Folder>
+	<Placemark><TimeStamp><when>2014-06-01T15:30:00Z</when></TimeStamp>
+		<ExtendedData><Data name="IMEI"><value>300434030000000</value></Data><Data name="In Emergency"><value>True</value></Data></ExtendedData>
+		<Point><coordinates>-105.4,39.95,2400</coordinates></Point></Placemark>
+	<Placemark><LineString><coordinates>-105.4,39.95,2400</coordinates></LineString></Placemark>
+</Folder></Document></kml>`
+
+func TestParseTrackerFeeds(t *testing.T) {
+	positions, err := parseSPOTFeed([]byte(testSPOTFeed))
+	if err != nil || len(positions) != 2 || positions[0].Tracker != "0-2345678" || positions[0].Latitude != 39.9 || !positions[0].At.Equal(time.Unix(1401627600, 0)) {
+		t.Errorf("Expected 2 SPOT positions, got %v - %v", positions, err)
+	}
+	single := `{"response":{"feedMessageResponse":{"messages":{"message":{"messengerId":"0-1","unixTime":1401627600,"messageType":"HELP","latitude":39.9,"longitude":-105.3}}}}}`
+	if positions, err = parseSPOTFeed([]byte(single)); err != nil || len(positions) != 1 || !positions[0].Distress() {
+		t.Errorf("Expected a single SPOT HELP message, got %v - %v", positions, err)
+	}
+	if _, err = parseSPOTFeed([]byte(`{"response":{"errors":{"error":{"code":"E-0195","description":"No displayable messages found"}}}}`)); err == nil {
+		t.Errorf("Expected the SPOT feed error")
+	}
+	positions, err = parseInReachFeed([]byte(testInReachFeed))
+	if err != nil || len(positions) != 1 || positions[0].Tracker != "300434030000000" || positions[0].Longitude != -105.4 || !positions[0].Distress() {
+		t.Errorf("Expected an inReach SOS position, got %v - %v", positions, err)
+	}
+}
+
+func TestUpdatePositions(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	if err := race.SetOptionalFields([]string{"Email", "Tracker"}); err != nil {
+		t.Fatalf("Error setting fields - %v", err)
+	}
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38, Optional: []string{"amy@host.com", "0-2345678"}},
+		{Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 31, Optional: []string{"bob@host.com", "300434030000000"}},
+		{Bib: 3, Fname: "Cal", Lname: "Cole", Male: true, Age: 52, Optional: []string{"cal@host.com", ""}},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	startRace(race)
+	spot, _ := parseSPOTFeed([]byte(testSPOTFeed))
+	inReach, _ := parseInReachFeed([]byte(testInReachFeed))
+	if moved := race.UpdatePositions(append(spot, inReach...)); moved != 2 {
+		t.Errorf("Expected Amy and Bob to move, got %d", moved) // Amy's older position comes second and is ignored
+	}
+	if moved := race.UpdatePositions(spot); moved != 0 {
+		t.Errorf("Expected the same positions again to move nobody, got %d", moved)
+	}
+	if race.positions[1].Latitude != 39.9 {
+		t.Errorf("Expected Amy's latest position, got %v", race.positions[1])
+	}
+
+	for _, page := range []string{"/onCourse", "/tracking"} {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", page, nil)
+		handler(w, r, race)
+		body := w.Body.String()
+		if w.Code != http.StatusOK || !strings.Contains(body, "mlat=39.90000") {
+			t.Errorf("Expected Amy's position on %s, got %d %s", page, w.Code, body)
+		}
+		if page == "/onCourse" && !strings.Contains(body, `class="danger"`) {
+			t.Errorf("Expected Bob's SOS flagged on the still on course report")
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// TransferStatus tracks a bib transfer request through admin approval
+type TransferStatus uint8
+
+const (
+	TransferPending TransferStatus = iota
+	TransferApproved
+	TransferRejected
+)
+
+func (ts TransferStatus) String() string {
+	switch ts {
+	case TransferApproved:
+		return "Approved"
+	case TransferRejected:
+		return "Rejected"
+	}
+	return "Pending"
+}
+
+// TransferEvent is one step in the audit trail of a transfer
+type TransferEvent struct {
+	Time   time.Time
+	Action string
+}
+
+// Transfer is a registered runner's request to give their bib to someone else
+type Transfer struct {
+	ID      int
+	Bib     Bib
+	From    string // the name on the bib when the transfer was requested
+	To      Entry  // the new runner, only the identity fields and e-mail are used
+	Email   string
+	Fee     float64
+	FeePaid bool
+	Status  TransferStatus
+	History []TransferEvent
+}
+
+func (t *Transfer) record(now time.Time, action string, args ...interface{}) {
+	event := TransferEvent{Time: now, Action: fmt.Sprintf(action, args...)}
+	log.Printf("Transfer %d of bib #%d - %s", t.ID, t.Bib, event.Action)
+	t.History = append(t.History, event)
+}
+
+// transferSecret signs transfer links, a new one each run unless RACERGOTRANSFERSECRET is set
+var transferSecret []byte
+
+func init() {
+	transferSecret = make([]byte, 32)
+	if _, err := rand.Read(transferSecret); err != nil {
+		log.Fatalf("Error generating transfer secret - %v", err)
+	}
+}
+
+func (race *Race) lockedEmailOf(e *Entry) string {
+	if race.optionalEmailIndex >= 0 && race.optionalEmailIndex < len(e.Optional) {
+		return e.Optional[race.optionalEmailIndex]
+	}
+	return ""
+}
+
+// lockedTransferToken signs the bib and who currently holds it, so links stop working once the bib changes hands
+func (race *Race) lockedTransferToken(e *Entry) string {
+	secret := transferSecret
+	if config.transferSecret != "" {
+		secret = []byte(config.transferSecret)
+	}
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%d|%s|%s|%s", e.Bib, e.Fname, e.Lname, race.lockedEmailOf(e))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:18])
+}
+
+func (race *Race) transferURL(bib Bib, token string) string {
+	return race.raceURL(fmt.Sprintf("/transfer?bib=%d&token=%s", bib, token))
+}
+
+func (race *Race) lockedTransfersOpen() error {
+	if !race.started.IsZero() {
+		return fmt.Errorf("Race has already started, bibs can no longer be transferred")
+	}
+	if !config.transferDeadline.IsZero() && !race.GetTime().Before(config.transferDeadline) {
+		return fmt.Errorf("The transfer deadline of %s has passed", config.transferDeadline.Format("Jan 2 3:04 PM"))
+	}
+	return nil
+}
+
+// lockedTransferEntry returns the entry for the bib if the token is a valid transfer link for it
+func (race *Race) lockedTransferEntry(bib Bib, token string) (*Entry, error) {
+	entry, ok := race.bibbedEntries[bib]
+	if !ok || !hmac.Equal([]byte(token), []byte(race.lockedTransferToken(entry))) {
+		return nil, fmt.Errorf("This transfer link is not valid, the bib may have already been transferred")
+	}
+	if !entry.Registration.HasSpot() {
+		return nil, fmt.Errorf("Bib #%d is %s and can't be transferred", bib, entry.Registration)
+	}
+	return entry, nil
+}
+
+// RequestTransferLink e-mails a transfer link to the address registered for the bib,
+// so only the runner holding the bib can start a transfer
+func (race *Race) RequestTransferLink(bib Bib) error {
+	race.RLock()
+	defer race.RUnlock()
+	if err := race.lockedTransfersOpen(); err != nil {
+		return err
+	}
+	entry, ok := race.bibbedEntries[bib]
+	if !ok {
+		return fmt.Errorf("Bib %d not found", bib)
+	}
+	if race.lockedEmailOf(entry) == "" {
+		return fmt.Errorf("No e-mail address is registered for bib #%d, contact the race director to transfer it", bib)
+	}
+	text := fmt.Sprintf("Hi %s %s,\n\nTo transfer bib #%d in the %s to someone else, fill in their details at %s\n\nThe transfer needs to be approved before it takes effect.", entry.Fname, entry.Lname, bib, race.lockedName(), race.transferURL(bib, race.lockedTransferToken(entry)))
+	go sendEmail(*entry, race.optionalEmailIndex, fmt.Sprintf("%s Bib Transfer", race.lockedName()), text)
+	return nil
+}
+
+// RequestTransfer queues a transfer of the bib to the new runner for admin approval
+func (race *Race) RequestTransfer(bib Bib, token string, to Entry, email string) (*Transfer, error) {
+	if to.Fname == "" || to.Lname == "" {
+		return nil, fmt.Errorf("The new runner's first and last name are required")
+	}
+	race.Lock()
+	defer race.Unlock()
+	if err := race.lockedTransfersOpen(); err != nil {
+		return nil, err
+	}
+	entry, err := race.lockedTransferEntry(bib, token)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range race.transfers {
+		if t.Bib == bib && t.Status == TransferPending {
+			return nil, fmt.Errorf("A transfer of bib #%d is already waiting for approval", bib)
+		}
+	}
+	race.nextTransferID++
+	transfer := &Transfer{
+		ID:    race.nextTransferID,
+		Bib:   bib,
+		From:  entry.Fname + " " + entry.Lname,
+		To:    to,
+		Email: email,
+		Fee:   config.transferFee,
+	}
+	transfer.record(race.GetTime(), "Requested by %s to %s %s", transfer.From, to.Fname, to.Lname)
+	race.transfers = append(race.transfers, transfer)
+	return transfer, nil
+}
+
+func (race *Race) lockedTransfer(id int) (*Transfer, error) {
+	for _, t := range race.transfers {
+		if t.ID == id {
+			if t.Status != TransferPending {
+				return nil, fmt.Errorf("Transfer %d was already %s", id, t.Status)
+			}
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("Transfer %d not found", id)
+}
+
+// MarkTransferFeePaid records the transfer fee as collected
+func (race *Race) MarkTransferFeePaid(id int) error {
+	race.Lock()
+	defer race.Unlock()
+	transfer, err := race.lockedTransfer(id)
+	if err != nil {
+		return err
+	}
+	transfer.FeePaid = true
+	transfer.record(race.GetTime(), "Fee of $%.2f paid", transfer.Fee)
+	return nil
+}
+
+// ApproveTransfer puts the new runner's name, age, gender and e-mail on the bib, keeping its registration
+func (race *Race) ApproveTransfer(id int) error {
+	race.Lock()
+	defer race.Unlock()
+	if err := race.lockedTransfersOpen(); err != nil {
+		return err
+	}
+	transfer, err := race.lockedTransfer(id)
+	if err != nil {
+		return err
+	}
+	if transfer.Fee > 0 && !transfer.FeePaid {
+		return fmt.Errorf("The $%.2f transfer fee hasn't been paid", transfer.Fee)
+	}
+	entry, previous, err := race.lockedApplyTransfer(transfer)
+	if err != nil {
+		return err
+	}
+	transfer.Status = TransferApproved
+	transfer.record(race.GetTime(), "Approved, bib #%d now belongs to %s %s", transfer.Bib, entry.Fname, entry.Lname)
+	approved := *transfer
+	race.lockedRecordEvent(Event{Kind: "transfer", Bib: transfer.Bib, Transfer: &approved})
+	subject := fmt.Sprintf("%s Bib Transfer", race.lockedName())
+	go sendEmail(previous, race.optionalEmailIndex, subject, fmt.Sprintf("Your transfer of bib #%d to %s %s has been approved.", transfer.Bib, entry.Fname, entry.Lname))
+	go sendEmail(*entry, race.optionalEmailIndex, subject, fmt.Sprintf("Welcome %s %s!  Bib #%d in the %s has been transferred to you.  We'll see you on race day!\n\nAdd the race day schedule to your calendar - %s", entry.Fname, entry.Lname, transfer.Bib, race.lockedName(), race.calendarURL()))
+	return nil
+}
+
+// lockedApplyTransfer puts the new runner's name, age, gender and e-mail on the bib, returning the entry and who
+// held the bib before
+func (race *Race) lockedApplyTransfer(transfer *Transfer) (*Entry, Entry, error) {
+	entry, ok := race.bibbedEntries[transfer.Bib]
+	if !ok || entry.Fname+" "+entry.Lname != transfer.From {
+		return nil, Entry{}, fmt.Errorf("Bib #%d has changed since the transfer was requested", transfer.Bib)
+	}
+	previous := *entry
+	previous.Optional = append([]string(nil), entry.Optional...)
+	entry.Fname = transfer.To.Fname
+	entry.Lname = transfer.To.Lname
+	entry.Age = transfer.To.Age
+	entry.Male = transfer.To.Male
+	if race.optionalEmailIndex >= 0 && race.optionalEmailIndex < len(entry.Optional) {
+		entry.Optional[race.optionalEmailIndex] = transfer.Email
+	}
+	race.lockedEntriesChanged()
+	race.lockedRecomputePrizes()
+	return entry, previous, nil
+}
+
+// lockedReplayTransfer applies an approved transfer from the event log, the request itself isn't logged
+func (race *Race) lockedReplayTransfer(transfer *Transfer) error {
+	if _, _, err := race.lockedApplyTransfer(transfer); err != nil {
+		return err
+	}
+	race.transfers = append(race.transfers, transfer)
+	if transfer.ID > race.nextTransferID {
+		race.nextTransferID = transfer.ID
+	}
+	return nil
+}
+
+func (race *Race) RejectTransfer(id int) error {
+	race.Lock()
+	defer race.Unlock()
+	transfer, err := race.lockedTransfer(id)
+	if err != nil {
+		return err
+	}
+	transfer.Status = TransferRejected
+	transfer.record(race.GetTime(), "Rejected")
+	return nil
+}
+
+func requestTransferLinkHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	bib, err := strconv.Atoi(r.FormValue("bib"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting bib number", err)
+		return
+	}
+	if err = race.RequestTransferLink(Bib(bib)); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/transfer?sent=true", 301)
+}
+
+func submitTransferHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	bib, err := strconv.Atoi(r.FormValue("bib"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting bib number", err)
+		return
+	}
+	to := Entry{Fname: r.FormValue("Fname"), Lname: r.FormValue("Lname"), Male: r.FormValue("Male") == "M"}
+	if r.FormValue("Age") != "" {
+		age, err := strconv.Atoi(r.FormValue("Age"))
+		if err != nil || age < 0 {
+			showErrorForAdmin(w, r.Referer(), "%s is not a valid age, must be >= 0", r.FormValue("Age"))
+			return
+		}
+		to.Age = uint(age)
+	}
+	_, err = race.RequestTransfer(Bib(bib), r.FormValue("token"), to, r.FormValue("Email"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/transfer?requested=true", 301)
+}
+
+func transferActionHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting transfer", err)
+		return
+	}
+	switch r.FormValue("action") {
+	case "approve":
+		err = race.ApproveTransfer(id)
+	case "reject":
+		err = race.RejectTransfer(id)
+	case "paid":
+		err = race.MarkTransferFeePaid(id)
+	default:
+		err = fmt.Errorf("Unknown transfer action %s", r.FormValue("action"))
+	}
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/transfers", 301)
+}
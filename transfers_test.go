@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func transferTestRace(t *testing.T) *Race {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	if err := race.SetOptionalFields([]string{"Email"}); err != nil {
+		t.Fatalf("Error setting optional fields - %v", err)
+	}
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38, Optional: []string{"amy@host.com"}},
+		{Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 31, Optional: []string{""}},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	return race
+}
+
+func TestBibTransfer(t *testing.T) {
+	defer func(fee float64) { config.transferFee = fee }(config.transferFee)
+	config.transferFee = 10
+	race := transferTestRace(t)
+	if err := race.RequestTransferLink(2); err == nil {
+		t.Errorf("Expected error requesting a link without an e-mail address")
+	}
+	if err := race.RequestTransferLink(1); err != nil {
+		t.Errorf("Unexpected error - %v", err)
+	}
+	race.RLock()
+	token := race.lockedTransferToken(race.bibbedEntries[1])
+	race.RUnlock()
+
+	to := Entry{Fname: "Cal", Lname: "Cole", Male: true, Age: 45}
+	if _, err := race.RequestTransfer(1, "forged", to, "cal@host.com"); err == nil {
+		t.Errorf("Expected error with a forged token")
+	}
+	transfer, err := race.RequestTransfer(1, token, to, "cal@host.com")
+	if err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if _, err := race.RequestTransfer(1, token, to, "cal@host.com"); err == nil {
+		t.Errorf("Expected error requesting a second transfer while one is pending")
+	}
+	if err := race.ApproveTransfer(transfer.ID); err == nil {
+		t.Errorf("Expected error approving before the fee is paid")
+	}
+	if err := race.MarkTransferFeePaid(transfer.ID); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if err := race.ApproveTransfer(transfer.ID); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	entry := race.bibbedEntries[1]
+	if entry.Fname != "Cal" || entry.Lname != "Cole" || !entry.Male || entry.Age != 45 || entry.Optional[0] != "cal@host.com" {
+		t.Errorf("Expected bib 1 transferred to Cal, got %#v", entry)
+	}
+	if len(transfer.History) != 3 || transfer.Status != TransferApproved {
+		t.Errorf("Expected requested, paid and approved in the history, got %v", transfer.History)
+	}
+	if err := race.RejectTransfer(transfer.ID); err == nil {
+		t.Errorf("Expected error rejecting an approved transfer")
+	}
+	// the old link no longer works once the bib has changed hands
+	if _, err := race.RequestTransfer(1, token, to, "cal@host.com"); err == nil {
+		t.Errorf("Expected error reusing the previous owner's link")
+	}
+
+	r, _ := http.NewRequest("GET", "/transfers", nil)
+	w := httptest.NewRecorder()
+	handler(w, r, race)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Approved, bib #1 now belongs to Cal Cole") {
+		t.Errorf("Expected the audit trail on the transfers page, got %d - %s", w.Code, w.Body.String())
+	}
+}
+
+func TestBibTransferWindow(t *testing.T) {
+	defer func(deadline time.Time) { config.transferDeadline = deadline }(config.transferDeadline)
+	race := transferTestRace(t)
+	race.RLock()
+	token := race.lockedTransferToken(race.bibbedEntries[1])
+	race.RUnlock()
+
+	r, _ := http.NewRequest("GET", "/transfer?bib=1&token="+token, nil)
+	w := httptest.NewRecorder()
+	handler(w, r, race)
+	if !strings.Contains(w.Body.String(), "Transferring bib #1 from Amy Brown") {
+		t.Errorf("Expected the transfer form for a valid link - %s", w.Body.String())
+	}
+
+	config.transferDeadline = race.GetTime()
+	if _, err := race.RequestTransfer(1, token, Entry{Fname: "Cal", Lname: "Cole"}, ""); err == nil {
+		t.Errorf("Expected error after the transfer deadline")
+	}
+	config.transferDeadline = race.GetTime().Add(time.Hour)
+	transfer, err := race.RequestTransfer(1, token, Entry{Fname: "Cal", Lname: "Cole"}, "")
+	if err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	startRace(race)
+	if err := race.ApproveTransfer(transfer.ID); err == nil {
+		t.Errorf("Expected error approving a transfer after the race started")
+	}
+	if err := race.RejectTransfer(transfer.ID); err != nil {
+		t.Errorf("Unexpected error - %v", err)
+	}
+}
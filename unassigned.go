@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// UnassignedTime is a finish time captured at the line that hasn't been linked to a racer yet,
+// either waiting for the operator to check the racer or rejected as the wrong bib
+type UnassignedTime struct {
+	ID   int
+	Time time.Time
+	Bib  Bib // the bib entered at the line, may well be wrong
+}
+
+func (u UnassignedTime) Duration(started time.Time) HumanDuration {
+	return HumanDuration(u.Time.Sub(started))
+}
+
+// CaptureTime holds the current time for the bib as unassigned until the operator confirms the racer
+func (race *Race) CaptureTime(bib Bib) (int, error) {
+	race.Lock()
+	defer race.Unlock()
+	if race.started.IsZero() {
+		return 0, fmt.Errorf("Race has not started yet, cannot link a bib")
+	}
+	if race.finalized {
+		return 0, fmt.Errorf("Results have been finalized, cannot link a bib")
+	}
+	race.nextUnassignedID++
+	now := race.GetTime()
+	race.unassigned = append(race.unassigned, &UnassignedTime{ID: race.nextUnassignedID, Time: now, Bib: bib})
+	log.Printf("Captured time for bib #%d, waiting for confirmation", bib)
+	race.lockedRecordEvent(Event{Kind: "capture", Bib: bib, Time: now, Unassigned: race.nextUnassignedID})
+	return race.nextUnassignedID, nil
+}
+
+func (race *Race) lockedUnassigned(id int) (int, *UnassignedTime) {
+	for x, u := range race.unassigned {
+		if u.ID == id {
+			return x, u
+		}
+	}
+	return -1, nil
+}
+
+// lockedCheckBib describes why the bib can't be given a finish time, blank if it can
+func (race *Race) lockedCheckBib(bib Bib) string {
+	entry, ok := race.bibbedEntries[bib]
+	switch {
+	case !ok:
+		return fmt.Sprintf("Bib #%d not found", bib)
+	case !entry.Registration.HasSpot():
+		return fmt.Sprintf("Bib #%d is %s and doesn't have a spot in the race", bib, entry.Registration)
+	case entry.HasFinished():
+		return fmt.Sprintf("Bib #%d already has a finish time of %s", bib, entry.Duration)
+	}
+	return ""
+}
+
+// AssignTime links the captured time to the bib, which doesn't have to be the bib entered when it was captured
+func (race *Race) AssignTime(id int, bib Bib) error {
+	race.Lock()
+	defer race.Unlock()
+	x, u := race.lockedUnassigned(id)
+	if u == nil {
+		return fmt.Errorf("Unassigned time %d not found", id)
+	}
+	if problem := race.lockedCheckBib(bib); problem != "" {
+		return fmt.Errorf("%s", problem)
+	}
+	if err := race.lockedLinkTime(bib, u.Time, true, id); err != nil {
+		return err
+	}
+	race.unassigned = append(race.unassigned[:x], race.unassigned[x+1:]...)
+	return nil
+}
+
+// DiscardTime throws away a captured time that doesn't belong to anyone, e.g. a spectator crossing the line
+func (race *Race) DiscardTime(id int) error {
+	race.Lock()
+	defer race.Unlock()
+	x, u := race.lockedUnassigned(id)
+	if u == nil {
+		return fmt.Errorf("Unassigned time %d not found", id)
+	}
+	log.Printf("Discarded unassigned time %s entered as bib #%d", u.Duration(race.started), u.Bib)
+	race.unassigned = append(race.unassigned[:x], race.unassigned[x+1:]...)
+	race.lockedRecordEvent(Event{Kind: "discard", Unassigned: id})
+	return nil
+}
+
+func checkBibHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	tmpBib, err := strconv.Atoi(r.FormValue("bib"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting bib number", err)
+		return
+	}
+	if tmpBib < 0 {
+		showErrorForAdmin(w, r.Referer(), "Cannot assign a negative bib number of %d", tmpBib)
+		return
+	}
+	id, err := race.CaptureTime(Bib(tmpBib))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	if r.FormValue("mobile") == "true" {
+		http.Redirect(w, r, fmt.Sprintf("/confirmBib?id=%d&mobile=true", id), 301)
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("/confirmBib?id=%d", id), 301)
+}
+
+func assignTimeHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting unassigned time", err)
+		return
+	}
+	if r.FormValue("discard") == "true" {
+		err = race.DiscardTime(id)
+	} else {
+		var bib int
+		bib, err = strconv.Atoi(r.FormValue("bib"))
+		if err != nil {
+			showErrorForAdmin(w, r.Referer(), "Error %s getting bib number", err)
+			return
+		}
+		err = race.AssignTime(id, Bib(bib))
+	}
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, adminPage(r), 301)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfirmBib(t *testing.T) {
+	race := NewRace()
+	raceStart := time.Now().Add(-time.Hour).Round(time.Second)
+	race.testingTime = &time.Time{}
+	*race.testingTime = raceStart
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38},
+		{Bib: 2, Fname: "Bob", Lname: "Adams", Male: true, Age: 31},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	if _, err := race.CaptureTime(1); err == nil {
+		t.Errorf("Expected error capturing a time before the race started")
+	}
+	startRace(race)
+
+	*race.testingTime = raceStart.Add(20 * time.Minute)
+	r, _ := http.NewRequest("POST", "/checkBib?bib=1", nil)
+	w := httptest.NewRecorder()
+	checkBibHandler(w, r, race)
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/confirmBib?id=1" {
+		t.Fatalf("Expected redirect to confirm, got %d %s", w.Code, w.Header().Get("Location"))
+	}
+	if race.bibbedEntries[1].HasFinished() {
+		t.Errorf("Expected no time linked until the operator confirms")
+	}
+
+	// the operator sees who they're about to give the time to
+	*race.testingTime = raceStart.Add(20*time.Minute + 5*time.Second)
+	r, _ = http.NewRequest("GET", "/confirmBib?id=1", nil)
+	w = httptest.NewRecorder()
+	handler(w, r, race)
+	if !strings.Contains(w.Body.String(), "Amy Brown") || !strings.Contains(w.Body.String(), "Female, age 38") {
+		t.Errorf("Expected the racer's identity on the confirmation screen - %s", w.Body.String())
+	}
+
+	// confirming links the time captured at the line, not the time the operator confirmed
+	if err := race.AssignTime(1, 1); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if d := race.bibbedEntries[1].Duration; d != HumanDuration(20*time.Minute) {
+		t.Errorf("Expected the captured duration of 20m, got %s", d)
+	}
+	if err := race.AssignTime(1, 1); err == nil {
+		t.Errorf("Expected error assigning a time twice")
+	}
+
+	// rejected times stay unassigned until they're given to the right racer
+	id, _ := race.CaptureTime(1)
+	race.RLock()
+	if problem := race.lockedCheckBib(1); problem == "" {
+		t.Errorf("Expected a problem giving bib 1 a second finish time")
+	}
+	race.RUnlock()
+	if err := race.AssignTime(id, 1); err == nil {
+		t.Errorf("Expected error assigning a second time to bib 1")
+	}
+	if err := race.AssignTime(id, 99); err == nil {
+		t.Errorf("Expected error assigning a time to a missing bib")
+	}
+	if len(race.unassigned) != 1 {
+		t.Fatalf("Expected the rejected time kept as unassigned, got %v", race.unassigned)
+	}
+	r, _ = http.NewRequest("POST", "/assignTime?bib=2&id=2", nil)
+	w = httptest.NewRecorder()
+	assignTimeHandler(w, r, race)
+	if w.Code != http.StatusMovedPermanently || !race.bibbedEntries[2].HasFinished() || len(race.unassigned) != 0 {
+		t.Errorf("Expected the unassigned time given to bib 2, got %d - %s", w.Code, w.Body.String())
+	}
+
+	id, _ = race.CaptureTime(5)
+	if err := race.DiscardTime(id); err != nil || len(race.unassigned) != 0 {
+		t.Errorf("Expected the time discarded - %v", err)
+	}
+	if err := race.DiscardTime(id); err == nil {
+		t.Errorf("Expected error discarding a time twice")
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+)
+
+// uploadLimit fails the read once more than the allowed bytes have come through, so a wrong file can't fill the laptop's memory
+type uploadLimit struct {
+	r         io.Reader
+	limit     int64
+	remaining int64
+}
+
+func limitUpload(r io.Reader) io.Reader {
+	return &uploadLimit{r, config.maxUploadBytes, config.maxUploadBytes}
+}
+
+func (u *uploadLimit) Read(p []byte) (int, error) {
+	if int64(len(p)) > u.remaining+1 {
+		p = p[:u.remaining+1] // one byte over is enough to know it's too big
+	}
+	n, err := u.r.Read(p)
+	if u.remaining -= int64(n); u.remaining < 0 {
+		return n, fmt.Errorf("the file is over the %s upload limit, RACERGOMAXUPLOAD raises it", humanBytes(uint64(u.limit)))
+	}
+	return n, err
+}
+
+// checkUploadRows is an error when a file has more rows than RACERGOMAXUPLOADROWS allows
+func checkUploadRows(rows int, what string) error {
+	if rows > config.maxUploadRows {
+		return fmt.Errorf("the file has %d %s, more than the %d allowed, RACERGOMAXUPLOADROWS raises it", rows, what, config.maxUploadRows)
+	}
+	return nil
+}
+
+// MemoryStats is how much memory racergo is using, for the admin page
+type MemoryStats struct {
+	Heap       string // in use by racers, results and pages being built
+	System     string // everything taken from the operating system
+	Collected  uint32 // garbage collections since startup
+	Goroutines int
+}
+
+func memoryStats() MemoryStats {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return MemoryStats{humanBytes(m.HeapAlloc), humanBytes(m.Sys), m.NumGC, runtime.NumGoroutine()}
+}
+
+func humanBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(b)/float64(div), "KMGT"[exp])
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUploadLimits(t *testing.T) {
+	defer func(bytes int64, rows int) { config.maxUploadBytes, config.maxUploadRows = bytes, rows }(config.maxUploadBytes, config.maxUploadRows)
+	race := NewRace()
+	config.maxUploadRows = 5
+	if testUploadRacersHelper(t, "test_runners.csv", 409, race); len(race.allEntries) != 0 {
+		t.Errorf("Expected no racers loaded over the row limit, got %d", len(race.allEntries))
+	}
+	config.maxUploadRows, config.maxUploadBytes = 100, 100
+	if testUploadRacersHelper(t, "test_runners.csv", 409, race); len(race.allEntries) != 0 {
+		t.Errorf("Expected no racers loaded over the size limit, got %d", len(race.allEntries))
+	}
+	config.maxUploadBytes = 1 << 20
+	testUploadRacersHelper(t, "test_runners.csv", 301, race)
+
+	config.maxUploadBytes = 10
+	buf := make([]byte, 64)
+	n, err := limitUpload(strings.NewReader("0123456789")).Read(buf)
+	if n != 10 || err != nil {
+		t.Errorf("Expected a file right at the limit read, got %d %v", n, err)
+	}
+	if _, err = limitUpload(strings.NewReader("0123456789A")).Read(buf); err == nil || !strings.Contains(err.Error(), "10 B upload limit") {
+		t.Errorf("Expected a file over the limit refused, got %v", err)
+	}
+	if got := humanBytes(3 << 20); got != "3.0 MB" {
+		t.Errorf("Expected 3.0 MB, got %s", got)
+	}
+	if m := memoryStats(); m.Heap == "" || m.Goroutines == 0 {
+		t.Errorf("Expected memory stats, got %#v", m)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// publicPages are the templates counted as public views, by the name they're reported under
+var publicPages = map[string]string{
+	"default":     "Results",
+	"results":     "Announcer",
+	"lookup":      "Lookup",
+	"finisher":    "Finisher",
+	"fundraising": "Fundraising",
+	"info":        "Info",
+}
+
+// PageViews is how many times a public page was viewed within an hour
+type PageViews struct {
+	Hour  time.Time
+	Page  string
+	Views uint64
+}
+
+// RunnerViews is how many times a runner's result was looked up or their finisher page viewed
+type RunnerViews struct {
+	*Entry
+	Views uint64
+}
+
+// lockedCountView tallies a view of the page, only counts are kept - nothing about who viewed it
+func (race *Race) lockedCountView(name string, now time.Time) {
+	page, ok := publicPages[name]
+	if !ok {
+		return
+	}
+	hour := now.Truncate(time.Hour)
+	race.statsLock.Lock()
+	defer race.statsLock.Unlock()
+	for _, pv := range race.pageViews {
+		if pv.Page == page && pv.Hour.Equal(hour) {
+			pv.Views++
+			return
+		}
+	}
+	race.pageViews = append(race.pageViews, &PageViews{Hour: hour, Page: page, Views: 1})
+}
+
+func (race *Race) lockedCountRunnerView(bib Bib) {
+	race.statsLock.Lock()
+	defer race.statsLock.Unlock()
+	if race.runnerViews == nil {
+		race.runnerViews = make(map[Bib]uint64)
+	}
+	race.runnerViews[bib]++
+}
+
+// lockedPageViewTotals sums the views of each page over the whole race
+func (race *Race) lockedPageViewTotals() map[string]uint64 {
+	totals := make(map[string]uint64)
+	race.statsLock.Lock()
+	defer race.statsLock.Unlock()
+	for _, pv := range race.pageViews {
+		totals[pv.Page] += pv.Views
+	}
+	return totals
+}
+
+// lockedRunnerViews lists the runners whose results were viewed, most viewed first
+func (race *Race) lockedRunnerViews() []RunnerViews {
+	race.statsLock.Lock()
+	defer race.statsLock.Unlock()
+	views := make([]RunnerViews, 0, len(race.runnerViews))
+	for bib, count := range race.runnerViews {
+		if entry, ok := race.bibbedEntries[bib]; ok {
+			views = append(views, RunnerViews{Entry: entry, Views: count})
+		}
+	}
+	sort.Slice(views, func(i, j int) bool {
+		if views[i].Views != views[j].Views {
+			return views[i].Views > views[j].Views
+		}
+		return views[i].Bib < views[j].Bib
+	})
+	return views
+}
+
+// viewReportHandler downloads the hourly page views, or the views per runner with by=runner
+func viewReportHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	byRunner := r.FormValue("by") == "runner"
+	filename := "views"
+	if byRunner {
+		filename = "runner-views"
+	}
+	w.Header().Set("Content-type", "application/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s-%s.csv\"", config.webserverHostname, filename))
+	writer := csv.NewWriter(w)
+	race.RLock()
+	if byRunner {
+		writer.Write([]string{"Bib", "Fname", "Lname", "Views"})
+		for _, rv := range race.lockedRunnerViews() {
+			writer.Write([]string{rv.Bib.String(), rv.Fname, rv.Lname, strconv.FormatUint(rv.Views, 10)})
+		}
+	} else {
+		writer.Write([]string{"Hour", "Page", "Views"})
+		race.statsLock.Lock()
+		for _, pv := range race.pageViews {
+			writer.Write([]string{pv.Hour.Format("2006-01-02 15:04"), pv.Page, strconv.FormatUint(pv.Views, 10)})
+		}
+		race.statsLock.Unlock()
+	}
+	race.RUnlock()
+	writer.Flush()
+}
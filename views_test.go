@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/csv"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPageViews(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{
+		{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 34},
+		{Bib: 2, Fname: "Bob", Lname: "Brown", Male: true, Age: 41},
+	} {
+		if err := race.AddEntry(e); err != nil {
+			t.Fatalf("Error adding entry - %v", err)
+		}
+	}
+	get := func(path string) {
+		r, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		handler(w, r, race)
+	}
+	get("/")
+	get("/admin")
+	get("/lookup?name=brown")
+	*race.testingTime = race.testingTime.Add(70 * time.Minute)
+	get("/")
+	get("/lookup?name=amy")
+
+	if len(race.pageViews) != 4 {
+		t.Fatalf("Expected 4 hourly counts, got %d", len(race.pageViews))
+	}
+	if pv := race.pageViews[2]; pv.Page != "Results" || pv.Views != 1 || pv.Hour.Hour() != 10 {
+		t.Errorf("Expected one results view in the 10:00 hour, got %#v", pv)
+	}
+	totals := race.lockedPageViewTotals()
+	if totals["Results"] != 2 || totals["Lookup"] != 2 || len(totals) != 2 {
+		t.Errorf("Expected 2 results and 2 lookup views and no admin views, got %v", totals)
+	}
+	runners := race.lockedRunnerViews()
+	if len(runners) != 2 || runners[0].Bib != 1 || runners[0].Views != 2 || runners[1].Views != 1 {
+		t.Errorf("Expected Amy looked up twice and Bob once, got %v", runners)
+	}
+
+	r, _ := http.NewRequest("GET", "/viewReport?by=runner", nil)
+	w := httptest.NewRecorder()
+	viewReportHandler(w, r, race)
+	if expected := "Bib,Fname,Lname,Views\n1,Amy,Brown,2\n2,Bob,Brown,1\n"; w.Body.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, w.Body.String())
+	}
+}
+
+func TestPagesDontBlockEachOther(t *testing.T) {
+	race := NewRace()
+	race.AddEntry(Entry{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 34})
+	startRace(race)
+	get := func(path string) {
+		r, _ := http.NewRequest("GET", path, nil)
+		handler(httptest.NewRecorder(), r, race)
+	}
+	race.RLock() // another page being drawn
+	done := make(chan struct{})
+	go func() {
+		get("/lookup?name=amy")
+		race.WriteCSV(csv.NewWriter(ioutil.Discard))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the page and the download drawn alongside another page")
+	}
+	race.RUnlock()
+
+	var wg sync.WaitGroup
+	for x := 0; x < 8; x++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for y := 0; y < 10; y++ {
+				get("/")
+				get("/lookup?name=amy")
+			}
+		}()
+	}
+	wg.Wait()
+	race.RLock()
+	defer race.RUnlock()
+	if views := race.lockedPageViewTotals(); views["Results"] != 80 || views["Lookup"] != 81 {
+		t.Errorf("Expected every view counted, got %v", views)
+	}
+	if views := race.lockedRunnerViews(); len(views) != 1 || views[0].Views != 81 {
+		t.Errorf("Expected every lookup of Amy counted, got %v", views)
+	}
+}
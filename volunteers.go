@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VolunteerShift is a volunteer's time on race day, from check in to check out
+type VolunteerShift struct {
+	ID       int
+	Name     string
+	Role     string
+	CheckIn  time.Time
+	CheckOut time.Time // zero while still on shift
+}
+
+// Hours is how long the shift was, up to now if they haven't checked out
+func (vs VolunteerShift) Hours(now time.Time) float64 {
+	end := vs.CheckOut
+	if end.IsZero() {
+		end = now
+	}
+	return end.Sub(vs.CheckIn).Hours()
+}
+
+// CheckInVolunteer starts a shift for the volunteer, who can work more than one role over the day
+func (race *Race) CheckInVolunteer(name, role string) error {
+	name, role = strings.TrimSpace(name), strings.TrimSpace(role)
+	if name == "" {
+		return fmt.Errorf("The volunteer's name is required")
+	}
+	race.Lock()
+	defer race.Unlock()
+	for _, vs := range race.volunteers {
+		if vs.CheckOut.IsZero() && strings.EqualFold(vs.Name, name) {
+			return fmt.Errorf("%s is already checked in as %s", vs.Name, vs.Role)
+		}
+	}
+	race.nextVolunteerID++
+	race.volunteers = append(race.volunteers, &VolunteerShift{ID: race.nextVolunteerID, Name: name, Role: role, CheckIn: race.GetTime()})
+	log.Printf("Volunteer %s checked in as %s", name, role)
+	return nil
+}
+
+// CheckOutVolunteer ends the shift
+func (race *Race) CheckOutVolunteer(id int) error {
+	race.Lock()
+	defer race.Unlock()
+	for _, vs := range race.volunteers {
+		if vs.ID == id {
+			if !vs.CheckOut.IsZero() {
+				return fmt.Errorf("%s already checked out at %s", vs.Name, vs.CheckOut.Format("3:04 PM"))
+			}
+			vs.CheckOut = race.GetTime()
+			log.Printf("Volunteer %s checked out after %.2f hours", vs.Name, vs.Hours(vs.CheckOut))
+			return nil
+		}
+	}
+	return fmt.Errorf("Volunteer shift %d not found", id)
+}
+
+func checkInVolunteerHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	if err := race.CheckInVolunteer(r.FormValue("name"), r.FormValue("role")); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/volunteers", 301)
+}
+
+func checkOutVolunteerHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		showErrorForAdmin(w, r.Referer(), "Error %s getting volunteer shift", err)
+		return
+	}
+	if err = race.CheckOutVolunteer(id); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/volunteers", 301)
+}
+
+// volunteerHoursHandler exports every shift for club volunteer credit programs
+func volunteerHoursHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	w.Header().Set("Content-type", "application/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s-volunteers.csv\"", config.webserverHostname))
+	writer := csv.NewWriter(w)
+	race.RLock()
+	race.lockedWriteVolunteerHours(writer)
+	race.RUnlock()
+	writer.Flush()
+}
+
+func (race *Race) lockedWriteVolunteerHours(writer *csv.Writer) {
+	now := race.GetTime()
+	writer.Write([]string{"Name", "Role", "Check In", "Check Out", "Hours"})
+	for _, vs := range race.volunteers {
+		checkOut := ""
+		if !vs.CheckOut.IsZero() {
+			checkOut = vs.CheckOut.Format("2006-01-02 15:04")
+		}
+		writer.Write([]string{vs.Name, vs.Role, vs.CheckIn.Format("2006-01-02 15:04"), checkOut, strconv.FormatFloat(vs.Hours(now), 'f', 2, 64)})
+	}
+}
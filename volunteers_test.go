@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVolunteerHours(t *testing.T) {
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 7, 0, 0, 0, time.Local)
+	if err := race.CheckInVolunteer(" ", "Water Stop"); err == nil {
+		t.Errorf("Expected error checking in without a name")
+	}
+	if err := race.CheckInVolunteer("Dee Dunn", "Water Stop"); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if err := race.CheckInVolunteer("dee dunn", "Course Marshal"); err == nil {
+		t.Errorf("Expected error checking in someone already on shift")
+	}
+	if err := race.CheckInVolunteer("Eve Earl", "Registration"); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	*race.testingTime = race.testingTime.Add(150 * time.Minute)
+	if err := race.CheckOutVolunteer(1); err != nil {
+		t.Fatalf("Unexpected error - %v", err)
+	}
+	if err := race.CheckOutVolunteer(1); err == nil {
+		t.Errorf("Expected error checking out twice")
+	}
+	*race.testingTime = race.testingTime.Add(30 * time.Minute)
+
+	r, _ := http.NewRequest("GET", "/volunteerHours", nil)
+	w := httptest.NewRecorder()
+	volunteerHoursHandler(w, r, race)
+	expected := "Name,Role,Check In,Check Out,Hours\nDee Dunn,Water Stop,2014-06-01 07:00,2014-06-01 09:30,2.50\nEve Earl,Registration,2014-06-01 07:00,,3.00\n"
+	if w.Body.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, w.Body.String())
+	}
+
+	r, _ = http.NewRequest("GET", "/volunteers", nil)
+	w = httptest.NewRecorder()
+	handler(w, r, race)
+	if !strings.Contains(w.Body.String(), "Eve Earl") || strings.Count(w.Body.String(), "Check Out</button>") != 1 {
+		t.Errorf("Expected Eve still on shift - %s", w.Body.String())
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Wave is a group of racers started together.  Racers are in the wave named in their RACERGOWAVEFIELD,
+// otherwise the first wave with their bib or division.
+type Wave struct {
+	Name      string
+	Offset    time.Duration // how long after the first wave it goes, unless At is set
+	At        time.Duration // the time of day it goes, 0 to go at Offset
+	Bibs      [][2]Bib      // the bib ranges in the wave
+	Divisions []string      // the divisions in the wave
+}
+
+// WaveStatus is a wave and when it started, for the start buttons
+type WaveStatus struct {
+	Name    string
+	Started time.Time // zero until it goes
+}
+
+var bibRange = regexp.MustCompile(`^(\d+)-(\d+)$`)
+
+// parseWaves reads RACERGOWAVES entries like name[=start][@racers].  The start is +10m to go 10 minutes after the
+// first wave or 9:30 to go at half past nine, and the racers are bib ranges or divisions separated by |,
+// e.g. Elite@1-99, Open=+10m@100-1999, Kids=9:30@F1-12|M1-12
+func parseWaves(list []string) ([]Wave, error) {
+	waves := make([]Wave, 0, len(list))
+	for _, spec := range list {
+		var wave Wave
+		name := spec
+		if x := strings.Index(name, "@"); x >= 0 {
+			for _, racers := range strings.Split(name[x+1:], "|") {
+				racers = strings.TrimSpace(racers)
+				if m := bibRange.FindStringSubmatch(racers); m != nil {
+					low, _ := strconv.Atoi(m[1])
+					high, _ := strconv.Atoi(m[2])
+					if low > high {
+						return nil, fmt.Errorf("wave %s has bibs %s, the low bib must come first", spec, racers)
+					}
+					wave.Bibs = append(wave.Bibs, [2]Bib{Bib(low), Bib(high)})
+				} else if racers != "" {
+					wave.Divisions = append(wave.Divisions, racers)
+				}
+			}
+			name = name[:x]
+		}
+		if x := strings.Index(name, "="); x >= 0 {
+			start := strings.TrimSpace(name[x+1:])
+			name = name[:x]
+			if strings.HasPrefix(start, "+") {
+				offset, err := time.ParseDuration(start[1:])
+				if err != nil || offset < 0 {
+					return nil, fmt.Errorf("wave %s must start a duration like +10m after the first wave, not %s", spec, start)
+				}
+				wave.Offset = offset
+			} else {
+				at, err := time.Parse("15:04:05", start)
+				if err != nil {
+					at, err = time.Parse("15:04", start)
+				}
+				if err != nil {
+					return nil, fmt.Errorf("wave %s must start at a time of day like 9:30, not %s", spec, start)
+				}
+				wave.At = time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute + time.Duration(at.Second())*time.Second
+			}
+		}
+		wave.Name = strings.TrimSpace(name)
+		if wave.Name == "" {
+			return nil, fmt.Errorf("wave %s needs a name", spec)
+		}
+		for _, w := range waves {
+			if strings.EqualFold(w.Name, wave.Name) {
+				return nil, fmt.Errorf("there are two %s waves", wave.Name)
+			}
+		}
+		waves = append(waves, wave)
+	}
+	return waves, nil
+}
+
+func findWave(name string) *Wave {
+	for x := range config.waveStarts {
+		if strings.EqualFold(config.waveStarts[x].Name, name) {
+			return &config.waveStarts[x]
+		}
+	}
+	return nil
+}
+
+// lockedWaveOf is the wave the entry starts in, nil if the race doesn't start in waves or the entry isn't in one
+func (race *Race) lockedWaveOf(e *Entry) *Wave {
+	if len(config.waveStarts) == 0 {
+		return nil
+	}
+	if x := race.lockedOptionalIndex(config.waveField); x >= 0 && x < len(e.Optional) {
+		if wave := findWave(strings.TrimSpace(e.Optional[x])); wave != nil {
+			return wave
+		}
+	}
+	for x, wave := range config.waveStarts {
+		for _, bibs := range wave.Bibs {
+			if e.Bib >= bibs[0] && e.Bib <= bibs[1] {
+				return &config.waveStarts[x]
+			}
+		}
+	}
+	division := race.lockedDivisionOf(e)
+	for x, wave := range config.waveStarts {
+		for _, d := range wave.Divisions {
+			if strings.EqualFold(d, division) {
+				return &config.waveStarts[x]
+			}
+		}
+	}
+	return nil
+}
+
+func (race *Race) lockedOptionalIndex(field string) int {
+	for x, fn := range race.optionalEntryFields {
+		if fn == field {
+			return x
+		}
+	}
+	return -1
+}
+
+// lockedWaveStart is when the wave went, by the start button if it was pressed or else by its schedule
+func (race *Race) lockedWaveStart(wave *Wave) time.Time {
+	if at, ok := race.waveStarts[wave.Name]; ok {
+		return at
+	}
+	if race.started.IsZero() {
+		return race.started
+	}
+	if wave.At > 0 {
+		y, m, d := race.started.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, race.started.Location()).Add(wave.At)
+	}
+	return race.started.Add(wave.Offset)
+}
+
+// lockedStartOf is when the entry's wave started, their time is measured from then
+func (race *Race) lockedStartOf(e *Entry) time.Time {
+	if wave := race.lockedWaveOf(e); wave != nil {
+		return race.lockedWaveStart(wave)
+	}
+	return race.started
+}
+
+// lockedWaves is every wave with when it started
+func (race *Race) lockedWaves() []WaveStatus {
+	waves := make([]WaveStatus, len(config.waveStarts))
+	for x := range config.waveStarts {
+		waves[x].Name = config.waveStarts[x].Name
+		if at, ok := race.waveStarts[config.waveStarts[x].Name]; ok {
+			waves[x].Started = at
+		}
+	}
+	return waves
+}
+
+// StartWave starts the wave now, or at t, starting the race too if it's the first wave to go
+func (race *Race) StartWave(name string, t *time.Time, source string) error {
+	race.Lock()
+	defer race.Unlock()
+	return race.lockedStartWave(name, t, source)
+}
+
+func (race *Race) lockedStartWave(name string, t *time.Time, source string) error {
+	wave := findWave(name)
+	if wave == nil {
+		return fmt.Errorf("There's no %s wave", name)
+	}
+	if race.finalized {
+		return fmt.Errorf("Results have been finalized, cannot start a wave")
+	}
+	if at, ok := race.waveStarts[wave.Name]; ok {
+		return fmt.Errorf("The %s wave already started at %s", wave.Name, at.Format("3:04:05 PM"))
+	}
+	at := race.GetTime()
+	if t != nil {
+		at = withMonotonic(*t)
+	}
+	if race.started.IsZero() {
+		if err := race.lockedStart(&at, source); err != nil {
+			return err
+		}
+	}
+	if race.waveStarts == nil {
+		race.waveStarts = make(map[string]time.Time)
+	}
+	race.waveStarts[wave.Name] = at
+	for _, e := range race.allEntries {
+		if e.HasFinished() && race.lockedWaveOf(e) == wave {
+			e.Duration = HumanDuration(e.TimeFinished.Sub(at))
+		}
+	}
+	race.lockedSortEntries()
+	race.lockedRecomputePrizes()
+	log.Printf("The %s wave started at %s", wave.Name, at.Format("3:04:05.00 PM"))
+	race.lockedRecordEvent(Event{Kind: "waveStart", Time: at, Wave: wave.Name, Source: source})
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseWaves(t *testing.T) {
+	waves, err := parseWaves([]string{"Elite@1-99", "Open=+10m@100-1999", "Kids=9:30@F1-12|M1-12"})
+	if err != nil {
+		t.Fatalf("Error parsing waves - %v", err)
+	}
+	if len(waves) != 3 || waves[0].Name != "Elite" || len(waves[0].Bibs) != 1 || waves[0].Bibs[0] != [2]Bib{1, 99} {
+		t.Errorf("Expected the Elite wave with bibs 1-99, got %#v", waves)
+	}
+	if waves[1].Offset != 10*time.Minute || waves[1].Bibs[0] != [2]Bib{100, 1999} {
+		t.Errorf("Expected the Open wave 10 minutes later, got %#v", waves[1])
+	}
+	if waves[2].At != 9*time.Hour+30*time.Minute || len(waves[2].Divisions) != 2 || waves[2].Divisions[1] != "M1-12" {
+		t.Errorf("Expected the Kids wave at 9:30 with two divisions, got %#v", waves[2])
+	}
+	for _, bad := range [][]string{{"Elite@99-1"}, {"Open=+ten"}, {"Kids=noon"}, {"=+10m"}, {"Elite", "elite"}} {
+		if _, err := parseWaves(bad); err == nil {
+			t.Errorf("Expected %v refused", bad)
+		}
+	}
+}
+
+func TestWaveStarts(t *testing.T) {
+	defer func(waves []string, starts []Wave) {
+		config.waves, config.waveStarts = waves, starts
+	}(config.waves, config.waveStarts)
+	config.waveStarts, _ = parseWaves([]string{"Elite@1-9", "Open=+10m@10-99"})
+	config.waves = []string{"Elite", "Open"}
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	for _, e := range []Entry{{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 38}, {Bib: 10, Fname: "Bob", Lname: "Adams", Male: true, Age: 31}} {
+		race.AddEntry(e)
+	}
+	r, _ := http.NewRequest("POST", "/start?wave=elite", nil)
+	w := httptest.NewRecorder()
+	startHandler(w, r, race)
+	if w.Code != 301 || race.started.IsZero() || !race.waveStarts["Elite"].Equal(race.started) {
+		t.Fatalf("Expected the Elite wave to start the race, got %d %s", w.Code, w.Body.String())
+	}
+	if err := race.StartWave("Elite", nil, ""); err == nil {
+		t.Errorf("Expected starting the Elite wave twice refused")
+	}
+	*race.testingTime = race.testingTime.Add(20 * time.Minute)
+	linkBibTesting(t, race, 1, false)
+	linkBibTesting(t, race, 10, false)
+	amy, bob := race.bibbedEntries[1], race.bibbedEntries[10]
+	if amy.Duration != HumanDuration(20*time.Minute) || bob.Duration != HumanDuration(10*time.Minute) {
+		t.Errorf("Expected Bob timed from the Open wave's scheduled start, got %s and %s", amy.Duration, bob.Duration)
+	}
+	if race.allEntries[0] != bob {
+		t.Errorf("Expected Bob first on his wave's time")
+	}
+	open := race.started.Add(12 * time.Minute)
+	if err := race.StartWave("Open", &open, "Start button"); err != nil {
+		t.Fatalf("Error starting the Open wave - %v", err)
+	}
+	if bob.Duration != HumanDuration(8*time.Minute) || amy.Duration != HumanDuration(20*time.Minute) {
+		t.Errorf("Expected Bob retimed from when the Open wave went, got %s and %s", amy.Duration, bob.Duration)
+	}
+
+	replayed := NewRace()
+	if err := replayed.Replay(race.events); err != nil {
+		t.Fatalf("Error replaying - %v", err)
+	}
+	if got := replayed.bibbedEntries[10].Duration; got != bob.Duration {
+		t.Errorf("Expected the wave start replayed, got %s for Bob", got)
+	}
+
+	if err := race.Undo(); err != nil {
+		t.Fatalf("Error undoing the Open wave start - %v", err)
+	}
+	if _, ok := race.waveStarts["Open"]; ok || !race.waveStarts["Elite"].Round(0).Equal(race.started.Round(0)) {
+		t.Errorf("Expected only the Open wave start taken back, got %v", race.waveStarts)
+	}
+	if got := race.bibbedEntries[10].Duration; got != HumanDuration(10*time.Minute) {
+		t.Errorf("Expected Bob timed from the scheduled start again, got %s", got)
+	}
+	if err := race.StartWave("Open", &open, "Start button"); err != nil {
+		t.Errorf("Expected the Open wave started again after the undo - %v", err)
+	}
+}
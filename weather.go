@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Conditions is the weather when it was logged at the start, the finish or by hand
+type Conditions struct {
+	At            time.Time `json:"at"`
+	When          string    `json:"when"` // Start, Finish or Logged
+	Temperature   float64   `json:"temperature"`
+	Wind          float64   `json:"wind"`
+	Precipitation float64   `json:"precipitation"`
+	Units         struct {
+		Temperature   string `json:"temperature_2m"`
+		Wind          string `json:"wind_speed_10m"`
+		Precipitation string `json:"precipitation"`
+	} `json:"units"`
+}
+
+func (c Conditions) String() string {
+	return fmt.Sprintf("%.0f%s, wind %.0f %s, precipitation %.1f %s", c.Temperature, c.Units.Temperature, c.Wind, c.Units.Wind, c.Precipitation, c.Units.Precipitation)
+}
+
+// fetchConditions reads the current weather from RACERGOWEATHERURL, which answers like Open-Meteo's current weather, e.g.
+// https://api.open-meteo.com/v1/forecast?latitude=39.74&longitude=-104.99&current=temperature_2m,wind_speed_10m,precipitation
+func fetchConditions(url string) (Conditions, error) {
+	var c Conditions
+	resp, err := syncClient.Get(url)
+	if err != nil {
+		return c, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return c, fmt.Errorf("%s", resp.Status)
+	}
+	var current struct {
+		Current struct {
+			Temperature   *float64 `json:"temperature_2m"`
+			Wind          float64  `json:"wind_speed_10m"`
+			Precipitation float64  `json:"precipitation"`
+		} `json:"current"`
+		Units json.RawMessage `json:"current_units"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&current); err != nil {
+		return c, err
+	}
+	if current.Current.Temperature == nil {
+		return c, fmt.Errorf("No current temperature in the weather, ask for current=temperature_2m,wind_speed_10m,precipitation")
+	}
+	c.Temperature, c.Wind, c.Precipitation = *current.Current.Temperature, current.Current.Wind, current.Current.Precipitation
+	if len(current.Units) > 0 {
+		json.Unmarshal(current.Units, &c.Units)
+	}
+	return c, nil
+}
+
+// LogConditions fetches the weather and keeps it with the race, doing nothing unless RACERGOWEATHERURL is set
+func (race *Race) LogConditions(when string) error {
+	if config.weatherURL == "" {
+		return nil
+	}
+	c, err := fetchConditions(config.weatherURL)
+	if err != nil {
+		return fmt.Errorf("Error getting the weather - %v", err)
+	}
+	race.Lock()
+	defer race.Unlock()
+	c.At, c.When = race.GetTime(), when
+	race.weather = append(race.weather, c)
+	race.lockedRecordEvent(Event{Kind: "weather", Time: c.At, Weather: &c})
+	log.Printf("%s conditions - %s", when, c)
+	return nil
+}
+
+// logConditionsLater fetches the weather in the background so a slow provider never holds up the start or finalizing
+func (race *Race) logConditionsLater(when string) {
+	if config.weatherURL == "" || race.replaying {
+		return
+	}
+	go func() {
+		if err := race.LogConditions(when); err != nil {
+			log.Printf("%v", err)
+		}
+	}()
+}
+
+func logConditionsHandler(w http.ResponseWriter, r *http.Request, race *Race) {
+	if config.weatherURL == "" {
+		showErrorForAdmin(w, r.Referer(), "Weather isn't configured, set RACERGOWEATHERURL")
+		return
+	}
+	if err := race.LogConditions("Logged"); err != nil {
+		showErrorForAdmin(w, r.Referer(), "%v", err)
+		return
+	}
+	http.Redirect(w, r, "/admin", 301)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWeather(t *testing.T) {
+	temperature := 12.4
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"current_units":{"temperature_2m":"°C","wind_speed_10m":"km/h","precipitation":"mm"},"current":{"temperature_2m":%.1f,"wind_speed_10m":8.2,"precipitation":0.0}}`, temperature)
+	}))
+	defer provider.Close()
+	weatherURL := config.weatherURL
+	defer func() { config.weatherURL = weatherURL }()
+	config.weatherURL = provider.URL
+
+	race := NewRace()
+	race.testingTime = &time.Time{}
+	*race.testingTime = time.Date(2014, 6, 1, 9, 0, 0, 0, time.Local)
+	if err := race.AddEntry(Entry{Bib: 1, Fname: "Amy", Lname: "Brown", Age: 28}); err != nil {
+		t.Fatalf("Error adding entry - %v", err)
+	}
+	if err := race.LogConditions("Start"); err != nil {
+		t.Fatalf("Unexpected error logging the weather - %v", err)
+	}
+	temperature = 18
+	*race.testingTime = race.testingTime.Add(time.Hour)
+	if err := race.LogConditions("Finish"); err != nil {
+		t.Fatalf("Unexpected error logging the weather - %v", err)
+	}
+	if len(race.weather) != 2 || race.weather[0].String() != "12°C, wind 8 km/h, precipitation 0.0 mm" || race.weather[1].Temperature != 18 {
+		t.Errorf("Expected start and finish conditions, got %v", race.weather)
+	}
+
+	r, _ := http.NewRequest("GET", "/results/print", nil)
+	w := httptest.NewRecorder()
+	handler(w, r, race)
+	if body := w.Body.String(); !strings.Contains(body, "Start conditions at 9:00 AM: 12°C") || !strings.Contains(body, "Finish conditions at 10:00 AM: 18°C") {
+		t.Errorf("Expected the conditions on the printed results - %s", body)
+	}
+
+	replayed := NewRace()
+	if err := replayed.Replay(race.events); err != nil {
+		t.Fatalf("Unexpected error replaying - %v", err)
+	}
+	if len(replayed.weather) != 2 {
+		t.Errorf("Expected the conditions kept in the event log, got %v", replayed.weather)
+	}
+	if err := race.Undo(); err != nil {
+		t.Fatalf("Unexpected error undoing - %v", err)
+	}
+	if len(race.allEntries) != 0 || len(race.weather) != 2 {
+		t.Errorf("Expected undo to take back adding Amy and keep the weather, got %d entries and %v", len(race.allEntries), race.weather)
+	}
+
+	config.weatherURL = provider.URL + "/missing"
+	provider.Config.Handler = http.NotFoundHandler()
+	if err := race.LogConditions("Logged"); err == nil {
+		t.Errorf("Expected an error when the provider fails")
+	}
+}